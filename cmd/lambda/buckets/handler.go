@@ -5,16 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
-	"sync"
-	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 
 	"github.com/forstmeier/findfile/pkg/db"
 	"github.com/forstmeier/findfile/pkg/evt"
 	"github.com/forstmeier/findfile/pkg/fs"
-	"github.com/forstmeier/findfile/pkg/pars"
+	"github.com/forstmeier/findfile/pkg/queue"
 	"github.com/forstmeier/findfile/util"
 )
 
@@ -26,7 +23,8 @@ type requestsPayload struct {
 func handler(
 	evtClient evt.Eventer,
 	fsClient fs.Filesystemer,
-	parsClient pars.Parser,
+	queueClient queue.Queuer,
+	queueURL string,
 	dbClient db.Databaser,
 	httpSecurityHeader, httpSecurityKey string,
 ) func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
@@ -57,6 +55,7 @@ func handler(
 			)
 		}
 
+		filesQueued := 0
 		if requestJSON.Add != nil {
 			if err := evtClient.AddBucketListeners(ctx, requestJSON.Add); err != nil {
 				return util.SendResponse(
@@ -66,9 +65,6 @@ func handler(
 				)
 			}
 
-			ctx, cancel := context.WithTimeout(ctx, 25*time.Second)
-			defer cancel()
-
 			for _, bucket := range requestJSON.Add {
 				fileKeys, err := fsClient.ListFiles(ctx, bucket)
 				if err != nil {
@@ -79,78 +75,35 @@ func handler(
 					)
 				}
 
-				chunk := 25
-				for i := 0; i < len(fileKeys); i += chunk {
-					var waitGroup sync.WaitGroup
-
-					end := i + chunk
-					if end > len(fileKeys) {
-						end = len(fileKeys)
-					}
-
-					documentsChannel := make(chan pars.Document, chunk)
-					errorsChannel := make(chan error)
-					doneChannel := make(chan bool)
-
-					chunkFileKeys := fileKeys[i:end]
-					for _, chunkFileKey := range chunkFileKeys {
-						waitGroup.Add(1)
-						if !hasSuffix(chunkFileKey) {
-							waitGroup.Done()
-							continue
-						}
-
-						go func(bucket, chunkFileKey string) {
-							defer waitGroup.Done()
-
-							select {
-							case <-errorsChannel:
-								return
-							case <-ctx.Done():
-								return
-							default:
-							}
-
-							document, err := parsClient.Parse(ctx, bucket, chunkFileKey)
-							if err != nil {
-								errorsChannel <- err
-								return
-							}
-							documentsChannel <- *document
-
-						}(bucket, chunkFileKey)
-					}
-
-					go func() {
-						waitGroup.Wait()
-						close(doneChannel)
-					}()
-
-					select {
-					case <-doneChannel:
-						close(documentsChannel)
-					case err := <-errorsChannel:
-						close(errorsChannel)
+				bodies := []string{}
+				for _, fileKey := range fileKeys {
+					body, err := evt.WriteMessage([]evt.Object{
+						{
+							Action: evt.PutObject,
+							Bucket: bucket,
+							Key:    fileKey,
+						},
+					})
+					if err != nil {
 						return util.SendResponse(
 							http.StatusInternalServerError,
 							err,
-							"PARSE_DOCUMENTS_ERROR",
+							"WRITE_MESSAGE_ERROR",
 						)
 					}
 
-					documents := []pars.Document{}
-					for document := range documentsChannel {
-						documents = append(documents, document)
-					}
+					bodies = append(bodies, body)
+				}
 
-					if err := dbClient.UpsertDocuments(ctx, documents); err != nil {
-						return util.SendResponse(
-							http.StatusInternalServerError,
-							err,
-							"UPSERT_DOCUMENTS_ERROR",
-						)
-					}
+				if err := queueClient.SendMessages(ctx, queueURL, bodies); err != nil {
+					return util.SendResponse(
+						http.StatusInternalServerError,
+						err,
+						"SEND_MESSAGES_ERROR",
+					)
 				}
+
+				filesQueued += len(bodies)
 			}
 		}
 
@@ -177,20 +130,9 @@ func handler(
 			map[string]int{
 				"buckets_added":   len(requestJSON.Add),
 				"buckets_removed": len(requestJSON.Remove),
+				"files_queued":    filesQueued,
 			},
 			"RESPONSE_BODY",
 		)
 	}
 }
-
-func hasSuffix(fileKey string) bool {
-	suffixes := []string{"png", "jpg", "jpeg", "tiff", "pdf"}
-
-	for _, suffix := range suffixes {
-		if strings.HasSuffix(fileKey, suffix) {
-			return true
-		}
-	}
-
-	return false
-}
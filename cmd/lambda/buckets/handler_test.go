@@ -6,12 +6,15 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 
 	"github.com/forstmeier/findfile/pkg/db"
 	"github.com/forstmeier/findfile/pkg/pars"
+	"github.com/forstmeier/findfile/pkg/queue"
 )
 
 func TestMain(m *testing.M) {
@@ -41,17 +44,33 @@ func (m *mockFSClient) ListFiles(ctx context.Context, bucket string) ([]string,
 	return m.mockListFilesOutput, m.mockListFilesError
 }
 
-type mockParsClient struct {
-	mockParseOutput *pars.Document
-	mockParseError  error
+type mockQueueClient struct {
+	mockSendMessagesBodies []string
+	mockSendMessagesError  error
 }
 
-func (m *mockParsClient) Parse(ctx context.Context, fileBucket, fileKey string) (*pars.Document, error) {
-	return m.mockParseOutput, m.mockParseError
+func (m *mockQueueClient) SendMessage(ctx context.Context, queueURL, body string, attributes map[string]string) error {
+	return nil
+}
+
+func (m *mockQueueClient) SendMessages(ctx context.Context, queueURL string, bodies []string) error {
+	m.mockSendMessagesBodies = append(m.mockSendMessagesBodies, bodies...)
+	return m.mockSendMessagesError
+}
+
+func (m *mockQueueClient) ReceiveMessages(ctx context.Context, queueURL string, maxMessages int) ([]queue.Message, error) {
+	return nil, nil
+}
+
+func (m *mockQueueClient) ChangeMessageVisibility(ctx context.Context, queueURL, receiptHandle string, timeout time.Duration) error {
+	return nil
+}
+
+func (m *mockQueueClient) DeleteMessage(ctx context.Context, queueURL, receiptHandle string) error {
+	return nil
 }
 
 type mockDBClient struct {
-	mockUpsertDocumentsError          error
 	mockDeleteDocumentsByBucketsError error
 }
 
@@ -60,10 +79,10 @@ func (m *mockDBClient) SetupDatabase(ctx context.Context) error {
 }
 
 func (m *mockDBClient) UpsertDocuments(ctx context.Context, documents []pars.Document) error {
-	return m.mockUpsertDocumentsError
+	return nil
 }
 
-func (m *mockDBClient) DeleteDocumentsByIDs(ctx context.Context, documentIDs []string) error {
+func (m *mockDBClient) DeleteDocumentsByKeys(ctx context.Context, fileKeys []db.FileKey) error {
 	return nil
 }
 
@@ -71,7 +90,7 @@ func (m *mockDBClient) DeleteDocumentsByBuckets(ctx context.Context, buckets []s
 	return m.mockDeleteDocumentsByBucketsError
 }
 
-func (m *mockDBClient) QueryDocuments(ctx context.Context, query db.Query) ([]pars.Document, error) {
+func (m *mockDBClient) QueryDocuments(ctx context.Context, query db.Query) (*db.Result, error) {
 	return nil, nil
 }
 
@@ -83,10 +102,9 @@ func Test_handler(t *testing.T) {
 		mockRemoveBucketListenersError    error
 		mockListFilesOutput               []string
 		mockListFilesError                error
-		mockParseOutput                   *pars.Document
-		mockParseError                    error
-		mockUpsertDocumentsError          error
+		mockSendMessagesError             error
 		mockDeleteDocumentsByBucketsError error
+		bodies                            []string
 		statusCode                        int
 		body                              string
 	}{
@@ -97,10 +115,9 @@ func Test_handler(t *testing.T) {
 			mockRemoveBucketListenersError:    nil,
 			mockListFilesOutput:               nil,
 			mockListFilesError:                nil,
-			mockParseOutput:                   nil,
-			mockParseError:                    nil,
-			mockUpsertDocumentsError:          nil,
+			mockSendMessagesError:             nil,
 			mockDeleteDocumentsByBucketsError: nil,
+			bodies:                            nil,
 			statusCode:                        400,
 			body:                              `{"error":"security key header 'http-security-header' not provided"}`,
 		},
@@ -115,10 +132,9 @@ func Test_handler(t *testing.T) {
 			mockRemoveBucketListenersError:    nil,
 			mockListFilesOutput:               nil,
 			mockListFilesError:                nil,
-			mockParseOutput:                   nil,
-			mockParseError:                    nil,
-			mockUpsertDocumentsError:          nil,
+			mockSendMessagesError:             nil,
 			mockDeleteDocumentsByBucketsError: nil,
+			bodies:                            nil,
 			statusCode:                        400,
 			body:                              `{"error":"security key 'incorrect-value' incorrect"}`,
 		},
@@ -134,10 +150,9 @@ func Test_handler(t *testing.T) {
 			mockRemoveBucketListenersError:    nil,
 			mockListFilesOutput:               nil,
 			mockListFilesError:                nil,
-			mockParseOutput:                   nil,
-			mockParseError:                    nil,
-			mockUpsertDocumentsError:          nil,
+			mockSendMessagesError:             nil,
 			mockDeleteDocumentsByBucketsError: nil,
+			bodies:                            nil,
 			statusCode:                        400,
 			body:                              `{"error":"invalid character 'i' looking for beginning of value"}`,
 		},
@@ -153,10 +168,9 @@ func Test_handler(t *testing.T) {
 			mockRemoveBucketListenersError:    nil,
 			mockListFilesOutput:               nil,
 			mockListFilesError:                nil,
-			mockParseOutput:                   nil,
-			mockParseError:                    nil,
-			mockUpsertDocumentsError:          nil,
+			mockSendMessagesError:             nil,
 			mockDeleteDocumentsByBucketsError: nil,
+			bodies:                            nil,
 			statusCode:                        500,
 			body:                              `{"error":"mock add bucket listeners error"}`,
 		},
@@ -172,15 +186,14 @@ func Test_handler(t *testing.T) {
 			mockRemoveBucketListenersError:    nil,
 			mockListFilesOutput:               nil,
 			mockListFilesError:                errors.New("mock list files error"),
-			mockParseOutput:                   nil,
-			mockParseError:                    nil,
-			mockUpsertDocumentsError:          nil,
+			mockSendMessagesError:             nil,
 			mockDeleteDocumentsByBucketsError: nil,
+			bodies:                            nil,
 			statusCode:                        500,
 			body:                              `{"error":"mock list files error"}`,
 		},
 		{
-			description: "parse error",
+			description: "send messages error",
 			request: events.APIGatewayProxyRequest{
 				Headers: map[string]string{
 					"http-security-header": "http-security-header-value",
@@ -191,31 +204,13 @@ func Test_handler(t *testing.T) {
 			mockRemoveBucketListenersError:    nil,
 			mockListFilesOutput:               []string{"key.jpeg"},
 			mockListFilesError:                nil,
-			mockParseOutput:                   nil,
-			mockParseError:                    errors.New("mock parse error"),
-			mockUpsertDocumentsError:          nil,
+			mockSendMessagesError:             errors.New("mock send messages error"),
 			mockDeleteDocumentsByBucketsError: nil,
-			statusCode:                        500,
-			body:                              `{"error":"mock parse error"}`,
-		},
-		{
-			description: "upsert documents error",
-			request: events.APIGatewayProxyRequest{
-				Headers: map[string]string{
-					"http-security-header": "http-security-header-value",
-				},
-				Body: `{"add": ["bucket"]}`,
+			bodies: []string{
+				`{"Records":[{"eventSource":"aws:s3","eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"bucket"},"object":{"key":"key.jpeg"}}}]}`,
 			},
-			mockAddBucketListenersError:       nil,
-			mockRemoveBucketListenersError:    nil,
-			mockListFilesOutput:               []string{"key.jpeg"},
-			mockListFilesError:                nil,
-			mockParseOutput:                   &pars.Document{},
-			mockParseError:                    nil,
-			mockUpsertDocumentsError:          errors.New("mock upsert documents error"),
-			mockDeleteDocumentsByBucketsError: nil,
-			statusCode:                        500,
-			body:                              `{"error":"mock upsert documents error"}`,
+			statusCode: 500,
+			body:       `{"error":"mock send messages error"}`,
 		},
 		{
 			description: "remove bucket listeners error",
@@ -229,10 +224,9 @@ func Test_handler(t *testing.T) {
 			mockRemoveBucketListenersError:    errors.New("mock remove bucket listeners error"),
 			mockListFilesOutput:               nil,
 			mockListFilesError:                nil,
-			mockParseOutput:                   nil,
-			mockParseError:                    nil,
-			mockUpsertDocumentsError:          nil,
+			mockSendMessagesError:             nil,
 			mockDeleteDocumentsByBucketsError: nil,
+			bodies:                            nil,
 			statusCode:                        500,
 			body:                              `{"error":"mock remove bucket listeners error"}`,
 		},
@@ -248,10 +242,9 @@ func Test_handler(t *testing.T) {
 			mockRemoveBucketListenersError:    nil,
 			mockListFilesOutput:               nil,
 			mockListFilesError:                nil,
-			mockParseOutput:                   nil,
-			mockParseError:                    nil,
-			mockUpsertDocumentsError:          nil,
+			mockSendMessagesError:             nil,
 			mockDeleteDocumentsByBucketsError: errors.New("mock delete documents by buckets error"),
+			bodies:                            nil,
 			statusCode:                        500,
 			body:                              `{"error":"mock delete documents by buckets error"}`,
 		},
@@ -265,14 +258,16 @@ func Test_handler(t *testing.T) {
 			},
 			mockAddBucketListenersError:       nil,
 			mockRemoveBucketListenersError:    nil,
-			mockListFilesOutput:               nil,
+			mockListFilesOutput:               []string{"key.jpeg", "folder/key.pdf"},
 			mockListFilesError:                nil,
-			mockParseOutput:                   nil,
-			mockParseError:                    nil,
-			mockUpsertDocumentsError:          nil,
+			mockSendMessagesError:             nil,
 			mockDeleteDocumentsByBucketsError: nil,
-			statusCode:                        200,
-			body:                              `{"message":"success","buckets_added":1,"buckets_removed":1}`,
+			bodies: []string{
+				`{"Records":[{"eventSource":"aws:s3","eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"add_bucket"},"object":{"key":"key.jpeg"}}}]}`,
+				`{"Records":[{"eventSource":"aws:s3","eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"add_bucket"},"object":{"key":"folder%2Fkey.pdf"}}}]}`,
+			},
+			statusCode: 200,
+			body:       `{"message":"success","buckets_added":1,"buckets_removed":1,"files_queued":2}`,
 		},
 	}
 
@@ -288,20 +283,19 @@ func Test_handler(t *testing.T) {
 				mockListFilesError:  test.mockListFilesError,
 			}
 
-			parsClient := &mockParsClient{
-				mockParseOutput: test.mockParseOutput,
-				mockParseError:  test.mockParseError,
+			queueClient := &mockQueueClient{
+				mockSendMessagesError: test.mockSendMessagesError,
 			}
 
 			dbClient := &mockDBClient{
-				mockUpsertDocumentsError:          test.mockUpsertDocumentsError,
 				mockDeleteDocumentsByBucketsError: test.mockDeleteDocumentsByBucketsError,
 			}
 
 			handlerFunc := handler(
 				evtClient,
 				fsClient,
-				parsClient,
+				queueClient,
+				"queue-url",
 				dbClient,
 				"http-security-header",
 				"http-security-header-value",
@@ -316,6 +310,10 @@ func Test_handler(t *testing.T) {
 			if response.Body != test.body {
 				t.Errorf("incorrect body, received: %q, expected: %q", response.Body, test.body)
 			}
+
+			if !reflect.DeepEqual(queueClient.mockSendMessagesBodies, test.bodies) {
+				t.Errorf("incorrect queued bodies, received: %v, expected: %v", queueClient.mockSendMessagesBodies, test.bodies)
+			}
 		})
 	}
 }
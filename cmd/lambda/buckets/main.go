@@ -9,40 +9,54 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws/session"
 
-	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/db/backend"
 	"github.com/forstmeier/findfile/pkg/evt"
+	"github.com/forstmeier/findfile/pkg/evt/notify"
 	"github.com/forstmeier/findfile/pkg/fs"
-	"github.com/forstmeier/findfile/pkg/pars"
+	"github.com/forstmeier/findfile/pkg/queue"
 )
 
 func main() {
 	newSession := session.New()
 
-	evtClient := evt.New(
-		newSession,
-		os.Getenv("TRAIL_NAME"),
-	)
+	var evtClient evt.Eventer
+	if os.Getenv("EVENTS_BACKEND") == "notifications" {
+		evtClient = notify.New(newSession, notify.Config{
+			ID:       os.Getenv("EVENTS_CONFIGURATION_ID"),
+			QueueARN: os.Getenv("EVENTS_QUEUE_ARN"),
+			RuleName: os.Getenv("EVENTS_RULE_NAME"),
+		})
+	} else {
+		evtClient = evt.New(
+			newSession,
+			os.Getenv("TRAIL_NAME"),
+		)
+	}
 
 	fsClient := fs.New(
 		newSession,
 	)
 
-	parsClient := pars.New(
+	queueClient := queue.New(
 		newSession,
+		os.Getenv("QUEUE_ENDPOINT"),
 	)
 
-	dbClient, err := db.New(
-		newSession,
-		os.Getenv("DATABASE_URL"),
-		os.Getenv("DATABASE_USERNAME"),
-		os.Getenv("DATABASE_PASSWORD"),
-	)
+	dbClient, err := backend.New(newSession, backend.Config{
+		Backend:  os.Getenv("DATABASE_BACKEND"),
+		URL:      os.Getenv("DATABASE_URL"),
+		Username: os.Getenv("DATABASE_USERNAME"),
+		Password: os.Getenv("DATABASE_PASSWORD"),
+		Analyzer: os.Getenv("DATABASE_ANALYZER"),
+		Path:     os.Getenv("DATABASE_PATH"),
+	})
 	if err != nil {
 		panic(fmt.Sprintf("error creating db client: %v", err))
 	}
 
+	queueURL := os.Getenv("QUEUE_URL")
 	httpSecurityHeader := os.Getenv("HTTP_SECURITY_HEADER")
 	httpSecurityKey := os.Getenv("HTTP_SECURITY_KEY")
 
-	lambda.Start(handler(evtClient, fsClient, parsClient, dbClient, httpSecurityHeader, httpSecurityKey))
+	lambda.Start(handler(evtClient, fsClient, queueClient, queueURL, dbClient, httpSecurityHeader, httpSecurityKey))
 }
@@ -9,9 +9,31 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 
 	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/pars"
 	"github.com/forstmeier/findfile/util"
 )
 
+type responsePayload struct {
+	Message   string   `json:"message"`
+	FilePaths []string `json:"file_paths"`
+	Results   []result `json:"results"`
+	Total     int64    `json:"total"`
+	Next      string   `json:"next,omitempty"`
+}
+
+type result struct {
+	FilePath string  `json:"file_path"`
+	Matches  []match `json:"matches"`
+}
+
+type match struct {
+	PageNumber  int64            `json:"page_number"`
+	Text        string           `json:"text"`
+	Highlight   string           `json:"highlight"`
+	Coordinates pars.Coordinates `json:"coordinates"`
+	Words       []pars.Word      `json:"words,omitempty"`
+}
+
 func handler(
 	dbClient db.Databaser,
 	httpSecurityHeader, httpSecurityKey string,
@@ -43,7 +65,15 @@ func handler(
 			)
 		}
 
-		documents, err := dbClient.QueryDocuments(ctx, requestJSON)
+		if err := requestJSON.Validate(); err != nil {
+			return util.SendResponse(
+				http.StatusBadRequest,
+				err,
+				"INVALID_QUERY_ERROR",
+			)
+		}
+
+		queryResult, err := dbClient.QueryDocuments(ctx, requestJSON)
 		if err != nil {
 			return util.SendResponse(
 				http.StatusInternalServerError,
@@ -53,13 +83,37 @@ func handler(
 		}
 
 		filePaths := []string{}
-		for _, document := range documents {
-			filePaths = append(filePaths, fmt.Sprintf("%s/%s", document.FileBucket, document.FileKey))
+		results := []result{}
+		for _, hit := range queryResult.Hits {
+			filePath := fmt.Sprintf("%s/%s", hit.Document.FileBucket, hit.Document.FileKey)
+			filePaths = append(filePaths, filePath)
+
+			matches := []match{}
+			for _, hitMatch := range hit.Matches {
+				matches = append(matches, match{
+					PageNumber:  hitMatch.PageNumber,
+					Text:        hitMatch.Text,
+					Highlight:   hitMatch.Highlight,
+					Coordinates: hitMatch.Coordinates,
+					Words:       hitMatch.Words,
+				})
+			}
+
+			results = append(results, result{
+				FilePath: filePath,
+				Matches:  matches,
+			})
 		}
 
 		return util.SendResponse(
 			http.StatusOK,
-			filePaths,
+			responsePayload{
+				Message:   "success",
+				FilePaths: filePaths,
+				Results:   results,
+				Total:     queryResult.Total,
+				Next:      queryResult.Next,
+			},
 			"RESPONSE_BODY",
 		)
 	}
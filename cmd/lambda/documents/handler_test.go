@@ -20,7 +20,7 @@ func TestMain(m *testing.M) {
 }
 
 type mockDBClient struct {
-	mockQueryDocumentsOutput []pars.Document
+	mockQueryDocumentsOutput *db.Result
 	mockQueryDocumentsError  error
 }
 
@@ -32,7 +32,7 @@ func (m *mockDBClient) UpsertDocuments(ctx context.Context, documents []pars.Doc
 	return nil
 }
 
-func (m *mockDBClient) DeleteDocumentsByIDs(ctx context.Context, documentIDs []string) error {
+func (m *mockDBClient) DeleteDocumentsByKeys(ctx context.Context, fileKeys []db.FileKey) error {
 	return nil
 }
 
@@ -40,7 +40,7 @@ func (m *mockDBClient) DeleteDocumentsByBuckets(ctx context.Context, documentIDs
 	return nil
 }
 
-func (m *mockDBClient) QueryDocuments(ctx context.Context, query db.Query) ([]pars.Document, error) {
+func (m *mockDBClient) QueryDocuments(ctx context.Context, query db.Query) (*db.Result, error) {
 	return m.mockQueryDocumentsOutput, m.mockQueryDocumentsError
 }
 
@@ -48,7 +48,7 @@ func Test_handler(t *testing.T) {
 	tests := []struct {
 		description              string
 		request                  events.APIGatewayProxyRequest
-		mockQueryDocumentsOutput []pars.Document
+		mockQueryDocumentsOutput *db.Result
 		mockQueryDocumentsError  error
 		statusCode               int
 		body                     string
@@ -87,6 +87,19 @@ func Test_handler(t *testing.T) {
 			statusCode:               400,
 			body:                     `{"error":"invalid character 'i' looking for beginning of value"}`,
 		},
+		{
+			description: "invalid query error",
+			request: events.APIGatewayProxyRequest{
+				Headers: map[string]string{
+					"http-security-header": "http-security-header-value",
+				},
+				Body: `{"query": {"type": "and"}}`,
+			},
+			mockQueryDocumentsOutput: nil,
+			mockQueryDocumentsError:  nil,
+			statusCode:               400,
+			body:                     `{"error":"package db: \"and\" clause requires at least one child clause"}`,
+		},
 		{
 			description: "query documents error",
 			request: events.APIGatewayProxyRequest{
@@ -108,15 +121,40 @@ func Test_handler(t *testing.T) {
 				},
 				Body: `{"text": "lookup text"}`,
 			},
-			mockQueryDocumentsOutput: []pars.Document{
-				{
-					FileBucket: "bucket",
-					FileKey:    "key.jpeg",
+			mockQueryDocumentsOutput: &db.Result{
+				Hits: []db.Hit{
+					{
+						Document: pars.Document{
+							FileBucket: "bucket",
+							FileKey:    "key.jpeg",
+						},
+						Matches: []db.Match{
+							{
+								PageNumber: 1,
+								Text:       "lookup text",
+								Highlight:  "<em>lookup</em> <em>text</em>",
+								Coordinates: pars.Coordinates{
+									TopLeft: pars.Point{
+										X: 0.1,
+										Y: 0.2,
+									},
+								},
+								Words: []pars.Word{
+									{
+										Text:       "lookup",
+										Confidence: 98.5,
+									},
+								},
+							},
+						},
+					},
 				},
+				Total: 2,
+				Next:  "next_cursor",
 			},
 			mockQueryDocumentsError: nil,
 			statusCode:              200,
-			body:                    `{"message":"success","file_paths":["bucket/key.jpeg"]}`,
+			body:                    `{"message":"success","file_paths":["bucket/key.jpeg"],"results":[{"file_path":"bucket/key.jpeg","matches":[{"page_number":1,"text":"lookup text","highlight":"\u003cem\u003elookup\u003c/em\u003e \u003cem\u003etext\u003c/em\u003e","coordinates":{"id":"","entity":"","top_left":{"x":0.1,"y":0.2},"top_right":{"x":0,"y":0},"bottom_left":{"x":0,"y":0},"bottom_right":{"x":0,"y":0}},"words":[{"id":"","entity":"","text":"lookup","confidence":98.5,"coordinates":{"id":"","entity":"","top_left":{"x":0,"y":0},"top_right":{"x":0,"y":0},"bottom_left":{"x":0,"y":0},"bottom_right":{"x":0,"y":0}}}]}]}],"total":2,"next":"next_cursor"}`,
 		},
 	}
 
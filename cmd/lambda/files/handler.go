@@ -2,47 +2,59 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-
-	"github.com/aws/aws-lambda-go/events"
+	"fmt"
 
 	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/evt"
 	"github.com/forstmeier/findfile/pkg/pars"
+	"github.com/forstmeier/findfile/pkg/sniff"
 	"github.com/forstmeier/findfile/util"
 )
 
-type detailsPayload struct {
-	EventName         string            `json:"eventName"`
-	RequestParameters requestParameters `json:"requestParameters"`
-}
-
-type requestParameters struct {
-	BucketName string `json:"bucketName"`
-	Key        string `json:"key"`
-}
-
-func handler(parsClient pars.Parser, dbClient db.Databaser) func(ctx context.Context, event events.CloudWatchEvent) error {
-	return func(ctx context.Context, event events.CloudWatchEvent) error {
-		detailsJSON := detailsPayload{}
-		if err := json.Unmarshal(event.Detail, &detailsJSON); err != nil {
+func handler(sniffClient sniff.Sniffer, parsClient pars.Parser, dbClient db.Databaser) func(ctx context.Context, event evt.Notification) error {
+	return func(ctx context.Context, event evt.Notification) error {
+		objects, err := evt.ReadObjects(event)
+		if err != nil {
 			util.Log("UNMARSHAL_REQUEST_PAYLOAD_ERROR", err.Error())
 			return err
 		}
 
-		if detailsJSON.EventName == "PutObject" {
-			document, err := parsClient.Parse(ctx, detailsJSON.RequestParameters.BucketName, detailsJSON.RequestParameters.Key)
-			if err != nil {
-				util.Log("PARSE_ERROR", err.Error())
-				return err
-			}
+		deleteKeys := []db.FileKey{}
+		for _, object := range latestObjects(objects) {
+			bucket, key := object.Bucket, object.Key
 
-			if err := dbClient.UpsertDocuments(ctx, []pars.Document{*document}); err != nil {
-				util.Log("UPSERT_DOCUMENTS_ERROR", err.Error())
-				return err
+			if object.Action == evt.PutObject {
+				file, err := sniffClient.Sniff(ctx, bucket, key)
+				if err != nil {
+					util.Log("SNIFF_ERROR", err.Error())
+					return err
+				}
+
+				var document *pars.Document
+				if file.Supported {
+					document, err = parsClient.Parse(ctx, file.Metadata)
+					if err != nil {
+						util.Log("PARSE_ERROR", err.Error())
+						return err
+					}
+				} else {
+					util.Log("SKIPPED_FILE", fmt.Sprintf("%s/%s: %s", bucket, key, file.Reason))
+					skipped := pars.NewSkippedDocument(file.Metadata, file.Reason)
+					document = &skipped
+				}
+
+				if err := dbClient.UpsertDocuments(ctx, []pars.Document{*document}); err != nil {
+					util.Log("UPSERT_DOCUMENTS_ERROR", err.Error())
+					return err
+				}
+
+			} else if object.Action == evt.DeleteObject {
+				deleteKeys = append(deleteKeys, db.FileKey{Bucket: bucket, Key: key})
 			}
+		}
 
-		} else if detailsJSON.EventName == "DeleteObject" {
-			if err := dbClient.DeleteDocumentsByIDs(ctx, []string{detailsJSON.RequestParameters.Key}); err != nil {
+		if len(deleteKeys) > 0 {
+			if err := dbClient.DeleteDocumentsByKeys(ctx, deleteKeys); err != nil {
 				util.Log("DELETE_DOCUMENTS_ERROR", err.Error())
 				return err
 			}
@@ -51,3 +63,26 @@ func handler(parsClient pars.Parser, dbClient db.Databaser) func(ctx context.Con
 		return nil
 	}
 }
+
+// latestObjects returns the latest event for each file in the order the
+// files first appear, so that a file deleted and then written again is
+// kept. The latest event is the one with the greatest S3 sequencer,
+// falling back to the notification order for events without one.
+func latestObjects(objects []evt.Object) []evt.Object {
+	output := []evt.Object{}
+	positions := map[db.FileKey]int{}
+	for _, object := range objects {
+		fileKey := db.FileKey{Bucket: object.Bucket, Key: object.Key}
+		if position, ok := positions[fileKey]; ok {
+			if evt.CompareSequencers(object.Sequencer, output[position].Sequencer) >= 0 {
+				output[position] = object
+			}
+			continue
+		}
+
+		positions[fileKey] = len(output)
+		output = append(output, object)
+	}
+
+	return output
+}
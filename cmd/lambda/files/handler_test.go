@@ -3,15 +3,19 @@ package main
 import (
 	"context"
 	"errors"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
 
 	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/evt"
 	"github.com/forstmeier/findfile/pkg/pars"
+	"github.com/forstmeier/findfile/pkg/sniff"
 )
 
 func TestMain(m *testing.M) {
@@ -19,18 +23,35 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+type mockSniffClient struct {
+	mockSniffOutput *sniff.File
+	mockSniffError  error
+}
+
+func (m *mockSniffClient) Sniff(ctx context.Context, bucket, key string) (*sniff.File, error) {
+	return m.mockSniffOutput, m.mockSniffError
+}
+
 type mockParsClient struct {
+	mockParseCalls  int
 	mockParseOutput *pars.Document
 	mockParseError  error
 }
 
-func (m *mockParsClient) Parse(ctx context.Context, fileBucket, fileKey string) (*pars.Document, error) {
+func (m *mockParsClient) Parse(ctx context.Context, file pars.File) (*pars.Document, error) {
+	m.mockParseCalls++
+	return m.mockParseOutput, m.mockParseError
+}
+
+func (m *mockParsClient) ParseReader(ctx context.Context, reader io.Reader, file pars.File) (*pars.Document, error) {
 	return m.mockParseOutput, m.mockParseError
 }
 
 type mockDBClient struct {
-	mockUpsertDocumentsError error
-	mockDeleteDocumentsError error
+	mockUpsertDocumentsReceived []pars.Document
+	mockUpsertDocumentsError    error
+	mockDeleteDocumentsReceived []db.FileKey
+	mockDeleteDocumentsError    error
 }
 
 func (m *mockDBClient) SetupDatabase(ctx context.Context) error {
@@ -38,10 +59,12 @@ func (m *mockDBClient) SetupDatabase(ctx context.Context) error {
 }
 
 func (m *mockDBClient) UpsertDocuments(ctx context.Context, documents []pars.Document) error {
+	m.mockUpsertDocumentsReceived = documents
 	return m.mockUpsertDocumentsError
 }
 
-func (m *mockDBClient) DeleteDocumentsByIDs(ctx context.Context, documentIDs []string) error {
+func (m *mockDBClient) DeleteDocumentsByKeys(ctx context.Context, fileKeys []db.FileKey) error {
+	m.mockDeleteDocumentsReceived = fileKeys
 	return m.mockDeleteDocumentsError
 }
 
@@ -49,72 +72,298 @@ func (m *mockDBClient) DeleteDocumentsByBuckets(ctx context.Context, documentIDs
 	return m.mockDeleteDocumentsError
 }
 
-func (m *mockDBClient) QueryDocuments(ctx context.Context, query db.Query) ([]pars.Document, error) {
+func (m *mockDBClient) QueryDocuments(ctx context.Context, query db.Query) (*db.Result, error) {
 	return nil, nil
 }
 
+func s3Record(eventName, key, sequencer string) events.S3EventRecord {
+	return events.S3EventRecord{
+		EventSource: "aws:s3",
+		EventName:   eventName,
+		S3: events.S3Entity{
+			Bucket: events.S3Bucket{
+				Name: "bucket",
+			},
+			Object: events.S3Object{
+				Key:       key,
+				Sequencer: sequencer,
+			},
+		},
+	}
+}
+
 func Test_handler(t *testing.T) {
+	sniffError := errors.New("mock sniff error")
 	parseError := errors.New("mock parse error")
 	upsertError := errors.New("mock upsert error")
 	deleteError := errors.New("mock delete error")
 
 	tests := []struct {
 		description              string
-		event                    events.CloudWatchEvent
+		event                    evt.Notification
+		mockSniffOutput          *sniff.File
+		mockSniffError           error
 		mockParseOutput          *pars.Document
 		mockParseError           error
 		mockUpsertDocumentsError error
 		mockDeleteDocumentsError error
+		parseCalls               int
+		skipReason               string
+		deleteKeys               []db.FileKey
 		error                    error
 	}{
+		{
+			description: "sniff file error",
+			event: evt.Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					Detail: []byte(`{ "eventName": "PutObject", "requestParameters": { "bucketName": "bucket", "key": "key.jpeg" } }`),
+				},
+			},
+			mockSniffOutput:          nil,
+			mockSniffError:           sniffError,
+			mockParseOutput:          nil,
+			mockParseError:           nil,
+			mockUpsertDocumentsError: nil,
+			mockDeleteDocumentsError: nil,
+			parseCalls:               0,
+			skipReason:               "",
+			deleteKeys:               nil,
+			error:                    sniffError,
+		},
+		{
+			description: "unsupported file skipped",
+			event: evt.Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					Detail: []byte(`{ "eventName": "PutObject", "requestParameters": { "bucketName": "bucket", "key": "notapdf" } }`),
+				},
+			},
+			mockSniffOutput: &sniff.File{
+				ContentType: "text/plain",
+				Reason:      "unsupported content type text/plain",
+			},
+			mockSniffError:           nil,
+			mockParseOutput:          nil,
+			mockParseError:           parseError,
+			mockUpsertDocumentsError: nil,
+			mockDeleteDocumentsError: nil,
+			parseCalls:               0,
+			skipReason:               "unsupported content type text/plain",
+			deleteKeys:               nil,
+			error:                    nil,
+		},
 		{
 			description: "parse file error",
-			event: events.CloudWatchEvent{
-				Detail: []byte(`{ "eventName": "PutObject", "requestParameters": { "bucketName": "bucket", "key": "key.jpeg" } }`),
+			event: evt.Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					Detail: []byte(`{ "eventName": "PutObject", "requestParameters": { "bucketName": "bucket", "key": "key.jpeg" } }`),
+				},
 			},
+			mockSniffOutput: &sniff.File{
+				ContentType: "image/jpeg",
+				Supported:   true,
+			},
+			mockSniffError:           nil,
 			mockParseOutput:          nil,
 			mockParseError:           parseError,
 			mockUpsertDocumentsError: nil,
 			mockDeleteDocumentsError: nil,
+			parseCalls:               1,
+			skipReason:               "",
+			deleteKeys:               nil,
 			error:                    parseError,
 		},
 		{
 			description: "upsert document error",
-			event: events.CloudWatchEvent{
-				Detail: []byte(`{ "eventName": "PutObject", "requestParameters": { "bucketName": "bucket", "key": "key.jpeg" } }`),
+			event: evt.Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					Detail: []byte(`{ "eventName": "PutObject", "requestParameters": { "bucketName": "bucket", "key": "key.jpeg" } }`),
+				},
+			},
+			mockSniffOutput: &sniff.File{
+				ContentType: "image/jpeg",
+				Supported:   true,
 			},
+			mockSniffError:           nil,
 			mockParseOutput:          &pars.Document{},
 			mockParseError:           nil,
 			mockUpsertDocumentsError: upsertError,
 			mockDeleteDocumentsError: nil,
+			parseCalls:               1,
+			skipReason:               "",
+			deleteKeys:               nil,
 			error:                    upsertError,
 		},
 		{
 			description: "delete document error",
-			event: events.CloudWatchEvent{
-				Detail: []byte(`{ "eventName": "DeleteObject", "requestParameters": { "bucketName": "bucket", "key": "key.jpeg" } }`),
+			event: evt.Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					Detail: []byte(`{ "eventName": "DeleteObject", "requestParameters": { "bucketName": "bucket", "key": "key.jpeg" } }`),
+				},
 			},
+			mockSniffOutput:          nil,
+			mockSniffError:           nil,
 			mockParseOutput:          nil,
 			mockParseError:           nil,
 			mockUpsertDocumentsError: nil,
 			mockDeleteDocumentsError: deleteError,
+			parseCalls:               0,
+			skipReason:               "",
+			deleteKeys:               []db.FileKey{{Bucket: "bucket", Key: "key.jpeg"}},
 			error:                    deleteError,
 		},
 		{
 			description: "successful invocation",
-			event: events.CloudWatchEvent{
-				Detail: []byte(`{ "eventName": "DeleteObject", "requestParameters": { "bucketName": "bucket", "key": "key.jpeg" } }`),
+			event: evt.Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					Detail: []byte(`{ "eventName": "DeleteObject", "requestParameters": { "bucketName": "bucket", "key": "key.jpeg" } }`),
+				},
+			},
+			mockSniffOutput:          nil,
+			mockSniffError:           nil,
+			mockParseOutput:          nil,
+			mockParseError:           nil,
+			mockUpsertDocumentsError: nil,
+			mockDeleteDocumentsError: nil,
+			parseCalls:               0,
+			skipReason:               "",
+			deleteKeys:               []db.FileKey{{Bucket: "bucket", Key: "key.jpeg"}},
+			error:                    nil,
+		},
+		{
+			description: "successful copy invocation",
+			event: evt.Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					Detail: []byte(`{ "eventName": "CopyObject", "requestParameters": { "bucketName": "bucket", "key": "copy.jpeg" } }`),
+				},
+			},
+			mockSniffOutput: &sniff.File{
+				ContentType: "image/jpeg",
+				Supported:   true,
+			},
+			mockSniffError:           nil,
+			mockParseOutput:          &pars.Document{},
+			mockParseError:           nil,
+			mockUpsertDocumentsError: nil,
+			mockDeleteDocumentsError: nil,
+			parseCalls:               1,
+			skipReason:               "",
+			deleteKeys:               nil,
+			error:                    nil,
+		},
+		{
+			description: "successful multipart upload invocation",
+			event: evt.Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					Detail: []byte(`{ "eventName": "CompleteMultipartUpload", "requestParameters": { "bucketName": "bucket", "key": "large.pdf", "uploadId": "upload" } }`),
+				},
+			},
+			mockSniffOutput: &sniff.File{
+				ContentType: "application/pdf",
+				Supported:   true,
+			},
+			mockSniffError:           nil,
+			mockParseOutput:          &pars.Document{},
+			mockParseError:           nil,
+			mockUpsertDocumentsError: nil,
+			mockDeleteDocumentsError: nil,
+			parseCalls:               1,
+			skipReason:               "",
+			deleteKeys:               nil,
+			error:                    nil,
+		},
+		{
+			description: "delete then put of the same key",
+			event: evt.Notification{
+				Records: []evt.NotificationRecord{
+					{S3EventRecord: s3Record("ObjectRemoved:Delete", "key.jpeg", "")},
+					{S3EventRecord: s3Record("ObjectCreated:Put", "key.jpeg", "")},
+				},
+			},
+			mockSniffOutput: &sniff.File{
+				ContentType: "image/jpeg",
+				Supported:   true,
 			},
+			mockSniffError:           nil,
+			mockParseOutput:          &pars.Document{},
+			mockParseError:           nil,
+			mockUpsertDocumentsError: nil,
+			mockDeleteDocumentsError: nil,
+			parseCalls:               1,
+			skipReason:               "",
+			deleteKeys:               nil,
+			error:                    nil,
+		},
+		{
+			description: "put then delete of the same key",
+			event: evt.Notification{
+				Records: []evt.NotificationRecord{
+					{S3EventRecord: s3Record("ObjectCreated:Put", "key.jpeg", "")},
+					{S3EventRecord: s3Record("ObjectRemoved:Delete", "key.jpeg", "")},
+				},
+			},
+			mockSniffOutput:          nil,
+			mockSniffError:           nil,
 			mockParseOutput:          nil,
 			mockParseError:           nil,
 			mockUpsertDocumentsError: nil,
 			mockDeleteDocumentsError: nil,
+			parseCalls:               0,
+			skipReason:               "",
+			deleteKeys:               []db.FileKey{{Bucket: "bucket", Key: "key.jpeg"}},
+			error:                    nil,
+		},
+		{
+			description: "out of order events ordered by sequencer",
+			event: evt.Notification{
+				Records: []evt.NotificationRecord{
+					{S3EventRecord: s3Record("ObjectCreated:Put", "key.jpeg", "0055AED6DCD90281E6")},
+					{S3EventRecord: s3Record("ObjectRemoved:Delete", "key.jpeg", "0055AED6DCD90281E5")},
+				},
+			},
+			mockSniffOutput: &sniff.File{
+				ContentType: "image/jpeg",
+				Supported:   true,
+			},
+			mockSniffError:           nil,
+			mockParseOutput:          &pars.Document{},
+			mockParseError:           nil,
+			mockUpsertDocumentsError: nil,
+			mockDeleteDocumentsError: nil,
+			parseCalls:               1,
+			skipReason:               "",
+			deleteKeys:               nil,
 			error:                    nil,
 		},
+		{
+			description: "successful batch delete invocation",
+			event: evt.Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					Detail: []byte(`{ "eventName": "DeleteObjects", "requestParameters": { "bucketName": "bucket", "delete": { "Object": [ { "Key": "a.jpeg" }, { "Key": "folder/b c/文字.pdf" } ] } } }`),
+				},
+			},
+			mockSniffOutput:          nil,
+			mockSniffError:           nil,
+			mockParseOutput:          nil,
+			mockParseError:           nil,
+			mockUpsertDocumentsError: nil,
+			mockDeleteDocumentsError: nil,
+			parseCalls:               0,
+			skipReason:               "",
+			deleteKeys: []db.FileKey{
+				{Bucket: "bucket", Key: "a.jpeg"},
+				{Bucket: "bucket", Key: "folder/b c/文字.pdf"},
+			},
+			error: nil,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
+			sniffClient := &mockSniffClient{
+				mockSniffOutput: test.mockSniffOutput,
+				mockSniffError:  test.mockSniffError,
+			}
+
 			parsClient := &mockParsClient{
 				mockParseOutput: test.mockParseOutput,
 				mockParseError:  test.mockParseError,
@@ -125,14 +374,32 @@ func Test_handler(t *testing.T) {
 				mockDeleteDocumentsError: test.mockDeleteDocumentsError,
 			}
 
-			handlerFunc := handler(parsClient, dbClient)
+			handlerFunc := handler(sniffClient, parsClient, dbClient)
 
 			err := handlerFunc(context.Background(), test.event)
 
 			if err != nil {
-				if !errors.As(err, &test.error) {
+				if !errors.Is(err, test.error) {
 					t.Errorf("incorrect error, received: %v, expected: %v", err, test.error)
 				}
+			} else if test.error != nil {
+				t.Errorf("incorrect error, received: nil, expected: %v", test.error)
+			}
+
+			if parsClient.mockParseCalls != test.parseCalls {
+				t.Errorf("incorrect parse calls, received: %d, expected: %d", parsClient.mockParseCalls, test.parseCalls)
+			}
+
+			if len(dbClient.mockUpsertDocumentsReceived) > 0 {
+				if skipReason := dbClient.mockUpsertDocumentsReceived[0].SkipReason; skipReason != test.skipReason {
+					t.Errorf("incorrect skip reason, received: %s, expected: %s", skipReason, test.skipReason)
+				}
+			} else if test.skipReason != "" {
+				t.Errorf("incorrect skip reason, received: none, expected: %s", test.skipReason)
+			}
+
+			if !reflect.DeepEqual(dbClient.mockDeleteDocumentsReceived, test.deleteKeys) {
+				t.Errorf("incorrect delete keys, received: %v, expected: %v", dbClient.mockDeleteDocumentsReceived, test.deleteKeys)
 			}
 		})
 	}
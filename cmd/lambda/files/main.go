@@ -9,24 +9,42 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws/session"
 
-	"github.com/forstmeier/findfile/pkg/db"
-	"github.com/forstmeier/findfile/pkg/pars"
+	"github.com/forstmeier/findfile/pkg/db/backend"
+	parsbackend "github.com/forstmeier/findfile/pkg/pars/backend"
+	"github.com/forstmeier/findfile/pkg/sniff"
 )
 
 func main() {
 	newSession := session.New()
 
-	parsClient := pars.New(newSession)
-
-	dbClient, err := db.New(
+	sniffClient := sniff.New(
 		newSession,
-		os.Getenv("DATABASE_URL"),
-		os.Getenv("DATABASE_USERNAME"),
-		os.Getenv("DATABASE_PASSWORD"),
+		sniff.ParseContentTypes(os.Getenv("FILE_CONTENT_TYPES")),
 	)
+
+	parsClient, err := parsbackend.New(newSession, parsbackend.Config{
+		Backend:      os.Getenv("PARSER_BACKEND"),
+		Analysis:     os.Getenv("PARSER_ANALYSIS") == "true",
+		Command:      os.Getenv("TESSERACT_COMMAND"),
+		PDFCommand:   os.Getenv("TESSERACT_PDF_COMMAND"),
+		Language:     os.Getenv("TESSERACT_LANGUAGE"),
+		PDFTextLayer: os.Getenv("PARSER_PDF_TEXT_LAYER") == "true",
+	})
+	if err != nil {
+		panic(fmt.Sprintf("error creating pars client: %v", err))
+	}
+
+	dbClient, err := backend.New(newSession, backend.Config{
+		Backend:  os.Getenv("DATABASE_BACKEND"),
+		URL:      os.Getenv("DATABASE_URL"),
+		Username: os.Getenv("DATABASE_USERNAME"),
+		Password: os.Getenv("DATABASE_PASSWORD"),
+		Analyzer: os.Getenv("DATABASE_ANALYZER"),
+		Path:     os.Getenv("DATABASE_PATH"),
+	})
 	if err != nil {
 		panic(fmt.Sprintf("error creating db client: %v", err))
 	}
 
-	lambda.Start(handler(parsClient, dbClient))
+	lambda.Start(handler(sniffClient, parsClient, dbClient))
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-lambda-go/cfn"
@@ -10,25 +11,76 @@ import (
 	"github.com/forstmeier/findfile/util"
 )
 
-func handler(dbClient db.Databaser, sendResponse func(response *cfn.Response) error) func(ctx context.Context, event cfn.Event) error {
-	return func(ctx context.Context, event cfn.Event) error {
+const reindexTokenProperty = "REINDEX_TOKEN"
+
+const reindexAction = "reindex"
+
+// request holds either the CloudFormation custom resource event or,
+// when the function is invoked directly by an operator, the name of
+// the action to run (e.g. {"action": "reindex"}).
+type request struct {
+	cfn.Event
+	Action string `json:"action"`
+}
+
+func handler(dbClient db.Databaser, sendResponse func(response *cfn.Response) error) func(ctx context.Context, event request) error {
+	return func(ctx context.Context, event request) error {
 		util.Log("EVENT_BODY", event)
 
-		response := cfn.NewResponse(&event)
+		if event.Action != "" {
+			return runAction(ctx, dbClient, event.Action)
+		}
+
+		response := cfn.NewResponse(&event.Event)
 		response.Status = cfn.StatusSuccess
 		response.PhysicalResourceID = "setupCustomResource"
 
-		if event.RequestType != cfn.RequestCreate {
-			message := fmt.Sprintf(`received non-create event type %s`, event.RequestType)
-			util.Log("NON_CREATE_EVENT_TYPE", message)
-			response.Reason = message
-		} else {
+		switch event.RequestType {
+		case cfn.RequestCreate:
 			if err := dbClient.SetupDatabase(ctx); err != nil {
 				util.Log("SETUP_DATABASE_ERROR", err.Error())
 				response.Reason = fmt.Sprintf("setup database error: %s", err.Error())
 			} else {
 				response.Reason = "successful invocation"
 			}
+
+		case cfn.RequestUpdate:
+			reindex := event.ResourceProperties[reindexTokenProperty] != event.OldResourceProperties[reindexTokenProperty]
+			if !reindex {
+				err := dbClient.SetupDatabase(ctx)
+				var mismatchErr *db.MappingMismatchError
+				if errors.As(err, &mismatchErr) {
+					util.Log("MAPPING_MISMATCH", err.Error())
+					reindex = true
+				} else if err != nil {
+					util.Log("SETUP_DATABASE_ERROR", err.Error())
+					response.Reason = fmt.Sprintf("setup database error: %s", err.Error())
+					break
+				}
+			}
+
+			if !reindex {
+				response.Reason = "successful invocation"
+				break
+			}
+
+			reindexer, ok := dbClient.(db.Reindexer)
+			if !ok {
+				response.Reason = "database does not support reindexing"
+				break
+			}
+
+			if err := reindexer.Reindex(ctx); err != nil {
+				util.Log("REINDEX_ERROR", err.Error())
+				response.Reason = fmt.Sprintf("reindex error: %s", err.Error())
+			} else {
+				response.Reason = "successful reindex"
+			}
+
+		default:
+			message := fmt.Sprintf(`received non-create event type %s`, event.RequestType)
+			util.Log("NON_CREATE_EVENT_TYPE", message)
+			response.Reason = message
 		}
 
 		if err := sendResponse(response); err != nil {
@@ -40,6 +92,28 @@ func handler(dbClient db.Databaser, sendResponse func(response *cfn.Response) er
 	}
 }
 
+// runAction runs an action requested by an operator and returns its
+// error so that it is reported to the caller.
+func runAction(ctx context.Context, dbClient db.Databaser, action string) error {
+	if action != reindexAction {
+		return fmt.Errorf("unsupported action %q", action)
+	}
+
+	reindexer, ok := dbClient.(db.Reindexer)
+	if !ok {
+		return errors.New("database does not support reindexing")
+	}
+
+	if err := reindexer.Reindex(ctx); err != nil {
+		util.Log("REINDEX_ERROR", err.Error())
+		return err
+	}
+
+	util.Log("REINDEX_COMPLETE", "successful reindex")
+
+	return nil
+}
+
 func sendResponse(response *cfn.Response) error {
 	return response.Send()
 }
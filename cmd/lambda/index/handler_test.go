@@ -31,7 +31,7 @@ func (m *mockDBClient) UpsertDocuments(ctx context.Context, documents []pars.Doc
 	return nil
 }
 
-func (m *mockDBClient) DeleteDocumentsByIDs(ctx context.Context, documentIDs []string) error {
+func (m *mockDBClient) DeleteDocumentsByKeys(ctx context.Context, fileKeys []db.FileKey) error {
 	return nil
 }
 
@@ -39,14 +39,25 @@ func (m *mockDBClient) DeleteDocumentsByBuckets(ctx context.Context, buckets []s
 	return nil
 }
 
-func (m *mockDBClient) QueryDocuments(ctx context.Context, query db.Query) ([]pars.Document, error) {
+func (m *mockDBClient) QueryDocuments(ctx context.Context, query db.Query) (*db.Result, error) {
 	return nil, nil
 }
 
+type mockReindexerDBClient struct {
+	mockDBClient
+	mockReindexError error
+}
+
+func (m *mockReindexerDBClient) Reindex(ctx context.Context) error {
+	return m.mockReindexError
+}
+
 func Test_handler(t *testing.T) {
 	tests := []struct {
 		description            string
 		mockSetupDatabaseError error
+		mockReindexError       error
+		reindexer              bool
 		mockSendResponseError  error
 		event                  cfn.Event
 		responseReason         string
@@ -87,13 +98,81 @@ func Test_handler(t *testing.T) {
 			},
 			responseReason: "successful invocation",
 		},
+		{
+			description:            "error setting up database on update",
+			mockSetupDatabaseError: errors.New("mock setup database error"),
+			reindexer:              true,
+			event: cfn.Event{
+				RequestType: cfn.RequestUpdate,
+			},
+			responseReason: "setup database error: mock setup database error",
+		},
+		{
+			description: "successful update invocation with current mapping",
+			reindexer:   true,
+			event: cfn.Event{
+				RequestType: cfn.RequestUpdate,
+			},
+			responseReason: "successful invocation",
+		},
+		{
+			description:            "mapping mismatch without reindex support",
+			mockSetupDatabaseError: &db.MappingMismatchError{},
+			reindexer:              false,
+			event: cfn.Event{
+				RequestType: cfn.RequestUpdate,
+			},
+			responseReason: "database does not support reindexing",
+		},
+		{
+			description:            "error reindexing on mapping mismatch",
+			mockSetupDatabaseError: &db.MappingMismatchError{},
+			mockReindexError:       errors.New("mock reindex error"),
+			reindexer:              true,
+			event: cfn.Event{
+				RequestType: cfn.RequestUpdate,
+			},
+			responseReason: "reindex error: mock reindex error",
+		},
+		{
+			description:            "successful reindex on mapping mismatch",
+			mockSetupDatabaseError: &db.MappingMismatchError{},
+			reindexer:              true,
+			event: cfn.Event{
+				RequestType: cfn.RequestUpdate,
+			},
+			responseReason: "successful reindex",
+		},
+		{
+			description:            "successful reindex on changed reindex token",
+			mockSetupDatabaseError: errors.New("mock setup database error"),
+			reindexer:              true,
+			event: cfn.Event{
+				RequestType: cfn.RequestUpdate,
+				ResourceProperties: map[string]interface{}{
+					"REINDEX_TOKEN": "2",
+				},
+				OldResourceProperties: map[string]interface{}{
+					"REINDEX_TOKEN": "1",
+				},
+			},
+			responseReason: "successful reindex",
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
-			dbClient := &mockDBClient{
+			var dbClient db.Databaser = &mockDBClient{
 				mockSetupDatabaseError: test.mockSetupDatabaseError,
 			}
+			if test.reindexer {
+				dbClient = &mockReindexerDBClient{
+					mockDBClient: mockDBClient{
+						mockSetupDatabaseError: test.mockSetupDatabaseError,
+					},
+					mockReindexError: test.mockReindexError,
+				}
+			}
 
 			mockResponse := &cfn.Response{}
 
@@ -105,7 +184,9 @@ func Test_handler(t *testing.T) {
 			handlerFunc := handler(dbClient, mockSendResponse)
 
 			// ignore error since returned value is always nil
-			handlerFunc(context.Background(), test.event)
+			handlerFunc(context.Background(), request{
+				Event: test.event,
+			})
 
 			if mockResponse.Reason != test.responseReason {
 				t.Errorf("incorrect response reason, received: %s, expected: %s", mockResponse.Reason, test.responseReason)
@@ -113,3 +194,68 @@ func Test_handler(t *testing.T) {
 		})
 	}
 }
+
+func Test_handlerAction(t *testing.T) {
+	tests := []struct {
+		description      string
+		action           string
+		mockReindexError error
+		reindexer        bool
+		errorExpected    bool
+	}{
+		{
+			description:   "unsupported action",
+			action:        "drop",
+			reindexer:     true,
+			errorExpected: true,
+		},
+		{
+			description:   "database without reindex support",
+			action:        reindexAction,
+			reindexer:     false,
+			errorExpected: true,
+		},
+		{
+			description:      "error reindexing",
+			action:           reindexAction,
+			mockReindexError: errors.New("mock reindex error"),
+			reindexer:        true,
+			errorExpected:    true,
+		},
+		{
+			description:   "successful reindex",
+			action:        reindexAction,
+			reindexer:     true,
+			errorExpected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var dbClient db.Databaser = &mockDBClient{}
+			if test.reindexer {
+				dbClient = &mockReindexerDBClient{
+					mockReindexError: test.mockReindexError,
+				}
+			}
+
+			sent := false
+			mockSendResponse := func(response *cfn.Response) error {
+				sent = true
+				return nil
+			}
+
+			err := handler(dbClient, mockSendResponse)(context.Background(), request{
+				Action: test.action,
+			})
+
+			if (err != nil) != test.errorExpected {
+				t.Errorf("incorrect error, received: %v, expected error: %t", err, test.errorExpected)
+			}
+
+			if sent {
+				t.Error("incorrect custom resource response, received: sent, expected: not sent")
+			}
+		})
+	}
+}
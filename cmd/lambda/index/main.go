@@ -9,18 +9,20 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws/session"
 
-	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/db/backend"
 )
 
 func main() {
 	newSession := session.New()
 
-	dbClient, err := db.New(
-		newSession,
-		os.Getenv("DATABASE_URL"),
-		os.Getenv("DATABASE_USERNAME"),
-		os.Getenv("DATABASE_PASSWORD"),
-	)
+	dbClient, err := backend.New(newSession, backend.Config{
+		Backend:  os.Getenv("DATABASE_BACKEND"),
+		URL:      os.Getenv("DATABASE_URL"),
+		Username: os.Getenv("DATABASE_USERNAME"),
+		Password: os.Getenv("DATABASE_PASSWORD"),
+		Analyzer: os.Getenv("DATABASE_ANALYZER"),
+		Path:     os.Getenv("DATABASE_PATH"),
+	})
 	if err != nil {
 		panic(fmt.Sprintf("error creating db client: %v", err))
 	}
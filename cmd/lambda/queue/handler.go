@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/forstmeier/findfile/pkg/queue"
+)
+
+// batchResponse reports the messages of an SQS batch to be received
+// again when the event source mapping has ReportBatchItemFailures set.
+type batchResponse struct {
+	BatchItemFailures []batchItemFailure `json:"batchItemFailures"`
+}
+
+type batchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+type processor interface {
+	Process(ctx context.Context, messages []queue.Message) []string
+}
+
+func handler(worker processor) func(ctx context.Context, event events.SQSEvent) (batchResponse, error) {
+	return func(ctx context.Context, event events.SQSEvent) (batchResponse, error) {
+		messages := []queue.Message{}
+		for _, record := range event.Records {
+			receiveCount, _ := strconv.Atoi(record.Attributes["ApproximateReceiveCount"])
+
+			attributes := map[string]string{}
+			for name, value := range record.MessageAttributes {
+				if value.StringValue != nil {
+					attributes[name] = *value.StringValue
+				}
+			}
+
+			messages = append(messages, queue.Message{
+				ID:            record.MessageId,
+				ReceiptHandle: record.ReceiptHandle,
+				Body:          record.Body,
+				ReceiveCount:  receiveCount,
+				Attributes:    attributes,
+			})
+		}
+
+		response := batchResponse{
+			BatchItemFailures: []batchItemFailure{},
+		}
+		for _, messageID := range worker.Process(ctx, messages) {
+			response.BatchItemFailures = append(response.BatchItemFailures, batchItemFailure{
+				ItemIdentifier: messageID,
+			})
+		}
+
+		return response, nil
+	}
+}
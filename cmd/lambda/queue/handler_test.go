@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/forstmeier/findfile/pkg/queue"
+)
+
+type mockWorker struct {
+	mockProcessReceived []queue.Message
+	mockProcessOutput   []string
+}
+
+func (m *mockWorker) Process(ctx context.Context, messages []queue.Message) []string {
+	m.mockProcessReceived = messages
+	return m.mockProcessOutput
+}
+
+func Test_handler(t *testing.T) {
+	errorValue := "mock error"
+
+	event := events.SQSEvent{
+		Records: []events.SQSMessage{
+			{
+				MessageId:     "1",
+				ReceiptHandle: "handle-1",
+				Body:          "body-1",
+				Attributes: map[string]string{
+					"ApproximateReceiveCount": "2",
+				},
+				MessageAttributes: map[string]events.SQSMessageAttribute{
+					"error": {
+						StringValue: &errorValue,
+						DataType:    "String",
+					},
+				},
+			},
+			{
+				MessageId:     "2",
+				ReceiptHandle: "handle-2",
+				Body:          "body-2",
+				Attributes: map[string]string{
+					"ApproximateReceiveCount": "1",
+				},
+			},
+		},
+	}
+
+	messages := []queue.Message{
+		{
+			ID:            "1",
+			ReceiptHandle: "handle-1",
+			Body:          "body-1",
+			ReceiveCount:  2,
+			Attributes: map[string]string{
+				"error": "mock error",
+			},
+		},
+		{
+			ID:            "2",
+			ReceiptHandle: "handle-2",
+			Body:          "body-2",
+			ReceiveCount:  1,
+			Attributes:    map[string]string{},
+		},
+	}
+
+	tests := []struct {
+		description       string
+		mockProcessOutput []string
+		response          batchResponse
+	}{
+		{
+			description:       "no failed messages",
+			mockProcessOutput: []string{},
+			response: batchResponse{
+				BatchItemFailures: []batchItemFailure{},
+			},
+		},
+		{
+			description:       "failed messages reported",
+			mockProcessOutput: []string{"2"},
+			response: batchResponse{
+				BatchItemFailures: []batchItemFailure{
+					{
+						ItemIdentifier: "2",
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			worker := &mockWorker{
+				mockProcessOutput: test.mockProcessOutput,
+			}
+
+			response, err := handler(worker)(context.Background(), event)
+			if err != nil {
+				t.Fatalf("incorrect error, received: %v, expected: nil", err)
+			}
+
+			if !reflect.DeepEqual(worker.mockProcessReceived, messages) {
+				t.Errorf("incorrect messages, received: %+v, expected: %+v", worker.mockProcessReceived, messages)
+			}
+
+			if !reflect.DeepEqual(response, test.response) {
+				t.Errorf("incorrect response, received: %+v, expected: %+v", response, test.response)
+			}
+		})
+	}
+}
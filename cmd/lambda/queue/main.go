@@ -0,0 +1,73 @@
+//+build !test
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/forstmeier/findfile/pkg/db/backend"
+	"github.com/forstmeier/findfile/pkg/ingest"
+	parsbackend "github.com/forstmeier/findfile/pkg/pars/backend"
+	"github.com/forstmeier/findfile/pkg/queue"
+	"github.com/forstmeier/findfile/pkg/sniff"
+)
+
+func main() {
+	newSession := session.New()
+
+	sniffClient := sniff.New(
+		newSession,
+		sniff.ParseContentTypes(os.Getenv("FILE_CONTENT_TYPES")),
+	)
+
+	parsClient, err := parsbackend.New(newSession, parsbackend.Config{
+		Backend:      os.Getenv("PARSER_BACKEND"),
+		Analysis:     os.Getenv("PARSER_ANALYSIS") == "true",
+		Command:      os.Getenv("TESSERACT_COMMAND"),
+		PDFCommand:   os.Getenv("TESSERACT_PDF_COMMAND"),
+		Language:     os.Getenv("TESSERACT_LANGUAGE"),
+		PDFTextLayer: os.Getenv("PARSER_PDF_TEXT_LAYER") == "true",
+	})
+	if err != nil {
+		panic(fmt.Sprintf("error creating pars client: %v", err))
+	}
+
+	dbClient, err := backend.New(newSession, backend.Config{
+		Backend:  os.Getenv("DATABASE_BACKEND"),
+		URL:      os.Getenv("DATABASE_URL"),
+		Username: os.Getenv("DATABASE_USERNAME"),
+		Password: os.Getenv("DATABASE_PASSWORD"),
+		Analyzer: os.Getenv("DATABASE_ANALYZER"),
+		Path:     os.Getenv("DATABASE_PATH"),
+	})
+	if err != nil {
+		panic(fmt.Sprintf("error creating db client: %v", err))
+	}
+
+	queueClient := queue.New(
+		newSession,
+		os.Getenv("QUEUE_ENDPOINT"),
+	)
+
+	concurrency, _ := strconv.Atoi(os.Getenv("QUEUE_CONCURRENCY"))
+	maxReceives, _ := strconv.Atoi(os.Getenv("QUEUE_MAX_RECEIVES"))
+	backoff, _ := time.ParseDuration(os.Getenv("QUEUE_BACKOFF"))
+	reserve, _ := time.ParseDuration(os.Getenv("QUEUE_RESERVE"))
+
+	worker := ingest.New(sniffClient, parsClient, dbClient, queueClient, ingest.Config{
+		QueueURL:           os.Getenv("QUEUE_URL"),
+		DeadLetterQueueURL: os.Getenv("DEAD_LETTER_QUEUE_URL"),
+		Concurrency:        concurrency,
+		MaxReceives:        maxReceives,
+		Backoff:            backoff,
+		Reserve:            reserve,
+	})
+
+	lambda.Start(handler(worker))
+}
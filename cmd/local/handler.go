@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/evt/watch"
+	"github.com/forstmeier/findfile/pkg/pars"
+	"github.com/forstmeier/findfile/pkg/sniff"
+	"github.com/forstmeier/findfile/util"
+)
+
+type opener interface {
+	Open(bucket, key string) (io.ReadCloser, *pars.File, error)
+}
+
+func handler(diskClient opener, types sniff.Types, parsClient pars.Parser, dbClient db.Databaser) func(ctx context.Context, event watch.Event) error {
+	return func(ctx context.Context, event watch.Event) error {
+		switch event.Type {
+		case watch.Put:
+			reader, file, err := diskClient.Open(event.Bucket, event.Key)
+			if err != nil {
+				util.Log("OPEN_FILE_ERROR", err.Error())
+				return err
+			}
+			defer reader.Close()
+
+			var document *pars.Document
+			if sniffed := types.File(file.ContentType); sniffed.Supported {
+				document, err = parsClient.ParseReader(ctx, reader, *file)
+				if err != nil {
+					util.Log("PARSE_ERROR", err.Error())
+					return err
+				}
+			} else {
+				util.Log("SKIPPED_FILE", fmt.Sprintf("%s/%s: %s", event.Bucket, event.Key, sniffed.Reason))
+				skipped := pars.NewSkippedDocument(*file, sniffed.Reason)
+				document = &skipped
+			}
+
+			if err := dbClient.UpsertDocuments(ctx, []pars.Document{*document}); err != nil {
+				util.Log("UPSERT_DOCUMENTS_ERROR", err.Error())
+				return err
+			}
+
+		case watch.Delete:
+			if err := dbClient.DeleteDocumentsByKeys(ctx, []db.FileKey{{Bucket: event.Bucket, Key: event.Key}}); err != nil {
+				util.Log("DELETE_DOCUMENTS_ERROR", err.Error())
+				return err
+			}
+		}
+
+		return nil
+	}
+}
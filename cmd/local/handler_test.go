@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/evt/watch"
+	"github.com/forstmeier/findfile/pkg/pars"
+	"github.com/forstmeier/findfile/pkg/sniff"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+type mockDiskClient struct {
+	mockOpenOutput *pars.File
+	mockOpenError  error
+}
+
+func (m *mockDiskClient) Open(bucket, key string) (io.ReadCloser, *pars.File, error) {
+	return io.NopCloser(strings.NewReader("content")), m.mockOpenOutput, m.mockOpenError
+}
+
+type mockParsClient struct {
+	mockParseReaderCalls int
+	mockParseOutput      *pars.Document
+	mockParseError       error
+}
+
+func (m *mockParsClient) Parse(ctx context.Context, file pars.File) (*pars.Document, error) {
+	return m.mockParseOutput, m.mockParseError
+}
+
+func (m *mockParsClient) ParseReader(ctx context.Context, reader io.Reader, file pars.File) (*pars.Document, error) {
+	m.mockParseReaderCalls++
+	return m.mockParseOutput, m.mockParseError
+}
+
+type mockDBClient struct {
+	mockUpsertDocumentsInput []pars.Document
+	mockUpsertDocumentsError error
+	mockDeleteDocumentsInput []db.FileKey
+	mockDeleteDocumentsError error
+}
+
+func (m *mockDBClient) SetupDatabase(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockDBClient) UpsertDocuments(ctx context.Context, documents []pars.Document) error {
+	m.mockUpsertDocumentsInput = documents
+	return m.mockUpsertDocumentsError
+}
+
+func (m *mockDBClient) DeleteDocumentsByKeys(ctx context.Context, fileKeys []db.FileKey) error {
+	m.mockDeleteDocumentsInput = fileKeys
+	return m.mockDeleteDocumentsError
+}
+
+func (m *mockDBClient) DeleteDocumentsByBuckets(ctx context.Context, buckets []string) error {
+	return nil
+}
+
+func (m *mockDBClient) QueryDocuments(ctx context.Context, query db.Query) (*db.Result, error) {
+	return nil, nil
+}
+
+func Test_handler(t *testing.T) {
+	openError := errors.New("mock open error")
+	parseError := errors.New("mock parse error")
+	upsertError := errors.New("mock upsert error")
+	deleteError := errors.New("mock delete error")
+
+	putEvent := watch.Event{
+		Type:   watch.Put,
+		Bucket: "/shares/invoices",
+		Key:    "2021/acme.pdf",
+	}
+
+	deleteEvent := watch.Event{
+		Type:   watch.Delete,
+		Bucket: "/shares/invoices",
+		Key:    "2021/acme.pdf",
+	}
+
+	pdfFile := &pars.File{
+		Bucket:      "/shares/invoices",
+		Key:         "2021/acme.pdf",
+		ContentType: "application/pdf",
+	}
+
+	tests := []struct {
+		description              string
+		event                    watch.Event
+		mockOpenOutput           *pars.File
+		mockOpenError            error
+		mockParseOutput          *pars.Document
+		mockParseError           error
+		mockUpsertDocumentsError error
+		mockDeleteDocumentsError error
+		parseCalls               int
+		skipReason               string
+		deleteInput              []db.FileKey
+		error                    error
+	}{
+		{
+			description:   "open file error",
+			event:         putEvent,
+			mockOpenError: openError,
+			parseCalls:    0,
+			error:         openError,
+		},
+		{
+			description: "unsupported file skipped",
+			event:       putEvent,
+			mockOpenOutput: &pars.File{
+				Key:         "notes.txt",
+				ContentType: "text/plain",
+			},
+			mockParseError: parseError,
+			parseCalls:     0,
+			skipReason:     "unsupported content type text/plain",
+			error:          nil,
+		},
+		{
+			description:    "parse file error",
+			event:          putEvent,
+			mockOpenOutput: pdfFile,
+			mockParseError: parseError,
+			parseCalls:     1,
+			error:          parseError,
+		},
+		{
+			description:              "upsert document error",
+			event:                    putEvent,
+			mockOpenOutput:           pdfFile,
+			mockParseOutput:          &pars.Document{},
+			mockUpsertDocumentsError: upsertError,
+			parseCalls:               1,
+			error:                    upsertError,
+		},
+		{
+			description:     "successful put",
+			event:           putEvent,
+			mockOpenOutput:  pdfFile,
+			mockParseOutput: &pars.Document{},
+			parseCalls:      1,
+			error:           nil,
+		},
+		{
+			description:              "delete document error",
+			event:                    deleteEvent,
+			mockDeleteDocumentsError: deleteError,
+			deleteInput:              []db.FileKey{{Bucket: "/shares/invoices", Key: "2021/acme.pdf"}},
+			error:                    deleteError,
+		},
+		{
+			description: "successful delete",
+			event:       deleteEvent,
+			deleteInput: []db.FileKey{{Bucket: "/shares/invoices", Key: "2021/acme.pdf"}},
+			error:       nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			diskClient := &mockDiskClient{
+				mockOpenOutput: test.mockOpenOutput,
+				mockOpenError:  test.mockOpenError,
+			}
+
+			parsClient := &mockParsClient{
+				mockParseOutput: test.mockParseOutput,
+				mockParseError:  test.mockParseError,
+			}
+
+			dbClient := &mockDBClient{
+				mockUpsertDocumentsError: test.mockUpsertDocumentsError,
+				mockDeleteDocumentsError: test.mockDeleteDocumentsError,
+			}
+
+			handlerFunc := handler(diskClient, sniff.NewTypes(nil), parsClient, dbClient)
+
+			err := handlerFunc(context.Background(), test.event)
+
+			if err != nil {
+				if !errors.Is(err, test.error) {
+					t.Errorf("incorrect error, received: %v, expected: %v", err, test.error)
+				}
+			} else if test.error != nil {
+				t.Errorf("incorrect error, received: nil, expected: %v", test.error)
+			}
+
+			if parsClient.mockParseReaderCalls != test.parseCalls {
+				t.Errorf("incorrect parse calls, received: %d, expected: %d", parsClient.mockParseReaderCalls, test.parseCalls)
+			}
+
+			if len(dbClient.mockUpsertDocumentsInput) > 0 {
+				if skipReason := dbClient.mockUpsertDocumentsInput[0].SkipReason; skipReason != test.skipReason {
+					t.Errorf("incorrect skip reason, received: %s, expected: %s", skipReason, test.skipReason)
+				}
+			} else if test.skipReason != "" {
+				t.Errorf("incorrect skip reason, received: none, expected: %s", test.skipReason)
+			}
+
+			if !reflect.DeepEqual(dbClient.mockDeleteDocumentsInput, test.deleteInput) {
+				t.Errorf("incorrect deleted documents, received: %v, expected: %v", dbClient.mockDeleteDocumentsInput, test.deleteInput)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+//+build !test
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/forstmeier/findfile/pkg/db/backend"
+	"github.com/forstmeier/findfile/pkg/evt/watch"
+	"github.com/forstmeier/findfile/pkg/fs/disk"
+	parsbackend "github.com/forstmeier/findfile/pkg/pars/backend"
+	"github.com/forstmeier/findfile/pkg/sniff"
+	"github.com/forstmeier/findfile/util"
+)
+
+// settle is how long a file must go without changes before it is
+// parsed.
+const settle = 2 * time.Second
+
+func main() {
+	if len(os.Args) < 2 {
+		panic("no directories provided")
+	}
+
+	buckets := []string{}
+	for _, directory := range os.Args[1:] {
+		bucket, err := filepath.Abs(directory)
+		if err != nil {
+			panic(fmt.Sprintf("error resolving directory: %v", err))
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	newSession := session.New()
+
+	parsClient, err := parsbackend.New(newSession, parsbackend.Config{
+		Backend:      os.Getenv("PARSER_BACKEND"),
+		Analysis:     os.Getenv("PARSER_ANALYSIS") == "true",
+		Command:      os.Getenv("TESSERACT_COMMAND"),
+		PDFCommand:   os.Getenv("TESSERACT_PDF_COMMAND"),
+		Language:     os.Getenv("TESSERACT_LANGUAGE"),
+		PDFTextLayer: os.Getenv("PARSER_PDF_TEXT_LAYER") == "true",
+	})
+	if err != nil {
+		panic(fmt.Sprintf("error creating pars client: %v", err))
+	}
+
+	dbClient, err := backend.New(newSession, backend.Config{
+		Backend:  os.Getenv("DATABASE_BACKEND"),
+		URL:      os.Getenv("DATABASE_URL"),
+		Username: os.Getenv("DATABASE_USERNAME"),
+		Password: os.Getenv("DATABASE_PASSWORD"),
+		Analyzer: os.Getenv("DATABASE_ANALYZER"),
+		Path:     os.Getenv("DATABASE_PATH"),
+	})
+	if err != nil {
+		panic(fmt.Sprintf("error creating db client: %v", err))
+	}
+
+	if err := dbClient.SetupDatabase(ctx); err != nil {
+		panic(fmt.Sprintf("error setting up database: %v", err))
+	}
+
+	watchClient, err := watch.New(settle)
+	if err != nil {
+		panic(fmt.Sprintf("error creating watch client: %v", err))
+	}
+	defer watchClient.Close()
+
+	// directories are watched before they are listed so that files
+	// changed while the existing files are indexed are not missed
+	if err := watchClient.AddBucketListeners(ctx, buckets); err != nil {
+		panic(fmt.Sprintf("error watching directories: %v", err))
+	}
+
+	diskClient := disk.New()
+	types := sniff.NewTypes(sniff.ParseContentTypes(os.Getenv("FILE_CONTENT_TYPES")))
+	handle := handler(diskClient, types, parsClient, dbClient)
+
+	for _, bucket := range buckets {
+		keys, err := diskClient.ListFiles(ctx, bucket)
+		if err != nil {
+			panic(fmt.Sprintf("error listing files: %v", err))
+		}
+
+		for _, key := range keys {
+			// errors are logged by the handler and the file is
+			// indexed again on its next change
+			handle(ctx, watch.Event{
+				Type:   watch.Put,
+				Bucket: bucket,
+				Key:    key,
+			})
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-watchClient.Events():
+			handle(ctx, event)
+		case err := <-watchClient.Errors():
+			util.Log("WATCH_ERROR", err.Error())
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// indexName returns the name of the versioned index with the provided
+// version number.
+func indexName(version int) string {
+	return indexPrefix + strconv.Itoa(version)
+}
+
+// nextIndexName returns the name of the versioned index following the
+// provided index.
+func nextIndexName(name string) (string, error) {
+	version, err := strconv.Atoi(strings.TrimPrefix(name, indexPrefix))
+	if err != nil || !strings.HasPrefix(name, indexPrefix) {
+		return "", fmt.Errorf("index %s is not a versioned index", name)
+	}
+
+	return indexName(version + 1), nil
+}
+
+type getAliasResponseBody map[string]struct {
+	Aliases map[string]interface{} `json:"aliases"`
+}
+
+// aliasIndex returns the single index a get alias response points the
+// alias at.
+func aliasIndex(data []byte) (string, error) {
+	var responseBody getAliasResponseBody
+	if err := json.Unmarshal(data, &responseBody); err != nil {
+		return "", err
+	}
+
+	if len(responseBody) != 1 {
+		return "", fmt.Errorf("alias points at %d indices, expected 1", len(responseBody))
+	}
+
+	for name := range responseBody {
+		return name, nil
+	}
+
+	return "", nil
+}
+
+// aliasActions returns the update aliases body adding the aliases to
+// the index and removing them from the previous index, if any, in a
+// single atomic request.
+func aliasActions(previous, index string, aliases ...string) object {
+	actions := []object{}
+	for _, alias := range aliases {
+		if previous != "" {
+			actions = append(actions, object{
+				"remove": object{
+					"index": previous,
+					"alias": alias,
+				},
+			})
+		}
+
+		actions = append(actions, object{
+			"add": object{
+				"index": index,
+				"alias": alias,
+			},
+		})
+	}
+
+	return object{
+		"actions": actions,
+	}
+}
+
+// reindexBody returns the reindex request body copying documents from
+// the source index into the destination index. Documents already
+// written to the destination through the write alias are newer than
+// the source copies and are left in place.
+func reindexBody(source, destination string) object {
+	return object{
+		"conflicts": "proceed",
+		"source": object{
+			"index": source,
+		},
+		"dest": object{
+			"index":   destination,
+			"op_type": "create",
+		},
+	}
+}
+
+type reindexResponseBody struct {
+	Failures []json.RawMessage `json:"failures"`
+}
+
+// checkReindex returns an error listing the failures in a reindex
+// response.
+func checkReindex(data []byte) error {
+	var responseBody reindexResponseBody
+	if err := json.Unmarshal(data, &responseBody); err != nil {
+		return err
+	}
+
+	if len(responseBody.Failures) == 0 {
+		return nil
+	}
+
+	failures := make([]string, len(responseBody.Failures))
+	for i, failure := range responseBody.Failures {
+		failures[i] = string(failure)
+	}
+
+	return fmt.Errorf("reindex failures: %s", strings.Join(failures, ", "))
+}
+
+// deletesPageSize is the number of logged deletes read at once when
+// they are applied again after a reindex.
+const deletesPageSize = 100
+
+// deletesDefinition returns the create index body of the deletes log,
+// which records the delete queries run while a reindex is copying
+// documents. The log is created behind the deletes alias so that
+// deletes are only recorded while the alias exists.
+func deletesDefinition() object {
+	return object{
+		"mappings": object{
+			"properties": object{
+				"sequence": keywordField(),
+				"query": object{
+					"type":    "object",
+					"enabled": false,
+				},
+			},
+		},
+		"aliases": object{
+			deletesAlias: object{},
+		},
+	}
+}
+
+// deleteEntry returns the deletes log document recording the delete
+// query. Entries are read back in sequence order.
+func deleteEntry(now time.Time, query object) object {
+	return object{
+		"sequence": now.UTC().Format("20060102150405.000000000") + "-" + uuid.NewString(),
+		"query":    query,
+	}
+}
+
+// deletesPage returns the search body reading the page of logged
+// deletes following the provided sequence.
+func deletesPage(after string) searchBody {
+	body := searchBody{
+		Query: matchAllQuery(),
+		Size:  deletesPageSize,
+		Sort: []object{
+			{
+				"sequence": "asc",
+			},
+		},
+	}
+
+	if after != "" {
+		body.SearchAfter = []interface{}{after}
+	}
+
+	return body
+}
+
+type deletesResponseBody struct {
+	Hits struct {
+		Hits []struct {
+			Source loggedDelete `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+type loggedDelete struct {
+	Sequence string          `json:"sequence"`
+	Query    json.RawMessage `json:"query"`
+}
+
+// loggedDeletes returns the logged deletes in a deletes log search
+// response.
+func loggedDeletes(data []byte) ([]loggedDelete, error) {
+	var responseBody deletesResponseBody
+	if err := json.Unmarshal(data, &responseBody); err != nil {
+		return nil, err
+	}
+
+	output := make([]loggedDelete, len(responseBody.Hits.Hits))
+	for i, hit := range responseBody.Hits.Hits {
+		output[i] = hit.Source
+	}
+
+	return output, nil
+}
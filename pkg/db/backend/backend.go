@@ -0,0 +1,59 @@
+// Package backend creates the db.Databaser implementation selected in
+// the function configuration.
+package backend
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/db/bleve"
+	"github.com/forstmeier/findfile/pkg/db/postgres"
+	"github.com/forstmeier/findfile/pkg/db/sqlite"
+)
+
+// Backends supported by backend.New.
+const (
+	OpenSearch = "opensearch"
+	SQLite     = "sqlite"
+	Bleve      = "bleve"
+	Postgres   = "postgres"
+)
+
+// Config holds the values used to create a db.Databaser. Backend
+// defaults to "opensearch" if empty. URL, Username, Password, and
+// Analyzer are used by the OpenSearch backend, URL as the connection
+// URL by the PostgreSQL backend, and Path by the SQLite and Bleve
+// backends.
+type Config struct {
+	Backend  string
+	URL      string
+	Username string
+	Password string
+	Analyzer string
+	Path     string
+}
+
+// New generates the db.Databaser implementation selected by the
+// config.
+func New(newSession *session.Session, config Config) (db.Databaser, error) {
+	switch config.Backend {
+	case "", OpenSearch:
+		return db.New(newSession, config.URL, config.Username, config.Password, config.Analyzer)
+
+	case SQLite:
+		return sqlite.New(config.Path)
+
+	case Bleve:
+		return bleve.New(config.Path)
+
+	case Postgres:
+		return postgres.New(config.URL)
+
+	default:
+		return nil, &UnsupportedBackendError{
+			err: fmt.Errorf("unsupported backend %q", config.Backend),
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/db/bleve"
+	"github.com/forstmeier/findfile/pkg/db/postgres"
+	"github.com/forstmeier/findfile/pkg/db/sqlite"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		description string
+		config      Config
+		databaser   db.Databaser
+		error       error
+	}{
+		{
+			description: "default opensearch backend",
+			config: Config{
+				URL: "url",
+			},
+			databaser: &db.Client{},
+			error:     nil,
+		},
+		{
+			description: "sqlite backend",
+			config: Config{
+				Backend: SQLite,
+				Path:    filepath.Join(t.TempDir(), "findfile.db"),
+			},
+			databaser: &sqlite.Client{},
+			error:     nil,
+		},
+		{
+			description: "bleve backend",
+			config: Config{
+				Backend: Bleve,
+				Path:    filepath.Join(t.TempDir(), "findfile.bleve"),
+			},
+			databaser: &bleve.Client{},
+			error:     nil,
+		},
+		{
+			description: "postgres backend",
+			config: Config{
+				Backend: Postgres,
+				URL:     "postgres://localhost/findfile",
+			},
+			databaser: &postgres.Client{},
+			error:     nil,
+		},
+		{
+			description: "unsupported backend",
+			config: Config{
+				Backend: "dynamodb",
+			},
+			error: &UnsupportedBackendError{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			databaser, err := New(session.New(), test.config)
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *UnsupportedBackendError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else if reflect.TypeOf(databaser) != reflect.TypeOf(test.databaser) {
+				t.Errorf("incorrect databaser, received: %T, expected: %T", databaser, test.databaser)
+			}
+		})
+	}
+}
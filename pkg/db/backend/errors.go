@@ -0,0 +1,15 @@
+package backend
+
+import "fmt"
+
+const errorMessage = "package backend: %v"
+
+// UnsupportedBackendError is returned by backend.New when the
+// configured backend is not supported.
+type UnsupportedBackendError struct {
+	err error
+}
+
+func (e *UnsupportedBackendError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
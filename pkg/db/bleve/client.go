@@ -0,0 +1,309 @@
+// Package bleve implements db.Databaser with an embedded Bleve
+// full-text index persisted to a local directory.
+package bleve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+// batchSize is the number of documents read by each search when
+// deleting or copying documents.
+const batchSize = 1000
+
+var _ db.Databaser = &Client{}
+
+// Client implements the db.Databaser methods using Bleve.
+type Client struct {
+	index bleve.Index
+	path  string
+}
+
+// New generates a bleve.Client pointer instance with the index in the
+// directory at path, creating it if it does not exist; an empty path
+// holds the index in memory instead.
+func New(path string) (*Client, error) {
+	var index bleve.Index
+	var err error
+	if path != "" {
+		index, err = bleve.Open(path)
+	}
+
+	if path == "" || err == bleve.ErrorIndexPathDoesNotExist {
+		index, err = newIndex(path)
+	}
+
+	if err != nil {
+		return nil, &NewClientError{
+			err: err,
+		}
+	}
+
+	return &Client{
+		index: index,
+		path:  path,
+	}, nil
+}
+
+func newIndex(path string) (bleve.Index, error) {
+	indexMapping, err := newIndexMapping()
+	if err != nil {
+		return nil, err
+	}
+
+	var index bleve.Index
+	if path == "" {
+		index, err = bleve.NewMemOnly(indexMapping)
+	} else {
+		index, err = bleve.New(path, indexMapping)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := index.SetInternal([]byte(mappingVersionKey), []byte(strconv.Itoa(mappingVersion))); err != nil {
+		index.Close()
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// Close closes the underlying index.
+func (c *Client) Close() error {
+	return c.index.Close()
+}
+
+// SetupDatabase implements the db.Databaser.SetupDatabase method
+// using Bleve. The index is created by bleve.New so only its mapping
+// version is checked. An index with an older mapping is rebuilt from
+// its stored documents and a bleve.MappingMismatchError is returned if
+// the mapping is newer than this version knows.
+func (c *Client) SetupDatabase(ctx context.Context) error {
+	value, err := c.index.GetInternal([]byte(mappingVersionKey))
+	if err != nil {
+		return &MappingMismatchError{
+			err: err,
+		}
+	}
+
+	version, _ := strconv.Atoi(string(value))
+	if version > mappingVersion {
+		return &MappingMismatchError{
+			err: fmt.Errorf("index has mapping version %q, expected version %d", value, mappingVersion),
+		}
+	}
+
+	if version < mappingVersion {
+		if err := c.rebuild(ctx); err != nil {
+			return &RebuildIndexError{
+				err: err,
+			}
+		}
+	}
+
+	return nil
+}
+
+// rebuild copies the stored documents into a new index with the current
+// mapping and replaces the index with it. The new index is built beside
+// the current one, which is kept in place if the copy fails.
+func (c *Client) rebuild(ctx context.Context) error {
+	rebuildPath := ""
+	if c.path != "" {
+		rebuildPath = c.path + ".rebuild"
+		if err := os.RemoveAll(rebuildPath); err != nil {
+			return err
+		}
+	}
+
+	index, err := newIndex(rebuildPath)
+	if err != nil {
+		return err
+	}
+
+	if err := copyDocuments(ctx, c.index, index); err != nil {
+		index.Close()
+		return err
+	}
+
+	if c.path == "" {
+		c.index.Close()
+		c.index = index
+		return nil
+	}
+
+	if err := index.Close(); err != nil {
+		return err
+	}
+
+	if err := c.index.Close(); err != nil {
+		return err
+	}
+
+	previousPath := c.path + ".previous"
+	if err := os.RemoveAll(previousPath); err != nil {
+		return err
+	}
+
+	if err := os.Rename(c.path, previousPath); err != nil {
+		return err
+	}
+
+	if err := os.Rename(rebuildPath, c.path); err != nil {
+		return err
+	}
+
+	c.index, err = bleve.Open(c.path)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(previousPath)
+}
+
+// copyDocuments indexes the stored documents of one index into another
+// in batches ordered by ID.
+func copyDocuments(ctx context.Context, from, to bleve.Index) error {
+	var after []string
+	for {
+		request := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), batchSize, 0, false)
+		request.Fields = []string{sourceField}
+		request.SortBy([]string{"_id"})
+		if after != nil {
+			request.SetSearchAfter(after)
+		}
+
+		result, err := from.SearchInContext(ctx, request)
+		if err != nil {
+			return err
+		}
+
+		if len(result.Hits) == 0 {
+			return nil
+		}
+
+		batch := to.NewBatch()
+		for _, hit := range result.Hits {
+			source, _ := hit.Fields[sourceField].(string)
+
+			document := pars.Document{}
+			if err := json.Unmarshal([]byte(source), &document); err != nil {
+				return err
+			}
+
+			indexRecord, err := newRecord(document)
+			if err != nil {
+				return err
+			}
+
+			if err := batch.Index(hit.ID, indexRecord); err != nil {
+				return err
+			}
+		}
+
+		if err := to.Batch(batch); err != nil {
+			return err
+		}
+
+		after = []string{result.Hits[len(result.Hits)-1].ID}
+	}
+}
+
+// UpsertDocuments implements the db.Databaser.UpsertDocuments method
+// using Bleve.
+func (c *Client) UpsertDocuments(ctx context.Context, documents []pars.Document) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	batch := c.index.NewBatch()
+	for _, document := range documents {
+		indexRecord, err := newRecord(document)
+		if err != nil {
+			return &IndexDocumentsError{
+				err: err,
+			}
+		}
+
+		if err := batch.Index(document.ID, indexRecord); err != nil {
+			return &IndexDocumentsError{
+				err: err,
+			}
+		}
+	}
+
+	if err := c.index.Batch(batch); err != nil {
+		return &IndexDocumentsError{
+			err: err,
+		}
+	}
+
+	return nil
+}
+
+// DeleteDocumentsByKeys implements the db.Databaser.DeleteDocumentsByKeys
+// method using Bleve.
+func (c *Client) DeleteDocumentsByKeys(ctx context.Context, fileKeys []db.FileKey) error {
+	if len(fileKeys) == 0 {
+		return nil
+	}
+
+	files := []query.Query{}
+	for _, fileKey := range fileKeys {
+		files = append(files, bleve.NewConjunctionQuery(
+			termQuery(fileBucketField, fileKey.Bucket),
+			termQuery(fileKeyField, fileKey.Key),
+		))
+	}
+
+	return c.deleteDocuments(ctx, bleve.NewDisjunctionQuery(files...))
+}
+
+// DeleteDocumentsByBuckets implements the
+// db.Databaser.DeleteDocumentsByBuckets method using Bleve.
+func (c *Client) DeleteDocumentsByBuckets(ctx context.Context, buckets []string) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	return c.deleteDocuments(ctx, termsQuery(fileBucketField, buckets))
+}
+
+// deleteDocuments removes the documents matching the query in batches
+// until none remain.
+func (c *Client) deleteDocuments(ctx context.Context, deleteQuery query.Query) error {
+	for {
+		request := bleve.NewSearchRequestOptions(deleteQuery, batchSize, 0, false)
+		result, err := c.index.SearchInContext(ctx, request)
+		if err != nil {
+			return &DeleteDocumentsError{
+				err: err,
+			}
+		}
+
+		if len(result.Hits) == 0 {
+			return nil
+		}
+
+		batch := c.index.NewBatch()
+		for _, hit := range result.Hits {
+			batch.Delete(hit.ID)
+		}
+
+		if err := c.index.Batch(batch); err != nil {
+			return &DeleteDocumentsError{
+				err: err,
+			}
+		}
+	}
+}
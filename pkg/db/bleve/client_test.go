@@ -0,0 +1,166 @@
+package bleve
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/db/dbtest"
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+func newTestClient(t *testing.T) *Client {
+	client, err := New(filepath.Join(t.TempDir(), "findfile.bleve"))
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	return client
+}
+
+func TestClient(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) db.Databaser {
+		return newTestClient(t)
+	})
+}
+
+func TestSetupDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "findfile.bleve")
+	ctx := context.Background()
+
+	client, err := New(path)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+
+	if err := client.SetupDatabase(ctx); err != nil {
+		t.Fatalf("incorrect error, received: %v, expected: nil", err)
+	}
+
+	if err := client.UpsertDocuments(ctx, []pars.Document{
+		{
+			ID:         "doc_id",
+			FileBucket: "bucket",
+			FileKey:    "key.pdf",
+		},
+	}); err != nil {
+		t.Fatalf("error upserting documents: %v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("error closing client: %v", err)
+	}
+
+	client, err = New(path)
+	if err != nil {
+		t.Fatalf("error reopening client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.SetupDatabase(ctx); err != nil {
+		t.Fatalf("incorrect error on existing index, received: %v, expected: nil", err)
+	}
+
+	count, err := client.index.DocCount()
+	if err != nil {
+		t.Fatalf("error counting documents: %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("incorrect document count, received: %d, expected: 1", count)
+	}
+
+	if err := client.index.SetInternal([]byte(mappingVersionKey), []byte(strconv.Itoa(mappingVersion+1))); err != nil {
+		t.Fatalf("error setting mapping version: %v", err)
+	}
+
+	err = client.SetupDatabase(ctx)
+	var mismatchErr *MappingMismatchError
+	if !errors.As(err, &mismatchErr) {
+		t.Errorf("incorrect error, received: %v, expected: %T", err, mismatchErr)
+	}
+}
+
+func TestSetupDatabaseRebuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "findfile.bleve")
+	ctx := context.Background()
+
+	client, err := New(path)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+
+	documents := []pars.Document{}
+	for i := 0; i < batchSize+1; i++ {
+		documents = append(documents, pars.Document{
+			ID:         "doc_id_" + strconv.Itoa(i),
+			FileBucket: "bucket",
+			FileKey:    "key_" + strconv.Itoa(i) + ".pdf",
+			Pages: []pars.Page{
+				{
+					Lines: []pars.Line{
+						{
+							Text: "rebuilt text",
+						},
+					},
+				},
+			},
+		})
+	}
+
+	if err := client.UpsertDocuments(ctx, documents); err != nil {
+		t.Fatalf("error upserting documents: %v", err)
+	}
+
+	if err := client.index.SetInternal([]byte(mappingVersionKey), []byte(strconv.Itoa(mappingVersion-1))); err != nil {
+		t.Fatalf("error setting mapping version: %v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("error closing client: %v", err)
+	}
+
+	client, err = New(path)
+	if err != nil {
+		t.Fatalf("error reopening client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.SetupDatabase(ctx); err != nil {
+		t.Fatalf("incorrect error, received: %v, expected: nil", err)
+	}
+
+	value, err := client.index.GetInternal([]byte(mappingVersionKey))
+	if err != nil {
+		t.Fatalf("error getting mapping version: %v", err)
+	}
+
+	if string(value) != strconv.Itoa(mappingVersion) {
+		t.Errorf("incorrect mapping version, received: %s, expected: %d", value, mappingVersion)
+	}
+
+	result, err := client.QueryDocuments(ctx, db.Query{
+		Text: "rebuilt",
+	})
+	if err != nil {
+		t.Fatalf("error querying documents: %v", err)
+	}
+
+	if result.Total != int64(len(documents)) {
+		t.Errorf("incorrect total, received: %d, expected: %d", result.Total, len(documents))
+	}
+
+	for _, leftover := range []string{path + ".rebuild", path + ".previous"} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("incorrect leftover index %s, received: %v, expected: not exist", leftover, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package bleve
+
+import "fmt"
+
+const errorMessage = "package bleve: %v"
+
+// NewClientError wraps errors returned by bleve.New.
+type NewClientError struct {
+	err error
+}
+
+func (e *NewClientError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// MappingMismatchError is returned by db.Databaser.SetupDatabase when
+// the existing index was created with a different mapping version.
+type MappingMismatchError struct {
+	err error
+}
+
+func (e *MappingMismatchError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// RebuildIndexError wraps errors returned copying the documents of an
+// index with an older mapping into a new index in
+// db.Databaser.SetupDatabase.
+type RebuildIndexError struct {
+	err error
+}
+
+func (e *RebuildIndexError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// IndexDocumentsError wraps errors returned by the index batch in
+// db.Databaser.UpsertDocuments.
+type IndexDocumentsError struct {
+	err error
+}
+
+func (e *IndexDocumentsError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// DeleteDocumentsError wraps errors returned by the index searches and
+// batches in db.Databaser.DeleteDocumentsByKeys and
+// db.Databaser.DeleteDocumentsByBuckets.
+type DeleteDocumentsError struct {
+	err error
+}
+
+func (e *DeleteDocumentsError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// QueryDocumentsError wraps errors returned by the index searches in
+// db.Databaser.QueryDocuments.
+type QueryDocumentsError struct {
+	err error
+}
+
+func (e *QueryDocumentsError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
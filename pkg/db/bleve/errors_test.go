@@ -0,0 +1,84 @@
+package bleve
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewClientError(t *testing.T) {
+	err := &NewClientError{
+		err: errors.New("mock new client error"),
+	}
+
+	recieved := err.Error()
+	expected := "package bleve: mock new client error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestMappingMismatchError(t *testing.T) {
+	err := &MappingMismatchError{
+		err: errors.New("mock mapping mismatch error"),
+	}
+
+	recieved := err.Error()
+	expected := "package bleve: mock mapping mismatch error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestRebuildIndexError(t *testing.T) {
+	err := &RebuildIndexError{
+		err: errors.New("mock rebuild index error"),
+	}
+
+	recieved := err.Error()
+	expected := "package bleve: mock rebuild index error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestIndexDocumentsError(t *testing.T) {
+	err := &IndexDocumentsError{
+		err: errors.New("mock index documents error"),
+	}
+
+	recieved := err.Error()
+	expected := "package bleve: mock index documents error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestDeleteDocumentsError(t *testing.T) {
+	err := &DeleteDocumentsError{
+		err: errors.New("mock delete documents error"),
+	}
+
+	recieved := err.Error()
+	expected := "package bleve: mock delete documents error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestQueryDocumentsError(t *testing.T) {
+	err := &QueryDocumentsError{
+		err: errors.New("mock query documents error"),
+	}
+
+	recieved := err.Error()
+	expected := "package bleve: mock query documents error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
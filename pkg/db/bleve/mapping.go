@@ -0,0 +1,212 @@
+package bleve
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/mapping"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+const (
+	mappingVersion = 4
+
+	mappingVersionKey = "mapping_version"
+
+	// lineTextAnalyzer splits text on Unicode word boundaries and
+	// lowercases it like the OpenSearch "standard" analyzer.
+	lineTextAnalyzer = "line_text"
+
+	fileBucketField   = "file_bucket"
+	fileKeyField      = "file_key"
+	fileExtField      = "file_extension"
+	contentTypeField  = "content_type"
+	lastModifiedField = "last_modified"
+	indexedAtField    = "indexed_at"
+	skippedField      = "skipped"
+	linesField        = "lines"
+	confidenceField   = "confidence"
+	fieldsField       = "fields"
+	fieldConfField    = "field_confidence"
+	sourceField       = "source"
+
+	maxConfidenceBand = 100
+)
+
+// record is the indexed form of a pars.Document. Lines holds the text
+// of every line in page and line order so that match locations can be
+// traced back to their line by array position. Confidence holds the
+// same text split by the whole-percent confidence band of each line,
+// keyed by band, so that queries can skip low confidence lines; each
+// band holds its lines in page and line order. Fields and
+// FieldConfidence hold the form fields as single terms of their
+// normalized key and value in the same way. Skipped is "true" for the
+// records of files that were not parsed and "false" otherwise. Source
+// holds the stored document returned in results.
+type record struct {
+	FileBucket      string              `json:"file_bucket"`
+	FileKey         string              `json:"file_key"`
+	FileExtension   string              `json:"file_extension"`
+	ContentType     string              `json:"content_type"`
+	LastModified    *time.Time          `json:"last_modified,omitempty"`
+	IndexedAt       *time.Time          `json:"indexed_at,omitempty"`
+	Skipped         string              `json:"skipped"`
+	Lines           []string            `json:"lines"`
+	Confidence      map[string][]string `json:"confidence"`
+	Fields          []string            `json:"fields"`
+	FieldConfidence map[string][]string `json:"field_confidence"`
+	Source          string              `json:"source"`
+}
+
+func newRecord(document pars.Document) (record, error) {
+	source, err := json.Marshal(document)
+	if err != nil {
+		return record{}, err
+	}
+
+	lines := []string{}
+	confidence := map[string][]string{}
+	fields := []string{}
+	fieldConfidence := map[string][]string{}
+	for _, page := range document.Pages {
+		for _, line := range page.Lines {
+			lines = append(lines, line.Text)
+
+			band := strconv.Itoa(confidenceBand(line.Confidence))
+			confidence[band] = append(confidence[band], line.Text)
+		}
+
+		for _, field := range page.Fields {
+			term := fieldTerm(field.Key, field.Value)
+			fields = append(fields, term)
+
+			band := strconv.Itoa(confidenceBand(field.Confidence))
+			fieldConfidence[band] = append(fieldConfidence[band], term)
+		}
+	}
+
+	return record{
+		FileBucket:      document.FileBucket,
+		FileKey:         document.FileKey,
+		FileExtension:   document.FileExtension,
+		ContentType:     document.ContentType,
+		LastModified:    document.LastModified,
+		IndexedAt:       document.IndexedAt,
+		Skipped:         strconv.FormatBool(document.SkipReason != ""),
+		Lines:           lines,
+		Confidence:      confidence,
+		Fields:          fields,
+		FieldConfidence: fieldConfidence,
+		Source:          string(source),
+	}, nil
+}
+
+// newIndexMapping returns the explicit mapping for the index; fields
+// not listed are ignored.
+func newIndexMapping() (mapping.IndexMapping, error) {
+	indexMapping := bleve.NewIndexMapping()
+	if err := indexMapping.AddCustomAnalyzer(lineTextAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name},
+	}); err != nil {
+		return nil, err
+	}
+
+	documentMapping := bleve.NewDocumentStaticMapping()
+
+	for _, field := range []string{fileBucketField, fileKeyField, fileExtField, contentTypeField, skippedField} {
+		keywordMapping := bleve.NewKeywordFieldMapping()
+		keywordMapping.IncludeInAll = false
+		documentMapping.AddFieldMappingsAt(field, keywordMapping)
+	}
+
+	for _, field := range []string{lastModifiedField, indexedAtField} {
+		dateMapping := bleve.NewDateTimeFieldMapping()
+		dateMapping.IncludeInAll = false
+		documentMapping.AddFieldMappingsAt(field, dateMapping)
+	}
+
+	documentMapping.AddFieldMappingsAt(linesField, linesFieldMapping())
+
+	confidenceMapping := bleve.NewDocumentStaticMapping()
+	for band := 0; band <= maxConfidenceBand; band++ {
+		confidenceMapping.AddFieldMappingsAt(strconv.Itoa(band), linesFieldMapping())
+	}
+	documentMapping.AddSubDocumentMapping(confidenceField, confidenceMapping)
+
+	documentMapping.AddFieldMappingsAt(fieldsField, fieldsFieldMapping())
+
+	fieldConfidenceMapping := bleve.NewDocumentStaticMapping()
+	for band := 0; band <= maxConfidenceBand; band++ {
+		fieldConfidenceMapping.AddFieldMappingsAt(strconv.Itoa(band), fieldsFieldMapping())
+	}
+	documentMapping.AddSubDocumentMapping(fieldConfField, fieldConfidenceMapping)
+
+	sourceMapping := bleve.NewTextFieldMapping()
+	sourceMapping.Index = false
+	sourceMapping.IncludeInAll = false
+	sourceMapping.IncludeTermVectors = false
+	documentMapping.AddFieldMappingsAt(sourceField, sourceMapping)
+
+	indexMapping.DefaultMapping = documentMapping
+
+	return indexMapping, nil
+}
+
+// linesFieldMapping returns the mapping of the fields holding line
+// text, which keep term locations for highlighting.
+func linesFieldMapping() *mapping.FieldMapping {
+	linesMapping := bleve.NewTextFieldMapping()
+	linesMapping.Analyzer = lineTextAnalyzer
+	linesMapping.Store = false
+	linesMapping.IncludeInAll = false
+	linesMapping.IncludeTermVectors = true
+	return linesMapping
+}
+
+// fieldsFieldMapping returns the mapping of the fields holding form
+// field terms, which are matched whole.
+func fieldsFieldMapping() *mapping.FieldMapping {
+	fieldsMapping := bleve.NewKeywordFieldMapping()
+	fieldsMapping.Store = false
+	fieldsMapping.IncludeInAll = false
+	fieldsMapping.IncludeTermVectors = false
+	return fieldsMapping
+}
+
+// fieldTerm returns the single term a form field is indexed and
+// searched as.
+func fieldTerm(key, value string) string {
+	return db.NormalizeField(key) + "=" + db.NormalizeField(value)
+}
+
+// confidenceBand returns the whole-percent band of a line or form
+// field confidence.
+func confidenceBand(confidence float64) int {
+	return int(math.Max(0, math.Min(maxConfidenceBand, math.Floor(confidence))))
+}
+
+// bandField returns the field under the provided confidence field
+// holding the values in a confidence band.
+func bandField(field string, band int) string {
+	return field + "." + strconv.Itoa(band)
+}
+
+// timeValue returns the time or the zero time, which Bleve treats as
+// an unbounded end of a date range.
+func timeValue(value *time.Time) time.Time {
+	if value == nil {
+		return time.Time{}
+	}
+
+	return *value
+}
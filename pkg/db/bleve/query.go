@@ -0,0 +1,547 @@
+package bleve
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/db/internal/match"
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+// relevanceBatchSize is the number of hits read at a time when paging
+// through relevance sorted results to find the cursor position.
+const relevanceBatchSize = 100
+
+func termQuery(field, term string) query.Query {
+	output := bleve.NewTermQuery(term)
+	output.SetField(field)
+	return output
+}
+
+func termsQuery(field string, terms []string) query.Query {
+	queries := make([]query.Query, len(terms))
+	for i, term := range terms {
+		queries[i] = termQuery(field, term)
+	}
+
+	return bleve.NewDisjunctionQuery(queries...)
+}
+
+// compile converts the query into a Bleve query. Validate must be
+// called before compile.
+func (c *Client) compile(input db.Query) (query.Query, error) {
+	queries := []query.Query{}
+
+	if root := input.Root(); root != nil {
+		fields, err := c.searchFields(input.MinConfidence)
+		if err != nil {
+			return nil, err
+		}
+
+		clauseQuery, err := c.compileClause(*root, fields)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, clauseQuery)
+	}
+
+	if input.Filters != nil {
+		queries = append(queries, filtersQuery(*input.Filters)...)
+	}
+
+	if len(queries) == 0 {
+		return bleve.NewMatchAllQuery(), nil
+	}
+
+	return bleve.NewConjunctionQuery(queries...), nil
+}
+
+// searchFields holds the fields searched by term clauses, which hold
+// line text, and by form field clauses.
+type searchFields struct {
+	lines []string
+	forms []string
+}
+
+// searchFields returns the fields holding the lines and form fields
+// with at least the minimum confidence, compared in whole percent: the
+// lines and fields fields for all of them or the confidence bands
+// present in the index.
+func (c *Client) searchFields(minConfidence float64) (searchFields, error) {
+	if minConfidence == 0 {
+		return searchFields{
+			lines: []string{linesField},
+			forms: []string{fieldsField},
+		}, nil
+	}
+
+	indexed, err := c.index.Fields()
+	if err != nil {
+		return searchFields{}, err
+	}
+
+	present := map[string]struct{}{}
+	for _, field := range indexed {
+		present[field] = struct{}{}
+	}
+
+	output := searchFields{
+		lines: []string{},
+		forms: []string{},
+	}
+	for band := confidenceBand(minConfidence); band <= maxConfidenceBand; band++ {
+		if _, ok := present[bandField(confidenceField, band)]; ok {
+			output.lines = append(output.lines, bandField(confidenceField, band))
+		}
+
+		if _, ok := present[bandField(fieldConfField, band)]; ok {
+			output.forms = append(output.forms, bandField(fieldConfField, band))
+		}
+	}
+
+	return output, nil
+}
+
+// fieldsQuery returns a query matching any of the fields with the
+// query built for each.
+func fieldsQuery(fields []string, fieldQuery func(field string) query.Query) query.Query {
+	if len(fields) == 0 {
+		return bleve.NewMatchNoneQuery()
+	}
+
+	if len(fields) == 1 {
+		return fieldQuery(fields[0])
+	}
+
+	queries := make([]query.Query, len(fields))
+	for i, field := range fields {
+		queries[i] = fieldQuery(field)
+	}
+
+	return bleve.NewDisjunctionQuery(queries...)
+}
+
+func (c *Client) compileClause(clause db.Clause, fields searchFields) (query.Query, error) {
+	switch clause.Type {
+	case db.ClauseAnd, db.ClauseOr, db.ClauseNot:
+		children := []query.Query{}
+		for _, child := range clause.Clauses {
+			childQuery, err := c.compileClause(child, fields)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, childQuery)
+		}
+
+		switch clause.Type {
+		case db.ClauseAnd:
+			return bleve.NewConjunctionQuery(children...), nil
+		case db.ClauseOr:
+			return bleve.NewDisjunctionQuery(children...), nil
+		default:
+			output := bleve.NewBooleanQuery()
+			output.AddMust(bleve.NewMatchAllQuery())
+			output.AddMustNot(children...)
+			return output, nil
+		}
+
+	case db.ClausePhrase:
+		return fieldsQuery(fields.lines, func(field string) query.Query {
+			output := bleve.NewMatchPhraseQuery(clause.Text)
+			output.SetField(field)
+			return output
+		}), nil
+
+	case db.ClausePrefix:
+		return fieldsQuery(fields.lines, func(field string) query.Query {
+			output := bleve.NewPrefixQuery(strings.ToLower(clause.Text))
+			output.SetField(field)
+			return output
+		}), nil
+
+	case db.ClauseWildcard:
+		return fieldsQuery(fields.lines, func(field string) query.Query {
+			output := bleve.NewWildcardQuery(strings.ToLower(clause.Text))
+			output.SetField(field)
+			return output
+		}), nil
+
+	case db.ClauseField:
+		term := fieldTerm(clause.Field, clause.Text)
+		return fieldsQuery(fields.forms, func(field string) query.Query {
+			return termQuery(field, term)
+		}), nil
+
+	default:
+		return c.matchQuery(clause.Text, strings.ToUpper(clause.Fuzziness), fields.lines)
+	}
+}
+
+// matchQuery returns a query matching any of the analyzed terms in
+// text within the fields. Fuzzy terms are expanded against the indexed
+// line terms so the allowed edits and transpositions follow OpenSearch.
+func (c *Client) matchQuery(text, fuzziness string, fields []string) (query.Query, error) {
+	var vocabulary []string
+	terms := []string{}
+	for _, token := range c.index.Mapping().AnalyzerNamed(lineTextAnalyzer).Analyze([]byte(text)) {
+		term := string(token.Term)
+		edits := match.MaxEdits(term, fuzziness)
+		if edits == 0 {
+			terms = append(terms, term)
+			continue
+		}
+
+		if vocabulary == nil {
+			var err error
+			vocabulary, err = c.vocabulary()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		terms = append(terms, match.Expand(term, edits, vocabulary)...)
+	}
+
+	if len(terms) == 0 {
+		return bleve.NewMatchNoneQuery(), nil
+	}
+
+	return fieldsQuery(fields, func(field string) query.Query {
+		return termsQuery(field, terms)
+	}), nil
+}
+
+// vocabulary returns the terms indexed in the lines field.
+func (c *Client) vocabulary() ([]string, error) {
+	dictionary, err := c.index.FieldDict(linesField)
+	if err != nil {
+		return nil, err
+	}
+	defer dictionary.Close()
+
+	output := []string{}
+	entry, err := dictionary.Next()
+	for err == nil && entry != nil {
+		output = append(output, entry.Term)
+		entry, err = dictionary.Next()
+	}
+
+	return output, err
+}
+
+func filtersQuery(filters db.Filters) []query.Query {
+	queries := []query.Query{}
+
+	if len(filters.Buckets) > 0 {
+		queries = append(queries, termsQuery(fileBucketField, filters.Buckets))
+	}
+
+	if filters.KeyPrefix != "" {
+		prefixQuery := bleve.NewPrefixQuery(filters.KeyPrefix)
+		prefixQuery.SetField(fileKeyField)
+		queries = append(queries, prefixQuery)
+	}
+
+	if len(filters.FileTypes) > 0 {
+		fileTypes := []query.Query{}
+		for _, fileType := range filters.FileTypes {
+			if strings.Contains(fileType, "/") {
+				fileTypes = append(fileTypes, termQuery(contentTypeField, strings.ToLower(strings.TrimSpace(fileType))))
+			} else {
+				fileTypes = append(fileTypes, termQuery(fileExtField, strings.ToLower(strings.TrimPrefix(strings.TrimSpace(fileType), "."))))
+			}
+		}
+
+		queries = append(queries, bleve.NewDisjunctionQuery(fileTypes...))
+	}
+
+	for field, dateRange := range map[string]*db.DateRange{
+		lastModifiedField: filters.LastModified,
+		indexedAtField:    filters.IndexedAt,
+	} {
+		if dateRange == nil {
+			continue
+		}
+
+		inclusive := true
+		rangeQuery := bleve.NewDateRangeInclusiveQuery(timeValue(dateRange.From), timeValue(dateRange.To), &inclusive, &inclusive)
+		rangeQuery.SetField(field)
+		queries = append(queries, rangeQuery)
+	}
+
+	if filters.Skipped != nil {
+		queries = append(queries, termQuery(skippedField, strconv.FormatBool(*filters.Skipped)))
+	}
+
+	return queries
+}
+
+// QueryDocuments implements the db.Databaser.QueryDocuments method
+// using Bleve with the same matching, filtering, sorting, and
+// pagination behavior as db.Client.
+func (c *Client) QueryDocuments(ctx context.Context, input db.Query) (*db.Result, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := &db.Result{
+		Hits: []db.Hit{},
+	}
+
+	if input.IsEmpty() {
+		return result, nil
+	}
+
+	searchQuery, err := c.compile(input)
+	if err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	var hits search.DocumentMatchCollection
+	var total uint64
+	if input.SortField() == db.SortRelevance {
+		hits, total, err = c.searchRelevance(ctx, input, searchQuery)
+	} else {
+		hits, total, err = c.searchSorted(ctx, input, searchQuery)
+	}
+	if err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	result.Total = int64(total)
+
+	if len(hits) > input.PageSize() {
+		hits = hits[:input.PageSize()]
+
+		next, err := input.EncodeCursor(cursorValues(input, hits[len(hits)-1]))
+		if err != nil {
+			return nil, &QueryDocumentsError{
+				err: err,
+			}
+		}
+		result.Next = next
+	}
+
+	for _, hit := range hits {
+		source, _ := hit.Fields[sourceField].(string)
+
+		document := pars.Document{}
+		if err := json.Unmarshal([]byte(source), &document); err != nil {
+			return nil, &QueryDocumentsError{
+				err: err,
+			}
+		}
+
+		result.Hits = append(result.Hits, db.Hit{
+			Document: document,
+			Matches:  matches(document, hit.Locations),
+		})
+	}
+
+	return result, nil
+}
+
+func newSearchRequest(searchQuery query.Query, size, from int) *bleve.SearchRequest {
+	request := bleve.NewSearchRequestOptions(searchQuery, size, from, false)
+	request.Fields = []string{sourceField}
+	request.IncludeLocations = true
+	return request
+}
+
+// searchSorted returns the page of hits sorted by a document field
+// following the cursor along with the total number of matches. The
+// cursor holds the Bleve sort values of the last hit.
+func (c *Client) searchSorted(ctx context.Context, input db.Query, searchQuery query.Query) (search.DocumentMatchCollection, uint64, error) {
+	field := fileKeyField
+	if input.SortField() == db.SortIndexedAt {
+		field = indexedAtField
+	}
+
+	request := newSearchRequest(searchQuery, input.PageSize()+1, 0)
+	request.SortByCustom(search.SortOrder{
+		&search.SortField{
+			Field:   field,
+			Desc:    input.SortOrder() == db.OrderDesc,
+			Missing: search.SortFieldMissingLast,
+		},
+		&search.SortDocID{},
+	})
+
+	if input.Cursor != "" {
+		values, err := input.SearchAfter()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		after := []string{}
+		for _, value := range values {
+			encoded, _ := value.(string)
+			decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, 0, err
+			}
+			after = append(after, string(decoded))
+		}
+		request.SetSearchAfter(after)
+	}
+
+	result, err := c.index.SearchInContext(ctx, request)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result.Hits, result.Total, nil
+}
+
+// searchRelevance returns the page of hits sorted by score following
+// the cursor along with the total number of matches. Bleve cannot
+// search after a score so the hits are read in batches until the
+// cursor position is passed.
+func (c *Client) searchRelevance(ctx context.Context, input db.Query, searchQuery query.Query) (search.DocumentMatchCollection, uint64, error) {
+	descending := input.SortOrder() == db.OrderDesc
+
+	var afterScore float64
+	var afterID string
+	if input.Cursor != "" {
+		values, err := input.SearchAfter()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if len(values) == 2 {
+			afterScore, _ = values[0].(float64)
+			afterID, _ = values[1].(string)
+		}
+	}
+
+	hits := search.DocumentMatchCollection{}
+	for from := 0; ; from += relevanceBatchSize {
+		request := newSearchRequest(searchQuery, relevanceBatchSize, from)
+		request.SortByCustom(search.SortOrder{
+			&search.SortScore{
+				Desc: descending,
+			},
+			&search.SortDocID{},
+		})
+
+		result, err := c.index.SearchInContext(ctx, request)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, hit := range result.Hits {
+			if input.Cursor != "" && !after(hit.Score, hit.ID, afterScore, afterID, descending) {
+				continue
+			}
+
+			hits = append(hits, hit)
+			if len(hits) > input.PageSize() {
+				return hits, result.Total, nil
+			}
+		}
+
+		if len(result.Hits) < relevanceBatchSize {
+			return hits, result.Total, nil
+		}
+	}
+}
+
+// after reports whether the hit sorts after the cursor with the score
+// in the requested direction and the document ID ascending as a
+// tiebreaker.
+func after(score float64, id string, afterScore float64, afterID string, descending bool) bool {
+	if score != afterScore {
+		return (score < afterScore) == descending
+	}
+
+	return id > afterID
+}
+
+// cursorValues returns the values identifying the position of the hit
+// in the sort order. Bleve sort values are binary encoded so they are
+// base64 encoded to survive JSON.
+func cursorValues(input db.Query, hit *search.DocumentMatch) []interface{} {
+	if input.SortField() == db.SortRelevance {
+		return []interface{}{hit.Score, hit.ID}
+	}
+
+	values := []interface{}{}
+	for _, value := range hit.Sort {
+		values = append(values, base64.RawURLEncoding.EncodeToString([]byte(value)))
+	}
+
+	return values
+}
+
+// matches converts the locations of the matched terms in the line
+// text fields into matches in page and line order. In the lines field
+// each location's array position is the index of its line across all
+// pages and in a confidence band field it is the index of its line
+// among the lines in the band.
+func matches(document pars.Document, fieldLocations search.FieldTermLocationMap) []db.Match {
+	type position struct {
+		field string
+		index int
+	}
+
+	lines := map[position]int{}
+	bands := map[string]int{}
+	index := 0
+	for _, page := range document.Pages {
+		for _, line := range page.Lines {
+			lines[position{field: linesField, index: index}] = index
+
+			field := bandField(confidenceField, confidenceBand(line.Confidence))
+			lines[position{field: field, index: bands[field]}] = index
+			bands[field]++
+
+			index++
+		}
+	}
+
+	spans := map[int][]match.Span{}
+	for field, termLocations := range fieldLocations {
+		for _, locations := range termLocations {
+			for _, location := range locations {
+				if len(location.ArrayPositions) == 0 {
+					continue
+				}
+
+				line, ok := lines[position{field: field, index: int(location.ArrayPositions[0])}]
+				if !ok {
+					continue
+				}
+
+				spans[line] = append(spans[line], match.Span{
+					Start: int(location.Start),
+					End:   int(location.End),
+				})
+			}
+		}
+	}
+
+	output := []db.Match{}
+	index = 0
+	for _, page := range document.Pages {
+		for _, line := range page.Lines {
+			if lineSpans, ok := spans[index]; ok {
+				output = append(output, db.NewMatch(page.PageNumber, line, match.Highlight(line.Text, lineSpans)))
+			}
+			index++
+		}
+	}
+
+	return output
+}
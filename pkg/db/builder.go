@@ -0,0 +1,221 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// object holds a JSON object in an OpenSearch request body. Request
+// bodies are only ever assembled from objects and typed queries and
+// then encoded with encoding/json so user-provided values are always
+// escaped and can never change the structure of a request.
+type object map[string]interface{}
+
+// boolQuery is the OpenSearch "bool" compound query.
+type boolQuery struct {
+	Must               []object `json:"must,omitempty"`
+	Should             []object `json:"should,omitempty"`
+	MustNot            []object `json:"must_not,omitempty"`
+	Filter             []object `json:"filter,omitempty"`
+	MinimumShouldMatch int      `json:"minimum_should_match,omitempty"`
+}
+
+func (b boolQuery) object() object {
+	return object{
+		"bool": b,
+	}
+}
+
+// searchBody is the OpenSearch search request body.
+type searchBody struct {
+	Query          object        `json:"query"`
+	Size           int           `json:"size,omitempty"`
+	Sort           []object      `json:"sort,omitempty"`
+	SearchAfter    []interface{} `json:"search_after,omitempty"`
+	TrackTotalHits bool          `json:"track_total_hits,omitempty"`
+}
+
+func searchRequest(query object) object {
+	return object{
+		"query": query,
+	}
+}
+
+func matchQuery(field, text, fuzziness string) object {
+	match := object{
+		"query": text,
+	}
+
+	if fuzziness != "" {
+		match["fuzziness"] = fuzziness
+	}
+
+	return object{
+		"match": object{
+			field: match,
+		},
+	}
+}
+
+func matchPhraseQuery(field, text string) object {
+	return object{
+		"match_phrase": object{
+			field: object{
+				"query": text,
+			},
+		},
+	}
+}
+
+func prefixQuery(field, value string) object {
+	return object{
+		"prefix": object{
+			field: object{
+				"value": value,
+			},
+		},
+	}
+}
+
+func wildcardQuery(field, value string) object {
+	return object{
+		"wildcard": object{
+			field: object{
+				"value": value,
+			},
+		},
+	}
+}
+
+func matchAllQuery() object {
+	return object{
+		"match_all": object{},
+	}
+}
+
+func termQuery(field, value string) object {
+	return object{
+		"term": object{
+			field: value,
+		},
+	}
+}
+
+func termsQuery(field string, values []string) object {
+	return object{
+		"terms": object{
+			field: values,
+		},
+	}
+}
+
+func existsQuery(field string) object {
+	return object{
+		"exists": object{
+			"field": field,
+		},
+	}
+}
+
+func rangeQuery(field string, from, to *time.Time) object {
+	bounds := object{}
+
+	if from != nil {
+		bounds["gte"] = from.UTC()
+	}
+
+	if to != nil {
+		bounds["lte"] = to.UTC()
+	}
+
+	return object{
+		"range": object{
+			field: bounds,
+		},
+	}
+}
+
+func minimumQuery(field string, value float64) object {
+	return object{
+		"range": object{
+			field: object{
+				"gte": value,
+			},
+		},
+	}
+}
+
+func nestedQuery(path string, query, innerHits object) object {
+	nested := object{
+		"path":  path,
+		"query": query,
+	}
+
+	if innerHits != nil {
+		nested["inner_hits"] = innerHits
+	}
+
+	return object{
+		"nested": nested,
+	}
+}
+
+// maxInnerHits is the default OpenSearch limit on the number of inner
+// hits returned per document.
+const maxInnerHits = 100
+
+// innerHitsRequest returns the matching nested objects with whole-value
+// highlighting of the provided field so that each highlighted fragment
+// is the complete field value.
+func innerHitsRequest(name, field string) object {
+	return object{
+		"name":    name,
+		"size":    maxInnerHits,
+		"_source": false,
+		"highlight": object{
+			"encoder":   "html",
+			"pre_tags":  []string{"<em>"},
+			"post_tags": []string{"</em>"},
+			"fields": object{
+				field: object{
+					"number_of_fragments": 0,
+				},
+			},
+		},
+	}
+}
+
+func bulkIndexAction(id string) object {
+	return object{
+		"index": object{
+			"_id": id,
+		},
+	}
+}
+
+func encodeBody(value interface{}) (*bytes.Buffer, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewBuffer(data), nil
+}
+
+// encodeBulkBody marshals the provided values into a newline-delimited
+// request body as required by the bulk API.
+func encodeBulkBody(values []interface{}) (*bytes.Buffer, error) {
+	var body bytes.Buffer
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		body.Write(data)
+		body.WriteString("\n")
+	}
+
+	return &body, nil
+}
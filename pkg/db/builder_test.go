@@ -0,0 +1,276 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+var fuzzSeeds = []string{
+	"text",
+	`"`,
+	`\`,
+	`" } }, { "match_all": {} } ] } }`,
+	`\", \"size\": 10000, \"x\": \"`,
+	"line\nbreak",
+	"\x00",
+	"ünïcödé 文字",
+}
+
+// shape replaces every string value in a decoded JSON body with an
+// empty string so that bodies can be compared by structure alone.
+func shape(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		output := map[string]interface{}{}
+		for key, child := range v {
+			output[key] = shape(child)
+		}
+		return output
+	case []interface{}:
+		output := make([]interface{}, len(v))
+		for i, child := range v {
+			output[i] = shape(child)
+		}
+		return output
+	case string:
+		return ""
+	default:
+		return v
+	}
+}
+
+// stringValues returns the string values in a decoded JSON body in
+// order; it is only used on bodies whose objects hold at most one string
+// value so that map ordering does not matter.
+func stringValues(value interface{}) []string {
+	output := []string{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			output = append(output, stringValues(child)...)
+		}
+	case []interface{}:
+		for _, child := range v {
+			output = append(output, stringValues(child)...)
+		}
+	case string:
+		output = append(output, v)
+	}
+	return output
+}
+
+func decodeBody(t *testing.T, body io.Reader) interface{} {
+	t.Helper()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("error reading body: %v", err)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatalf("error decoding body %q: %v", data, err)
+	}
+
+	return value
+}
+
+func FuzzQueryDocuments(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	newQuery := func(text string) Query {
+		return Query{
+			Clause: &Clause{
+				Type: ClauseOr,
+				Clauses: []Clause{
+					{
+						Type:      ClauseMatch,
+						Text:      text,
+						Fuzziness: "AUTO",
+					},
+					{
+						Type: ClausePhrase,
+						Text: text,
+					},
+					{
+						Type: ClauseNot,
+						Clauses: []Clause{
+							{
+								Type: ClausePrefix,
+								Text: text,
+							},
+							{
+								Type: ClauseWildcard,
+								Text: text,
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	queryBody := func(t *testing.T, query Query) interface{} {
+		h := &mockHelper{
+			mockExecuteQueryOutput: io.NopCloser(strings.NewReader(`{"hits":{"hits":[]}}`)),
+		}
+
+		c := &Client{
+			helper: h,
+		}
+
+		if _, err := c.QueryDocuments(context.Background(), query); err != nil {
+			t.Fatalf("error querying documents: %v", err)
+		}
+
+		return decodeBody(t, h.mockExecuteQueryBody)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		if strings.TrimSpace(text) == "" {
+			t.Skip()
+		}
+
+		expected := shape(queryBody(t, newQuery("text")))
+		received := queryBody(t, newQuery(text))
+
+		if !reflect.DeepEqual(shape(received), expected) {
+			t.Errorf("query structure changed by input %q, received: %+v", text, received)
+		}
+
+		if utf8.ValidString(text) {
+			phrase := received.(map[string]interface{})["query"].(map[string]interface{})["bool"].(map[string]interface{})["should"].([]interface{})[1]
+			nested := phrase.(map[string]interface{})["nested"].(map[string]interface{})["query"]
+			value := nested.(map[string]interface{})["match_phrase"].(map[string]interface{})[linesTextField].(map[string]interface{})["query"]
+			if value != text {
+				t.Errorf("incorrect phrase value, received: %q, expected: %q", value, text)
+			}
+		}
+	})
+}
+
+func FuzzUpsertDocuments(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed, seed)
+	}
+
+	f.Fuzz(func(t *testing.T, id, fileKey string) {
+		h := &mockHelper{}
+
+		c := &Client{
+			helper: h,
+		}
+
+		if err := c.UpsertDocuments(context.Background(), []pars.Document{
+			{
+				ID:      id,
+				FileKey: fileKey,
+			},
+		}); err != nil {
+			t.Fatalf("error upserting documents: %v", err)
+		}
+
+		data, err := io.ReadAll(h.mockExecuteBulkBody)
+		if err != nil {
+			t.Fatalf("error reading body: %v", err)
+		}
+
+		lines := strings.Split(string(data), "\n")
+		if len(lines) != 3 || lines[2] != "" {
+			t.Fatalf("incorrect bulk lines, received: %q", lines)
+		}
+
+		var action interface{}
+		if err := json.Unmarshal([]byte(lines[0]), &action); err != nil {
+			t.Fatalf("error decoding action %q: %v", lines[0], err)
+		}
+
+		expected := shape(map[string]interface{}{
+			"index": map[string]interface{}{
+				"_id": "",
+			},
+		})
+
+		if !reflect.DeepEqual(shape(action), expected) {
+			t.Errorf("action structure changed by input %q, received: %+v", id, action)
+		}
+	})
+}
+
+func FuzzDeleteDocumentsByKeys(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed, seed)
+	}
+	f.Add("bucket", "2021/q1/acme invoice.pdf")
+	f.Add("bucket", "/")
+
+	deleteBody := func(t *testing.T, bucket, fileKey string) interface{} {
+		h := &mockHelper{}
+
+		c := &Client{
+			helper: h,
+		}
+
+		if err := c.DeleteDocumentsByKeys(context.Background(), []FileKey{{Bucket: bucket, Key: fileKey}}); err != nil {
+			t.Fatalf("error deleting documents: %v", err)
+		}
+
+		return decodeBody(t, h.mockExecuteDeleteBody)
+	}
+
+	f.Fuzz(func(t *testing.T, bucket, fileKey string) {
+		if !utf8.ValidString(bucket) || !utf8.ValidString(fileKey) {
+			t.Skip()
+		}
+
+		expected := shape(deleteBody(t, "bucket", "key"))
+		received := deleteBody(t, bucket, fileKey)
+
+		if !reflect.DeepEqual(shape(received), expected) {
+			t.Errorf("delete structure changed by input %q, received: %+v", bucket+"/"+fileKey, received)
+		}
+
+		id := pars.DocumentID(bucket, fileKey)
+		if values := stringValues(received); !reflect.DeepEqual(values, []string{id}) {
+			t.Errorf("incorrect delete values, received: %q, expected: %q", values, []string{id})
+		}
+	})
+}
+
+func FuzzDeleteDocumentsByBuckets(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	deleteBody := func(t *testing.T, bucket string) interface{} {
+		h := &mockHelper{}
+
+		c := &Client{
+			helper: h,
+		}
+
+		if err := c.DeleteDocumentsByBuckets(context.Background(), []string{bucket}); err != nil {
+			t.Fatalf("error deleting documents: %v", err)
+		}
+
+		return decodeBody(t, h.mockExecuteDeleteBody)
+	}
+
+	f.Fuzz(func(t *testing.T, bucket string) {
+		expected := shape(deleteBody(t, "bucket"))
+		received := deleteBody(t, bucket)
+
+		if !reflect.DeepEqual(shape(received), expected) {
+			t.Errorf("delete structure changed by input %q, received: %+v", bucket, received)
+		}
+	})
+}
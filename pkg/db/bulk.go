@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const (
+	maxBulkAttempts = 5
+	bulkBackoff     = 200 * time.Millisecond
+)
+
+// BulkFailure holds a document the bulk request failed to write along
+// with the status and reason returned for it.
+type BulkFailure struct {
+	ID     string
+	Status int
+	Reason string
+}
+
+type bulkResponseBody struct {
+	Errors bool                  `json:"errors"`
+	Items  []map[string]bulkItem `json:"items"`
+}
+
+type bulkItem struct {
+	ID     string `json:"_id"`
+	Status int    `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// bulkFailures returns the positions of the failed items in a bulk
+// response keyed to their failure. Items are returned in the order of
+// the request actions.
+func bulkFailures(data []byte) (map[int]BulkFailure, error) {
+	var responseBody bulkResponseBody
+	if err := json.Unmarshal(data, &responseBody); err != nil {
+		return nil, err
+	}
+
+	failures := map[int]BulkFailure{}
+	if !responseBody.Errors {
+		return failures, nil
+	}
+
+	for i, action := range responseBody.Items {
+		for _, item := range action {
+			if item.Error == nil {
+				continue
+			}
+
+			failures[i] = BulkFailure{
+				ID:     item.ID,
+				Status: item.Status,
+				Reason: item.Error.Type + ": " + item.Error.Reason,
+			}
+		}
+	}
+
+	return failures, nil
+}
+
+// retryable reports whether a bulk item failure is transient and the
+// item may be sent again.
+func (f BulkFailure) retryable() bool {
+	return f.Status == http.StatusTooManyRequests || f.Status == http.StatusConflict
+}
+
+// backoff returns the wait before the provided retry attempt.
+func backoff(attempt int) time.Duration {
+	return bulkBackoff << (attempt - 1)
+}
+
+func wait(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -1,12 +1,13 @@
 package db
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/opensearch-project/opensearch-go"
@@ -16,19 +17,29 @@ import (
 
 var _ Databaser = &Client{}
 
-// Query holds the fields required for building an OpenSearch query
-// from the values provided by the user.
-type Query struct {
-	Text string `json:"text"`
-}
+var _ Reindexer = &Client{}
 
 // Client implements the db.Databaser methods using AWS OpenSearch.
 type Client struct {
-	helper helper
+	analyzer string
+	helper   helper
+	wait     func(ctx context.Context, duration time.Duration) error
 }
 
-// New generates a db.Client pointer instance with AWS OpenSearch.
-func New(newSession *session.Session, url, username, password string) (*Client, error) {
+// New generates a db.Client pointer instance with AWS OpenSearch. The
+// analyzer names the built-in OpenSearch analyzer applied to the
+// parsed line text and defaults to "standard" if empty.
+func New(newSession *session.Session, url, username, password, analyzer string) (*Client, error) {
+	if analyzer == "" {
+		analyzer = defaultAnalyzer
+	}
+
+	if _, ok := analyzers[analyzer]; !ok {
+		return nil, &NewClientError{
+			err: fmt.Errorf("unsupported analyzer %q", analyzer),
+		}
+	}
+
 	opensearchClient, err := opensearch.NewClient(opensearch.Config{
 		Addresses: []string{url},
 		Username:  username,
@@ -41,48 +52,167 @@ func New(newSession *session.Session, url, username, password string) (*Client,
 	}
 
 	return &Client{
+		analyzer: analyzer,
 		helper: &help{
 			opensearchClient: opensearchClient,
 		},
+		wait: wait,
 	}, nil
 }
 
 // SetupDatabase implements the db.Databaser.SetupDatabase method
-// using AWS OpenSearch.
+// using AWS OpenSearch. The first versioned index is created with the
+// current mapping behind the read and write aliases if the aliases do
+// not exist; otherwise the mapping of the index behind the read alias
+// is checked for compatibility and a db.MappingMismatchError is
+// returned if it is out of date.
 func (c *Client) SetupDatabase(ctx context.Context) error {
-	if err := c.helper.executeCreate(ctx); err != nil {
-		return &ExecuteCreateError{
+	exists, err := c.helper.executeExists(ctx, readAlias)
+	if err != nil {
+		return &ExecuteExistsError{
 			err: err,
 		}
 	}
 
-	return nil
-}
+	if exists {
+		current, err := c.aliasIndex(ctx, readAlias)
+		if err != nil {
+			return err
+		}
+
+		response, err := c.helper.executeGetMapping(ctx, current)
+		if err != nil {
+			return &ExecuteGetMappingError{
+				err: err,
+			}
+		}
+		defer response.Close()
+
+		responseData, err := io.ReadAll(response)
+		if err != nil {
+			return &ExecuteGetMappingError{
+				err: err,
+			}
+		}
+
+		if err := checkMapping(responseData, c.analyzer); err != nil {
+			return &MappingMismatchError{
+				err: err,
+			}
+		}
 
-// UpsertDocuments implements the db.Databaser.UpsertDocuments method
-// using AWS OpenSearch.
-func (c *Client) UpsertDocuments(ctx context.Context, documents []pars.Document) error {
-	if len(documents) == 0 {
 		return nil
 	}
 
-	var body bytes.Buffer
-	for _, document := range documents {
-		metadata := fmt.Sprintf(`{ "index": { "_id": "%s" } }`, document.ID)
-		body.WriteString(metadata + "\n")
+	initial := indexName(1)
+	if err := c.createIndex(ctx, initial); err != nil {
+		return err
+	}
+
+	return c.updateAliases(ctx, aliasActions("", initial, readAlias, writeAlias))
+}
+
+// Reindex implements the db.Reindexer.Reindex method using AWS
+// OpenSearch. The next versioned index is created with the current
+// mapping, the write alias is moved onto it, the documents in the
+// index behind the read alias are copied into it and the read alias
+// is then moved onto it. The previous index is left in place so the
+// aliases may be moved back by hand.
+//
+// Deletes made while the documents are copied are recorded in a
+// deletes log and applied to the next index again once the copy is
+// complete, since the copy may otherwise restore documents deleted
+// after it started.
+func (c *Client) Reindex(ctx context.Context) error {
+	current, err := c.aliasIndex(ctx, readAlias)
+	if err != nil {
+		return err
+	}
+
+	next, err := nextIndexName(current)
+	if err != nil {
+		return &ExecuteGetAliasError{
+			err: err,
+		}
+	}
+
+	logExists, err := c.helper.executeExists(ctx, deletesAlias)
+	if err != nil {
+		return &ExecuteExistsError{
+			err: err,
+		}
+	}
 
-		data, err := json.Marshal(document)
+	if !logExists {
+		body, err := encodeBody(deletesDefinition())
 		if err != nil {
-			return &MarshalDocumentError{
+			return &MarshalQueryError{
+				err: err,
+			}
+		}
+
+		if err := c.helper.executeCreate(ctx, deletesIndex, body); err != nil {
+			return &ExecuteCreateError{
 				err: err,
 			}
 		}
-		body.Write(data)
-		body.WriteString("\n")
 	}
 
-	if err := c.helper.executeBulk(ctx, &body); err != nil {
-		return &ExecuteBulkError{
+	exists, err := c.helper.executeExists(ctx, next)
+	if err != nil {
+		return &ExecuteExistsError{
+			err: err,
+		}
+	}
+
+	if !exists {
+		if err := c.createIndex(ctx, next); err != nil {
+			return err
+		}
+	}
+
+	if err := c.updateAliases(ctx, aliasActions(current, next, writeAlias)); err != nil {
+		return err
+	}
+
+	body, err := encodeBody(reindexBody(current, next))
+	if err != nil {
+		return &MarshalQueryError{
+			err: err,
+		}
+	}
+
+	response, err := c.helper.executeReindex(ctx, body)
+	if err != nil {
+		return &ExecuteReindexError{
+			err: err,
+		}
+	}
+	defer response.Close()
+
+	responseData, err := io.ReadAll(response)
+	if err != nil {
+		return &ExecuteReindexError{
+			err: err,
+		}
+	}
+
+	if err := checkReindex(responseData); err != nil {
+		return &ExecuteReindexError{
+			err: err,
+		}
+	}
+
+	if err := c.replayDeletes(ctx); err != nil {
+		return err
+	}
+
+	if err := c.updateAliases(ctx, aliasActions(current, next, readAlias)); err != nil {
+		return err
+	}
+
+	if err := c.helper.executeDeleteIndex(ctx, deletesIndex); err != nil {
+		return &ExecuteReindexError{
 			err: err,
 		}
 	}
@@ -90,29 +220,118 @@ func (c *Client) UpsertDocuments(ctx context.Context, documents []pars.Document)
 	return nil
 }
 
-// DeleteDocumentsByIDs implements the db.Databaser.DeleteDocumentsByIDs
-// method using AWS OpenSearch.
-func (c *Client) DeleteDocumentsByIDs(ctx context.Context, documentPaths []string) error {
-	if len(documentPaths) == 0 {
-		return nil
+// replayDeletes runs the delete queries in the deletes log again in
+// the order they were recorded.
+func (c *Client) replayDeletes(ctx context.Context) error {
+	after := ""
+	for {
+		body, err := encodeBody(deletesPage(after))
+		if err != nil {
+			return &MarshalQueryError{
+				err: err,
+			}
+		}
+
+		response, err := c.helper.executeGetDeletes(ctx, body)
+		if err != nil {
+			return &ExecuteReindexError{
+				err: err,
+			}
+		}
+
+		responseData, err := io.ReadAll(response)
+		response.Close()
+		if err != nil {
+			return &ExecuteReindexError{
+				err: err,
+			}
+		}
+
+		deletes, err := loggedDeletes(responseData)
+		if err != nil {
+			return &ExecuteReindexError{
+				err: err,
+			}
+		}
+
+		for _, loggedDelete := range deletes {
+			body, err := encodeBody(object{
+				"query": loggedDelete.Query,
+			})
+			if err != nil {
+				return &MarshalQueryError{
+					err: err,
+				}
+			}
+
+			if err := c.helper.executeDelete(ctx, body); err != nil {
+				return &ExecuteReindexError{
+					err: err,
+				}
+			}
+
+			after = loggedDelete.Sequence
+		}
+
+		if len(deletes) < deletesPageSize {
+			return nil
+		}
+	}
+}
+
+func (c *Client) aliasIndex(ctx context.Context, alias string) (string, error) {
+	response, err := c.helper.executeGetAlias(ctx, alias)
+	if err != nil {
+		return "", &ExecuteGetAliasError{
+			err: err,
+		}
+	}
+	defer response.Close()
+
+	responseData, err := io.ReadAll(response)
+	if err != nil {
+		return "", &ExecuteGetAliasError{
+			err: err,
+		}
+	}
+
+	name, err := aliasIndex(responseData)
+	if err != nil {
+		return "", &ExecuteGetAliasError{
+			err: err,
+		}
 	}
 
-	queryString := `{ "query": { "bool": { "minimum_should_match": 1, "should": [ %s ] } } }`
+	return name, nil
+}
 
-	matches := []string{}
-	for _, documentPath := range documentPaths {
-		documentInfo := strings.Split(documentPath, "/")
-		match := fmt.Sprintf(`{ "match": { "file_bucket": "%s", "file_key": "%s" } }`, documentInfo[0], documentInfo[1])
-		matches = append(matches, match)
+func (c *Client) createIndex(ctx context.Context, name string) error {
+	body, err := encodeBody(indexDefinition(c.analyzer))
+	if err != nil {
+		return &MarshalQueryError{
+			err: err,
+		}
 	}
 
-	queryString = fmt.Sprintf(queryString, strings.Join(matches, ", "))
+	if err := c.helper.executeCreate(ctx, name, body); err != nil {
+		return &ExecuteCreateError{
+			err: err,
+		}
+	}
 
-	var body bytes.Buffer
-	body.WriteString(queryString)
+	return nil
+}
 
-	if err := c.helper.executeDelete(ctx, &body); err != nil {
-		return &ExecuteDeleteError{
+func (c *Client) updateAliases(ctx context.Context, actions object) error {
+	body, err := encodeBody(actions)
+	if err != nil {
+		return &MarshalQueryError{
+			err: err,
+		}
+	}
+
+	if err := c.helper.executeUpdateAliases(ctx, body); err != nil {
+		return &ExecuteUpdateAliasesError{
 			err: err,
 		}
 	}
@@ -120,6 +339,103 @@ func (c *Client) DeleteDocumentsByIDs(ctx context.Context, documentPaths []strin
 	return nil
 }
 
+// UpsertDocuments implements the db.Databaser.UpsertDocuments method
+// using AWS OpenSearch. Documents rejected with a retryable status are
+// sent again with backoff and a db.BulkItemsError listing the
+// documents that could not be written is returned.
+func (c *Client) UpsertDocuments(ctx context.Context, documents []pars.Document) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	pending := documents
+	failed := []BulkFailure{}
+	for attempt := 1; len(pending) > 0; attempt++ {
+		if attempt > 1 {
+			if err := c.wait(ctx, backoff(attempt-1)); err != nil {
+				return &ExecuteBulkError{
+					err: err,
+				}
+			}
+		}
+
+		values := make([]interface{}, 0, len(pending)*2)
+		for _, document := range pending {
+			values = append(values, bulkIndexAction(document.ID), document)
+		}
+
+		body, err := encodeBulkBody(values)
+		if err != nil {
+			return &MarshalDocumentError{
+				err: err,
+			}
+		}
+
+		response, err := c.helper.executeBulk(ctx, body)
+		if err != nil {
+			return &ExecuteBulkError{
+				err: err,
+			}
+		}
+
+		responseData, err := io.ReadAll(response)
+		response.Close()
+		if err != nil {
+			return &ExecuteBulkError{
+				err: err,
+			}
+		}
+
+		failures, err := bulkFailures(responseData)
+		if err != nil {
+			return &ExecuteBulkError{
+				err: err,
+			}
+		}
+
+		retry := []pars.Document{}
+		for i, document := range pending {
+			failure, ok := failures[i]
+			if !ok {
+				continue
+			}
+
+			if failure.retryable() && attempt < maxBulkAttempts {
+				retry = append(retry, document)
+			} else {
+				failed = append(failed, failure)
+			}
+		}
+
+		pending = retry
+	}
+
+	if len(failed) > 0 {
+		return &BulkItemsError{
+			Failures: failed,
+		}
+	}
+
+	return nil
+}
+
+// DeleteDocumentsByKeys implements the db.Databaser.DeleteDocumentsByKeys
+// method using AWS OpenSearch. The documents are matched by the IDs
+// derived from their keys in a single terms query, which counts as one
+// clause however many keys are deleted.
+func (c *Client) DeleteDocumentsByKeys(ctx context.Context, fileKeys []FileKey) error {
+	if len(fileKeys) == 0 {
+		return nil
+	}
+
+	ids := []string{}
+	for _, fileKey := range fileKeys {
+		ids = append(ids, pars.DocumentID(fileKey.Bucket, fileKey.Key))
+	}
+
+	return c.deleteDocuments(ctx, termsQuery(idField, ids))
+}
+
 // DeleteDocumentsByBuckets implements the db.Databaser.DeleteDocumentsByBuckets
 // method using AWS OpenSearch.
 func (c *Client) DeleteDocumentsByBuckets(ctx context.Context, buckets []string) error {
@@ -127,20 +443,34 @@ func (c *Client) DeleteDocumentsByBuckets(ctx context.Context, buckets []string)
 		return nil
 	}
 
-	queryString := `{ "query": { "bool": { "minimum_should_match": 1, "should": [ %s ] } } }`
+	return c.deleteDocuments(ctx, termsQuery(fileBucketField, buckets))
+}
 
-	matches := []string{}
-	for _, bucket := range buckets {
-		match := fmt.Sprintf(`{ "match": { "file_bucket": "%s" } }`, bucket)
-		matches = append(matches, match)
+// deleteDocuments removes the documents matching the query and
+// records the query in the deletes log so that it is applied again if
+// a reindex is copying documents.
+func (c *Client) deleteDocuments(ctx context.Context, query object) error {
+	body, err := encodeBody(searchRequest(query))
+	if err != nil {
+		return &MarshalQueryError{
+			err: err,
+		}
 	}
 
-	queryString = fmt.Sprintf(queryString, strings.Join(matches, ", "))
+	if err := c.helper.executeDelete(ctx, body); err != nil {
+		return &ExecuteDeleteError{
+			err: err,
+		}
+	}
 
-	var body bytes.Buffer
-	body.WriteString(queryString)
+	entry, err := encodeBody(deleteEntry(time.Now(), query))
+	if err != nil {
+		return &MarshalQueryError{
+			err: err,
+		}
+	}
 
-	if err := c.helper.executeDelete(ctx, &body); err != nil {
+	if err := c.helper.executeLogDelete(ctx, entry); err != nil {
 		return &ExecuteDeleteError{
 			err: err,
 		}
@@ -154,30 +484,65 @@ type queryResponseBody struct {
 }
 
 type queryHits struct {
-	Hits []hits `json:"hits"`
+	Total queryTotal `json:"total"`
+	Hits  []hits     `json:"hits"`
+}
+
+type queryTotal struct {
+	Value int64 `json:"value"`
 }
 
 type hits struct {
-	Source pars.Document `json:"_source"`
+	Source    pars.Document        `json:"_source"`
+	Sort      []interface{}        `json:"sort"`
+	InnerHits map[string]innerHits `json:"inner_hits"`
+}
+
+type innerHits struct {
+	Hits struct {
+		Hits []innerHit `json:"hits"`
+	} `json:"hits"`
+}
+
+type innerHit struct {
+	Nested    nestedIdentity      `json:"_nested"`
+	Highlight map[string][]string `json:"highlight"`
+}
+
+type nestedIdentity struct {
+	Field  string          `json:"field"`
+	Offset int             `json:"offset"`
+	Nested *nestedIdentity `json:"_nested"`
 }
 
 // QueryDocuments implements the db.Databaser.QueryDocuments method
 // using AWS OpenSearch.
-func (c *Client) QueryDocuments(ctx context.Context, query Query) ([]pars.Document, error) {
-	if query.Text == "" {
-		return []pars.Document{}, nil
+func (c *Client) QueryDocuments(ctx context.Context, query Query) (*Result, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
 	}
 
-	queryString := fmt.Sprintf(`{ "query": { "match": { "pages.lines.text": { "query": "%s", "fuzziness": "AUTO" } } } }`, query.Text)
+	if query.IsEmpty() {
+		return &Result{
+			Hits: []Hit{},
+		}, nil
+	}
 
-	response, err := c.helper.executeQuery(ctx, strings.NewReader(queryString))
+	body, err := encodeBody(query.search())
+	if err != nil {
+		return nil, &MarshalQueryError{
+			err: err,
+		}
+	}
+
+	response, err := c.helper.executeQuery(ctx, body)
 	if err != nil {
 		return nil, &ExecuteQueryError{
 			err: err,
 		}
 	}
 
-	body, err := io.ReadAll(response)
+	responseData, err := io.ReadAll(response)
 	if err != nil {
 		return nil, &ReadQueryResponseBodyError{
 			err: err,
@@ -185,16 +550,86 @@ func (c *Client) QueryDocuments(ctx context.Context, query Query) ([]pars.Docume
 	}
 
 	var responseBody queryResponseBody
-	if err := json.Unmarshal(body, &responseBody); err != nil {
+	if err := json.Unmarshal(responseData, &responseBody); err != nil {
 		return nil, &UnmarshalQueryResponseBodyError{
 			err: err,
 		}
 	}
 
-	documents := []pars.Document{}
-	for _, document := range responseBody.Hits.Hits {
-		documents = append(documents, document.Source)
+	result := &Result{
+		Hits:  []Hit{},
+		Total: responseBody.Hits.Total.Value,
+	}
+
+	responseHits := responseBody.Hits.Hits
+	if len(responseHits) > query.PageSize() {
+		responseHits = responseHits[:query.PageSize()]
+
+		next, err := query.EncodeCursor(responseHits[len(responseHits)-1].Sort)
+		if err != nil {
+			return nil, &MarshalQueryError{
+				err: err,
+			}
+		}
+		result.Next = next
+	}
+
+	for _, hit := range responseHits {
+		result.Hits = append(result.Hits, Hit{
+			Document: hit.Source,
+			Matches:  matchLines(hit.Source, hit.InnerHits),
+		})
+	}
+
+	return result, nil
+}
+
+// matchLines converts the line inner hits returned for each term
+// clause into matches, locating each line in the document by its
+// nested page and line offsets.
+func matchLines(document pars.Document, innerHits map[string]innerHits) []Match {
+	type location struct {
+		page int
+		line int
+	}
+
+	highlights := map[location]string{}
+	for _, clauseHits := range innerHits {
+		for _, hit := range clauseHits.Hits.Hits {
+			if hit.Nested.Nested == nil {
+				continue
+			}
+
+			lineLocation := location{
+				page: hit.Nested.Offset,
+				line: hit.Nested.Nested.Offset,
+			}
+
+			if lineLocation.page >= len(document.Pages) || lineLocation.line >= len(document.Pages[lineLocation.page].Lines) {
+				continue
+			}
+
+			fragments := hit.Highlight[linesTextField]
+			if len(fragments) == 0 {
+				fragments = []string{html.EscapeString(document.Pages[lineLocation.page].Lines[lineLocation.line].Text)}
+			}
+
+			// keep the fragment with the most highlighted terms when
+			// several clauses match the same line
+			if existing, ok := highlights[lineLocation]; !ok || strings.Count(fragments[0], "<em>") > strings.Count(existing, "<em>") {
+				highlights[lineLocation] = fragments[0]
+			}
+		}
+	}
+
+	matches := []Match{}
+	for pageIndex, page := range document.Pages {
+		for lineIndex, line := range page.Lines {
+			if highlight, ok := highlights[location{page: pageIndex, line: lineIndex}]; ok {
+				matches = append(matches, NewMatch(page.PageNumber, line, highlight))
+			}
+		}
 	}
 
-	return documents, nil
+	return matches
 }
@@ -2,11 +2,14 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/session"
 
@@ -19,6 +22,7 @@ func TestNew(t *testing.T) {
 		"url",
 		"username",
 		"password",
+		"",
 	)
 	if err != nil {
 		t.Errorf("incorrect error, received: %v, expected: nil", err)
@@ -27,74 +31,618 @@ func TestNew(t *testing.T) {
 	if client == nil {
 		t.Error("error creating parser client")
 	}
+
+	if _, err := New(session.New(), "url", "username", "password", "klingon"); err == nil {
+		t.Error("incorrect error, received: nil, expected: unsupported analyzer")
+	}
 }
 
 type mockHelper struct {
-	mockExecuteCreateError error
-	mockExecuteBulkBody    io.Reader
-	mockExecuteBulkError   error
-	mockExecuteDeleteBody  io.Reader
-	mockExecuteDeleteError error
-	mockExecuteQueryBody   io.Reader
-	mockExecuteQueryOutput io.ReadCloser
-	mockExecuteQueryError  error
+	mockExecuteExistsOutput        bool
+	mockExecuteExistsError         error
+	mockExecuteCreateName          string
+	mockExecuteCreateNames         []string
+	mockExecuteCreateBody          io.Reader
+	mockExecuteCreateError         error
+	mockExecuteGetMappingOutput    io.ReadCloser
+	mockExecuteGetMappingError     error
+	mockExecuteGetAliasOutput      io.ReadCloser
+	mockExecuteGetAliasError       error
+	mockExecuteUpdateAliasesBodies []io.Reader
+	mockExecuteUpdateAliasesError  error
+	mockExecuteReindexBody         io.Reader
+	mockExecuteReindexOutput       io.ReadCloser
+	mockExecuteReindexError        error
+	mockExecuteBulkBody            io.Reader
+	mockExecuteBulkBodies          []io.Reader
+	mockExecuteBulkOutputs         []string
+	mockExecuteBulkError           error
+	mockExecuteDeleteBody          io.Reader
+	mockExecuteDeleteBodies        []io.Reader
+	mockExecuteDeleteError         error
+	mockExecuteLogDeleteBody       io.Reader
+	mockExecuteLogDeleteError      error
+	mockExecuteGetDeletesOutputs   []string
+	mockExecuteGetDeletesError     error
+	mockExecuteDeleteIndexName     string
+	mockExecuteDeleteIndexError    error
+	mockExecuteQueryBody           io.Reader
+	mockExecuteQueryOutput         io.ReadCloser
+	mockExecuteQueryError          error
+}
+
+func (m *mockHelper) executeExists(ctx context.Context, name string) (bool, error) {
+	return m.mockExecuteExistsOutput, m.mockExecuteExistsError
 }
 
-func (m *mockHelper) executeCreate(ctx context.Context) error {
+func (m *mockHelper) executeCreate(ctx context.Context, name string, body io.Reader) error {
+	m.mockExecuteCreateName = name
+	m.mockExecuteCreateNames = append(m.mockExecuteCreateNames, name)
+	m.mockExecuteCreateBody = body
 	return m.mockExecuteCreateError
 }
 
-func (m *mockHelper) executeBulk(ctx context.Context, body io.Reader) error {
+func (m *mockHelper) executeGetMapping(ctx context.Context, name string) (io.ReadCloser, error) {
+	return m.mockExecuteGetMappingOutput, m.mockExecuteGetMappingError
+}
+
+func (m *mockHelper) executeGetAlias(ctx context.Context, alias string) (io.ReadCloser, error) {
+	return m.mockExecuteGetAliasOutput, m.mockExecuteGetAliasError
+}
+
+func (m *mockHelper) executeUpdateAliases(ctx context.Context, body io.Reader) error {
+	m.mockExecuteUpdateAliasesBodies = append(m.mockExecuteUpdateAliasesBodies, body)
+	return m.mockExecuteUpdateAliasesError
+}
+
+func (m *mockHelper) executeReindex(ctx context.Context, body io.Reader) (io.ReadCloser, error) {
+	m.mockExecuteReindexBody = body
+	return m.mockExecuteReindexOutput, m.mockExecuteReindexError
+}
+
+func (m *mockHelper) executeBulk(ctx context.Context, body io.Reader) (io.ReadCloser, error) {
 	m.mockExecuteBulkBody = body
-	return m.mockExecuteBulkError
+	m.mockExecuteBulkBodies = append(m.mockExecuteBulkBodies, body)
+	if m.mockExecuteBulkError != nil {
+		return nil, m.mockExecuteBulkError
+	}
+
+	output := `{"errors":false,"items":[]}`
+	if len(m.mockExecuteBulkOutputs) > 0 {
+		output, m.mockExecuteBulkOutputs = m.mockExecuteBulkOutputs[0], m.mockExecuteBulkOutputs[1:]
+	}
+
+	return readerFrom(output), nil
 }
 
 func (m *mockHelper) executeDelete(ctx context.Context, body io.Reader) error {
 	m.mockExecuteDeleteBody = body
+	m.mockExecuteDeleteBodies = append(m.mockExecuteDeleteBodies, body)
 	return m.mockExecuteDeleteError
 }
 
+func (m *mockHelper) executeLogDelete(ctx context.Context, body io.Reader) error {
+	m.mockExecuteLogDeleteBody = body
+	return m.mockExecuteLogDeleteError
+}
+
+func (m *mockHelper) executeGetDeletes(ctx context.Context, body io.Reader) (io.ReadCloser, error) {
+	if m.mockExecuteGetDeletesError != nil {
+		return nil, m.mockExecuteGetDeletesError
+	}
+
+	output := `{"hits":{"hits":[]}}`
+	if len(m.mockExecuteGetDeletesOutputs) > 0 {
+		output, m.mockExecuteGetDeletesOutputs = m.mockExecuteGetDeletesOutputs[0], m.mockExecuteGetDeletesOutputs[1:]
+	}
+
+	return readerFrom(output), nil
+}
+
+func (m *mockHelper) executeDeleteIndex(ctx context.Context, name string) error {
+	m.mockExecuteDeleteIndexName = name
+	return m.mockExecuteDeleteIndexError
+}
+
 func (m *mockHelper) executeQuery(ctx context.Context, body io.Reader) (io.ReadCloser, error) {
 	m.mockExecuteQueryBody = body
 	return m.mockExecuteQueryOutput, m.mockExecuteQueryError
 }
 
+func readerFrom(data string) io.ReadCloser {
+	return io.NopCloser(strings.NewReader(data))
+}
+
+func readBodies(t *testing.T, bodies []io.Reader) []string {
+	output := []string{}
+	for _, body := range bodies {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatalf("error reading body: %v", err)
+		}
+		output = append(output, strings.TrimSpace(string(data)))
+	}
+
+	return output
+}
+
+func TestSetupDatabase(t *testing.T) {
+	currentAlias := `{"files-v1":{"aliases":{"files-read":{}}}}`
+
+	tests := []struct {
+		description                   string
+		mockExecuteExistsOutput       bool
+		mockExecuteExistsError        error
+		mockExecuteCreateError        error
+		mockExecuteGetMappingOutput   io.ReadCloser
+		mockExecuteGetMappingError    error
+		mockExecuteGetAliasOutput     io.ReadCloser
+		mockExecuteGetAliasError      error
+		mockExecuteUpdateAliasesError error
+		created                       bool
+		error                         error
+	}{
+		{
+			description:            "error checking alias exists",
+			mockExecuteExistsError: errors.New("mock execute exists error"),
+			error:                  &ExecuteExistsError{},
+		},
+		{
+			description:             "error creating index",
+			mockExecuteExistsOutput: false,
+			mockExecuteCreateError:  errors.New("mock execute create error"),
+			error:                   &ExecuteCreateError{},
+		},
+		{
+			description:                   "error adding aliases",
+			mockExecuteExistsOutput:       false,
+			mockExecuteUpdateAliasesError: errors.New("mock execute update aliases error"),
+			error:                         &ExecuteUpdateAliasesError{},
+		},
+		{
+			description:              "error getting alias index",
+			mockExecuteExistsOutput:  true,
+			mockExecuteGetAliasError: errors.New("mock execute get alias error"),
+			error:                    &ExecuteGetAliasError{},
+		},
+		{
+			description:               "alias pointing at multiple indices",
+			mockExecuteExistsOutput:   true,
+			mockExecuteGetAliasOutput: readerFrom(`{"files-v1":{"aliases":{"files-read":{}}},"files-v2":{"aliases":{"files-read":{}}}}`),
+			error:                     &ExecuteGetAliasError{},
+		},
+		{
+			description:                "error getting existing mapping",
+			mockExecuteExistsOutput:    true,
+			mockExecuteGetAliasOutput:  readerFrom(currentAlias),
+			mockExecuteGetMappingError: errors.New("mock execute get mapping error"),
+			error:                      &ExecuteGetMappingError{},
+		},
+		{
+			description:                 "existing mapping version mismatch",
+			mockExecuteExistsOutput:     true,
+			mockExecuteGetAliasOutput:   readerFrom(currentAlias),
+			mockExecuteGetMappingOutput: readerFrom(`{"files-v1":{"mappings":{"properties":{}}}}`),
+			error:                       &MappingMismatchError{},
+		},
+		{
+			description:                 "existing mapping analyzer mismatch",
+			mockExecuteExistsOutput:     true,
+			mockExecuteGetAliasOutput:   readerFrom(currentAlias),
+			mockExecuteGetMappingOutput: readerFrom(fmt.Sprintf(`{"files-v1":{"mappings":{"_meta":{"version":%d,"analyzer":"english"}}}}`, mappingVersion)),
+			error:                       &MappingMismatchError{},
+		},
+		{
+			description:                 "successful invocation with existing index",
+			mockExecuteExistsOutput:     true,
+			mockExecuteGetAliasOutput:   readerFrom(currentAlias),
+			mockExecuteGetMappingOutput: readerFrom(fmt.Sprintf(`{"files-v1":{"mappings":{"_meta":{"version":%d,"analyzer":"standard"}}}}`, mappingVersion)),
+			error:                       nil,
+		},
+		{
+			description:             "successful invocation creating index",
+			mockExecuteExistsOutput: false,
+			created:                 true,
+			error:                   nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			h := &mockHelper{
+				mockExecuteExistsOutput:       test.mockExecuteExistsOutput,
+				mockExecuteExistsError:        test.mockExecuteExistsError,
+				mockExecuteCreateError:        test.mockExecuteCreateError,
+				mockExecuteGetMappingOutput:   test.mockExecuteGetMappingOutput,
+				mockExecuteGetMappingError:    test.mockExecuteGetMappingError,
+				mockExecuteGetAliasOutput:     test.mockExecuteGetAliasOutput,
+				mockExecuteGetAliasError:      test.mockExecuteGetAliasError,
+				mockExecuteUpdateAliasesError: test.mockExecuteUpdateAliasesError,
+			}
+
+			c := &Client{
+				analyzer: defaultAnalyzer,
+				helper:   h,
+			}
+
+			err := c.SetupDatabase(context.Background())
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *ExecuteExistsError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *ExecuteCreateError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *ExecuteUpdateAliasesError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *ExecuteGetAliasError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *ExecuteGetMappingError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *MappingMismatchError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else if test.error != nil {
+				t.Errorf("incorrect error, received: nil, expected: %v", test.error)
+			} else if test.created {
+				if h.mockExecuteCreateName != "files-v1" {
+					t.Errorf("incorrect index name, received: %s, expected: files-v1", h.mockExecuteCreateName)
+				}
+
+				definition := decodeBody(t, h.mockExecuteCreateBody).(map[string]interface{})
+				properties := definition["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+
+				for _, field := range []string{"file_bucket", "file_key"} {
+					if fieldType := properties[field].(map[string]interface{})["type"]; fieldType != "keyword" {
+						t.Errorf("incorrect %s type, received: %v, expected: keyword", field, fieldType)
+					}
+				}
+
+				if pagesType := properties["pages"].(map[string]interface{})["type"]; pagesType != "nested" {
+					t.Errorf("incorrect pages type, received: %v, expected: nested", pagesType)
+				}
+
+				received := readBodies(t, h.mockExecuteUpdateAliasesBodies)
+				expected := []string{`{"actions":[{"add":{"alias":"files-read","index":"files-v1"}},{"add":{"alias":"files-write","index":"files-v1"}}]}`}
+				if !reflect.DeepEqual(received, expected) {
+					t.Errorf("incorrect aliases bodies, received: %v, expected: %v", received, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestReindex(t *testing.T) {
+	currentAlias := `{"files-v1":{"aliases":{"files-read":{}}}}`
+
+	tests := []struct {
+		description                   string
+		mockExecuteGetAliasOutput     io.ReadCloser
+		mockExecuteGetAliasError      error
+		mockExecuteExistsOutput       bool
+		mockExecuteExistsError        error
+		mockExecuteCreateError        error
+		mockExecuteUpdateAliasesError error
+		mockExecuteReindexOutput      io.ReadCloser
+		mockExecuteReindexError       error
+		mockExecuteGetDeletesOutputs  []string
+		mockExecuteGetDeletesError    error
+		mockExecuteDeleteError        error
+		mockExecuteDeleteIndexError   error
+		createdNames                  []string
+		replayedBodies                []string
+		updateAliasesBodies           []string
+		error                         error
+	}{
+		{
+			description:              "error getting alias index",
+			mockExecuteGetAliasError: errors.New("mock execute get alias error"),
+			error:                    &ExecuteGetAliasError{},
+		},
+		{
+			description:               "alias pointing at unversioned index",
+			mockExecuteGetAliasOutput: readerFrom(`{"files":{"aliases":{"files-read":{}}}}`),
+			error:                     &ExecuteGetAliasError{},
+		},
+		{
+			description:               "error checking next index exists",
+			mockExecuteGetAliasOutput: readerFrom(currentAlias),
+			mockExecuteExistsError:    errors.New("mock execute exists error"),
+			error:                     &ExecuteExistsError{},
+		},
+		{
+			description:               "error creating next index",
+			mockExecuteGetAliasOutput: readerFrom(currentAlias),
+			mockExecuteCreateError:    errors.New("mock execute create error"),
+			error:                     &ExecuteCreateError{},
+		},
+		{
+			description:                   "error moving write alias",
+			mockExecuteGetAliasOutput:     readerFrom(currentAlias),
+			mockExecuteUpdateAliasesError: errors.New("mock execute update aliases error"),
+			error:                         &ExecuteUpdateAliasesError{},
+		},
+		{
+			description:               "error executing reindex",
+			mockExecuteGetAliasOutput: readerFrom(currentAlias),
+			mockExecuteReindexError:   errors.New("mock execute reindex error"),
+			error:                     &ExecuteReindexError{},
+		},
+		{
+			description:               "reindex response with failures",
+			mockExecuteGetAliasOutput: readerFrom(currentAlias),
+			mockExecuteReindexOutput:  readerFrom(`{"failures":[{"id":"doc_id","cause":{"type":"mapper_parsing_exception"}}]}`),
+			error:                     &ExecuteReindexError{},
+		},
+		{
+			description:                "error reading deletes log",
+			mockExecuteGetAliasOutput:  readerFrom(currentAlias),
+			mockExecuteReindexOutput:   readerFrom(`{"total":1,"created":1,"failures":[]}`),
+			mockExecuteGetDeletesError: errors.New("mock execute get deletes error"),
+			error:                      &ExecuteReindexError{},
+		},
+		{
+			description:                  "error replaying logged delete",
+			mockExecuteGetAliasOutput:    readerFrom(currentAlias),
+			mockExecuteReindexOutput:     readerFrom(`{"total":1,"created":1,"failures":[]}`),
+			mockExecuteGetDeletesOutputs: []string{`{"hits":{"hits":[{"_source":{"sequence":"1","query":{"match_all":{}}}}]}}`},
+			mockExecuteDeleteError:       errors.New("mock execute delete error"),
+			error:                        &ExecuteReindexError{},
+		},
+		{
+			description:                 "error removing deletes log",
+			mockExecuteGetAliasOutput:   readerFrom(currentAlias),
+			mockExecuteReindexOutput:    readerFrom(`{"total":1,"created":1,"failures":[]}`),
+			mockExecuteDeleteIndexError: errors.New("mock execute delete index error"),
+			error:                       &ExecuteReindexError{},
+		},
+		{
+			description:               "successful invocation replaying logged deletes",
+			mockExecuteGetAliasOutput: readerFrom(currentAlias),
+			mockExecuteReindexOutput:  readerFrom(`{"total":1,"created":1,"failures":[]}`),
+			mockExecuteGetDeletesOutputs: []string{
+				`{"hits":{"hits":[{"_source":{"sequence":"1","query":{"terms":{"file_bucket":["bucket"]}}}},{"_source":{"sequence":"2","query":{"ids":{"values":["id"]}}}}]}}`,
+			},
+			createdNames: []string{"files-deletes-log", "files-v2"},
+			replayedBodies: []string{
+				`{"query":{"terms":{"file_bucket":["bucket"]}}}`,
+				`{"query":{"ids":{"values":["id"]}}}`,
+			},
+			updateAliasesBodies: []string{
+				`{"actions":[{"remove":{"alias":"files-write","index":"files-v1"}},{"add":{"alias":"files-write","index":"files-v2"}}]}`,
+				`{"actions":[{"remove":{"alias":"files-read","index":"files-v1"}},{"add":{"alias":"files-read","index":"files-v2"}}]}`,
+			},
+			error: nil,
+		},
+		{
+			description:               "successful invocation reusing next index",
+			mockExecuteGetAliasOutput: readerFrom(currentAlias),
+			mockExecuteExistsOutput:   true,
+			mockExecuteReindexOutput:  readerFrom(`{"total":1,"created":1,"failures":[]}`),
+			createdNames:              nil,
+			updateAliasesBodies: []string{
+				`{"actions":[{"remove":{"alias":"files-write","index":"files-v1"}},{"add":{"alias":"files-write","index":"files-v2"}}]}`,
+				`{"actions":[{"remove":{"alias":"files-read","index":"files-v1"}},{"add":{"alias":"files-read","index":"files-v2"}}]}`,
+			},
+			error: nil,
+		},
+		{
+			description:               "successful invocation creating next index",
+			mockExecuteGetAliasOutput: readerFrom(currentAlias),
+			mockExecuteReindexOutput:  readerFrom(`{"total":1,"created":1,"failures":[]}`),
+			createdNames:              []string{"files-deletes-log", "files-v2"},
+			updateAliasesBodies: []string{
+				`{"actions":[{"remove":{"alias":"files-write","index":"files-v1"}},{"add":{"alias":"files-write","index":"files-v2"}}]}`,
+				`{"actions":[{"remove":{"alias":"files-read","index":"files-v1"}},{"add":{"alias":"files-read","index":"files-v2"}}]}`,
+			},
+			error: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			h := &mockHelper{
+				mockExecuteGetAliasOutput:     test.mockExecuteGetAliasOutput,
+				mockExecuteGetAliasError:      test.mockExecuteGetAliasError,
+				mockExecuteExistsOutput:       test.mockExecuteExistsOutput,
+				mockExecuteExistsError:        test.mockExecuteExistsError,
+				mockExecuteCreateError:        test.mockExecuteCreateError,
+				mockExecuteUpdateAliasesError: test.mockExecuteUpdateAliasesError,
+				mockExecuteReindexOutput:      test.mockExecuteReindexOutput,
+				mockExecuteReindexError:       test.mockExecuteReindexError,
+				mockExecuteGetDeletesOutputs:  test.mockExecuteGetDeletesOutputs,
+				mockExecuteGetDeletesError:    test.mockExecuteGetDeletesError,
+				mockExecuteDeleteError:        test.mockExecuteDeleteError,
+				mockExecuteDeleteIndexError:   test.mockExecuteDeleteIndexError,
+			}
+
+			c := &Client{
+				analyzer: defaultAnalyzer,
+				helper:   h,
+			}
+
+			err := c.Reindex(context.Background())
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *ExecuteGetAliasError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *ExecuteExistsError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *ExecuteCreateError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *ExecuteUpdateAliasesError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *ExecuteReindexError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else if test.error != nil {
+				t.Errorf("incorrect error, received: nil, expected: %v", test.error)
+			} else {
+				if !reflect.DeepEqual(h.mockExecuteCreateNames, test.createdNames) {
+					t.Errorf("incorrect created indices, received: %v, expected: %v", h.mockExecuteCreateNames, test.createdNames)
+				}
+
+				reindexBody := readBodies(t, []io.Reader{h.mockExecuteReindexBody})[0]
+				expectedReindexBody := `{"conflicts":"proceed","dest":{"index":"files-v2","op_type":"create"},"source":{"index":"files-v1"}}`
+				if reindexBody != expectedReindexBody {
+					t.Errorf("incorrect reindex body, received: %s, expected: %s", reindexBody, expectedReindexBody)
+				}
+
+				received := readBodies(t, h.mockExecuteUpdateAliasesBodies)
+				if !reflect.DeepEqual(received, test.updateAliasesBodies) {
+					t.Errorf("incorrect aliases bodies, received: %v, expected: %v", received, test.updateAliasesBodies)
+				}
+
+				if replayed := readBodies(t, h.mockExecuteDeleteBodies); len(replayed) > 0 || len(test.replayedBodies) > 0 {
+					if !reflect.DeepEqual(replayed, test.replayedBodies) {
+						t.Errorf("incorrect replayed deletes, received: %v, expected: %v", replayed, test.replayedBodies)
+					}
+				}
+
+				if h.mockExecuteDeleteIndexName != deletesIndex {
+					t.Errorf("incorrect deleted index, received: %s, expected: %s", h.mockExecuteDeleteIndexName, deletesIndex)
+				}
+			}
+		})
+	}
+}
+
 func TestUpsertDocuments(t *testing.T) {
+	conflict := `{"index":{"_id":"%s","status":409,"error":{"type":"version_conflict_engine_exception","reason":"version conflict"}}}`
+	throttled := `{"index":{"_id":"%s","status":429,"error":{"type":"es_rejected_execution_exception","reason":"rejected execution"}}}`
+	invalid := `{"index":{"_id":"%s","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`
+	created := `{"index":{"_id":"%s","status":201}}`
+
 	tests := []struct {
-		description          string
-		mockExecuteBulkBody  string
-		mockExecuteBulkError error
-		error                error
+		description            string
+		mockExecuteBulkOutputs []string
+		mockExecuteBulkError   error
+		mockWaitError          error
+		bulkBodies             []string
+		failures               []BulkFailure
+		error                  error
 	}{
 		{
 			description:          "error executing bulk request",
-			mockExecuteBulkBody:  "",
 			mockExecuteBulkError: errors.New("mock execute bulk error"),
 			error:                &ExecuteBulkError{},
 		},
+		{
+			description:            "error unmarshalling bulk response",
+			mockExecuteBulkOutputs: []string{"---------"},
+			error:                  &ExecuteBulkError{},
+		},
+		{
+			description: "non-retryable item failure",
+			mockExecuteBulkOutputs: []string{
+				`{"errors":true,"items":[` + fmt.Sprintf(created, "first_id") + `,` + fmt.Sprintf(invalid, "second_id") + `]}`,
+			},
+			bulkBodies: []string{"first_id,second_id"},
+			failures: []BulkFailure{
+				{
+					ID:     "second_id",
+					Status: 400,
+					Reason: "mapper_parsing_exception: failed to parse",
+				},
+			},
+			error: &BulkItemsError{},
+		},
+		{
+			description: "retryable item failures retried until successful",
+			mockExecuteBulkOutputs: []string{
+				`{"errors":true,"items":[` + fmt.Sprintf(throttled, "first_id") + `,` + fmt.Sprintf(conflict, "second_id") + `]}`,
+				`{"errors":true,"items":[` + fmt.Sprintf(created, "first_id") + `,` + fmt.Sprintf(throttled, "second_id") + `]}`,
+				`{"errors":false,"items":[` + fmt.Sprintf(created, "second_id") + `]}`,
+			},
+			bulkBodies: []string{"first_id,second_id", "first_id,second_id", "second_id"},
+			error:      nil,
+		},
+		{
+			description: "retryable item failure exhausting attempts",
+			mockExecuteBulkOutputs: []string{
+				`{"errors":true,"items":[` + fmt.Sprintf(created, "first_id") + `,` + fmt.Sprintf(throttled, "second_id") + `]}`,
+				`{"errors":true,"items":[` + fmt.Sprintf(throttled, "second_id") + `]}`,
+				`{"errors":true,"items":[` + fmt.Sprintf(throttled, "second_id") + `]}`,
+				`{"errors":true,"items":[` + fmt.Sprintf(throttled, "second_id") + `]}`,
+				`{"errors":true,"items":[` + fmt.Sprintf(throttled, "second_id") + `]}`,
+			},
+			bulkBodies: []string{"first_id,second_id", "second_id", "second_id", "second_id", "second_id"},
+			failures: []BulkFailure{
+				{
+					ID:     "second_id",
+					Status: 429,
+					Reason: "es_rejected_execution_exception: rejected execution",
+				},
+			},
+			error: &BulkItemsError{},
+		},
+		{
+			description: "context cancelled while waiting to retry",
+			mockExecuteBulkOutputs: []string{
+				`{"errors":true,"items":[` + fmt.Sprintf(created, "first_id") + `,` + fmt.Sprintf(throttled, "second_id") + `]}`,
+			},
+			mockWaitError: context.Canceled,
+			error:         &ExecuteBulkError{},
+		},
 		{
 			description: "successful invocation",
-			mockExecuteBulkBody: `{ "index": { "_id": "doc_id" } }
-{"id":"doc_id","entity":"","file_bucket":"","file_key":""}
-`,
-			mockExecuteBulkError: nil,
-			error:                nil,
+			mockExecuteBulkOutputs: []string{
+				`{"errors":false,"items":[` + fmt.Sprintf(created, "first_id") + `,` + fmt.Sprintf(created, "second_id") + `]}`,
+			},
+			bulkBodies: []string{"first_id,second_id"},
+			error:      nil,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
 			h := &mockHelper{
-				mockExecuteBulkError: test.mockExecuteBulkError,
+				mockExecuteBulkOutputs: test.mockExecuteBulkOutputs,
+				mockExecuteBulkError:   test.mockExecuteBulkError,
 			}
 
+			waits := []time.Duration{}
 			c := &Client{
 				helper: h,
+				wait: func(ctx context.Context, duration time.Duration) error {
+					waits = append(waits, duration)
+					return test.mockWaitError
+				},
 			}
 
 			err := c.UpsertDocuments(context.Background(), []pars.Document{
 				{
-					ID: "doc_id",
+					ID: "first_id",
+				},
+				{
+					ID: "second_id",
 				},
 			})
 
@@ -104,39 +652,106 @@ func TestUpsertDocuments(t *testing.T) {
 					if !errors.As(err, &e) {
 						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
 					}
+				case *BulkItemsError:
+					if !errors.As(err, &e) {
+						t.Fatalf("incorrect error, received: %v, expected: %v", err, e)
+					}
+
+					if !reflect.DeepEqual(e.Failures, test.failures) {
+						t.Errorf("incorrect failures, received: %+v, expected: %+v", e.Failures, test.failures)
+					}
 				default:
 					t.Fatalf("unexpected error type: %v", err)
 				}
-			} else {
-				mockExecuteBulkBody, err := io.ReadAll(c.helper.(*mockHelper).mockExecuteBulkBody)
-				if err != nil {
-					t.Fatalf("error reading body: %v", err)
+			} else if test.error != nil {
+				t.Errorf("incorrect error, received: nil, expected: %v", test.error)
+			}
+
+			if test.bulkBodies == nil {
+				return
+			}
+
+			bulkBodies := []string{}
+			for _, body := range readBodies(t, h.mockExecuteBulkBodies) {
+				ids := []string{}
+				for i, line := range strings.Split(body, "\n") {
+					if i%2 == 1 {
+						continue
+					}
+
+					action := struct {
+						Index struct {
+							ID string `json:"_id"`
+						} `json:"index"`
+					}{}
+					if err := json.Unmarshal([]byte(line), &action); err != nil {
+						t.Fatalf("error decoding action %q: %v", line, err)
+					}
+					ids = append(ids, action.Index.ID)
 				}
+				bulkBodies = append(bulkBodies, strings.Join(ids, ","))
+			}
+
+			if !reflect.DeepEqual(bulkBodies, test.bulkBodies) {
+				t.Errorf("incorrect bulk bodies, received: %v, expected: %v", bulkBodies, test.bulkBodies)
+			}
+
+			if len(waits) != len(test.bulkBodies)-1 {
+				t.Errorf("incorrect waits, received: %v, expected: %d", waits, len(test.bulkBodies)-1)
+			}
 
-				if string(mockExecuteBulkBody) != test.mockExecuteBulkBody {
-					t.Errorf("incorrect body, received: %s, expected: %s", mockExecuteBulkBody, test.mockExecuteBulkBody)
+			for i, duration := range waits {
+				if duration != backoff(i+1) {
+					t.Errorf("incorrect wait %d, received: %s, expected: %s", i, duration, backoff(i+1))
 				}
 			}
 		})
 	}
 }
 
-func TestDeleteDocumentsByIDs(t *testing.T) {
+func TestDeleteDocumentsByKeys(t *testing.T) {
+	fileKeys := []FileKey{}
+	ids := []string{}
+	for i := 0; i < 1000; i++ {
+		fileKey := FileKey{Bucket: "bucket", Key: fmt.Sprintf("scans/%04d.jpeg", i)}
+		fileKeys = append(fileKeys, fileKey)
+		ids = append(ids, fmt.Sprintf("%q", pars.DocumentID(fileKey.Bucket, fileKey.Key)))
+	}
+
 	tests := []struct {
 		description            string
+		fileKeys               []FileKey
 		mockExecuteDeleteBody  string
 		mockExecuteDeleteError error
+		mockExecuteLogError    error
 		error                  error
 	}{
 		{
 			description:            "error executing delete request",
+			fileKeys:               []FileKey{{Bucket: "bucket", Key: "2021/q1/scan 文字.jpeg"}},
 			mockExecuteDeleteBody:  "",
 			mockExecuteDeleteError: errors.New("mock execute delete error"),
 			error:                  &ExecuteDeleteError{},
 		},
+		{
+			description:            "error logging delete",
+			fileKeys:               []FileKey{{Bucket: "bucket", Key: "2021/q1/scan 文字.jpeg"}},
+			mockExecuteDeleteBody:  "",
+			mockExecuteDeleteError: nil,
+			mockExecuteLogError:    errors.New("mock execute log delete error"),
+			error:                  &ExecuteDeleteError{},
+		},
 		{
 			description:            "successful invocation",
-			mockExecuteDeleteBody:  `{ "query": { "bool": { "minimum_should_match": 1, "should": [ { "match": { "file_bucket": "bucket", "file_key": "key.jpeg" } } ] } } }`,
+			fileKeys:               []FileKey{{Bucket: "bucket", Key: "2021/q1/scan 文字.jpeg"}},
+			mockExecuteDeleteBody:  `{"query":{"terms":{"id":["` + pars.DocumentID("bucket", "2021/q1/scan 文字.jpeg") + `"]}}}`,
+			mockExecuteDeleteError: nil,
+			error:                  nil,
+		},
+		{
+			description:            "successful invocation with maximum keys",
+			fileKeys:               fileKeys,
+			mockExecuteDeleteBody:  `{"query":{"terms":{"id":[` + strings.Join(ids, ",") + `]}}}`,
 			mockExecuteDeleteError: nil,
 			error:                  nil,
 		},
@@ -145,14 +760,15 @@ func TestDeleteDocumentsByIDs(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
 			h := &mockHelper{
-				mockExecuteDeleteError: test.mockExecuteDeleteError,
+				mockExecuteDeleteError:    test.mockExecuteDeleteError,
+				mockExecuteLogDeleteError: test.mockExecuteLogError,
 			}
 
 			c := &Client{
 				helper: h,
 			}
 
-			err := c.DeleteDocumentsByIDs(context.Background(), []string{"bucket/key.jpeg"})
+			err := c.DeleteDocumentsByKeys(context.Background(), test.fileKeys)
 
 			if err != nil {
 				switch e := test.error.(type) {
@@ -172,6 +788,15 @@ func TestDeleteDocumentsByIDs(t *testing.T) {
 				if string(mockExecuteDeleteBody) != test.mockExecuteDeleteBody {
 					t.Errorf("incorrect body, received: %s, expected: %s", mockExecuteDeleteBody, test.mockExecuteDeleteBody)
 				}
+
+				logged := loggedDelete{}
+				if err := json.NewDecoder(c.helper.(*mockHelper).mockExecuteLogDeleteBody).Decode(&logged); err != nil {
+					t.Fatalf("error decoding logged delete: %v", err)
+				}
+
+				if expected := `{"query":` + string(logged.Query) + `}`; expected != test.mockExecuteDeleteBody || logged.Sequence == "" {
+					t.Errorf("incorrect logged delete, received: %+v, expected query: %s", logged, test.mockExecuteDeleteBody)
+				}
 			}
 		})
 	}
@@ -182,6 +807,7 @@ func TestDeleteDocumentsByBuckets(t *testing.T) {
 		description            string
 		mockExecuteDeleteBody  string
 		mockExecuteDeleteError error
+		mockExecuteLogError    error
 		error                  error
 	}{
 		{
@@ -190,9 +816,16 @@ func TestDeleteDocumentsByBuckets(t *testing.T) {
 			mockExecuteDeleteError: errors.New("mock execute delete error"),
 			error:                  &ExecuteDeleteError{},
 		},
+		{
+			description:            "error logging delete",
+			mockExecuteDeleteBody:  "",
+			mockExecuteDeleteError: nil,
+			mockExecuteLogError:    errors.New("mock execute log delete error"),
+			error:                  &ExecuteDeleteError{},
+		},
 		{
 			description:            "successful invocation",
-			mockExecuteDeleteBody:  `{ "query": { "bool": { "minimum_should_match": 1, "should": [ { "match": { "file_bucket": "bucket" } } ] } } }`,
+			mockExecuteDeleteBody:  `{"query":{"terms":{"file_bucket":["bucket"]}}}`,
 			mockExecuteDeleteError: nil,
 			error:                  nil,
 		},
@@ -201,7 +834,8 @@ func TestDeleteDocumentsByBuckets(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
 			h := &mockHelper{
-				mockExecuteDeleteError: test.mockExecuteDeleteError,
+				mockExecuteDeleteError:    test.mockExecuteDeleteError,
+				mockExecuteLogDeleteError: test.mockExecuteLogError,
 			}
 
 			c := &Client{
@@ -228,6 +862,15 @@ func TestDeleteDocumentsByBuckets(t *testing.T) {
 				if string(mockExecuteDeleteBody) != test.mockExecuteDeleteBody {
 					t.Errorf("incorrect body, received: %s, expected: %s", mockExecuteDeleteBody, test.mockExecuteDeleteBody)
 				}
+
+				logged := loggedDelete{}
+				if err := json.NewDecoder(c.helper.(*mockHelper).mockExecuteLogDeleteBody).Decode(&logged); err != nil {
+					t.Fatalf("error decoding logged delete: %v", err)
+				}
+
+				if expected := `{"query":` + string(logged.Query) + `}`; expected != test.mockExecuteDeleteBody || logged.Sequence == "" {
+					t.Errorf("incorrect logged delete, received: %+v, expected query: %s", logged, test.mockExecuteDeleteBody)
+				}
 			}
 		})
 	}
@@ -236,28 +879,147 @@ func TestDeleteDocumentsByBuckets(t *testing.T) {
 func TestQueryDocuments(t *testing.T) {
 	tests := []struct {
 		description            string
+		query                  Query
 		mockExecuteQueryBody   string
 		mockExecuteQueryOutput io.ReadCloser
 		mockExecuteQueryError  error
-		documents              []pars.Document
+		result                 *Result
 		error                  error
 	}{
 		{
-			description:            "error executing query request",
+			description: "invalid query",
+			query: Query{
+				Text: "example text",
+				Size: maxSize + 1,
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "error executing query request",
+			query: Query{
+				Text: "example text",
+			},
 			mockExecuteQueryBody:   "",
 			mockExecuteQueryOutput: nil,
 			mockExecuteQueryError:  errors.New("mock execute query error"),
 			error:                  &ExecuteQueryError{},
 		},
 		{
-			description:            "successful invocation",
-			mockExecuteQueryBody:   `{ "query": { "match": { "pages.lines.text": { "query": "example text", "fuzziness": "AUTO" } } } }`,
-			mockExecuteQueryOutput: io.NopCloser(strings.NewReader(`{ "hits": { "hits": [ { "_source": { "id": "doc_id" } } ] } }`)),
+			description: "error unmarshalling query response",
+			query: Query{
+				Text: "example text",
+			},
+			mockExecuteQueryOutput: io.NopCloser(strings.NewReader(`invalid`)),
 			mockExecuteQueryError:  nil,
-			documents: []pars.Document{
-				{
-					ID: "doc_id",
+			error:                  &UnmarshalQueryResponseBodyError{},
+		},
+		{
+			description: "successful invocation",
+			query: Query{
+				Text: "example text",
+			},
+			mockExecuteQueryBody:   `{"query":{"nested":{"inner_hits":{"_source":false,"highlight":{"encoder":"html","fields":{"pages.lines.text":{"number_of_fragments":0}},"post_tags":["\u003c/em\u003e"],"pre_tags":["\u003cem\u003e"]},"name":"match","size":100},"path":"pages.lines","query":{"match":{"pages.lines.text":{"fuzziness":"AUTO","query":"example text"}}}}},"size":11,"sort":[{"_score":"desc"},{"id":"asc"}],"track_total_hits":true}`,
+			mockExecuteQueryOutput: io.NopCloser(strings.NewReader(`{ "hits": { "total": { "value": 1 }, "hits": [ { "_source": { "id": "doc_id" }, "sort": [ 1.5, "doc_id" ] } ] } }`)),
+			mockExecuteQueryError:  nil,
+			result: &Result{
+				Hits: []Hit{
+					{
+						Document: pars.Document{
+							ID: "doc_id",
+						},
+						Matches: []Match{},
+					},
+				},
+				Total: 1,
+			},
+			error: nil,
+		},
+		{
+			description: "successful highlighted invocation",
+			query: Query{
+				Clause: &Clause{
+					Type: ClausePhrase,
+					Text: "total due",
+				},
+				Size: 1,
+			},
+			mockExecuteQueryBody:   `{"query":{"nested":{"inner_hits":{"_source":false,"highlight":{"encoder":"html","fields":{"pages.lines.text":{"number_of_fragments":0}},"post_tags":["\u003c/em\u003e"],"pre_tags":["\u003cem\u003e"]},"name":"match","size":100},"path":"pages.lines","query":{"match_phrase":{"pages.lines.text":{"query":"total due"}}}}},"size":2,"sort":[{"_score":"desc"},{"id":"asc"}],"track_total_hits":true}`,
+			mockExecuteQueryOutput: io.NopCloser(strings.NewReader(`{ "hits": { "total": { "value": 1 }, "hits": [ { "_source": { "id": "doc_id", "pages": [ { "page_number": 1, "lines": [ { "id": "line_0", "text": "invoice" } ] }, { "page_number": 2, "lines": [ { "id": "line_1", "text": "total due: <5>", "coordinates": { "top_left": { "x": 0.1, "y": 0.2 } } } ] } ] }, "sort": [ 2.5, "doc_id" ], "inner_hits": { "match": { "hits": { "hits": [ { "_nested": { "field": "pages", "offset": 1, "_nested": { "field": "lines", "offset": 0 } }, "highlight": { "pages.lines.text": [ "<em>total</em> <em>due</em>: &lt;5&gt;" ] } } ] } } } } ] } }`)),
+			mockExecuteQueryError:  nil,
+			result: &Result{
+				Hits: []Hit{
+					{
+						Document: pars.Document{
+							ID: "doc_id",
+							Pages: []pars.Page{
+								{
+									PageNumber: 1,
+									Lines: []pars.Line{
+										{
+											ID:   "line_0",
+											Text: "invoice",
+										},
+									},
+								},
+								{
+									PageNumber: 2,
+									Lines: []pars.Line{
+										{
+											ID:   "line_1",
+											Text: "total due: <5>",
+											Coordinates: pars.Coordinates{
+												TopLeft: pars.Point{
+													X: 0.1,
+													Y: 0.2,
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+						Matches: []Match{
+							{
+								PageNumber: 2,
+								LineID:     "line_1",
+								Text:       "total due: <5>",
+								Highlight:  "<em>total</em> <em>due</em>: &lt;5&gt;",
+								Coordinates: pars.Coordinates{
+									TopLeft: pars.Point{
+										X: 0.1,
+										Y: 0.2,
+									},
+								},
+							},
+						},
+					},
 				},
+				Total: 1,
+			},
+			error: nil,
+		},
+		{
+			description: "successful paginated invocation",
+			query: Query{
+				Text:   "example text",
+				Size:   1,
+				Sort:   SortKey,
+				Cursor: keyCursor(t, "a.jpg", "doc_a"),
+			},
+			mockExecuteQueryBody:   `{"query":{"nested":{"inner_hits":{"_source":false,"highlight":{"encoder":"html","fields":{"pages.lines.text":{"number_of_fragments":0}},"post_tags":["\u003c/em\u003e"],"pre_tags":["\u003cem\u003e"]},"name":"match","size":100},"path":"pages.lines","query":{"match":{"pages.lines.text":{"fuzziness":"AUTO","query":"example text"}}}}},"size":2,"sort":[{"file_key":"asc"},{"id":"asc"}],"search_after":["a.jpg","doc_a"],"track_total_hits":true}`,
+			mockExecuteQueryOutput: io.NopCloser(strings.NewReader(`{ "hits": { "total": { "value": 3 }, "hits": [ { "_source": { "id": "doc_b" }, "sort": [ "b.jpg", "doc_b" ] }, { "_source": { "id": "doc_c" }, "sort": [ "c.jpg", "doc_c" ] } ] } }`)),
+			mockExecuteQueryError:  nil,
+			result: &Result{
+				Hits: []Hit{
+					{
+						Document: pars.Document{
+							ID: "doc_b",
+						},
+						Matches: []Match{},
+					},
+				},
+				Total: 3,
+				Next:  keyCursor(t, "b.jpg", "doc_b"),
 			},
 			error: nil,
 		},
@@ -274,16 +1036,22 @@ func TestQueryDocuments(t *testing.T) {
 				helper: h,
 			}
 
-			documents, err := c.QueryDocuments(context.Background(), Query{
-				Text: "example text",
-			})
+			result, err := c.QueryDocuments(context.Background(), test.query)
 
 			if err != nil {
 				switch e := test.error.(type) {
+				case *InvalidQueryError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
 				case *ExecuteQueryError:
 					if !errors.As(err, &e) {
 						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
 					}
+				case *UnmarshalQueryResponseBodyError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
 				default:
 					t.Fatalf("unexpected error type: %v", err)
 				}
@@ -297,10 +1065,92 @@ func TestQueryDocuments(t *testing.T) {
 					t.Errorf("incorrect body, received: %s, expected: %s", mockExecuteQueryBody, test.mockExecuteQueryBody)
 				}
 
-				if !reflect.DeepEqual(documents, test.documents) {
-					t.Errorf("incorrect documents, received: %+v, expected: %+v", documents, test.documents)
+				if !reflect.DeepEqual(result, test.result) {
+					t.Errorf("incorrect result, received: %+v, expected: %+v", result, test.result)
 				}
 			}
 		})
 	}
 }
+
+func keyCursor(t *testing.T, fileKey, id string) string {
+	t.Helper()
+
+	cursor, err := Query{Sort: SortKey}.EncodeCursor([]interface{}{fileKey, id})
+	if err != nil {
+		t.Fatalf("error encoding cursor: %v", err)
+	}
+
+	return cursor
+}
+
+func Test_matchLines(t *testing.T) {
+	document := pars.Document{
+		Pages: []pars.Page{
+			{
+				PageNumber: 1,
+				Lines: []pars.Line{
+					{
+						ID:   "line_0",
+						Text: "invoice total",
+					},
+					{
+						ID:   "line_1",
+						Text: "a < b",
+					},
+				},
+			},
+		},
+	}
+
+	lineHit := func(page, line int, fragments ...string) innerHit {
+		hit := innerHit{
+			Nested: nestedIdentity{
+				Field:  "pages",
+				Offset: page,
+				Nested: &nestedIdentity{
+					Field:  "lines",
+					Offset: line,
+				},
+			},
+		}
+
+		if len(fragments) > 0 {
+			hit.Highlight = map[string][]string{
+				linesTextField: fragments,
+			}
+		}
+
+		return hit
+	}
+
+	clauseHits := func(hits ...innerHit) innerHits {
+		clause := innerHits{}
+		clause.Hits.Hits = hits
+		return clause
+	}
+
+	matches := matchLines(document, map[string]innerHits{
+		"match_0": clauseHits(lineHit(0, 0, "<em>invoice</em> total"), lineHit(0, 5), lineHit(3, 0)),
+		"match_1": clauseHits(lineHit(0, 0, "<em>invoice</em> <em>total</em>"), lineHit(0, 1)),
+	})
+
+	expected := []Match{
+		{
+			PageNumber: 1,
+			LineID:     "line_0",
+			Text:       "invoice total",
+			Highlight:  "<em>invoice</em> <em>total</em>",
+		},
+		{
+			PageNumber: 1,
+			LineID:     "line_1",
+			Text:       "a < b",
+			Highlight:  "a &lt; b",
+		},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("incorrect matches, received: %+v, expected: %+v", matches, expected)
+	}
+}
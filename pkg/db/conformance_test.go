@@ -0,0 +1,96 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/opensearch-project/opensearch-go"
+	"github.com/opensearch-project/opensearch-go/opensearchapi"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/db/dbtest"
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+// refreshClient refreshes the indices after every write so that the
+// conformance tests can query the documents they have just written.
+type refreshClient struct {
+	*db.Client
+	opensearchClient *opensearch.Client
+}
+
+func (c *refreshClient) refresh(ctx context.Context, err error) error {
+	if err != nil {
+		return err
+	}
+
+	response, err := opensearchapi.IndicesRefreshRequest{
+		Index: []string{"files-*"},
+	}.Do(ctx, c.opensearchClient)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	return nil
+}
+
+func (c *refreshClient) UpsertDocuments(ctx context.Context, documents []pars.Document) error {
+	return c.refresh(ctx, c.Client.UpsertDocuments(ctx, documents))
+}
+
+func (c *refreshClient) DeleteDocumentsByKeys(ctx context.Context, fileKeys []db.FileKey) error {
+	return c.refresh(ctx, c.Client.DeleteDocumentsByKeys(ctx, fileKeys))
+}
+
+func (c *refreshClient) DeleteDocumentsByBuckets(ctx context.Context, buckets []string) error {
+	return c.refresh(ctx, c.Client.DeleteDocumentsByBuckets(ctx, buckets))
+}
+
+// newTestClient returns a client for the cluster at the
+// FINDFILE_OPENSEARCH_URL address with the findfile indices removed,
+// skipping the test if the variable is not set. The cluster is
+// authenticated with FINDFILE_OPENSEARCH_USERNAME and
+// FINDFILE_OPENSEARCH_PASSWORD if they are set.
+func newTestClient(t *testing.T) db.Databaser {
+	url := os.Getenv("FINDFILE_OPENSEARCH_URL")
+	if url == "" {
+		t.Skip("FINDFILE_OPENSEARCH_URL not set")
+	}
+
+	username := os.Getenv("FINDFILE_OPENSEARCH_USERNAME")
+	password := os.Getenv("FINDFILE_OPENSEARCH_PASSWORD")
+
+	opensearchClient, err := opensearch.NewClient(opensearch.Config{
+		Addresses: []string{url},
+		Username:  username,
+		Password:  password,
+	})
+	if err != nil {
+		t.Fatalf("error creating opensearch client: %v", err)
+	}
+
+	response, err := opensearchapi.IndicesDeleteRequest{
+		Index: []string{"files-*"},
+	}.Do(context.Background(), opensearchClient)
+	if err != nil {
+		t.Fatalf("error deleting indices: %v", err)
+	}
+	response.Body.Close()
+
+	client, err := db.New(session.New(), url, username, password, "")
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+
+	return &refreshClient{
+		Client:           client,
+		opensearchClient: opensearchClient,
+	}
+}
+
+func TestClient(t *testing.T) {
+	dbtest.Run(t, newTestClient)
+}
@@ -2,6 +2,9 @@ package db
 
 import (
 	"context"
+	"html"
+	"strings"
+	"unicode"
 
 	"github.com/forstmeier/findfile/pkg/pars"
 )
@@ -11,7 +14,98 @@ import (
 type Databaser interface {
 	SetupDatabase(ctx context.Context) error
 	UpsertDocuments(ctx context.Context, documents []pars.Document) error
-	DeleteDocumentsByIDs(ctx context.Context, documentIDs []string) error
+	DeleteDocumentsByKeys(ctx context.Context, fileKeys []FileKey) error
 	DeleteDocumentsByBuckets(ctx context.Context, buckets []string) error
-	QueryDocuments(ctx context.Context, query Query) ([]pars.Document, error)
+	QueryDocuments(ctx context.Context, query Query) (*Result, error)
+}
+
+// FileKey identifies the stored document of a file by the exact bucket
+// and key of the file. Keys are not split, so they may hold slashes.
+type FileKey struct {
+	Bucket string
+	Key    string
+}
+
+// Reindexer defines the method for moving the parsed documents into
+// a new index built with the current mapping.
+type Reindexer interface {
+	Reindex(ctx context.Context) error
+}
+
+// Result holds a page of documents matching a query along with the
+// total number of matching documents and the cursor for the next page.
+// Next is empty on the final page.
+type Result struct {
+	Hits  []Hit
+	Total int64
+	Next  string
+}
+
+// Hit holds a document matching a query and the lines within the
+// document that matched the query text.
+type Hit struct {
+	Document pars.Document
+	Matches  []Match
+}
+
+// Match holds a line that matched the query text along with the line
+// text highlighted with <em> tags and the location of the line on its
+// page. Highlight is HTML-escaped apart from the tags. Words holds the
+// words of the line containing a highlighted term.
+type Match struct {
+	PageNumber  int64
+	LineID      string
+	Text        string
+	Highlight   string
+	Coordinates pars.Coordinates
+	Words       []pars.Word
+}
+
+// NewMatch returns the match for a line on the provided page with its
+// highlighted text.
+func NewMatch(pageNumber int64, line pars.Line, highlight string) Match {
+	return Match{
+		PageNumber:  pageNumber,
+		LineID:      line.ID,
+		Text:        line.Text,
+		Highlight:   highlight,
+		Coordinates: line.Coordinates,
+		Words:       highlightedWords(line.Words, highlight),
+	}
+}
+
+// highlightedWords returns the words sharing a term with the text
+// highlighted with <em> tags.
+func highlightedWords(words []pars.Word, highlight string) []pars.Word {
+	terms := map[string]struct{}{}
+	for _, fragment := range strings.Split(highlight, "<em>")[1:] {
+		end := strings.Index(fragment, "</em>")
+		if end < 0 {
+			continue
+		}
+
+		for _, term := range wordTerms(html.UnescapeString(fragment[:end])) {
+			terms[term] = struct{}{}
+		}
+	}
+
+	var output []pars.Word
+	for _, word := range words {
+		for _, term := range wordTerms(word.Text) {
+			if _, ok := terms[term]; ok {
+				output = append(output, word)
+				break
+			}
+		}
+	}
+
+	return output
+}
+
+// wordTerms splits text into lowercased terms of letters and digits so
+// that highlighted terms can be compared with words holding punctuation.
+func wordTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_')
+	})
 }
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+func TestNewMatch(t *testing.T) {
+	line := pars.Line{
+		ID:   "line_id",
+		Text: "Total due: $250 for <widgets>",
+		Words: []pars.Word{
+			{Text: "Total"},
+			{Text: "due:"},
+			{Text: "$250"},
+			{Text: "for"},
+			{Text: "<widgets>"},
+		},
+	}
+
+	tests := []struct {
+		description string
+		highlight   string
+		words       []pars.Word
+	}{
+		{
+			description: "no highlighted terms",
+			highlight:   "Total due: $250 for &lt;widgets&gt;",
+			words:       nil,
+		},
+		{
+			description: "highlighted terms within punctuated words",
+			highlight:   "<em>Total</em> due: $<em>250</em> for &lt;<em>widgets</em>&gt;",
+			words: []pars.Word{
+				{Text: "Total"},
+				{Text: "$250"},
+				{Text: "<widgets>"},
+			},
+		},
+		{
+			description: "highlighted phrase",
+			highlight:   "Total <em>due: $250</em> for &lt;widgets&gt;",
+			words: []pars.Word{
+				{Text: "due:"},
+				{Text: "$250"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			match := NewMatch(2, line, test.highlight)
+
+			if match.PageNumber != 2 || match.LineID != line.ID || match.Text != line.Text || match.Highlight != test.highlight {
+				t.Errorf("incorrect match, received: %+v", match)
+			}
+
+			if !reflect.DeepEqual(match.Words, test.words) {
+				t.Errorf("incorrect words, received: %+v, expected: %+v", match.Words, test.words)
+			}
+		})
+	}
+}
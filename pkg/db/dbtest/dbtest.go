@@ -0,0 +1,1081 @@
+// Package dbtest provides the conformance tests every db.Databaser
+// implementation is expected to pass.
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+// Run runs the conformance tests against the db.Databaser returned by
+// newDatabaser. Each test calls newDatabaser for an empty database and
+// then calls SetupDatabase on it.
+func Run(t *testing.T, newDatabaser func(t *testing.T) db.Databaser) {
+	tests := []struct {
+		description string
+		test        func(t *testing.T, databaser db.Databaser)
+	}{
+		{
+			description: "empty query",
+			test:        testEmptyQuery,
+		},
+		{
+			description: "invalid query",
+			test:        testInvalidQuery,
+		},
+		{
+			description: "text query",
+			test:        testTextQuery,
+		},
+		{
+			description: "clause queries",
+			test:        testClauseQueries,
+		},
+		{
+			description: "highlights",
+			test:        testHighlights,
+		},
+		{
+			description: "filters",
+			test:        testFilters,
+		},
+		{
+			description: "min confidence",
+			test:        testMinConfidence,
+		},
+		{
+			description: "field queries",
+			test:        testFieldQueries,
+		},
+		{
+			description: "sorting",
+			test:        testSorting,
+		},
+		{
+			description: "pagination",
+			test:        testPagination,
+		},
+		{
+			description: "upsert replaces documents",
+			test:        testUpsertReplaces,
+		},
+		{
+			description: "delete documents by keys",
+			test:        testDeleteDocumentsByKeys,
+		},
+		{
+			description: "delete documents by exact keys",
+			test:        testDeleteDocumentsByExactKeys,
+		},
+		{
+			description: "delete documents by many keys",
+			test:        testDeleteDocumentsByManyKeys,
+		},
+		{
+			description: "delete documents by buckets",
+			test:        testDeleteDocumentsByBuckets,
+		},
+		{
+			description: "skipped files",
+			test:        testSkippedFiles,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			databaser := newDatabaser(t)
+
+			if err := databaser.SetupDatabase(context.Background()); err != nil {
+				t.Fatalf("error setting up database: %v", err)
+			}
+
+			test.test(t, databaser)
+		})
+	}
+}
+
+// Confidences of the fixture lines.
+const (
+	defaultConfidence  = 99
+	lowConfidenceValue = 40
+)
+
+func date(day int) *time.Time {
+	value := time.Date(2021, time.November, day, 12, 0, 0, 0, time.UTC)
+	return &value
+}
+
+func document(bucket, key, extension, contentType string, day int, lines ...string) pars.Document {
+	id := pars.DocumentID(bucket, key)
+	page := pars.Page{
+		ID:         pars.NewID(id, "page", "1"),
+		Entity:     "page",
+		PageNumber: 1,
+	}
+
+	for i, text := range lines {
+		lineID := pars.NewID(page.ID, "line", string(rune('a'+i)))
+
+		words := []pars.Word{}
+		fields := strings.Fields(text)
+		for j, field := range fields {
+			wordID := pars.NewID(lineID, "word", string(rune('a'+j)))
+			width := 0.8 / float64(len(fields))
+			words = append(words, pars.Word{
+				ID:          wordID,
+				Entity:      "word",
+				Text:        field,
+				Confidence:  defaultConfidence,
+				Coordinates: pars.NewCoordinates(pars.NewID(wordID, "coordinates"), 0.1+width*float64(j), 0.1*float64(i+1), width, 0.05),
+			})
+		}
+
+		page.Lines = append(page.Lines, pars.Line{
+			ID:         lineID,
+			Entity:     "line",
+			Text:       text,
+			Confidence: defaultConfidence,
+			Words:      words,
+			Coordinates: pars.Coordinates{
+				ID:     pars.NewID(lineID, "coordinates"),
+				Entity: "coordinates",
+				TopLeft: pars.Point{
+					X: 0.1,
+					Y: 0.1 * float64(i+1),
+				},
+				TopRight: pars.Point{
+					X: 0.9,
+					Y: 0.1 * float64(i+1),
+				},
+				BottomLeft: pars.Point{
+					X: 0.1,
+					Y: 0.1*float64(i+1) + 0.05,
+				},
+				BottomRight: pars.Point{
+					X: 0.9,
+					Y: 0.1*float64(i+1) + 0.05,
+				},
+			},
+		})
+	}
+
+	return pars.Document{
+		ID:            id,
+		Entity:        "document",
+		FileBucket:    bucket,
+		FileKey:       key,
+		FileExtension: extension,
+		ContentType:   contentType,
+		LastModified:  date(day),
+		IndexedAt:     date(day + 1),
+		Pages:         []pars.Page{page},
+	}
+}
+
+// lowConfidence sets the confidence of the line and its words below
+// the defaultConfidence of the fixture lines.
+func lowConfidence(document pars.Document, line int) pars.Document {
+	target := &document.Pages[0].Lines[line]
+	target.Confidence = lowConfidenceValue
+	for i := range target.Words {
+		target.Words[i].Confidence = lowConfidenceValue
+	}
+
+	return document
+}
+
+// withField adds a form field with the provided confidence to the
+// first page of the document.
+func withField(document pars.Document, key, value string, confidence float64) pars.Document {
+	page := &document.Pages[0]
+	id := pars.NewID(page.ID, "field", string(rune('a'+len(page.Fields))))
+	page.Fields = append(page.Fields, pars.Field{
+		ID:          id,
+		Entity:      "field",
+		Key:         key,
+		Value:       value,
+		Confidence:  confidence,
+		Coordinates: pars.NewCoordinates(pars.NewID(id, "coordinates"), 0.1, 0.2, 0.5, 0.05),
+	})
+
+	return document
+}
+
+// withTable adds a table of the provided rows to the first page of the
+// document.
+func withTable(document pars.Document, rows ...[]string) pars.Document {
+	page := &document.Pages[0]
+	id := pars.NewID(page.ID, "table", string(rune('a'+len(page.Tables))))
+	table := pars.Table{
+		ID:          id,
+		Entity:      "table",
+		Confidence:  defaultConfidence,
+		Coordinates: pars.NewCoordinates(pars.NewID(id, "coordinates"), 0.1, 0.5, 0.8, 0.2),
+	}
+
+	for i, row := range rows {
+		for j, text := range row {
+			cellID := pars.NewID(id, "cell", string(rune('a'+len(table.Cells))))
+			table.Cells = append(table.Cells, pars.Cell{
+				ID:          cellID,
+				Entity:      "cell",
+				RowIndex:    int64(i + 1),
+				ColumnIndex: int64(j + 1),
+				RowSpan:     1,
+				ColumnSpan:  1,
+				Text:        text,
+				Confidence:  defaultConfidence,
+				Coordinates: pars.NewCoordinates(pars.NewID(cellID, "coordinates"), 0.1+0.4*float64(j), 0.5+0.1*float64(i), 0.4, 0.1),
+			})
+		}
+	}
+	page.Tables = append(page.Tables, table)
+
+	return document
+}
+
+// fixtures returns the documents loaded for the query tests. The
+// second Globex line and the Globex invoice number form field are
+// recognized with low confidence.
+func fixtures() []pars.Document {
+	acme := document("invoices", "2021/acme.pdf", "pdf", "application/pdf", 1,
+		"ACME Corporation",
+		"Invoice number 1001",
+		"Total due: $250 for widgets",
+	)
+	acme = withField(acme, "Invoice Number:", "1001", defaultConfidence)
+	acme = withField(acme, "Total Due", "$250.00", defaultConfidence)
+	acme = withTable(acme, []string{"Item", "Quantity"}, []string{"Widgets", "4"})
+
+	globex := lowConfidence(document("invoices", "2021/globex.png", "png", "image/png", 2,
+		"Globex Corporation",
+		"Invoice number 1002",
+		"Total due: $75 for gadgets",
+	), 1)
+	globex = withField(globex, "Invoice Number", "#1002", lowConfidenceValue)
+	globex = withField(globex, "Total Due", "$75.00", defaultConfidence)
+
+	return []pars.Document{
+		acme,
+		globex,
+		document("receipts", "2021/acme.jpg", "jpg", "image/jpeg", 3,
+			"ACME Corporation",
+			"Receipt for widgets & <gizmos>",
+		),
+		document("receipts", "2022/initech.pdf", "pdf", "application/pdf", 4,
+			"Initech",
+			"Receipt for the quick brown fox",
+		),
+	}
+}
+
+func load(t *testing.T, databaser db.Databaser, documents []pars.Document) {
+	t.Helper()
+
+	if err := databaser.UpsertDocuments(context.Background(), documents); err != nil {
+		t.Fatalf("error upserting documents: %v", err)
+	}
+}
+
+func query(t *testing.T, databaser db.Databaser, query db.Query) *db.Result {
+	t.Helper()
+
+	result, err := databaser.QueryDocuments(context.Background(), query)
+	if err != nil {
+		t.Fatalf("error querying documents %+v: %v", query, err)
+	}
+
+	return result
+}
+
+// keys returns the file keys of the hits in result order.
+func keys(result *db.Result) []string {
+	output := []string{}
+	for _, hit := range result.Hits {
+		output = append(output, hit.Document.FileBucket+"/"+hit.Document.FileKey)
+	}
+
+	return output
+}
+
+func sorted(values []string) []string {
+	output := append([]string{}, values...)
+	sort.Strings(output)
+	return output
+}
+
+func checkKeys(t *testing.T, description string, received, expected []string) {
+	t.Helper()
+
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("incorrect %s documents, received: %v, expected: %v", description, received, expected)
+	}
+}
+
+func testEmptyQuery(t *testing.T, databaser db.Databaser) {
+	load(t, databaser, fixtures())
+
+	result := query(t, databaser, db.Query{})
+	if len(result.Hits) != 0 || result.Next != "" {
+		t.Errorf("incorrect empty query result, received: %+v, expected: no hits", result)
+	}
+}
+
+func testInvalidQuery(t *testing.T, databaser db.Databaser) {
+	_, err := databaser.QueryDocuments(context.Background(), db.Query{
+		Text: "acme",
+		Clause: &db.Clause{
+			Type: db.ClauseMatch,
+			Text: "acme",
+		},
+	})
+
+	var invalidErr *db.InvalidQueryError
+	if !errors.As(err, &invalidErr) {
+		t.Errorf("incorrect error, received: %v, expected: %T", err, invalidErr)
+	}
+}
+
+func testTextQuery(t *testing.T, databaser db.Databaser) {
+	load(t, databaser, fixtures())
+
+	tests := []struct {
+		description string
+		text        string
+		expected    []string
+	}{
+		{
+			description: "exact term",
+			text:        "globex",
+			expected:    []string{"invoices/2021/globex.png"},
+		},
+		{
+			description: "case insensitive term",
+			text:        "INITECH",
+			expected:    []string{"receipts/2022/initech.pdf"},
+		},
+		{
+			description: "any term",
+			text:        "globex initech",
+			expected:    []string{"invoices/2021/globex.png", "receipts/2022/initech.pdf"},
+		},
+		{
+			description: "fuzzy term",
+			text:        "widgts",
+			expected:    []string{"invoices/2021/acme.pdf", "receipts/2021/acme.jpg"},
+		},
+		{
+			description: "no matching term",
+			text:        "umbrella",
+			expected:    []string{},
+		},
+	}
+
+	for _, test := range tests {
+		result := query(t, databaser, db.Query{
+			Text: test.text,
+		})
+
+		checkKeys(t, test.description, sorted(keys(result)), test.expected)
+
+		if result.Total != int64(len(test.expected)) {
+			t.Errorf("incorrect %s total, received: %d, expected: %d", test.description, result.Total, len(test.expected))
+		}
+	}
+}
+
+func testClauseQueries(t *testing.T, databaser db.Databaser) {
+	load(t, databaser, fixtures())
+
+	tests := []struct {
+		description string
+		clause      db.Clause
+		expected    []string
+	}{
+		{
+			description: "phrase",
+			clause: db.Clause{
+				Type: db.ClausePhrase,
+				Text: "quick brown",
+			},
+			expected: []string{"receipts/2022/initech.pdf"},
+		},
+		{
+			description: "phrase out of order",
+			clause: db.Clause{
+				Type: db.ClausePhrase,
+				Text: "brown quick",
+			},
+			expected: []string{},
+		},
+		{
+			description: "prefix",
+			clause: db.Clause{
+				Type: db.ClausePrefix,
+				Text: "Gad",
+			},
+			expected: []string{"invoices/2021/globex.png"},
+		},
+		{
+			description: "wildcard",
+			clause: db.Clause{
+				Type: db.ClauseWildcard,
+				Text: "g?zm*",
+			},
+			expected: []string{"receipts/2021/acme.jpg"},
+		},
+		{
+			description: "match without fuzziness",
+			clause: db.Clause{
+				Type: db.ClauseMatch,
+				Text: "widgts",
+			},
+			expected: []string{},
+		},
+		{
+			description: "and",
+			clause: db.Clause{
+				Type: db.ClauseAnd,
+				Clauses: []db.Clause{
+					{
+						Type: db.ClauseMatch,
+						Text: "acme",
+					},
+					{
+						Type: db.ClauseMatch,
+						Text: "invoice",
+					},
+				},
+			},
+			expected: []string{"invoices/2021/acme.pdf"},
+		},
+		{
+			description: "or",
+			clause: db.Clause{
+				Type: db.ClauseOr,
+				Clauses: []db.Clause{
+					{
+						Type: db.ClauseMatch,
+						Text: "globex",
+					},
+					{
+						Type: db.ClauseMatch,
+						Text: "initech",
+					},
+				},
+			},
+			expected: []string{"invoices/2021/globex.png", "receipts/2022/initech.pdf"},
+		},
+		{
+			description: "and not",
+			clause: db.Clause{
+				Type: db.ClauseAnd,
+				Clauses: []db.Clause{
+					{
+						Type: db.ClauseMatch,
+						Text: "receipt",
+					},
+					{
+						Type: db.ClauseNot,
+						Clauses: []db.Clause{
+							{
+								Type: db.ClauseMatch,
+								Text: "acme",
+							},
+						},
+					},
+				},
+			},
+			expected: []string{"receipts/2022/initech.pdf"},
+		},
+	}
+
+	for _, test := range tests {
+		clause := test.clause
+		result := query(t, databaser, db.Query{
+			Clause: &clause,
+		})
+
+		checkKeys(t, test.description, sorted(keys(result)), test.expected)
+	}
+}
+
+func testHighlights(t *testing.T, databaser db.Databaser) {
+	documents := fixtures()
+	load(t, databaser, documents)
+
+	result := query(t, databaser, db.Query{
+		Clause: &db.Clause{
+			Type: db.ClauseAnd,
+			Clauses: []db.Clause{
+				{
+					Type: db.ClauseMatch,
+					Text: "gizmos",
+				},
+				{
+					Type: db.ClauseNot,
+					Clauses: []db.Clause{
+						{
+							Type: db.ClauseMatch,
+							Text: "globex",
+						},
+					},
+				},
+			},
+		},
+	})
+
+	if len(result.Hits) != 1 {
+		t.Fatalf("incorrect hits, received: %v, expected: [receipts/2021/acme.jpg]", keys(result))
+	}
+
+	line := documents[2].Pages[0].Lines[1]
+	expected := []db.Match{
+		{
+			PageNumber:  1,
+			LineID:      line.ID,
+			Text:        line.Text,
+			Highlight:   "Receipt for widgets &amp; &lt;<em>gizmos</em>&gt;",
+			Coordinates: line.Coordinates,
+			Words:       line.Words[4:],
+		},
+	}
+
+	if !reflect.DeepEqual(result.Hits[0].Matches, expected) {
+		t.Errorf("incorrect matches, received: %+v, expected: %+v", result.Hits[0].Matches, expected)
+	}
+
+	filtered := query(t, databaser, db.Query{
+		Filters: &db.Filters{
+			Buckets: []string{"receipts"},
+		},
+	})
+
+	for _, hit := range filtered.Hits {
+		if len(hit.Matches) != 0 {
+			t.Errorf("incorrect filter only matches, received: %+v, expected: none", hit.Matches)
+		}
+	}
+}
+
+func testFilters(t *testing.T, databaser db.Databaser) {
+	load(t, databaser, fixtures())
+
+	tests := []struct {
+		description string
+		text        string
+		filters     db.Filters
+		expected    []string
+	}{
+		{
+			description: "buckets",
+			filters: db.Filters{
+				Buckets: []string{"receipts"},
+			},
+			expected: []string{"receipts/2021/acme.jpg", "receipts/2022/initech.pdf"},
+		},
+		{
+			description: "buckets with text",
+			text:        "acme",
+			filters: db.Filters{
+				Buckets: []string{"receipts"},
+			},
+			expected: []string{"receipts/2021/acme.jpg"},
+		},
+		{
+			description: "key prefix",
+			filters: db.Filters{
+				KeyPrefix: "2022/",
+			},
+			expected: []string{"receipts/2022/initech.pdf"},
+		},
+		{
+			description: "file extensions",
+			filters: db.Filters{
+				FileTypes: []string{".PDF"},
+			},
+			expected: []string{"invoices/2021/acme.pdf", "receipts/2022/initech.pdf"},
+		},
+		{
+			description: "file extensions and content types",
+			filters: db.Filters{
+				FileTypes: []string{"png", "image/jpeg"},
+			},
+			expected: []string{"invoices/2021/globex.png", "receipts/2021/acme.jpg"},
+		},
+		{
+			description: "padded content types",
+			filters: db.Filters{
+				FileTypes: []string{" Application/PDF "},
+			},
+			expected: []string{"invoices/2021/acme.pdf", "receipts/2022/initech.pdf"},
+		},
+		{
+			description: "last modified range",
+			filters: db.Filters{
+				LastModified: &db.DateRange{
+					From: date(2),
+					To:   date(3),
+				},
+			},
+			expected: []string{"invoices/2021/globex.png", "receipts/2021/acme.jpg"},
+		},
+		{
+			description: "indexed at lower bound",
+			filters: db.Filters{
+				IndexedAt: &db.DateRange{
+					From: date(5),
+				},
+			},
+			expected: []string{"receipts/2022/initech.pdf"},
+		},
+	}
+
+	for _, test := range tests {
+		filters := test.filters
+		result := query(t, databaser, db.Query{
+			Text:    test.text,
+			Filters: &filters,
+		})
+
+		checkKeys(t, test.description, sorted(keys(result)), test.expected)
+	}
+}
+
+func testMinConfidence(t *testing.T, databaser db.Databaser) {
+	load(t, databaser, fixtures())
+
+	tests := []struct {
+		description string
+		query       db.Query
+		expected    []string
+	}{
+		{
+			description: "text without min confidence",
+			query: db.Query{
+				Text: "invoice",
+			},
+			expected: []string{"invoices/2021/acme.pdf", "invoices/2021/globex.png"},
+		},
+		{
+			description: "text with min confidence",
+			query: db.Query{
+				Text:          "invoice",
+				MinConfidence: 50,
+			},
+			expected: []string{"invoices/2021/acme.pdf"},
+		},
+		{
+			description: "text above every line confidence",
+			query: db.Query{
+				Text:          "corporation",
+				MinConfidence: 100,
+			},
+			expected: []string{},
+		},
+		{
+			description: "negated clause with min confidence",
+			query: db.Query{
+				Clause: &db.Clause{
+					Type: db.ClauseAnd,
+					Clauses: []db.Clause{
+						{
+							Type: db.ClauseMatch,
+							Text: "corporation",
+						},
+						{
+							Type: db.ClauseNot,
+							Clauses: []db.Clause{
+								{
+									Type: db.ClausePhrase,
+									Text: "invoice number",
+								},
+							},
+						},
+					},
+				},
+				MinConfidence: 50,
+			},
+			expected: []string{"invoices/2021/globex.png", "receipts/2021/acme.jpg"},
+		},
+	}
+
+	for _, test := range tests {
+		result := query(t, databaser, test.query)
+
+		checkKeys(t, test.description, sorted(keys(result)), test.expected)
+
+		for _, hit := range result.Hits {
+			for _, match := range hit.Matches {
+				for _, page := range hit.Document.Pages {
+					for _, line := range page.Lines {
+						if line.ID == match.LineID && line.Confidence < test.query.MinConfidence {
+							t.Errorf("incorrect %s match below min confidence, received: %+v", test.description, match)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func testFieldQueries(t *testing.T, databaser db.Databaser) {
+	documents := fixtures()
+	load(t, databaser, documents)
+
+	tests := []struct {
+		description string
+		query       db.Query
+		expected    []string
+	}{
+		{
+			description: "field text",
+			query: db.Query{
+				Text: `field:"Invoice Number" = 1001`,
+			},
+			expected: []string{"invoices/2021/acme.pdf"},
+		},
+		{
+			description: "field text ignoring case and punctuation",
+			query: db.Query{
+				Text: `field:"invoice number" = "1002"`,
+			},
+			expected: []string{"invoices/2021/globex.png"},
+		},
+		{
+			description: "field clause with partial key",
+			query: db.Query{
+				Clause: &db.Clause{
+					Type:  db.ClauseField,
+					Field: "Invoice",
+					Text:  "1001",
+				},
+			},
+			expected: []string{},
+		},
+		{
+			description: "field clause with partial value",
+			query: db.Query{
+				Clause: &db.Clause{
+					Type:  db.ClauseField,
+					Field: "Total Due",
+					Text:  "250",
+				},
+			},
+			expected: []string{},
+		},
+		{
+			description: "field text below min confidence",
+			query: db.Query{
+				Text:          `field:"Invoice Number" = 1002`,
+				MinConfidence: 50,
+			},
+			expected: []string{},
+		},
+		{
+			description: "field text above min confidence",
+			query: db.Query{
+				Text:          `field:"Total Due" = 75.00`,
+				MinConfidence: 50,
+			},
+			expected: []string{"invoices/2021/globex.png"},
+		},
+		{
+			description: "negated field clause",
+			query: db.Query{
+				Clause: &db.Clause{
+					Type: db.ClauseNot,
+					Clauses: []db.Clause{
+						{
+							Type:  db.ClauseField,
+							Field: "Invoice Number",
+							Text:  "1001",
+						},
+					},
+				},
+				Filters: &db.Filters{
+					Buckets: []string{"invoices"},
+				},
+			},
+			expected: []string{"invoices/2021/globex.png"},
+		},
+	}
+
+	for _, test := range tests {
+		result := query(t, databaser, test.query)
+
+		checkKeys(t, test.description, sorted(keys(result)), test.expected)
+	}
+
+	result := query(t, databaser, db.Query{
+		Clause: &db.Clause{
+			Type: db.ClauseAnd,
+			Clauses: []db.Clause{
+				{
+					Type:  db.ClauseField,
+					Field: "Total Due",
+					Text:  "$250.00",
+				},
+				{
+					Type: db.ClausePhrase,
+					Text: "for widgets",
+				},
+			},
+		},
+	})
+
+	checkKeys(t, "field and phrase", keys(result), []string{"invoices/2021/acme.pdf"})
+	if len(result.Hits) != 1 {
+		return
+	}
+
+	hit := result.Hits[0]
+	if len(hit.Matches) != 1 || hit.Matches[0].LineID != documents[0].Pages[0].Lines[2].ID {
+		t.Errorf("incorrect field and phrase matches, received: %+v, expected: the phrase line", hit.Matches)
+	}
+
+	expectedPage := documents[0].Pages[0]
+	receivedPage := hit.Document.Pages[0]
+	if !reflect.DeepEqual(receivedPage.Fields, expectedPage.Fields) {
+		t.Errorf("incorrect fields, received: %+v, expected: %+v", receivedPage.Fields, expectedPage.Fields)
+	}
+
+	if !reflect.DeepEqual(receivedPage.Tables, expectedPage.Tables) {
+		t.Errorf("incorrect tables, received: %+v, expected: %+v", receivedPage.Tables, expectedPage.Tables)
+	}
+}
+
+func testSorting(t *testing.T, databaser db.Databaser) {
+	load(t, databaser, fixtures())
+
+	all := &db.Filters{
+		KeyPrefix: "20",
+	}
+
+	tests := []struct {
+		description string
+		sort        string
+		order       string
+		expected    []string
+	}{
+		{
+			description: "key default order",
+			sort:        db.SortKey,
+			expected:    []string{"receipts/2021/acme.jpg", "invoices/2021/acme.pdf", "invoices/2021/globex.png", "receipts/2022/initech.pdf"},
+		},
+		{
+			description: "key descending",
+			sort:        db.SortKey,
+			order:       db.OrderDesc,
+			expected:    []string{"receipts/2022/initech.pdf", "invoices/2021/globex.png", "invoices/2021/acme.pdf", "receipts/2021/acme.jpg"},
+		},
+		{
+			description: "indexed at default order",
+			sort:        db.SortIndexedAt,
+			expected:    []string{"receipts/2022/initech.pdf", "receipts/2021/acme.jpg", "invoices/2021/globex.png", "invoices/2021/acme.pdf"},
+		},
+		{
+			description: "indexed at ascending",
+			sort:        db.SortIndexedAt,
+			order:       db.OrderAsc,
+			expected:    []string{"invoices/2021/acme.pdf", "invoices/2021/globex.png", "receipts/2021/acme.jpg", "receipts/2022/initech.pdf"},
+		},
+	}
+
+	for _, test := range tests {
+		result := query(t, databaser, db.Query{
+			Filters: all,
+			Sort:    test.sort,
+			Order:   test.order,
+		})
+
+		checkKeys(t, test.description, keys(result), test.expected)
+	}
+}
+
+func testPagination(t *testing.T, databaser db.Databaser) {
+	load(t, databaser, fixtures())
+
+	for _, sortField := range []string{db.SortRelevance, db.SortKey, db.SortIndexedAt} {
+		received := []string{}
+		cursor := ""
+		for page := 0; page < 5; page++ {
+			result := query(t, databaser, db.Query{
+				Text:   "corporation receipt",
+				Size:   1,
+				Sort:   sortField,
+				Cursor: cursor,
+			})
+
+			if result.Total != 4 {
+				t.Errorf("incorrect %s total, received: %d, expected: 4", sortField, result.Total)
+			}
+
+			received = append(received, keys(result)...)
+
+			cursor = result.Next
+			if cursor == "" {
+				break
+			}
+		}
+
+		expected := []string{"invoices/2021/acme.pdf", "invoices/2021/globex.png", "receipts/2021/acme.jpg", "receipts/2022/initech.pdf"}
+		checkKeys(t, sortField+" paginated", sorted(received), expected)
+	}
+}
+
+func testUpsertReplaces(t *testing.T, databaser db.Databaser) {
+	documents := fixtures()
+	load(t, databaser, documents)
+
+	updated := document("invoices", "2021/acme.pdf", "pdf", "application/pdf", 1, "Hooli invoice")
+	load(t, databaser, []pars.Document{updated})
+
+	checkKeys(t, "replaced", keys(query(t, databaser, db.Query{Text: "hooli"})), []string{"invoices/2021/acme.pdf"})
+	checkKeys(t, "previous", keys(query(t, databaser, db.Query{Clause: &db.Clause{Type: db.ClauseMatch, Text: "1001"}})), []string{})
+}
+
+func testDeleteDocumentsByKeys(t *testing.T, databaser db.Databaser) {
+	load(t, databaser, fixtures())
+
+	if err := databaser.DeleteDocumentsByKeys(context.Background(), []db.FileKey{
+		{Bucket: "invoices", Key: "2021/acme.pdf"},
+		{Bucket: "receipts", Key: "2022/initech.pdf"},
+	}); err != nil {
+		t.Fatalf("error deleting documents: %v", err)
+	}
+
+	result := query(t, databaser, db.Query{
+		Filters: &db.Filters{
+			KeyPrefix: "20",
+		},
+	})
+
+	checkKeys(t, "remaining", sorted(keys(result)), []string{"invoices/2021/globex.png", "receipts/2021/acme.jpg"})
+}
+
+func testDeleteDocumentsByExactKeys(t *testing.T, databaser db.Databaser) {
+	load(t, databaser, []pars.Document{
+		document("archive", "acme.pdf", "pdf", "application/pdf", 1, "ACME invoice"),
+		document("archive", "2021/q1/acme invoice.pdf", "pdf", "application/pdf", 2, "ACME invoice"),
+		document("archive", "2021/q1/acme invoice copy.pdf", "pdf", "application/pdf", 3, "ACME invoice"),
+		document("archive", "2021/q1", "pdf", "application/pdf", 4, "ACME invoice"),
+		document("archive", "rechnungen/größe 文字.pdf", "pdf", "application/pdf", 5, "ACME invoice"),
+		document("2021", "q1/acme invoice.pdf", "pdf", "application/pdf", 6, "ACME invoice"),
+		document("backup", "acme.pdf", "pdf", "application/pdf", 7, "ACME invoice"),
+	})
+
+	if err := databaser.DeleteDocumentsByKeys(context.Background(), []db.FileKey{
+		{Bucket: "archive", Key: "acme.pdf"},
+		{Bucket: "archive", Key: "2021/q1/acme invoice.pdf"},
+		{Bucket: "archive", Key: "rechnungen/größe 文字.pdf"},
+		{Bucket: "archive", Key: "missing.pdf"},
+	}); err != nil {
+		t.Fatalf("error deleting documents: %v", err)
+	}
+
+	result := query(t, databaser, db.Query{Text: "invoice"})
+
+	checkKeys(t, "remaining", sorted(keys(result)), []string{
+		"2021/q1/acme invoice.pdf",
+		"archive/2021/q1",
+		"archive/2021/q1/acme invoice copy.pdf",
+		"backup/acme.pdf",
+	})
+}
+
+func testDeleteDocumentsByManyKeys(t *testing.T, databaser db.Databaser) {
+	load(t, databaser, fixtures())
+
+	fileKeys := []db.FileKey{
+		{Bucket: "invoices", Key: "2021/acme.pdf"},
+		{Bucket: "receipts", Key: "2022/initech.pdf"},
+	}
+	for i := len(fileKeys); i < 1000; i++ {
+		fileKeys = append(fileKeys, db.FileKey{Bucket: "invoices", Key: fmt.Sprintf("missing/%04d.pdf", i)})
+	}
+
+	if err := databaser.DeleteDocumentsByKeys(context.Background(), fileKeys); err != nil {
+		t.Fatalf("error deleting documents: %v", err)
+	}
+
+	result := query(t, databaser, db.Query{
+		Filters: &db.Filters{
+			KeyPrefix: "20",
+		},
+	})
+
+	checkKeys(t, "remaining", sorted(keys(result)), []string{"invoices/2021/globex.png", "receipts/2021/acme.jpg"})
+}
+
+func testDeleteDocumentsByBuckets(t *testing.T, databaser db.Databaser) {
+	load(t, databaser, fixtures())
+
+	if err := databaser.DeleteDocumentsByBuckets(context.Background(), []string{"invoices"}); err != nil {
+		t.Fatalf("error deleting documents: %v", err)
+	}
+
+	result := query(t, databaser, db.Query{
+		Filters: &db.Filters{
+			KeyPrefix: "20",
+		},
+	})
+
+	checkKeys(t, "remaining", sorted(keys(result)), []string{"receipts/2021/acme.jpg", "receipts/2022/initech.pdf"})
+}
+
+func testSkippedFiles(t *testing.T, databaser db.Databaser) {
+	skipped, parsed := true, false
+
+	notes := pars.NewSkippedDocument(pars.File{
+		Bucket:       "invoices",
+		Key:          "2021/notes.txt",
+		ContentType:  "text/plain",
+		LastModified: date(5),
+	}, "unsupported content type text/plain")
+
+	load(t, databaser, append(fixtures(), notes))
+
+	result := query(t, databaser, db.Query{
+		Filters: &db.Filters{
+			Skipped: &skipped,
+		},
+	})
+
+	checkKeys(t, "skipped", keys(result), []string{"invoices/2021/notes.txt"})
+	if len(result.Hits) == 1 && result.Hits[0].Document.SkipReason != notes.SkipReason {
+		t.Errorf("incorrect skip reason, received: %q, expected: %q", result.Hits[0].Document.SkipReason, notes.SkipReason)
+	}
+
+	checkKeys(t, "parsed", sorted(keys(query(t, databaser, db.Query{
+		Filters: &db.Filters{
+			Buckets: []string{"invoices"},
+			Skipped: &parsed,
+		},
+	}))), []string{"invoices/2021/acme.pdf", "invoices/2021/globex.png"})
+
+	checkKeys(t, "matched", sorted(keys(query(t, databaser, db.Query{Text: "corporation"}))), []string{
+		"invoices/2021/acme.pdf",
+		"invoices/2021/globex.png",
+		"receipts/2021/acme.jpg",
+	})
+
+	load(t, databaser, []pars.Document{
+		document("invoices", "2021/notes.txt", "txt", "text/plain", 5, "Meeting notes"),
+	})
+
+	checkKeys(t, "replaced", keys(query(t, databaser, db.Query{
+		Filters: &db.Filters{
+			Skipped: &skipped,
+		},
+	})), []string{})
+}
@@ -1,6 +1,9 @@
 package db
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 const errorMessage = "package db: %v"
 
@@ -13,6 +16,36 @@ func (e *NewClientError) Error() string {
 	return fmt.Sprintf(errorMessage, e.err)
 }
 
+// ExecuteExistsError wraps errors returned by
+// db.helper.executeExists in db.Databaser.SetupDatabase.
+type ExecuteExistsError struct {
+	err error
+}
+
+func (e *ExecuteExistsError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// ExecuteGetMappingError wraps errors returned by
+// db.helper.executeGetMapping in db.Databaser.SetupDatabase.
+type ExecuteGetMappingError struct {
+	err error
+}
+
+func (e *ExecuteGetMappingError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// MappingMismatchError is returned by db.Databaser.SetupDatabase when
+// the existing index mapping is incompatible with the current mapping.
+type MappingMismatchError struct {
+	err error
+}
+
+func (e *MappingMismatchError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
 // ExecuteCreateError wraps errors returned by
 // db.helper.executeCreate in db.Databaser.SetupDatabase.
 type ExecuteCreateError struct {
@@ -23,6 +56,39 @@ func (e *ExecuteCreateError) Error() string {
 	return fmt.Sprintf(errorMessage, e.err)
 }
 
+// ExecuteGetAliasError wraps errors returned by
+// db.helper.executeGetAlias in db.Databaser.SetupDatabase and
+// db.Reindexer.Reindex.
+type ExecuteGetAliasError struct {
+	err error
+}
+
+func (e *ExecuteGetAliasError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// ExecuteUpdateAliasesError wraps errors returned by
+// db.helper.executeUpdateAliases in db.Databaser.SetupDatabase and
+// db.Reindexer.Reindex.
+type ExecuteUpdateAliasesError struct {
+	err error
+}
+
+func (e *ExecuteUpdateAliasesError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// ExecuteReindexError wraps errors returned by
+// db.helper.executeReindex, the replayed deletes, and
+// db.helper.executeDeleteIndex in db.Reindexer.Reindex.
+type ExecuteReindexError struct {
+	err error
+}
+
+func (e *ExecuteReindexError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
 // MarshalDocumentError wraps errors returned by json.Marshal
 // in db.Databaser.UpsertDocuments.
 type MarshalDocumentError struct {
@@ -43,8 +109,24 @@ func (e *ExecuteBulkError) Error() string {
 	return fmt.Sprintf(errorMessage, e.err)
 }
 
+// BulkItemsError is returned by db.Databaser.UpsertDocuments when
+// documents in the bulk request could not be written.
+type BulkItemsError struct {
+	Failures []BulkFailure
+}
+
+func (e *BulkItemsError) Error() string {
+	failures := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		failures[i] = fmt.Sprintf("%s (%d): %s", failure.ID, failure.Status, failure.Reason)
+	}
+
+	return fmt.Sprintf(errorMessage, fmt.Sprintf("failed to write documents: %s", strings.Join(failures, ", ")))
+}
+
 // ExecuteDeleteError wraps errors returned by db.helper.executeDelete
-// in db.Databaser.DeleteDocuments.
+// and db.helper.executeLogDelete in db.Databaser.DeleteDocumentsByKeys
+// and db.Databaser.DeleteDocumentsByBuckets.
 type ExecuteDeleteError struct {
 	err error
 }
@@ -53,6 +135,26 @@ func (e *ExecuteDeleteError) Error() string {
 	return fmt.Sprintf(errorMessage, e.err)
 }
 
+// InvalidQueryError wraps errors returned by db.Query.Validate
+// for malformed user queries.
+type InvalidQueryError struct {
+	err error
+}
+
+func (e *InvalidQueryError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// MarshalQueryError wraps errors returned by json.Marshal when
+// encoding request bodies.
+type MarshalQueryError struct {
+	err error
+}
+
+func (e *MarshalQueryError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
 // ExecuteQueryError wraps errors returned by db.helper.executeQuery
 // in db.Databaser.QueryDocuments.
 type ExecuteQueryError struct {
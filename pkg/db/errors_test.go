@@ -18,6 +18,45 @@ func TestNewClientError(t *testing.T) {
 	}
 }
 
+func TestExecuteExistsError(t *testing.T) {
+	err := &ExecuteExistsError{
+		err: errors.New("mock execute exists error"),
+	}
+
+	recieved := err.Error()
+	expected := "package db: mock execute exists error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestExecuteGetMappingError(t *testing.T) {
+	err := &ExecuteGetMappingError{
+		err: errors.New("mock execute get mapping error"),
+	}
+
+	recieved := err.Error()
+	expected := "package db: mock execute get mapping error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestMappingMismatchError(t *testing.T) {
+	err := &MappingMismatchError{
+		err: errors.New("mock mapping mismatch error"),
+	}
+
+	recieved := err.Error()
+	expected := "package db: mock mapping mismatch error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
 func TestExecuteCreateError(t *testing.T) {
 	err := &ExecuteCreateError{
 		err: errors.New("mock execute create error"),
@@ -31,6 +70,45 @@ func TestExecuteCreateError(t *testing.T) {
 	}
 }
 
+func TestExecuteGetAliasError(t *testing.T) {
+	err := &ExecuteGetAliasError{
+		err: errors.New("mock execute get alias error"),
+	}
+
+	recieved := err.Error()
+	expected := "package db: mock execute get alias error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestExecuteUpdateAliasesError(t *testing.T) {
+	err := &ExecuteUpdateAliasesError{
+		err: errors.New("mock execute update aliases error"),
+	}
+
+	recieved := err.Error()
+	expected := "package db: mock execute update aliases error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestExecuteReindexError(t *testing.T) {
+	err := &ExecuteReindexError{
+		err: errors.New("mock execute reindex error"),
+	}
+
+	recieved := err.Error()
+	expected := "package db: mock execute reindex error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
 func TestMarshalDocumentError(t *testing.T) {
 	err := &MarshalDocumentError{
 		err: errors.New("mock marshal document error"),
@@ -57,6 +135,30 @@ func TestExecuteBulkError(t *testing.T) {
 	}
 }
 
+func TestBulkItemsError(t *testing.T) {
+	err := &BulkItemsError{
+		Failures: []BulkFailure{
+			{
+				ID:     "first_id",
+				Status: 400,
+				Reason: "mapper_parsing_exception: failed to parse",
+			},
+			{
+				ID:     "second_id",
+				Status: 429,
+				Reason: "es_rejected_execution_exception: rejected execution",
+			},
+		},
+	}
+
+	recieved := err.Error()
+	expected := "package db: failed to write documents: first_id (400): mapper_parsing_exception: failed to parse, second_id (429): es_rejected_execution_exception: rejected execution"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
 func TestExecuteDeleteError(t *testing.T) {
 	err := &ExecuteDeleteError{
 		err: errors.New("mock execute delete error"),
@@ -70,6 +172,32 @@ func TestExecuteDeleteError(t *testing.T) {
 	}
 }
 
+func TestInvalidQueryError(t *testing.T) {
+	err := &InvalidQueryError{
+		err: errors.New("mock invalid query error"),
+	}
+
+	recieved := err.Error()
+	expected := "package db: mock invalid query error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestMarshalQueryError(t *testing.T) {
+	err := &MarshalQueryError{
+		err: errors.New("mock marshal query error"),
+	}
+
+	recieved := err.Error()
+	expected := "package db: mock marshal query error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
 func TestExecuteQueryError(t *testing.T) {
 	err := &ExecuteQueryError{
 		err: errors.New("mock execute query error"),
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"net/http"
 	"time"
 
 	"github.com/opensearch-project/opensearch-go"
@@ -11,16 +12,28 @@ import (
 )
 
 const (
-	index        = "files"
-	documentType = "file"
+	indexPrefix = "files-v"
+	readAlias   = "files-read"
+	writeAlias  = "files-write"
+
+	deletesIndex = "files-deletes-log"
+	deletesAlias = "files-deletes"
 )
 
 var _ helper = &help{}
 
 type helper interface {
-	executeCreate(ctx context.Context) error
-	executeBulk(ctx context.Context, body io.Reader) error
+	executeExists(ctx context.Context, name string) (bool, error)
+	executeCreate(ctx context.Context, name string, body io.Reader) error
+	executeGetMapping(ctx context.Context, name string) (io.ReadCloser, error)
+	executeGetAlias(ctx context.Context, alias string) (io.ReadCloser, error)
+	executeUpdateAliases(ctx context.Context, body io.Reader) error
+	executeReindex(ctx context.Context, body io.Reader) (io.ReadCloser, error)
+	executeBulk(ctx context.Context, body io.Reader) (io.ReadCloser, error)
 	executeDelete(ctx context.Context, body io.Reader) error
+	executeLogDelete(ctx context.Context, body io.Reader) error
+	executeGetDeletes(ctx context.Context, body io.Reader) (io.ReadCloser, error)
+	executeDeleteIndex(ctx context.Context, name string) error
 	executeQuery(ctx context.Context, body io.Reader) (io.ReadCloser, error)
 }
 
@@ -28,14 +41,37 @@ type help struct {
 	opensearchClient *opensearch.Client
 }
 
-func (h *help) executeCreate(ctx context.Context) error {
+func (h *help) executeExists(ctx context.Context, name string) (bool, error) {
+	request := opensearchapi.IndicesExistsRequest{
+		Index: []string{name},
+	}
+
+	response, err := request.Do(ctx, h.opensearchClient)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	if err := checkResponse(response); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (h *help) executeCreate(ctx context.Context, name string, body io.Reader) error {
 	request := opensearchapi.IndicesCreateRequest{
-		Index: index,
+		Index: name,
+		Body:  body,
 	}
 
 	response, err := request.Do(ctx, h.opensearchClient)
 	if err != nil {
-		return nil
+		return err
 	}
 
 	if err := checkResponse(response); err != nil {
@@ -45,12 +81,43 @@ func (h *help) executeCreate(ctx context.Context) error {
 	return nil
 }
 
-func (h *help) executeBulk(ctx context.Context, body io.Reader) error {
-	request := opensearchapi.BulkRequest{
-		Index:        index,
-		DocumentType: documentType,
-		Body:         body,
-		Timeout:      time.Duration(1 * time.Minute),
+func (h *help) executeGetMapping(ctx context.Context, name string) (io.ReadCloser, error) {
+	request := opensearchapi.IndicesGetMappingRequest{
+		Index: []string{name},
+	}
+
+	response, err := request.Do(ctx, h.opensearchClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(response); err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+func (h *help) executeGetAlias(ctx context.Context, alias string) (io.ReadCloser, error) {
+	request := opensearchapi.IndicesGetAliasRequest{
+		Name: []string{alias},
+	}
+
+	response, err := request.Do(ctx, h.opensearchClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(response); err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+func (h *help) executeUpdateAliases(ctx context.Context, body io.Reader) error {
+	request := opensearchapi.IndicesUpdateAliasesRequest{
+		Body: body,
 	}
 
 	response, err := request.Do(ctx, h.opensearchClient)
@@ -65,11 +132,114 @@ func (h *help) executeBulk(ctx context.Context, body io.Reader) error {
 	return nil
 }
 
+func (h *help) executeReindex(ctx context.Context, body io.Reader) (io.ReadCloser, error) {
+	waitForCompletion := true
+	request := opensearchapi.ReindexRequest{
+		Body:              body,
+		WaitForCompletion: &waitForCompletion,
+		Timeout:           time.Duration(10 * time.Minute),
+	}
+
+	response, err := request.Do(ctx, h.opensearchClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(response); err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+func (h *help) executeBulk(ctx context.Context, body io.Reader) (io.ReadCloser, error) {
+	request := opensearchapi.BulkRequest{
+		Index:   writeAlias,
+		Body:    body,
+		Timeout: time.Duration(1 * time.Minute),
+	}
+
+	response, err := request.Do(ctx, h.opensearchClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(response); err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+// executeDelete runs the delete query through both aliases, which
+// point at the same index unless a reindex is running.
 func (h *help) executeDelete(ctx context.Context, body io.Reader) error {
 	request := opensearchapi.DeleteByQueryRequest{
-		Index:        []string{index},
-		DocumentType: []string{documentType},
+		Index: []string{readAlias, writeAlias},
+		Body:  body,
+	}
+
+	response, err := request.Do(ctx, h.opensearchClient)
+	if err != nil {
+		return err
+	}
+
+	if err := checkResponse(response); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// executeLogDelete records the delete query in the deletes log if the
+// deletes alias exists and does nothing otherwise.
+func (h *help) executeLogDelete(ctx context.Context, body io.Reader) error {
+	requireAlias := true
+	request := opensearchapi.IndexRequest{
+		Index:        deletesAlias,
 		Body:         body,
+		RequireAlias: &requireAlias,
+		Refresh:      "true",
+	}
+
+	response, err := request.Do(ctx, h.opensearchClient)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if err := checkResponse(response); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (h *help) executeGetDeletes(ctx context.Context, body io.Reader) (io.ReadCloser, error) {
+	request := opensearchapi.SearchRequest{
+		Index: []string{deletesAlias},
+		Body:  body,
+	}
+
+	response, err := request.Do(ctx, h.opensearchClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(response); err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+func (h *help) executeDeleteIndex(ctx context.Context, name string) error {
+	request := opensearchapi.IndicesDeleteRequest{
+		Index: []string{name},
 	}
 
 	response, err := request.Do(ctx, h.opensearchClient)
@@ -86,9 +256,8 @@ func (h *help) executeDelete(ctx context.Context, body io.Reader) error {
 
 func (h *help) executeQuery(ctx context.Context, body io.Reader) (io.ReadCloser, error) {
 	request := opensearchapi.SearchRequest{
-		Index:        []string{index},
-		DocumentType: []string{documentType},
-		Body:         body,
+		Index: []string{readAlias},
+		Body:  body,
 	}
 
 	response, err := request.Do(ctx, h.opensearchClient)
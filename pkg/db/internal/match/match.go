@@ -0,0 +1,133 @@
+// Package match holds the term matching and highlighting rules shared
+// by the db.Databaser implementations so that they behave like the
+// OpenSearch queries built by db.Client.
+package match
+
+import (
+	"html"
+	"sort"
+	"unicode/utf8"
+)
+
+const (
+	// MaxFuzzyExpansions matches the OpenSearch match query default
+	// for the number of terms a fuzzy term expands to.
+	MaxFuzzyExpansions = 50
+
+	// MaxPatternExpansions matches the OpenSearch default maximum
+	// number of clauses a prefix or wildcard term expands to.
+	MaxPatternExpansions = 1024
+)
+
+// MaxEdits returns the number of edits allowed for a query term under
+// the provided fuzziness, following the OpenSearch "AUTO" thresholds.
+func MaxEdits(term, fuzziness string) int {
+	switch fuzziness {
+	case "1":
+		return 1
+	case "2":
+		return 2
+	case "AUTO":
+		length := utf8.RuneCountInString(term)
+		if length > 5 {
+			return 2
+		} else if length > 2 {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// Distance returns the Damerau-Levenshtein distance between the terms
+// counting an adjacent transposition as a single edit as OpenSearch
+// fuzzy matching does.
+func Distance(a, b string) int {
+	first, second := []rune(a), []rune(b)
+	rows := make([][]int, len(first)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(second)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(first); i++ {
+		for j := 1; j <= len(second); j++ {
+			cost := 1
+			if first[i-1] == second[j-1] {
+				cost = 0
+			}
+
+			rows[i][j] = minimum(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && first[i-1] == second[j-2] && first[i-2] == second[j-1] {
+				rows[i][j] = minimum(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return rows[len(first)][len(second)]
+}
+
+// Expand returns the candidate terms within the allowed edits of the
+// term, closest first and capped at MaxFuzzyExpansions.
+func Expand(term string, edits int, candidates []string) []string {
+	matched := []string{}
+	distances := map[string]int{}
+	for _, candidate := range candidates {
+		if distance := Distance(term, candidate); distance <= edits {
+			matched = append(matched, candidate)
+			distances[candidate] = distance
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return distances[matched[i]] < distances[matched[j]]
+	})
+
+	if len(matched) > MaxFuzzyExpansions {
+		matched = matched[:MaxFuzzyExpansions]
+	}
+
+	return matched
+}
+
+func minimum(values ...int) int {
+	output := values[0]
+	for _, value := range values[1:] {
+		if value < output {
+			output = value
+		}
+	}
+
+	return output
+}
+
+// Span holds the byte offsets of a matched term in a line of text.
+type Span struct {
+	Start int
+	End   int
+}
+
+// Highlight returns the HTML-escaped line text with the spans wrapped
+// in <em> tags. Overlapping and repeated spans are highlighted once.
+func Highlight(text string, spans []Span) string {
+	sorted := append([]Span{}, spans...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	output := ""
+	position := 0
+	for _, span := range sorted {
+		if span.Start < position || span.End > len(text) {
+			continue
+		}
+
+		output += html.EscapeString(text[position:span.Start]) + "<em>" + html.EscapeString(text[span.Start:span.End]) + "</em>"
+		position = span.End
+	}
+
+	return output + html.EscapeString(text[position:])
+}
@@ -0,0 +1,105 @@
+package match
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMaxEdits(t *testing.T) {
+	tests := []struct {
+		term      string
+		fuzziness string
+		edits     int
+	}{
+		{term: "ox", fuzziness: "AUTO", edits: 0},
+		{term: "fox", fuzziness: "AUTO", edits: 1},
+		{term: "quick", fuzziness: "AUTO", edits: 1},
+		{term: "widgets", fuzziness: "AUTO", edits: 2},
+		{term: "widgets", fuzziness: "", edits: 0},
+		{term: "ox", fuzziness: "2", edits: 2},
+	}
+
+	for _, test := range tests {
+		if received := MaxEdits(test.term, test.fuzziness); received != test.edits {
+			t.Errorf("incorrect edits for %q with %q, received: %d, expected: %d", test.term, test.fuzziness, received, test.edits)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		description string
+		first       string
+		second      string
+		distance    int
+	}{
+		{
+			description: "identical terms",
+			first:       "widget",
+			second:      "widget",
+			distance:    0,
+		},
+		{
+			description: "deletion",
+			first:       "widget",
+			second:      "widgt",
+			distance:    1,
+		},
+		{
+			description: "transposition",
+			first:       "widget",
+			second:      "wdiget",
+			distance:    1,
+		},
+		{
+			description: "substitutions",
+			first:       "gadgets",
+			second:      "widgets",
+			distance:    2,
+		},
+		{
+			description: "empty term",
+			first:       "",
+			second:      "fox",
+			distance:    3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			if received := Distance(test.first, test.second); received != test.distance {
+				t.Errorf("incorrect distance, received: %d, expected: %d", received, test.distance)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	received := Expand("widget", 1, []string{"gadget", "widgets", "widget", "wdiget", "midge"})
+	expected := []string{"widget", "widgets", "wdiget"}
+
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("incorrect expansions, received: %v, expected: %v", received, expected)
+	}
+
+	candidates := []string{}
+	for i := 0; i < MaxFuzzyExpansions+10; i++ {
+		candidates = append(candidates, fmt.Sprintf("term%02d", i))
+	}
+
+	if received := Expand("term00", 2, candidates); len(received) != MaxFuzzyExpansions || received[0] != "term00" {
+		t.Errorf("incorrect capped expansions, received: %v, expected: %d starting with term00", received, MaxFuzzyExpansions)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	text := "Widgets & <gizmos> widgets"
+
+	received := Highlight(text, []Span{{Start: 19, End: 26}, {Start: 0, End: 7}, {Start: 0, End: 7}})
+	expected := "<em>Widgets</em> &amp; &lt;gizmos&gt; <em>widgets</em>"
+
+	if received != expected {
+		t.Errorf("incorrect highlight, received: %s, expected: %s", received, expected)
+	}
+}
@@ -0,0 +1,197 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// mappingVersion is recorded in the index mapping metadata and must be
+// incremented whenever the mapping or analysis settings change.
+const mappingVersion = 4
+
+const (
+	defaultAnalyzer = "standard"
+	textAnalyzer    = "line_text"
+
+	// fieldNormalizer lowercases form field keys and values and
+	// replaces their punctuation with single spaces to match
+	// NormalizeField.
+	fieldNormalizer  = "field_text"
+	fieldPunctuation = "field_punctuation"
+
+	// maxFieldLength is the longest form field key or value indexed;
+	// longer ones are kept in the source but cannot be matched.
+	maxFieldLength = 1024
+)
+
+// analyzers holds the built-in OpenSearch analyzers that may be
+// configured for the parsed line text.
+var analyzers = map[string]struct{}{
+	"standard":   {},
+	"simple":     {},
+	"whitespace": {},
+	"stop":       {},
+	"english":    {},
+	"french":     {},
+	"german":     {},
+	"italian":    {},
+	"portuguese": {},
+	"spanish":    {},
+}
+
+type mappingMeta struct {
+	Version  int    `json:"version"`
+	Analyzer string `json:"analyzer"`
+}
+
+func keywordField() object {
+	return object{
+		"type": "keyword",
+	}
+}
+
+func fieldTextField() object {
+	return object{
+		"type":         "keyword",
+		"normalizer":   fieldNormalizer,
+		"ignore_above": maxFieldLength,
+	}
+}
+
+func dateField() object {
+	return object{
+		"type": "date",
+	}
+}
+
+// indexDefinition returns the settings and mapping used when creating
+// the documents index with the provided line text analyzer.
+func indexDefinition(analyzer string) object {
+	return object{
+		"settings": object{
+			"analysis": object{
+				"analyzer": object{
+					textAnalyzer: object{
+						"type": analyzer,
+					},
+				},
+				"char_filter": object{
+					fieldPunctuation: object{
+						"type":        "pattern_replace",
+						"pattern":     `[^\p{L}\p{Nd}\p{M}_]+`,
+						"replacement": " ",
+					},
+				},
+				"normalizer": object{
+					fieldNormalizer: object{
+						"type":        "custom",
+						"char_filter": []string{fieldPunctuation},
+						"filter":      []string{"lowercase", "trim"},
+					},
+				},
+			},
+		},
+		"mappings": object{
+			"_meta": mappingMeta{
+				Version:  mappingVersion,
+				Analyzer: analyzer,
+			},
+			"dynamic": "strict",
+			"properties": object{
+				"id":             keywordField(),
+				"entity":         keywordField(),
+				"file_bucket":    keywordField(),
+				"file_key":       keywordField(),
+				"file_version":   keywordField(),
+				"file_extension": keywordField(),
+				"content_type":   keywordField(),
+				"last_modified":  dateField(),
+				"indexed_at":     dateField(),
+				"skip_reason":    keywordField(),
+				"pages": object{
+					"type": "nested",
+					"properties": object{
+						"id":     keywordField(),
+						"entity": keywordField(),
+						"page_number": object{
+							"type": "integer",
+						},
+						"lines": object{
+							"type": "nested",
+							"properties": object{
+								"id":     keywordField(),
+								"entity": keywordField(),
+								"text": object{
+									"type":     "text",
+									"analyzer": textAnalyzer,
+								},
+								"confidence": object{
+									"type": "float",
+								},
+								"coordinates": object{
+									"type":    "object",
+									"enabled": false,
+								},
+								"words": object{
+									"type":    "object",
+									"enabled": false,
+								},
+							},
+						},
+						"tables": object{
+							"type":    "object",
+							"enabled": false,
+						},
+						"fields": object{
+							"type": "nested",
+							"properties": object{
+								"id":     keywordField(),
+								"entity": keywordField(),
+								"key":    fieldTextField(),
+								"value":  fieldTextField(),
+								"confidence": object{
+									"type": "float",
+								},
+								"coordinates": object{
+									"type":    "object",
+									"enabled": false,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+type getMappingResponseBody map[string]struct {
+	Mappings struct {
+		Meta mappingMeta `json:"_meta"`
+	} `json:"mappings"`
+}
+
+// checkMapping compares the mapping metadata in a get mapping response
+// with the mapping that would be installed for the provided analyzer.
+func checkMapping(data []byte, analyzer string) error {
+	var responseBody getMappingResponseBody
+	if err := json.Unmarshal(data, &responseBody); err != nil {
+		return err
+	}
+
+	for indexName, indexMapping := range responseBody {
+		meta := indexMapping.Mappings.Meta
+		if meta.Version != mappingVersion || meta.Analyzer != analyzer {
+			return fmt.Errorf(
+				"index %s has mapping version %d with analyzer %q, expected version %d with analyzer %q",
+				indexName,
+				meta.Version,
+				meta.Analyzer,
+				mappingVersion,
+				analyzer,
+			)
+		}
+	}
+
+	return nil
+}
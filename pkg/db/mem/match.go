@@ -0,0 +1,293 @@
+package mem
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/db/internal/match"
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+// token is a single analyzed term in a line of text along with its
+// byte offsets in the original text.
+type token struct {
+	term  string
+	start int
+	end   int
+}
+
+// analyze splits text into lowercased terms the way the OpenSearch
+// standard analyzer does for plain words and numbers.
+func analyze(text string) []token {
+	tokens := []token{}
+	start := -1
+	for i, r := range text {
+		if isTermRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+
+		if start >= 0 {
+			tokens = append(tokens, newToken(text, start, i))
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, newToken(text, start, len(text)))
+	}
+
+	return tokens
+}
+
+func isTermRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_'
+}
+
+func newToken(text string, start, end int) token {
+	return token{
+		term:  strings.ToLower(text[start:end]),
+		start: start,
+		end:   end,
+	}
+}
+
+// lineLocation identifies a line by its page and line offsets in a
+// document.
+type lineLocation struct {
+	page int
+	line int
+}
+
+// evaluation holds the outcome of running a query against a single
+// document: whether it matched, its relevance score, and the spans to
+// highlight on each matching line.
+type evaluation struct {
+	matched bool
+	score   float64
+	spans   map[lineLocation][]match.Span
+}
+
+func newEvaluation() *evaluation {
+	return &evaluation{
+		spans: map[lineLocation][]match.Span{},
+	}
+}
+
+func (e *evaluation) merge(other *evaluation) {
+	e.score += other.score
+	for location, spans := range other.spans {
+		e.spans[location] = append(e.spans[location], spans...)
+	}
+}
+
+// evaluate runs the query text or clause tree against the document
+// lines and form fields with at least the query minimum confidence.
+// Queries holding only filters match every document without lines.
+func evaluate(query db.Query, document pars.Document) *evaluation {
+	if root := query.Root(); root != nil {
+		return evaluateClause(*root, document, false, query.MinConfidence)
+	}
+
+	output := newEvaluation()
+	output.matched = true
+	output.score = 1
+
+	return output
+}
+
+func evaluateClause(clause db.Clause, document pars.Document, negated bool, minConfidence float64) *evaluation {
+	output := newEvaluation()
+
+	switch clause.Type {
+	case db.ClauseAnd:
+		output.matched = true
+		for _, child := range clause.Clauses {
+			childOutput := evaluateClause(child, document, negated, minConfidence)
+			if !childOutput.matched {
+				output.matched = false
+			}
+			output.merge(childOutput)
+		}
+
+	case db.ClauseOr:
+		for _, child := range clause.Clauses {
+			childOutput := evaluateClause(child, document, negated, minConfidence)
+			if childOutput.matched {
+				output.matched = true
+				output.merge(childOutput)
+			}
+		}
+
+	case db.ClauseNot:
+		output.matched = true
+		for _, child := range clause.Clauses {
+			if evaluateClause(child, document, !negated, minConfidence).matched {
+				output.matched = false
+			}
+		}
+
+	case db.ClauseField:
+		key := db.NormalizeField(clause.Field)
+		value := db.NormalizeField(clause.Text)
+		for _, page := range document.Pages {
+			for _, field := range page.Fields {
+				if field.Confidence < minConfidence {
+					continue
+				}
+
+				if db.NormalizeField(field.Key) == key && db.NormalizeField(field.Value) == value {
+					output.matched = true
+					output.score++
+				}
+			}
+		}
+
+	default:
+		matcher := newMatcher(clause)
+		for pageIndex, page := range document.Pages {
+			for lineIndex, line := range page.Lines {
+				if line.Confidence < minConfidence {
+					continue
+				}
+
+				spans := matcher(analyze(line.Text))
+				if len(spans) == 0 {
+					continue
+				}
+
+				output.matched = true
+				output.score += float64(len(spans))
+				if !negated {
+					location := lineLocation{
+						page: pageIndex,
+						line: lineIndex,
+					}
+					for _, span := range spans {
+						output.spans[location] = append(output.spans[location], match.Span{
+							Start: span.start,
+							End:   span.end,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	if !output.matched {
+		return newEvaluation()
+	}
+
+	return output
+}
+
+// newMatcher returns a function reporting the tokens in a line that
+// match the term clause; no tokens are returned if the line does not
+// match.
+func newMatcher(clause db.Clause) func(tokens []token) []token {
+	switch clause.Type {
+	case db.ClausePhrase:
+		terms := analyze(clause.Text)
+		return func(tokens []token) []token {
+			if len(terms) == 0 {
+				return nil
+			}
+
+			matched := []token{}
+			for i := 0; i+len(terms) <= len(tokens); i++ {
+				found := true
+				for j, term := range terms {
+					if tokens[i+j].term != term.term {
+						found = false
+						break
+					}
+				}
+
+				if found {
+					matched = append(matched, tokens[i:i+len(terms)]...)
+				}
+			}
+
+			return matched
+		}
+
+	case db.ClausePrefix:
+		prefix := strings.ToLower(clause.Text)
+		return filterTokens(func(term string) bool {
+			return strings.HasPrefix(term, prefix)
+		})
+
+	case db.ClauseWildcard:
+		pattern := wildcardPattern(strings.ToLower(clause.Text))
+		return filterTokens(pattern.MatchString)
+
+	default:
+		terms := analyze(clause.Text)
+		fuzziness := strings.ToUpper(clause.Fuzziness)
+		return filterTokens(func(term string) bool {
+			for _, queryTerm := range terms {
+				if match.Distance(queryTerm.term, term) <= match.MaxEdits(queryTerm.term, fuzziness) {
+					return true
+				}
+			}
+
+			return false
+		})
+	}
+}
+
+func filterTokens(match func(term string) bool) func(tokens []token) []token {
+	return func(tokens []token) []token {
+		matched := []token{}
+		for _, token := range tokens {
+			if match(token.term) {
+				matched = append(matched, token)
+			}
+		}
+
+		return matched
+	}
+}
+
+// wildcardPattern converts a wildcard expression where "*" matches any
+// sequence of characters and "?" matches a single character into an
+// anchored regular expression.
+func wildcardPattern(text string) *regexp.Regexp {
+	builder := strings.Builder{}
+	builder.WriteString("^")
+	for _, r := range text {
+		switch r {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	builder.WriteString("$")
+
+	return regexp.MustCompile(builder.String())
+}
+
+// matches converts the highlighted spans into matches in page and
+// line order.
+func matches(document pars.Document, spans map[lineLocation][]match.Span) []db.Match {
+	output := []db.Match{}
+	for pageIndex, page := range document.Pages {
+		for lineIndex, line := range page.Lines {
+			lineSpans, ok := spans[lineLocation{page: pageIndex, line: lineIndex}]
+			if !ok {
+				continue
+			}
+
+			output = append(output, db.NewMatch(page.PageNumber, line, match.Highlight(line.Text, lineSpans)))
+		}
+	}
+
+	return output
+}
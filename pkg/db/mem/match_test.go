@@ -0,0 +1,37 @@
+package mem
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_analyze(t *testing.T) {
+	received := analyze("Total due: $250, ACME_Corp café")
+	expected := []token{
+		{term: "total", start: 0, end: 5},
+		{term: "due", start: 6, end: 9},
+		{term: "250", start: 12, end: 15},
+		{term: "acme_corp", start: 17, end: 26},
+		{term: "café", start: 27, end: 32},
+	}
+
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("incorrect tokens, received: %+v, expected: %+v", received, expected)
+	}
+}
+
+func Test_wildcardPattern(t *testing.T) {
+	pattern := wildcardPattern("g?z*.s")
+
+	for term, expected := range map[string]bool{
+		"gizmo.s":  true,
+		"gaz.s":    true,
+		"gz.s":     false,
+		"gizmos":   false,
+		"xgizmo.s": false,
+	} {
+		if received := pattern.MatchString(term); received != expected {
+			t.Errorf("incorrect match for %q, received: %t, expected: %t", term, received, expected)
+		}
+	}
+}
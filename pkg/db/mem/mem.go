@@ -0,0 +1,296 @@
+// Package mem implements db.Databaser by holding the parsed documents
+// in memory. It is intended for tests and local development where an
+// OpenSearch domain is not available.
+package mem
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+var _ db.Databaser = &Client{}
+
+// Client implements the db.Databaser methods in memory.
+type Client struct {
+	mutex     sync.RWMutex
+	documents map[string]pars.Document
+}
+
+// New generates an empty mem.Client pointer instance.
+func New() *Client {
+	return &Client{
+		documents: map[string]pars.Document{},
+	}
+}
+
+// SetupDatabase implements the db.Databaser.SetupDatabase method in
+// memory; there is nothing to set up.
+func (c *Client) SetupDatabase(ctx context.Context) error {
+	return nil
+}
+
+// UpsertDocuments implements the db.Databaser.UpsertDocuments method
+// in memory.
+func (c *Client) UpsertDocuments(ctx context.Context, documents []pars.Document) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, document := range documents {
+		c.documents[document.ID] = document
+	}
+
+	return nil
+}
+
+// DeleteDocumentsByKeys implements the db.Databaser.DeleteDocumentsByKeys
+// method in memory.
+func (c *Client) DeleteDocumentsByKeys(ctx context.Context, fileKeys []db.FileKey) error {
+	files := map[db.FileKey]struct{}{}
+	for _, fileKey := range fileKeys {
+		files[fileKey] = struct{}{}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for id, document := range c.documents {
+		if _, ok := files[db.FileKey{Bucket: document.FileBucket, Key: document.FileKey}]; ok {
+			delete(c.documents, id)
+		}
+	}
+
+	return nil
+}
+
+// DeleteDocumentsByBuckets implements the
+// db.Databaser.DeleteDocumentsByBuckets method in memory.
+func (c *Client) DeleteDocumentsByBuckets(ctx context.Context, buckets []string) error {
+	names := map[string]struct{}{}
+	for _, bucket := range buckets {
+		names[bucket] = struct{}{}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for id, document := range c.documents {
+		if _, ok := names[document.FileBucket]; ok {
+			delete(c.documents, id)
+		}
+	}
+
+	return nil
+}
+
+// hit holds a matched document with the values it is sorted by.
+type hit struct {
+	document   pars.Document
+	evaluation *evaluation
+	value      interface{}
+}
+
+// QueryDocuments implements the db.Databaser.QueryDocuments method in
+// memory with the same matching, filtering, sorting, and pagination
+// behavior as db.Client.
+func (c *Client) QueryDocuments(ctx context.Context, query db.Query) (*db.Result, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := &db.Result{
+		Hits: []db.Hit{},
+	}
+
+	if query.IsEmpty() {
+		return result, nil
+	}
+
+	c.mutex.RLock()
+	hits := []hit{}
+	for _, document := range c.documents {
+		if query.Filters != nil && !filter(*query.Filters, document) {
+			continue
+		}
+
+		output := evaluate(query, document)
+		if !output.matched {
+			continue
+		}
+
+		hits = append(hits, hit{
+			document:   document,
+			evaluation: output,
+			value:      sortValue(query, document, output),
+		})
+	}
+	c.mutex.RUnlock()
+
+	descending := query.SortOrder() == db.OrderDesc
+	sort.Slice(hits, func(i, j int) bool {
+		return less(hits[i].value, hits[i].document.ID, hits[j].value, hits[j].document.ID, descending)
+	})
+
+	result.Total = int64(len(hits))
+
+	if query.Cursor != "" {
+		values, err := query.SearchAfter()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(values) == 2 {
+			afterID, _ := values[1].(string)
+			position := sort.Search(len(hits), func(i int) bool {
+				return less(values[0], afterID, hits[i].value, hits[i].document.ID, descending)
+			})
+			hits = hits[position:]
+		}
+	}
+
+	if len(hits) > query.PageSize() {
+		hits = hits[:query.PageSize()]
+
+		last := hits[len(hits)-1]
+		next, err := query.EncodeCursor([]interface{}{last.value, last.document.ID})
+		if err != nil {
+			return nil, err
+		}
+		result.Next = next
+	}
+
+	for _, hit := range hits {
+		result.Hits = append(result.Hits, db.Hit{
+			Document: hit.document,
+			Matches:  matches(hit.document, hit.evaluation.spans),
+		})
+	}
+
+	return result, nil
+}
+
+func filter(filters db.Filters, document pars.Document) bool {
+	if len(filters.Buckets) > 0 && !contains(filters.Buckets, document.FileBucket) {
+		return false
+	}
+
+	if !strings.HasPrefix(document.FileKey, filters.KeyPrefix) {
+		return false
+	}
+
+	if len(filters.FileTypes) > 0 {
+		matched := false
+		for _, fileType := range filters.FileTypes {
+			if strings.Contains(fileType, "/") {
+				matched = matched || strings.ToLower(strings.TrimSpace(fileType)) == document.ContentType
+			} else {
+				matched = matched || strings.ToLower(strings.TrimPrefix(strings.TrimSpace(fileType), ".")) == document.FileExtension
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if filters.Skipped != nil && *filters.Skipped != (document.SkipReason != "") {
+		return false
+	}
+
+	return inRange(filters.LastModified, document.LastModified) && inRange(filters.IndexedAt, document.IndexedAt)
+}
+
+func contains(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inRange(dateRange *db.DateRange, value *time.Time) bool {
+	if dateRange == nil {
+		return true
+	}
+
+	if value == nil {
+		return false
+	}
+
+	if dateRange.From != nil && value.Before(*dateRange.From) {
+		return false
+	}
+
+	if dateRange.To != nil && value.After(*dateRange.To) {
+		return false
+	}
+
+	return true
+}
+
+// sortValue returns the primary sort value of a document. Timestamps
+// are sorted as epoch milliseconds with missing values placed last as
+// OpenSearch does.
+func sortValue(query db.Query, document pars.Document, output *evaluation) interface{} {
+	switch query.SortField() {
+	case db.SortKey:
+		return document.FileKey
+
+	case db.SortIndexedAt:
+		if document.IndexedAt == nil {
+			if query.SortOrder() == db.OrderDesc {
+				return float64(math.MinInt64)
+			}
+
+			return float64(math.MaxInt64)
+		}
+
+		return float64(document.IndexedAt.UnixMilli())
+
+	default:
+		return output.score
+	}
+}
+
+// less reports whether the first sort values order before the second
+// with the primary value in the requested direction and the document
+// ID ascending as a tiebreaker. Cursor values decoded from JSON hold
+// numbers as float64.
+func less(firstValue interface{}, firstID string, secondValue interface{}, secondID string, descending bool) bool {
+	comparison := compare(firstValue, secondValue)
+	if descending {
+		comparison = -comparison
+	}
+
+	if comparison != 0 {
+		return comparison < 0
+	}
+
+	return firstID < secondID
+}
+
+func compare(first, second interface{}) int {
+	switch firstValue := first.(type) {
+	case string:
+		secondValue, _ := second.(string)
+		return strings.Compare(firstValue, secondValue)
+
+	case float64:
+		secondValue, _ := second.(float64)
+		if firstValue < secondValue {
+			return -1
+		} else if firstValue > secondValue {
+			return 1
+		}
+	}
+
+	return 0
+}
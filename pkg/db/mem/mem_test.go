@@ -0,0 +1,14 @@
+package mem
+
+import (
+	"testing"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/db/dbtest"
+)
+
+func TestClient(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) db.Databaser {
+		return New()
+	})
+}
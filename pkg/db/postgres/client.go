@@ -0,0 +1,365 @@
+// Package postgres implements db.Databaser with PostgreSQL using
+// tsvector full-text search over the parsed lines and pg_trgm for
+// fuzzy term matching.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+// migrationLock is the advisory lock key held while migrations run so
+// that concurrent SetupDatabase calls apply them once.
+const migrationLock = 7242019
+
+// migrations holds the statements of each schema migration in the
+// order they are applied; the version of a migration is its position
+// plus one. Released migrations must not be edited, only appended to.
+var migrations = [][]string{
+	{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE TABLE documents (
+			id TEXT PRIMARY KEY,
+			entity TEXT NOT NULL,
+			file_bucket TEXT NOT NULL,
+			file_key TEXT NOT NULL,
+			file_version TEXT NOT NULL,
+			file_extension TEXT NOT NULL,
+			content_type TEXT NOT NULL,
+			last_modified TIMESTAMPTZ,
+			indexed_at TIMESTAMPTZ
+		)`,
+		`CREATE INDEX documents_file ON documents (file_bucket, file_key)`,
+		`CREATE TABLE pages (
+			document_id TEXT NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			id TEXT NOT NULL,
+			entity TEXT NOT NULL,
+			page_number INTEGER NOT NULL,
+			PRIMARY KEY (document_id, position)
+		)`,
+		`CREATE TABLE lines (
+			row_id BIGSERIAL PRIMARY KEY,
+			document_id TEXT NOT NULL,
+			page_position INTEGER NOT NULL,
+			position INTEGER NOT NULL,
+			id TEXT NOT NULL,
+			entity TEXT NOT NULL,
+			text TEXT NOT NULL,
+			coordinates JSONB NOT NULL,
+			text_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', text)) STORED,
+			FOREIGN KEY (document_id, page_position) REFERENCES pages (document_id, position) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX lines_document ON lines (document_id)`,
+		`CREATE INDEX lines_text_vector ON lines USING GIN (text_vector)`,
+		`CREATE TABLE terms (
+			term TEXT PRIMARY KEY
+		)`,
+		`CREATE INDEX terms_trigram ON terms USING GIST (term gist_trgm_ops)`,
+	},
+	{
+		`ALTER TABLE lines ADD COLUMN confidence DOUBLE PRECISION NOT NULL DEFAULT 0`,
+		`ALTER TABLE lines ADD COLUMN words JSONB NOT NULL DEFAULT 'null'`,
+	},
+	{
+		`ALTER TABLE pages ADD COLUMN tables JSONB NOT NULL DEFAULT 'null'`,
+		`ALTER TABLE pages ADD COLUMN fields JSONB NOT NULL DEFAULT 'null'`,
+		`CREATE TABLE field_terms (
+			document_id TEXT NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+			key_terms TEXT NOT NULL,
+			value_terms TEXT NOT NULL,
+			confidence DOUBLE PRECISION NOT NULL
+		)`,
+		`CREATE INDEX field_terms_key_value ON field_terms (key_terms, value_terms)`,
+		`CREATE INDEX field_terms_document ON field_terms (document_id)`,
+	},
+	{
+		`ALTER TABLE documents ADD COLUMN skip_reason TEXT NOT NULL DEFAULT ''`,
+	},
+}
+
+var _ db.Databaser = &Client{}
+
+// Client implements the db.Databaser methods using PostgreSQL.
+type Client struct {
+	database *sql.DB
+}
+
+// New generates a postgres.Client pointer instance connecting to the
+// database at the provided connection URL.
+func New(url string) (*Client, error) {
+	database, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, &NewClientError{
+			err: err,
+		}
+	}
+
+	return &Client{
+		database: database,
+	}, nil
+}
+
+// Close closes the underlying database connections.
+func (c *Client) Close() error {
+	return c.database.Close()
+}
+
+// SetupDatabase implements the db.Databaser.SetupDatabase method
+// using PostgreSQL. Migrations that have not been applied are run in
+// order and recorded in the schema_migrations table; a
+// postgres.SchemaMismatchError is returned if the database has
+// migrations newer than this version knows.
+func (c *Client) SetupDatabase(ctx context.Context) error {
+	err := c.transaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLock); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`); err != nil {
+			return err
+		}
+
+		var version int
+		if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+			return err
+		}
+
+		if version > len(migrations) {
+			return &SchemaMismatchError{
+				err: fmt.Errorf("database has schema version %d, expected version %d", version, len(migrations)),
+			}
+		}
+
+		for i := version; i < len(migrations); i++ {
+			for _, statement := range migrations[i] {
+				if _, err := tx.ExecContext(ctx, statement); err != nil {
+					return fmt.Errorf("migration %d: %w", i+1, err)
+				}
+			}
+
+			if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, i+1); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*SchemaMismatchError); ok {
+			return err
+		}
+
+		return &SetupDatabaseError{
+			err: err,
+		}
+	}
+
+	return nil
+}
+
+// UpsertDocuments implements the db.Databaser.UpsertDocuments method
+// using PostgreSQL.
+func (c *Client) UpsertDocuments(ctx context.Context, documents []pars.Document) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	// the last copy of a repeated document wins as it does in a bulk
+	// request
+	positions := map[string]int{}
+	ids := []string{}
+	for i, document := range documents {
+		if _, ok := positions[document.ID]; !ok {
+			ids = append(ids, document.ID)
+		}
+		positions[document.ID] = i
+	}
+
+	err := c.transaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM documents WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if err := insertDocument(ctx, tx, documents[positions[id]]); err != nil {
+				return err
+			}
+		}
+
+		// terms keeps the vocabulary used to expand fuzzy and
+		// wildcard terms; terms of removed lines are left in place
+		// since they only cost an unmatched expansion
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO terms (term) SELECT DISTINCT unnest(tsvector_to_array(text_vector)) FROM lines WHERE document_id = ANY($1) ON CONFLICT DO NOTHING`,
+			pq.Array(ids),
+		)
+		return err
+	})
+	if err != nil {
+		return &UpsertDocumentsError{
+			err: err,
+		}
+	}
+
+	return nil
+}
+
+func insertDocument(ctx context.Context, tx *sql.Tx, document pars.Document) error {
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO documents (id, entity, file_bucket, file_key, file_version, file_extension, content_type, last_modified, indexed_at, skip_reason) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		document.ID,
+		document.Entity,
+		document.FileBucket,
+		document.FileKey,
+		document.FileVersion,
+		document.FileExtension,
+		document.ContentType,
+		document.LastModified,
+		document.IndexedAt,
+		document.SkipReason,
+	); err != nil {
+		return err
+	}
+
+	for pagePosition, page := range document.Pages {
+		tables, err := json.Marshal(page.Tables)
+		if err != nil {
+			return err
+		}
+
+		fields, err := json.Marshal(page.Fields)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO pages (document_id, position, id, entity, page_number, tables, fields) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			document.ID,
+			pagePosition,
+			page.ID,
+			page.Entity,
+			page.PageNumber,
+			string(tables),
+			string(fields),
+		); err != nil {
+			return err
+		}
+
+		for _, field := range page.Fields {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO field_terms (document_id, key_terms, value_terms, confidence) VALUES ($1, $2, $3, $4)`,
+				document.ID,
+				db.NormalizeField(field.Key),
+				db.NormalizeField(field.Value),
+				field.Confidence,
+			); err != nil {
+				return err
+			}
+		}
+
+		for linePosition, line := range page.Lines {
+			coordinates, err := json.Marshal(line.Coordinates)
+			if err != nil {
+				return err
+			}
+
+			words, err := json.Marshal(line.Words)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO lines (document_id, page_position, position, id, entity, text, confidence, coordinates, words) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+				document.ID,
+				pagePosition,
+				linePosition,
+				line.ID,
+				line.Entity,
+				line.Text,
+				line.Confidence,
+				string(coordinates),
+				string(words),
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// DeleteDocumentsByKeys implements the db.Databaser.DeleteDocumentsByKeys
+// method using PostgreSQL, matching the documents by the IDs derived
+// from their keys.
+func (c *Client) DeleteDocumentsByKeys(ctx context.Context, fileKeys []db.FileKey) error {
+	if len(fileKeys) == 0 {
+		return nil
+	}
+
+	ids := []string{}
+	for _, fileKey := range fileKeys {
+		ids = append(ids, pars.DocumentID(fileKey.Bucket, fileKey.Key))
+	}
+
+	return c.deleteDocuments(ctx, `id = ANY($1)`, pq.Array(ids))
+}
+
+// DeleteDocumentsByBuckets implements the
+// db.Databaser.DeleteDocumentsByBuckets method using PostgreSQL.
+func (c *Client) DeleteDocumentsByBuckets(ctx context.Context, buckets []string) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	return c.deleteDocuments(ctx, `file_bucket = ANY($1)`, pq.Array(buckets))
+}
+
+// deleteDocuments removes the documents matching the condition on the
+// documents table; their pages, lines, and form fields are removed by
+// cascade.
+func (c *Client) deleteDocuments(ctx context.Context, condition string, args ...interface{}) error {
+	if _, err := c.database.ExecContext(ctx, `DELETE FROM documents WHERE `+condition, args...); err != nil {
+		return &DeleteDocumentsError{
+			err: err,
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) transaction(ctx context.Context, run func(tx *sql.Tx) error) error {
+	tx, err := c.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := run(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// arguments collects the values of a statement and numbers their
+// placeholders.
+type arguments struct {
+	values []interface{}
+}
+
+// add appends the value and returns its placeholder.
+func (a *arguments) add(value interface{}) string {
+	a.values = append(a.values, value)
+	return fmt.Sprintf("$%d", len(a.values))
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/db/dbtest"
+)
+
+// newTestClient returns a client for the database at the
+// FINDFILE_POSTGRES_URL connection URL with its documents removed,
+// skipping the test if the variable is not set.
+func newTestClient(t *testing.T) *Client {
+	url := os.Getenv("FINDFILE_POSTGRES_URL")
+	if url == "" {
+		t.Skip("FINDFILE_POSTGRES_URL not set")
+	}
+
+	client, err := New(url)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	if err := client.SetupDatabase(context.Background()); err != nil {
+		t.Fatalf("error setting up database: %v", err)
+	}
+
+	if _, err := client.database.Exec(`TRUNCATE documents, terms CASCADE`); err != nil {
+		t.Fatalf("error truncating tables: %v", err)
+	}
+
+	return client
+}
+
+func TestClient(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) db.Databaser {
+		return newTestClient(t)
+	})
+}
+
+func TestSetupDatabase(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	if err := client.SetupDatabase(ctx); err != nil {
+		t.Fatalf("incorrect error on existing schema, received: %v, expected: nil", err)
+	}
+
+	if _, err := client.database.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, len(migrations)+1); err != nil {
+		t.Fatalf("error inserting schema version: %v", err)
+	}
+
+	t.Cleanup(func() {
+		client.database.Exec(`DELETE FROM schema_migrations WHERE version > $1`, len(migrations))
+	})
+
+	err := client.SetupDatabase(ctx)
+	var mismatchErr *SchemaMismatchError
+	if !errors.As(err, &mismatchErr) {
+		t.Errorf("incorrect error, received: %v, expected: %T", err, mismatchErr)
+	}
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewClientError(t *testing.T) {
+	err := &NewClientError{
+		err: errors.New("mock new client error"),
+	}
+
+	recieved := err.Error()
+	expected := "package postgres: mock new client error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestSetupDatabaseError(t *testing.T) {
+	err := &SetupDatabaseError{
+		err: errors.New("mock setup database error"),
+	}
+
+	recieved := err.Error()
+	expected := "package postgres: mock setup database error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestSchemaMismatchError(t *testing.T) {
+	err := &SchemaMismatchError{
+		err: errors.New("mock schema mismatch error"),
+	}
+
+	recieved := err.Error()
+	expected := "package postgres: mock schema mismatch error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestUpsertDocumentsError(t *testing.T) {
+	err := &UpsertDocumentsError{
+		err: errors.New("mock upsert documents error"),
+	}
+
+	recieved := err.Error()
+	expected := "package postgres: mock upsert documents error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestDeleteDocumentsError(t *testing.T) {
+	err := &DeleteDocumentsError{
+		err: errors.New("mock delete documents error"),
+	}
+
+	recieved := err.Error()
+	expected := "package postgres: mock delete documents error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestQueryDocumentsError(t *testing.T) {
+	err := &QueryDocumentsError{
+		err: errors.New("mock query documents error"),
+	}
+
+	recieved := err.Error()
+	expected := "package postgres: mock query documents error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
@@ -0,0 +1,707 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/db/internal/match"
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+const (
+	// private use characters mark highlighted terms so the line text
+	// can be HTML-escaped before the marks are replaced with tags
+	highlightStart = "\ue000"
+	highlightEnd   = "\ue001"
+
+	// fuzzyCandidates is the number of indexed terms nearest to a
+	// fuzzy term by trigram distance that are checked for edits.
+	fuzzyCandidates = 1000
+)
+
+// leaf is a term clause compiled into a tsquery and stored as a common
+// table expression of the matching documents and their scores.
+type leaf struct {
+	name    string
+	query   string
+	negated bool
+}
+
+// statement holds a query compiled into SQL conditions on the
+// documents table, aliased as "d", along with the term clauses they
+// reference, the minimum confidence of the lines and form fields they
+// match, and the arguments of both.
+type statement struct {
+	leaves        []leaf
+	condition     string
+	args          *arguments
+	minConfidence float64
+}
+
+// with returns the common table expressions for the term clauses
+// matching lines with at least the minimum confidence argument.
+func (s statement) with(leafArgs []string, confidenceArg string) string {
+	if len(s.leaves) == 0 {
+		return ""
+	}
+
+	tables := []string{}
+	for i, leaf := range s.leaves {
+		tables = append(tables, leaf.name+` (document_id, score) AS (
+			SELECT l.document_id, SUM(ts_rank(l.text_vector, `+leafArgs[i]+`::tsquery))::DOUBLE PRECISION
+			FROM lines l
+			WHERE l.text_vector @@ `+leafArgs[i]+`::tsquery AND l.confidence >= `+confidenceArg+`
+			GROUP BY l.document_id
+		)`)
+	}
+
+	return `WITH ` + strings.Join(tables, `, `) + ` `
+}
+
+// score returns the relevance expression for a document summing the
+// scores of the term clauses that are not negated.
+func (s statement) score() string {
+	scores := []string{}
+	for _, leaf := range s.leaves {
+		if !leaf.negated {
+			scores = append(scores, `COALESCE((SELECT score FROM `+leaf.name+` WHERE document_id = d.id), 0.0)`)
+		}
+	}
+
+	if len(scores) == 0 {
+		return `1.0::DOUBLE PRECISION`
+	}
+
+	return `(` + strings.Join(scores, ` + `) + `)`
+}
+
+// compile converts the query into SQL conditions. The arguments of
+// the term clauses are added first so that the common table
+// expressions can be placed ahead of the conditions. Validate must be
+// called before compile.
+func (c *Client) compile(ctx context.Context, query db.Query) (*statement, string, error) {
+	s := &statement{
+		args:          &arguments{},
+		minConfidence: query.MinConfidence,
+	}
+	conditions := []string{}
+
+	if root := query.Root(); root != nil {
+		condition, err := c.compileClause(ctx, s, *root, false)
+		if err != nil {
+			return nil, "", err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	leafArgs := make([]string, len(s.leaves))
+	for i, leaf := range s.leaves {
+		leafArgs[i] = s.args.add(leaf.query)
+	}
+
+	confidenceArg := ""
+	if len(s.leaves) > 0 {
+		confidenceArg = s.args.add(s.minConfidence)
+	}
+
+	if query.Filters != nil {
+		conditions = append(conditions, filtersSQL(*query.Filters, s.args))
+	}
+
+	if len(conditions) == 0 {
+		conditions = append(conditions, `TRUE`)
+	}
+	s.condition = strings.Join(conditions, ` AND `)
+
+	return s, s.with(leafArgs, confidenceArg), nil
+}
+
+func (c *Client) compileClause(ctx context.Context, s *statement, clause db.Clause, negated bool) (string, error) {
+	switch clause.Type {
+	case db.ClauseAnd, db.ClauseOr, db.ClauseNot:
+		children := []string{}
+		for _, child := range clause.Clauses {
+			childNegated := negated
+			if clause.Type == db.ClauseNot {
+				childNegated = !negated
+			}
+
+			condition, err := c.compileClause(ctx, s, child, childNegated)
+			if err != nil {
+				return "", err
+			}
+			children = append(children, condition)
+		}
+
+		switch clause.Type {
+		case db.ClauseAnd:
+			return `(` + strings.Join(children, ` AND `) + `)`, nil
+		case db.ClauseOr:
+			return `(` + strings.Join(children, ` OR `) + `)`, nil
+		default:
+			return `NOT (` + strings.Join(children, ` OR `) + `)`, nil
+		}
+
+	case db.ClauseField:
+		return `d.id IN (SELECT document_id FROM field_terms WHERE key_terms = ` + s.args.add(db.NormalizeField(clause.Field)) +
+			` AND value_terms = ` + s.args.add(db.NormalizeField(clause.Text)) +
+			` AND confidence >= ` + s.args.add(s.minConfidence) + `)`, nil
+
+	default:
+		query, err := c.tsquery(ctx, clause)
+		if err != nil {
+			return "", err
+		}
+
+		if query == "" {
+			return `FALSE`, nil
+		}
+
+		name := fmt.Sprintf("leaf_%d", len(s.leaves))
+		s.leaves = append(s.leaves, leaf{
+			name:    name,
+			query:   query,
+			negated: negated,
+		})
+
+		return `d.id IN (SELECT document_id FROM ` + name + `)`, nil
+	}
+}
+
+// tsquery converts a term clause into tsquery text. The clause text is
+// parsed by PostgreSQL the way the lines are indexed; fuzzy terms are
+// expanded against the nearest indexed terms by trigram distance and
+// wildcard terms against the indexed terms matching the pattern. An
+// empty tsquery matches no lines.
+func (c *Client) tsquery(ctx context.Context, clause db.Clause) (string, error) {
+	switch clause.Type {
+	case db.ClausePhrase:
+		var query string
+		if err := c.database.QueryRowContext(ctx, `SELECT phraseto_tsquery('simple', $1)::TEXT`, clause.Text).Scan(&query); err != nil {
+			return "", err
+		}
+
+		return query, nil
+
+	case db.ClausePrefix:
+		if clause.Text == "" {
+			return "", nil
+		}
+
+		return quote(strings.ToLower(clause.Text)) + ":*", nil
+
+	case db.ClauseWildcard:
+		candidates, err := c.terms(ctx,
+			`SELECT term FROM terms WHERE term LIKE $1 ORDER BY term LIMIT $2`,
+			likePattern(strings.ToLower(clause.Text)),
+			match.MaxPatternExpansions,
+		)
+		if err != nil {
+			return "", err
+		}
+
+		return disjunction(candidates), nil
+
+	default:
+		lexemes, err := c.terms(ctx, `SELECT unnest(tsvector_to_array(to_tsvector('simple', $1)))`, clause.Text)
+		if err != nil {
+			return "", err
+		}
+
+		fuzziness := strings.ToUpper(clause.Fuzziness)
+		expanded := []string{}
+		for _, lexeme := range lexemes {
+			edits := match.MaxEdits(lexeme, fuzziness)
+			if edits == 0 {
+				expanded = append(expanded, lexeme)
+				continue
+			}
+
+			length := utf8.RuneCountInString(lexeme)
+			candidates, err := c.terms(ctx,
+				`SELECT term FROM terms WHERE char_length(term) BETWEEN $2 AND $3 ORDER BY term <-> $1, term LIMIT $4`,
+				lexeme,
+				length-edits,
+				length+edits,
+				fuzzyCandidates,
+			)
+			if err != nil {
+				return "", err
+			}
+
+			sort.Strings(candidates)
+			expanded = append(expanded, match.Expand(lexeme, edits, candidates)...)
+		}
+
+		return disjunction(expanded), nil
+	}
+}
+
+// terms returns the first column of the rows returned by the query.
+func (c *Client) terms(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := c.database.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	output := []string{}
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, err
+		}
+		output = append(output, term)
+	}
+
+	return output, rows.Err()
+}
+
+func filtersSQL(filters db.Filters, args *arguments) string {
+	conditions := []string{}
+
+	if len(filters.Buckets) > 0 {
+		conditions = append(conditions, `d.file_bucket = ANY(`+args.add(pq.Array(filters.Buckets))+`)`)
+	}
+
+	if filters.KeyPrefix != "" {
+		conditions = append(conditions, `starts_with(d.file_key, `+args.add(filters.KeyPrefix)+`)`)
+	}
+
+	if len(filters.FileTypes) > 0 {
+		extensions := []string{}
+		contentTypes := []string{}
+		for _, fileType := range filters.FileTypes {
+			if strings.Contains(fileType, "/") {
+				contentTypes = append(contentTypes, strings.ToLower(strings.TrimSpace(fileType)))
+			} else {
+				extensions = append(extensions, strings.ToLower(strings.TrimPrefix(strings.TrimSpace(fileType), ".")))
+			}
+		}
+
+		fileTypes := []string{}
+		if len(extensions) > 0 {
+			fileTypes = append(fileTypes, `d.file_extension = ANY(`+args.add(pq.Array(extensions))+`)`)
+		}
+
+		if len(contentTypes) > 0 {
+			fileTypes = append(fileTypes, `d.content_type = ANY(`+args.add(pq.Array(contentTypes))+`)`)
+		}
+
+		conditions = append(conditions, `(`+strings.Join(fileTypes, ` OR `)+`)`)
+	}
+
+	for _, dateFilter := range []struct {
+		column    string
+		dateRange *db.DateRange
+	}{
+		{
+			column:    `d.last_modified`,
+			dateRange: filters.LastModified,
+		},
+		{
+			column:    `d.indexed_at`,
+			dateRange: filters.IndexedAt,
+		},
+	} {
+		if dateFilter.dateRange == nil {
+			continue
+		}
+
+		if dateFilter.dateRange.From != nil {
+			conditions = append(conditions, dateFilter.column+` >= `+args.add(*dateFilter.dateRange.From))
+		}
+
+		if dateFilter.dateRange.To != nil {
+			conditions = append(conditions, dateFilter.column+` <= `+args.add(*dateFilter.dateRange.To))
+		}
+	}
+
+	if filters.Skipped != nil {
+		if *filters.Skipped {
+			conditions = append(conditions, `d.skip_reason <> ''`)
+		} else {
+			conditions = append(conditions, `d.skip_reason = ''`)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return `TRUE`
+	}
+
+	return strings.Join(conditions, ` AND `)
+}
+
+// sortSQL returns the expression documents are sorted by. Keys are
+// compared by byte as OpenSearch keywords are and timestamps are
+// sorted as epoch milliseconds with missing values placed last as
+// OpenSearch does.
+func sortSQL(query db.Query, s *statement) string {
+	switch query.SortField() {
+	case db.SortKey:
+		return `d.file_key COLLATE "C"`
+
+	case db.SortIndexedAt:
+		missing := float64(math.MinInt64)
+		if query.SortOrder() == db.OrderAsc {
+			missing = float64(math.MaxInt64)
+		}
+
+		return `COALESCE(round(EXTRACT(EPOCH FROM date_trunc('milliseconds', d.indexed_at)) * 1000)::DOUBLE PRECISION, ` + s.args.add(missing) + `::DOUBLE PRECISION)`
+
+	default:
+		return s.score()
+	}
+}
+
+// QueryDocuments implements the db.Databaser.QueryDocuments method
+// using PostgreSQL with the same matching, filtering, sorting, and
+// pagination behavior as db.Client.
+func (c *Client) QueryDocuments(ctx context.Context, query db.Query) (*db.Result, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := &db.Result{
+		Hits: []db.Hit{},
+	}
+
+	if query.IsEmpty() {
+		return result, nil
+	}
+
+	s, with, err := c.compile(ctx, query)
+	if err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	if err := c.database.QueryRowContext(ctx,
+		with+`SELECT COUNT(*) FROM documents d WHERE `+s.condition,
+		s.args.values...,
+	).Scan(&result.Total); err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	value := sortSQL(query, s)
+	order := `ASC`
+	comparison := `>`
+	if query.SortOrder() == db.OrderDesc {
+		order = `DESC`
+		comparison = `<`
+	}
+
+	after := `TRUE`
+	if query.Cursor != "" {
+		values, err := query.SearchAfter()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(values) == 2 {
+			afterValue := s.args.add(values[0])
+			afterID := s.args.add(values[1])
+			after = `(value ` + comparison + ` ` + afterValue + ` OR (value = ` + afterValue + ` AND id > ` + afterID + `))`
+		}
+	}
+	limit := s.args.add(query.PageSize() + 1)
+
+	rows, err := c.database.QueryContext(ctx,
+		with+`SELECT id, value FROM (SELECT d.id COLLATE "C" AS id, `+value+` AS value FROM documents d WHERE `+s.condition+`) sorted WHERE `+after+` ORDER BY value `+order+`, id ASC LIMIT `+limit,
+		s.args.values...,
+	)
+	if err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	ids := []string{}
+	values := []interface{}{}
+	for rows.Next() {
+		var id string
+		var value interface{}
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return nil, &QueryDocumentsError{
+				err: err,
+			}
+		}
+
+		if text, ok := value.([]byte); ok {
+			value = string(text)
+		}
+
+		ids = append(ids, id)
+		values = append(values, value)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	if len(ids) > query.PageSize() {
+		ids = ids[:query.PageSize()]
+
+		next, err := query.EncodeCursor([]interface{}{values[len(ids)-1], ids[len(ids)-1]})
+		if err != nil {
+			return nil, &QueryDocumentsError{
+				err: err,
+			}
+		}
+		result.Next = next
+	}
+
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	documents, err := c.loadDocuments(ctx, ids)
+	if err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	highlights, err := c.highlights(ctx, s, ids)
+	if err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	for _, id := range ids {
+		loaded := documents[id]
+		result.Hits = append(result.Hits, db.Hit{
+			Document: loaded.document,
+			Matches:  loaded.matches(highlights),
+		})
+	}
+
+	return result, nil
+}
+
+// loaded holds a document read back from the database along with the
+// row ID of each of its lines.
+type loaded struct {
+	document pars.Document
+	rows     [][]int64
+}
+
+// matches returns the highlighted lines of the document in page and
+// line order.
+func (l loaded) matches(highlights map[int64]string) []db.Match {
+	output := []db.Match{}
+	for pageIndex, page := range l.document.Pages {
+		for lineIndex, line := range page.Lines {
+			highlight, ok := highlights[l.rows[pageIndex][lineIndex]]
+			if !ok {
+				continue
+			}
+
+			output = append(output, db.NewMatch(page.PageNumber, line, highlight))
+		}
+	}
+
+	return output
+}
+
+func (c *Client) loadDocuments(ctx context.Context, ids []string) (map[string]*loaded, error) {
+	output := map[string]*loaded{}
+
+	documentRows, err := c.database.QueryContext(ctx,
+		`SELECT id, entity, file_bucket, file_key, file_version, file_extension, content_type, last_modified, indexed_at, skip_reason FROM documents WHERE id = ANY($1)`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer documentRows.Close()
+
+	for documentRows.Next() {
+		document := pars.Document{}
+		var lastModified, indexedAt sql.NullTime
+		if err := documentRows.Scan(
+			&document.ID,
+			&document.Entity,
+			&document.FileBucket,
+			&document.FileKey,
+			&document.FileVersion,
+			&document.FileExtension,
+			&document.ContentType,
+			&lastModified,
+			&indexedAt,
+			&document.SkipReason,
+		); err != nil {
+			return nil, err
+		}
+
+		if lastModified.Valid {
+			value := lastModified.Time.UTC()
+			document.LastModified = &value
+		}
+
+		if indexedAt.Valid {
+			value := indexedAt.Time.UTC()
+			document.IndexedAt = &value
+		}
+
+		output[document.ID] = &loaded{
+			document: document,
+		}
+	}
+
+	if err := documentRows.Err(); err != nil {
+		return nil, err
+	}
+	documentRows.Close()
+
+	pageRows, err := c.database.QueryContext(ctx,
+		`SELECT document_id, id, entity, page_number, tables, fields FROM pages WHERE document_id = ANY($1) ORDER BY document_id, position`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer pageRows.Close()
+
+	for pageRows.Next() {
+		page := pars.Page{}
+		var documentID, tables, fields string
+		if err := pageRows.Scan(&documentID, &page.ID, &page.Entity, &page.PageNumber, &tables, &fields); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(tables), &page.Tables); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(fields), &page.Fields); err != nil {
+			return nil, err
+		}
+
+		document := output[documentID]
+		document.document.Pages = append(document.document.Pages, page)
+		document.rows = append(document.rows, []int64{})
+	}
+
+	if err := pageRows.Err(); err != nil {
+		return nil, err
+	}
+	pageRows.Close()
+
+	lineRows, err := c.database.QueryContext(ctx,
+		`SELECT row_id, document_id, page_position, id, entity, text, confidence, coordinates, words FROM lines WHERE document_id = ANY($1) ORDER BY document_id, page_position, position`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer lineRows.Close()
+
+	for lineRows.Next() {
+		line := pars.Line{}
+		var row int64
+		var position int
+		var documentID, coordinates, words string
+		if err := lineRows.Scan(&row, &documentID, &position, &line.ID, &line.Entity, &line.Text, &line.Confidence, &coordinates, &words); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(coordinates), &line.Coordinates); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(words), &line.Words); err != nil {
+			return nil, err
+		}
+
+		document := output[documentID]
+		document.document.Pages[position].Lines = append(document.document.Pages[position].Lines, line)
+		document.rows[position] = append(document.rows[position], row)
+	}
+
+	return output, lineRows.Err()
+}
+
+// highlights returns the highlighted text of each matching line in
+// the documents keyed by line row ID. When several term clauses match
+// a line the highlight with the most highlighted terms is kept.
+func (c *Client) highlights(ctx context.Context, s *statement, ids []string) (map[int64]string, error) {
+	options := fmt.Sprintf(`StartSel="%s", StopSel="%s", HighlightAll=true`, highlightStart, highlightEnd)
+
+	output := map[int64]string{}
+	for _, leaf := range s.leaves {
+		if leaf.negated {
+			continue
+		}
+
+		rows, err := c.database.QueryContext(ctx,
+			`SELECT l.row_id, ts_headline('simple', l.text, $1::tsquery, $2) FROM lines l WHERE l.text_vector @@ $1::tsquery AND l.confidence >= $3 AND l.document_id = ANY($4)`,
+			leaf.query,
+			options,
+			s.minConfidence,
+			pq.Array(ids),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var row int64
+			var text string
+			if err := rows.Scan(&row, &text); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			highlight := strings.NewReplacer(highlightStart, "<em>", highlightEnd, "</em>").Replace(html.EscapeString(text))
+			if existing, ok := output[row]; !ok || strings.Count(highlight, "<em>") > strings.Count(existing, "<em>") {
+				output[row] = highlight
+			}
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
+
+// quote returns the term as a tsquery lexeme.
+func quote(term string) string {
+	return `'` + strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(term) + `'`
+}
+
+// disjunction returns a tsquery matching any of the terms.
+func disjunction(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = quote(term)
+	}
+
+	return strings.Join(quoted, ` | `)
+}
+
+// likePattern converts a wildcard pattern using "*" and "?" into a
+// LIKE pattern with the LIKE wildcards in the text escaped.
+func likePattern(pattern string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `%`, `?`, `_`).Replace(pattern)
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/forstmeier/findfile/pkg/db"
+)
+
+func Test_quote(t *testing.T) {
+	received := quote(`it's a\b`)
+	expected := `'it''s a\\b'`
+
+	if received != expected {
+		t.Errorf("incorrect lexeme, received: %s, expected: %s", received, expected)
+	}
+}
+
+func Test_disjunction(t *testing.T) {
+	received := disjunction([]string{"acme", "acne"})
+	expected := `'acme' | 'acne'`
+
+	if received != expected {
+		t.Errorf("incorrect tsquery, received: %s, expected: %s", received, expected)
+	}
+}
+
+func Test_likePattern(t *testing.T) {
+	received := likePattern(`g?zm*_100%\`)
+	expected := `g_zm%\_100\%\\`
+
+	if received != expected {
+		t.Errorf("incorrect pattern, received: %s, expected: %s", received, expected)
+	}
+}
+
+func Test_filtersSQL(t *testing.T) {
+	from := time.Date(2021, time.November, 1, 0, 0, 0, 0, time.UTC)
+
+	args := &arguments{}
+	args.add("leaf")
+
+	condition := filtersSQL(db.Filters{
+		Buckets:   []string{"invoices", "receipts"},
+		KeyPrefix: "2021/",
+		FileTypes: []string{".PDF", "image/png"},
+		IndexedAt: &db.DateRange{
+			From: &from,
+		},
+	}, args)
+
+	expectedCondition := `d.file_bucket = ANY($2) AND starts_with(d.file_key, $3) AND (d.file_extension = ANY($4) OR d.content_type = ANY($5)) AND d.indexed_at >= $6`
+	if condition != expectedCondition {
+		t.Errorf("incorrect condition, received: %s, expected: %s", condition, expectedCondition)
+	}
+
+	expectedArgs := []interface{}{
+		"leaf",
+		pq.Array([]string{"invoices", "receipts"}),
+		"2021/",
+		pq.Array([]string{"pdf"}),
+		pq.Array([]string{"image/png"}),
+		from,
+	}
+	if !reflect.DeepEqual(args.values, expectedArgs) {
+		t.Errorf("incorrect args, received: %v, expected: %v", args.values, expectedArgs)
+	}
+}
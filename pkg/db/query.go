@@ -0,0 +1,594 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	linesPath         = "pages.lines"
+	linesTextField    = "pages.lines.text"
+	linesConfField    = "pages.lines.confidence"
+	fieldsPath        = "pages.fields"
+	fieldsKeyField    = "pages.fields.key"
+	fieldsValueField  = "pages.fields.value"
+	fieldsConfField   = "pages.fields.confidence"
+	fileBucketField   = "file_bucket"
+	fileKeyField      = "file_key"
+	fileExtField      = "file_extension"
+	contentTypeField  = "content_type"
+	lastModifiedField = "last_modified"
+	indexedAtField    = "indexed_at"
+	skipReasonField   = "skip_reason"
+	idField           = "id"
+
+	innerHitsName = "match"
+
+	maxClauseDepth = 8
+
+	defaultSize = 10
+	maxSize     = 100
+
+	maxConfidence = 100
+)
+
+// Sort options supported for ordering query results.
+const (
+	SortRelevance = "relevance"
+	SortKey       = "key"
+	SortIndexedAt = "indexed_at"
+)
+
+// Sort orders supported for ordering query results.
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// Clause types supported in the structured query tree.
+const (
+	ClauseAnd      = "and"
+	ClauseOr       = "or"
+	ClauseNot      = "not"
+	ClauseMatch    = "match"
+	ClausePhrase   = "phrase"
+	ClausePrefix   = "prefix"
+	ClauseWildcard = "wildcard"
+	ClauseField    = "field"
+)
+
+// fieldText matches query text of the form
+// field:"Invoice Number" = 1234 with the key quoted if it holds spaces
+// and the value optionally quoted.
+var fieldText = regexp.MustCompile(`^field:\s*(?:"([^"]*)"|(\S+?))\s*=\s*(?:"([^"]*)"|(.*?))\s*$`)
+
+// Query holds the fields required for building an OpenSearch query
+// from the values provided by the user. Text runs a single fuzzy match,
+// or a form field match if it is written as field:"key" = value, and
+// Clause runs a structured query tree; only one may be provided.
+// Filters restrict the matched documents without affecting scoring.
+// MinConfidence ignores lines and form fields recognized with a lower
+// 0-100 confidence when matching the text or clauses. Size, Sort, and
+// Order control the returned page of results and Cursor holds the
+// Result.Next value from the previous page.
+type Query struct {
+	Text          string   `json:"text,omitempty"`
+	Clause        *Clause  `json:"query,omitempty"`
+	Filters       *Filters `json:"filters,omitempty"`
+	MinConfidence float64  `json:"min_confidence,omitempty"`
+	Size          int      `json:"size,omitempty"`
+	Sort          string   `json:"sort,omitempty"`
+	Order         string   `json:"order,omitempty"`
+	Cursor        string   `json:"cursor,omitempty"`
+}
+
+// Clause is a single node in a structured query tree. The "and", "or",
+// and "not" types combine the child Clauses values while the "match",
+// "phrase", "prefix", and "wildcard" types search the parsed line text
+// for the Text value. The "field" type matches documents with a form
+// field whose key is the Field value and whose value is the Text value,
+// compared by their terms ignoring case and punctuation. Field clauses
+// do not highlight any lines.
+type Clause struct {
+	Type      string   `json:"type"`
+	Clauses   []Clause `json:"clauses,omitempty"`
+	Field     string   `json:"field,omitempty"`
+	Text      string   `json:"text,omitempty"`
+	Fuzziness string   `json:"fuzziness,omitempty"`
+}
+
+// Filters holds the non-scoring restrictions applied to a query. File
+// types may be given as extensions (e.g. "pdf") or content types (e.g.
+// "application/pdf"); a document matching any listed type is kept.
+// Skipped keeps only the records of files that were not parsed if true
+// and only parsed documents if false.
+type Filters struct {
+	Buckets      []string   `json:"buckets,omitempty"`
+	KeyPrefix    string     `json:"key_prefix,omitempty"`
+	FileTypes    []string   `json:"file_types,omitempty"`
+	LastModified *DateRange `json:"last_modified,omitempty"`
+	IndexedAt    *DateRange `json:"indexed_at,omitempty"`
+	Skipped      *bool      `json:"skipped,omitempty"`
+}
+
+// DateRange holds inclusive bounds on a document timestamp; either
+// bound may be omitted.
+type DateRange struct {
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+}
+
+// IsEmpty reports whether the query contains no text, clauses, or
+// filters and so cannot match any documents.
+func (q Query) IsEmpty() bool {
+	return q.Text == "" && q.Clause == nil && q.Filters == nil
+}
+
+// Root returns the clause run for the query text or clause tree, or
+// nil if the query holds only filters. Text written as
+// field:"key" = value becomes a "field" clause and other text a fuzzy
+// "match" clause.
+func (q Query) Root() *Clause {
+	if q.Clause != nil {
+		return q.Clause
+	}
+
+	if q.Text == "" {
+		return nil
+	}
+
+	if parts := fieldText.FindStringSubmatch(strings.TrimSpace(q.Text)); parts != nil {
+		return &Clause{
+			Type:  ClauseField,
+			Field: parts[1] + parts[2],
+			Text:  parts[3] + parts[4],
+		}
+	}
+
+	return &Clause{
+		Type:      ClauseMatch,
+		Text:      q.Text,
+		Fuzziness: "AUTO",
+	}
+}
+
+// NormalizeField returns the lowercased terms of a form field key or
+// value joined by single spaces, the form in which "field" clauses
+// compare them.
+func NormalizeField(text string) string {
+	return strings.Join(wordTerms(text), " ")
+}
+
+// Validate checks that the query is well formed before it is
+// converted into an OpenSearch request.
+func (q Query) Validate() error {
+	if q.Text != "" && q.Clause != nil {
+		return &InvalidQueryError{
+			err: errors.New("only one of text or query may be provided"),
+		}
+	}
+
+	if root := q.Root(); root != nil {
+		if err := root.validate(1); err != nil {
+			return &InvalidQueryError{
+				err: err,
+			}
+		}
+	}
+
+	if q.Filters != nil {
+		if err := q.Filters.validate(); err != nil {
+			return &InvalidQueryError{
+				err: err,
+			}
+		}
+	}
+
+	if q.MinConfidence < 0 || q.MinConfidence > maxConfidence {
+		return &InvalidQueryError{
+			err: fmt.Errorf("min confidence must be between 0 and %d", maxConfidence),
+		}
+	}
+
+	if q.Size < 0 || q.Size > maxSize {
+		return &InvalidQueryError{
+			err: fmt.Errorf("size must be between 0 and %d (0 uses the default)", maxSize),
+		}
+	}
+
+	switch q.Sort {
+	case "", SortRelevance, SortKey, SortIndexedAt:
+	default:
+		return &InvalidQueryError{
+			err: fmt.Errorf("sort %q must be one of relevance, key, or indexed_at", q.Sort),
+		}
+	}
+
+	switch q.Order {
+	case "", OrderAsc, OrderDesc:
+	default:
+		return &InvalidQueryError{
+			err: fmt.Errorf("order %q must be one of asc or desc", q.Order),
+		}
+	}
+
+	if q.Cursor != "" {
+		if _, err := q.SearchAfter(); err != nil {
+			return &InvalidQueryError{
+				err: err,
+			}
+		}
+	}
+
+	return nil
+}
+
+// PageSize returns the number of results requested per page.
+func (q Query) PageSize() int {
+	if q.Size == 0 {
+		return defaultSize
+	}
+
+	return q.Size
+}
+
+// SortField returns the requested sort option, defaulting to relevance.
+func (q Query) SortField() string {
+	if q.Sort == "" {
+		return SortRelevance
+	}
+
+	return q.Sort
+}
+
+// SortOrder returns the requested sort order. Relevance and indexed
+// time default to descending and file key defaults to ascending.
+func (q Query) SortOrder() string {
+	if q.Order != "" {
+		return q.Order
+	}
+
+	if q.SortField() == SortKey {
+		return OrderAsc
+	}
+
+	return OrderDesc
+}
+
+// cursor is the decoded form of Query.Cursor and Result.Next.
+type cursor struct {
+	Sort   string        `json:"sort"`
+	Order  string        `json:"order"`
+	Values []interface{} `json:"values"`
+}
+
+// EncodeCursor returns an opaque page token holding the sort values
+// of the last result on the current page.
+func (q Query) EncodeCursor(values []interface{}) (string, error) {
+	data, err := json.Marshal(cursor{
+		Sort:   q.SortField(),
+		Order:  q.SortOrder(),
+		Values: values,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// SearchAfter decodes the query cursor into the sort values of the
+// last result on the previous page.
+func (q Query) SearchAfter() ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, errors.New("cursor is malformed")
+	}
+
+	decoded := cursor{}
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded.Values) == 0 {
+		return nil, errors.New("cursor is malformed")
+	}
+
+	if decoded.Sort != q.SortField() || decoded.Order != q.SortOrder() {
+		return nil, errors.New("cursor does not match the requested sort")
+	}
+
+	return decoded.Values, nil
+}
+
+func (f Filters) validate() error {
+	for _, bucket := range f.Buckets {
+		if bucket == "" {
+			return errors.New("bucket filter values must not be empty")
+		}
+	}
+
+	for _, fileType := range f.FileTypes {
+		if strings.Trim(fileType, ". ") == "" {
+			return errors.New("file type filter values must not be empty")
+		}
+	}
+
+	if err := f.LastModified.validate("last_modified"); err != nil {
+		return err
+	}
+
+	return f.IndexedAt.validate("indexed_at")
+}
+
+func (d *DateRange) validate(name string) error {
+	if d == nil {
+		return nil
+	}
+
+	if d.From == nil && d.To == nil {
+		return fmt.Errorf("%s filter requires from or to", name)
+	}
+
+	if d.From != nil && d.To != nil && d.From.After(*d.To) {
+		return fmt.Errorf("%s filter from must not be after to", name)
+	}
+
+	return nil
+}
+
+func (c Clause) validate(depth int) error {
+	if depth > maxClauseDepth {
+		return fmt.Errorf("query exceeds maximum depth of %d", maxClauseDepth)
+	}
+
+	switch c.Type {
+	case ClauseAnd, ClauseOr, ClauseNot:
+		if len(c.Clauses) == 0 {
+			return fmt.Errorf("%q clause requires at least one child clause", c.Type)
+		}
+
+		if c.Field != "" || c.Text != "" || c.Fuzziness != "" {
+			return fmt.Errorf("%q clause does not accept field, text, or fuzziness", c.Type)
+		}
+
+		for _, clause := range c.Clauses {
+			if err := clause.validate(depth + 1); err != nil {
+				return err
+			}
+		}
+
+	case ClauseMatch, ClausePhrase, ClausePrefix, ClauseWildcard:
+		if strings.TrimSpace(c.Text) == "" {
+			return fmt.Errorf("%q clause requires text", c.Type)
+		}
+
+		if len(c.Clauses) != 0 {
+			return fmt.Errorf("%q clause does not accept child clauses", c.Type)
+		}
+
+		if c.Field != "" {
+			return fmt.Errorf("%q clause does not accept field", c.Type)
+		}
+
+		if c.Fuzziness != "" {
+			if c.Type != ClauseMatch {
+				return fmt.Errorf("%q clause does not accept fuzziness", c.Type)
+			}
+
+			switch strings.ToUpper(c.Fuzziness) {
+			case "AUTO", "0", "1", "2":
+			default:
+				return fmt.Errorf("fuzziness %q must be one of AUTO, 0, 1, or 2", c.Fuzziness)
+			}
+		}
+
+	case ClauseField:
+		if NormalizeField(c.Field) == "" {
+			return fmt.Errorf("%q clause requires field", c.Type)
+		}
+
+		if NormalizeField(c.Text) == "" {
+			return fmt.Errorf("%q clause requires text", c.Type)
+		}
+
+		if len(c.Clauses) != 0 || c.Fuzziness != "" {
+			return fmt.Errorf("%q clause does not accept child clauses or fuzziness", c.Type)
+		}
+
+	default:
+		return fmt.Errorf("unsupported clause type %q", c.Type)
+	}
+
+	return nil
+}
+
+// dsl converts the query into the OpenSearch query DSL. Validate must
+// be called before dsl.
+func (q Query) dsl() object {
+	var query object
+	if root := q.Root(); root != nil {
+		query = root.dsl(innerHitsName, false, q.MinConfidence)
+	}
+
+	if q.Filters == nil {
+		if query == nil {
+			return matchAllQuery()
+		}
+
+		return query
+	}
+
+	filtered := boolQuery{
+		Filter: q.Filters.dsl(),
+	}
+
+	if query != nil {
+		filtered.Must = []object{query}
+	}
+
+	return filtered.object()
+}
+
+// search converts the query into an OpenSearch search request body
+// for one more result than the page size so that the presence of a
+// following page can be detected. Validate must be called before
+// search.
+func (q Query) search() searchBody {
+	body := searchBody{
+		Query:          q.dsl(),
+		Size:           q.PageSize() + 1,
+		TrackTotalHits: true,
+	}
+
+	order := q.SortOrder()
+	switch q.SortField() {
+	case SortKey:
+		body.Sort = []object{{fileKeyField: order}}
+	case SortIndexedAt:
+		body.Sort = []object{{indexedAtField: order}}
+	default:
+		body.Sort = []object{{"_score": order}}
+	}
+	body.Sort = append(body.Sort, object{idField: OrderAsc})
+
+	if q.Cursor != "" {
+		body.SearchAfter, _ = q.SearchAfter()
+	}
+
+	return body
+}
+
+func (f Filters) dsl() []object {
+	filters := []object{}
+
+	if len(f.Buckets) > 0 {
+		filters = append(filters, termsQuery(fileBucketField, f.Buckets))
+	}
+
+	if f.KeyPrefix != "" {
+		filters = append(filters, prefixQuery(fileKeyField, f.KeyPrefix))
+	}
+
+	if len(f.FileTypes) > 0 {
+		extensions := []string{}
+		contentTypes := []string{}
+		for _, fileType := range f.FileTypes {
+			if strings.Contains(fileType, "/") {
+				contentTypes = append(contentTypes, strings.ToLower(strings.TrimSpace(fileType)))
+			} else {
+				extensions = append(extensions, strings.ToLower(strings.TrimPrefix(strings.TrimSpace(fileType), ".")))
+			}
+		}
+
+		fileTypes := boolQuery{
+			MinimumShouldMatch: 1,
+		}
+
+		if len(extensions) > 0 {
+			fileTypes.Should = append(fileTypes.Should, termsQuery(fileExtField, extensions))
+		}
+
+		if len(contentTypes) > 0 {
+			fileTypes.Should = append(fileTypes.Should, termsQuery(contentTypeField, contentTypes))
+		}
+
+		filters = append(filters, fileTypes.object())
+	}
+
+	if f.LastModified != nil {
+		filters = append(filters, rangeQuery(lastModifiedField, f.LastModified.From, f.LastModified.To))
+	}
+
+	if f.IndexedAt != nil {
+		filters = append(filters, rangeQuery(indexedAtField, f.IndexedAt.From, f.IndexedAt.To))
+	}
+
+	if f.Skipped != nil {
+		if *f.Skipped {
+			filters = append(filters, existsQuery(skipReasonField))
+		} else {
+			filters = append(filters, boolQuery{
+				MustNot: []object{existsQuery(skipReasonField)},
+			}.object())
+		}
+	}
+
+	return filters
+}
+
+// dsl converts the clause into the OpenSearch query DSL. Each term
+// clause is run against the nested lines with at least the minimum
+// confidence and, unless it is negated, returns the matching lines as
+// inner hits under a name derived from its position in the tree. Field
+// clauses are run against the nested form fields without inner hits.
+func (c Clause) dsl(name string, negated bool, minConfidence float64) object {
+	switch c.Type {
+	case ClauseAnd:
+		return boolQuery{
+			Must: clausesDSL(c.Clauses, name, negated, minConfidence),
+		}.object()
+
+	case ClauseOr:
+		return boolQuery{
+			Should:             clausesDSL(c.Clauses, name, negated, minConfidence),
+			MinimumShouldMatch: 1,
+		}.object()
+
+	case ClauseNot:
+		return boolQuery{
+			MustNot: clausesDSL(c.Clauses, name, !negated, minConfidence),
+		}.object()
+
+	case ClausePhrase:
+		return linesQuery(matchPhraseQuery(linesTextField, c.Text), name, negated, minConfidence)
+
+	case ClausePrefix:
+		return linesQuery(prefixQuery(linesTextField, strings.ToLower(c.Text)), name, negated, minConfidence)
+
+	case ClauseWildcard:
+		return linesQuery(wildcardQuery(linesTextField, strings.ToLower(c.Text)), name, negated, minConfidence)
+
+	case ClauseField:
+		filters := []object{
+			termQuery(fieldsKeyField, NormalizeField(c.Field)),
+			termQuery(fieldsValueField, NormalizeField(c.Text)),
+		}
+
+		if minConfidence > 0 {
+			filters = append(filters, minimumQuery(fieldsConfField, minConfidence))
+		}
+
+		return nestedQuery(fieldsPath, boolQuery{
+			Filter: filters,
+		}.object(), nil)
+
+	default:
+		return linesQuery(matchQuery(linesTextField, c.Text, strings.ToUpper(c.Fuzziness)), name, negated, minConfidence)
+	}
+}
+
+func clausesDSL(clauses []Clause, name string, negated bool, minConfidence float64) []object {
+	queries := make([]object, len(clauses))
+	for i, clause := range clauses {
+		queries[i] = clause.dsl(fmt.Sprintf("%s_%d", name, i), negated, minConfidence)
+	}
+
+	return queries
+}
+
+func linesQuery(query object, name string, negated bool, minConfidence float64) object {
+	if minConfidence > 0 {
+		query = boolQuery{
+			Must:   []object{query},
+			Filter: []object{minimumQuery(linesConfField, minConfidence)},
+		}.object()
+	}
+
+	if negated {
+		return nestedQuery(linesPath, query, nil)
+	}
+
+	return nestedQuery(linesPath, query, innerHitsRequest(name, linesTextField))
+}
@@ -0,0 +1,525 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueryValidate(t *testing.T) {
+	earlier := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	later := time.Date(2021, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		description string
+		query       Query
+		error       error
+	}{
+		{
+			description: "text and query both provided",
+			query: Query{
+				Text: "text",
+				Clause: &Clause{
+					Type: ClauseMatch,
+					Text: "text",
+				},
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "unsupported clause type",
+			query: Query{
+				Clause: &Clause{
+					Type: "regexp",
+					Text: "text",
+				},
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "boolean clause without children",
+			query: Query{
+				Clause: &Clause{
+					Type: ClauseAnd,
+				},
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "term clause without text",
+			query: Query{
+				Clause: &Clause{
+					Type: ClausePhrase,
+					Text: " ",
+				},
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "fuzziness on non-match clause",
+			query: Query{
+				Clause: &Clause{
+					Type:      ClausePrefix,
+					Text:      "text",
+					Fuzziness: "1",
+				},
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "invalid fuzziness value",
+			query: Query{
+				Clause: &Clause{
+					Type:      ClauseMatch,
+					Text:      "text",
+					Fuzziness: "3",
+				},
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "field clause without field",
+			query: Query{
+				Clause: &Clause{
+					Type: ClauseField,
+					Text: "1234",
+				},
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "field on term clause",
+			query: Query{
+				Clause: &Clause{
+					Type:  ClauseMatch,
+					Field: "Invoice Number",
+					Text:  "1234",
+				},
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "field text without value",
+			query: Query{
+				Text: `field:"Invoice Number" =`,
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "query exceeds maximum depth",
+			query: Query{
+				Clause: nestedClause(maxClauseDepth + 1),
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "empty bucket filter",
+			query: Query{
+				Text: "text",
+				Filters: &Filters{
+					Buckets: []string{""},
+				},
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "empty file type filter",
+			query: Query{
+				Text: "text",
+				Filters: &Filters{
+					FileTypes: []string{"."},
+				},
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "date range without bounds",
+			query: Query{
+				Text: "text",
+				Filters: &Filters{
+					LastModified: &DateRange{},
+				},
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "date range from after to",
+			query: Query{
+				Text: "text",
+				Filters: &Filters{
+					IndexedAt: &DateRange{
+						From: &later,
+						To:   &earlier,
+					},
+				},
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "valid filters only query",
+			query: Query{
+				Filters: &Filters{
+					Buckets:   []string{"bucket"},
+					FileTypes: []string{".PDF", "image/png"},
+					LastModified: &DateRange{
+						From: &earlier,
+						To:   &later,
+					},
+				},
+			},
+			error: nil,
+		},
+		{
+			description: "min confidence above maximum",
+			query: Query{
+				Text:          "text",
+				MinConfidence: maxConfidence + 1,
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "size above maximum",
+			query: Query{
+				Text: "text",
+				Size: maxSize + 1,
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "unsupported sort",
+			query: Query{
+				Text: "text",
+				Sort: "size",
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "unsupported order",
+			query: Query{
+				Text:  "text",
+				Order: "up",
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "malformed cursor",
+			query: Query{
+				Text:   "text",
+				Cursor: "not a cursor",
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "cursor from a different sort",
+			query: Query{
+				Text:   "text",
+				Sort:   SortIndexedAt,
+				Cursor: cursorFor(t, Query{Sort: SortKey}),
+			},
+			error: &InvalidQueryError{},
+		},
+		{
+			description: "valid paginated query",
+			query: Query{
+				Text:   "text",
+				Size:   maxSize,
+				Sort:   SortKey,
+				Order:  OrderDesc,
+				Cursor: cursorFor(t, Query{Sort: SortKey, Order: OrderDesc}),
+			},
+			error: nil,
+		},
+		{
+			description: "valid text query",
+			query: Query{
+				Text: "text",
+			},
+			error: nil,
+		},
+		{
+			description: "valid structured query",
+			query: Query{
+				Clause: &Clause{
+					Type: ClauseOr,
+					Clauses: []Clause{
+						{
+							Type:      ClauseMatch,
+							Text:      "text",
+							Fuzziness: "auto",
+						},
+						{
+							Type: ClauseNot,
+							Clauses: []Clause{
+								{
+									Type: ClauseWildcard,
+									Text: "te*t",
+								},
+							},
+						},
+					},
+				},
+			},
+			error: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := test.query.Validate()
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *InvalidQueryError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else if test.error != nil {
+				t.Errorf("incorrect error, received: nil, expected: %v", test.error)
+			}
+		})
+	}
+}
+
+func TestQueryDSL(t *testing.T) {
+	from := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	skipped, parsed := true, false
+
+	tests := []struct {
+		description string
+		query       Query
+		dsl         string
+	}{
+		{
+			description: "text query",
+			query: Query{
+				Text: "example",
+			},
+			dsl: `{"nested":{"inner_hits":{"_source":false,"highlight":{"encoder":"html","fields":{"pages.lines.text":{"number_of_fragments":0}},"post_tags":["\u003c/em\u003e"],"pre_tags":["\u003cem\u003e"]},"name":"match","size":100},"path":"pages.lines","query":{"match":{"pages.lines.text":{"fuzziness":"AUTO","query":"example"}}}}}`,
+		},
+		{
+			description: "filters only query",
+			query: Query{
+				Filters: &Filters{
+					KeyPrefix: "invoices/",
+				},
+			},
+			dsl: `{"bool":{"filter":[{"prefix":{"file_key":{"value":"invoices/"}}}]}}`,
+		},
+		{
+			description: "skipped files query",
+			query: Query{
+				Filters: &Filters{
+					Skipped: &skipped,
+				},
+			},
+			dsl: `{"bool":{"filter":[{"exists":{"field":"skip_reason"}}]}}`,
+		},
+		{
+			description: "parsed files query",
+			query: Query{
+				Filters: &Filters{
+					Skipped: &parsed,
+				},
+			},
+			dsl: `{"bool":{"filter":[{"bool":{"must_not":[{"exists":{"field":"skip_reason"}}]}}]}}`,
+		},
+		{
+			description: "text query with filters",
+			query: Query{
+				Text: "example",
+				Filters: &Filters{
+					Buckets:   []string{"bucket"},
+					FileTypes: []string{".PDF", "image/png"},
+					IndexedAt: &DateRange{
+						From: &from,
+					},
+				},
+			},
+			dsl: `{"bool":{"must":[{"nested":{"inner_hits":{"_source":false,"highlight":{"encoder":"html","fields":{"pages.lines.text":{"number_of_fragments":0}},"post_tags":["\u003c/em\u003e"],"pre_tags":["\u003cem\u003e"]},"name":"match","size":100},"path":"pages.lines","query":{"match":{"pages.lines.text":{"fuzziness":"AUTO","query":"example"}}}}}],"filter":[{"terms":{"file_bucket":["bucket"]}},{"bool":{"should":[{"terms":{"file_extension":["pdf"]}},{"terms":{"content_type":["image/png"]}}],"minimum_should_match":1}},{"range":{"indexed_at":{"gte":"2021-01-01T00:00:00Z"}}}]}}`,
+		},
+		{
+			description: "text query with min confidence",
+			query: Query{
+				Text:          "example",
+				MinConfidence: 80,
+			},
+			dsl: `{"nested":{"inner_hits":{"_source":false,"highlight":{"encoder":"html","fields":{"pages.lines.text":{"number_of_fragments":0}},"post_tags":["\u003c/em\u003e"],"pre_tags":["\u003cem\u003e"]},"name":"match","size":100},"path":"pages.lines","query":{"bool":{"must":[{"match":{"pages.lines.text":{"fuzziness":"AUTO","query":"example"}}}],"filter":[{"range":{"pages.lines.confidence":{"gte":80}}}]}}}}`,
+		},
+		{
+			description: "field text query with min confidence",
+			query: Query{
+				Text:          `field:"Invoice Number" = #1234`,
+				MinConfidence: 80,
+			},
+			dsl: `{"nested":{"path":"pages.fields","query":{"bool":{"filter":[{"term":{"pages.fields.key":"invoice number"}},{"term":{"pages.fields.value":"1234"}},{"range":{"pages.fields.confidence":{"gte":80}}}]}}}}`,
+		},
+		{
+			description: "phrase query",
+			query: Query{
+				Clause: &Clause{
+					Type: ClausePhrase,
+					Text: `say "hello"`,
+				},
+			},
+			dsl: `{"nested":{"inner_hits":{"_source":false,"highlight":{"encoder":"html","fields":{"pages.lines.text":{"number_of_fragments":0}},"post_tags":["\u003c/em\u003e"],"pre_tags":["\u003cem\u003e"]},"name":"match","size":100},"path":"pages.lines","query":{"match_phrase":{"pages.lines.text":{"query":"say \"hello\""}}}}}`,
+		},
+		{
+			description: "boolean query",
+			query: Query{
+				Clause: &Clause{
+					Type: ClauseAnd,
+					Clauses: []Clause{
+						{
+							Type: ClausePrefix,
+							Text: "Inv",
+						},
+						{
+							Type: ClauseOr,
+							Clauses: []Clause{
+								{
+									Type:      ClauseMatch,
+									Text:      "total",
+									Fuzziness: "0",
+								},
+								{
+									Type: ClauseWildcard,
+									Text: "sum?",
+								},
+							},
+						},
+						{
+							Type: ClauseNot,
+							Clauses: []Clause{
+								{
+									Type: ClauseMatch,
+									Text: "draft",
+								},
+							},
+						},
+					},
+				},
+			},
+			dsl: `{"bool":{"must":[{"nested":{"inner_hits":{"_source":false,"highlight":{"encoder":"html","fields":{"pages.lines.text":{"number_of_fragments":0}},"post_tags":["\u003c/em\u003e"],"pre_tags":["\u003cem\u003e"]},"name":"match_0","size":100},"path":"pages.lines","query":{"prefix":{"pages.lines.text":{"value":"inv"}}}}},{"bool":{"should":[{"nested":{"inner_hits":{"_source":false,"highlight":{"encoder":"html","fields":{"pages.lines.text":{"number_of_fragments":0}},"post_tags":["\u003c/em\u003e"],"pre_tags":["\u003cem\u003e"]},"name":"match_1_0","size":100},"path":"pages.lines","query":{"match":{"pages.lines.text":{"fuzziness":"0","query":"total"}}}}},{"nested":{"inner_hits":{"_source":false,"highlight":{"encoder":"html","fields":{"pages.lines.text":{"number_of_fragments":0}},"post_tags":["\u003c/em\u003e"],"pre_tags":["\u003cem\u003e"]},"name":"match_1_1","size":100},"path":"pages.lines","query":{"wildcard":{"pages.lines.text":{"value":"sum?"}}}}}],"minimum_should_match":1}},{"bool":{"must_not":[{"nested":{"path":"pages.lines","query":{"match":{"pages.lines.text":{"query":"draft"}}}}}]}}]}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			dsl, err := json.Marshal(test.query.dsl())
+			if err != nil {
+				t.Fatalf("error marshalling dsl: %v", err)
+			}
+
+			if string(dsl) != test.dsl {
+				t.Errorf("incorrect dsl, received: %s, expected: %s", dsl, test.dsl)
+			}
+		})
+	}
+}
+
+func TestQueryRoot(t *testing.T) {
+	clause := &Clause{
+		Type: ClausePhrase,
+		Text: "text",
+	}
+
+	tests := []struct {
+		description string
+		query       Query
+		root        *Clause
+	}{
+		{
+			description: "filters only query",
+			query:       Query{},
+			root:        nil,
+		},
+		{
+			description: "structured query",
+			query: Query{
+				Clause: clause,
+			},
+			root: clause,
+		},
+		{
+			description: "text query",
+			query: Query{
+				Text: "invoice = 1234",
+			},
+			root: &Clause{
+				Type:      ClauseMatch,
+				Text:      "invoice = 1234",
+				Fuzziness: "AUTO",
+			},
+		},
+		{
+			description: "quoted field text query",
+			query: Query{
+				Text: ` field:"Invoice Number" = "ACME 1234" `,
+			},
+			root: &Clause{
+				Type:  ClauseField,
+				Field: "Invoice Number",
+				Text:  "ACME 1234",
+			},
+		},
+		{
+			description: "unquoted field text query",
+			query: Query{
+				Text: "field:total=$250.00",
+			},
+			root: &Clause{
+				Type:  ClauseField,
+				Field: "total",
+				Text:  "$250.00",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			root := test.query.Root()
+
+			if !reflect.DeepEqual(root, test.root) {
+				t.Errorf("incorrect root, received: %+v, expected: %+v", root, test.root)
+			}
+		})
+	}
+}
+
+func TestNormalizeField(t *testing.T) {
+	if received := NormalizeField(" Invoice  No.: #1234-B "); received != "invoice no 1234 b" {
+		t.Errorf("incorrect normalized field, received: %q, expected: %q", received, "invoice no 1234 b")
+	}
+}
+
+func cursorFor(t *testing.T, query Query) string {
+	t.Helper()
+
+	cursor, err := query.EncodeCursor([]interface{}{"key.jpg", "doc_id"})
+	if err != nil {
+		t.Fatalf("error encoding cursor: %v", err)
+	}
+
+	return cursor
+}
+
+func nestedClause(depth int) *Clause {
+	clause := &Clause{
+		Type: ClauseMatch,
+		Text: "text",
+	}
+
+	for i := 1; i < depth; i++ {
+		clause = &Clause{
+			Type:    ClauseNot,
+			Clauses: []Clause{*clause},
+		}
+	}
+
+	return clause
+}
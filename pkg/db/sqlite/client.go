@@ -0,0 +1,379 @@
+// Package sqlite implements db.Databaser with an embedded SQLite
+// database using FTS5 full-text search over the parsed lines.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+// migrations holds the statements of each schema migration in the
+// order they are applied; the version of a migration is its position
+// plus one and the version of a database is kept in its user_version.
+// Released migrations must not be edited, only appended to; a migration
+// that changes how lines_fts indexes text must also rebuild it.
+var migrations = [][]string{
+	{
+		`CREATE TABLE documents (
+			id TEXT PRIMARY KEY,
+			entity TEXT NOT NULL,
+			file_bucket TEXT NOT NULL,
+			file_key TEXT NOT NULL,
+			file_version TEXT NOT NULL,
+			file_extension TEXT NOT NULL,
+			content_type TEXT NOT NULL,
+			last_modified INTEGER,
+			indexed_at INTEGER
+		)`,
+		`CREATE INDEX documents_file ON documents (file_bucket, file_key)`,
+		`CREATE TABLE pages (
+			id TEXT PRIMARY KEY,
+			document_id TEXT NOT NULL,
+			entity TEXT NOT NULL,
+			page_number INTEGER NOT NULL,
+			position INTEGER NOT NULL
+		)`,
+		`CREATE INDEX pages_document ON pages (document_id)`,
+		`CREATE TABLE lines (
+			row INTEGER PRIMARY KEY,
+			id TEXT NOT NULL UNIQUE,
+			document_id TEXT NOT NULL,
+			page_id TEXT NOT NULL,
+			entity TEXT NOT NULL,
+			text TEXT NOT NULL,
+			coordinates TEXT NOT NULL,
+			position INTEGER NOT NULL
+		)`,
+		`CREATE INDEX lines_document ON lines (document_id)`,
+		`CREATE VIRTUAL TABLE lines_fts USING fts5(
+			text,
+			content='lines',
+			content_rowid='row',
+			tokenize="unicode61 remove_diacritics 0 tokenchars '_'"
+		)`,
+		`CREATE VIRTUAL TABLE lines_vocab USING fts5vocab(lines_fts, 'row')`,
+		`CREATE TRIGGER lines_insert AFTER INSERT ON lines BEGIN
+			INSERT INTO lines_fts (rowid, text) VALUES (new.row, new.text);
+		END`,
+		`CREATE TRIGGER lines_delete AFTER DELETE ON lines BEGIN
+			INSERT INTO lines_fts (lines_fts, rowid, text) VALUES ('delete', old.row, old.text);
+		END`,
+	},
+	{
+		`ALTER TABLE lines ADD COLUMN confidence REAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE lines ADD COLUMN words TEXT NOT NULL DEFAULT 'null'`,
+	},
+	{
+		`ALTER TABLE pages ADD COLUMN tables TEXT NOT NULL DEFAULT 'null'`,
+		`ALTER TABLE pages ADD COLUMN fields TEXT NOT NULL DEFAULT 'null'`,
+		`CREATE TABLE field_terms (
+			document_id TEXT NOT NULL,
+			key_terms TEXT NOT NULL,
+			value_terms TEXT NOT NULL,
+			confidence REAL NOT NULL
+		)`,
+		`CREATE INDEX field_terms_key_value ON field_terms (key_terms, value_terms)`,
+		`CREATE INDEX field_terms_document ON field_terms (document_id)`,
+	},
+	{
+		`ALTER TABLE documents ADD COLUMN skip_reason TEXT NOT NULL DEFAULT ''`,
+	},
+}
+
+var _ db.Databaser = &Client{}
+
+// Client implements the db.Databaser methods using SQLite.
+type Client struct {
+	database *sql.DB
+}
+
+// New generates a sqlite.Client pointer instance storing the parsed
+// documents in the database file at path; ":memory:" holds them in
+// memory instead.
+func New(path string) (*Client, error) {
+	database, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, &NewClientError{
+			err: err,
+		}
+	}
+
+	// a single connection serializes writes and keeps an in-memory
+	// database shared across calls
+	database.SetMaxOpenConns(1)
+
+	return &Client{
+		database: database,
+	}, nil
+}
+
+// Close closes the underlying database.
+func (c *Client) Close() error {
+	return c.database.Close()
+}
+
+// SetupDatabase implements the db.Databaser.SetupDatabase method
+// using SQLite. Migrations that have not been applied are run in order
+// in a single transaction; a sqlite.SchemaMismatchError is returned if
+// the database has migrations newer than this version knows.
+func (c *Client) SetupDatabase(ctx context.Context) error {
+	err := c.transaction(ctx, func(tx *sql.Tx) error {
+		var version int
+		if err := tx.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+			return err
+		}
+
+		if version > len(migrations) {
+			return &SchemaMismatchError{
+				err: fmt.Errorf("database has schema version %d, expected version %d", version, len(migrations)),
+			}
+		}
+
+		for i := version; i < len(migrations); i++ {
+			for _, statement := range migrations[i] {
+				if _, err := tx.ExecContext(ctx, statement); err != nil {
+					return fmt.Errorf("migration %d: %w", i+1, err)
+				}
+			}
+		}
+
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, len(migrations)))
+		return err
+	})
+	if err != nil {
+		if _, ok := err.(*SchemaMismatchError); ok {
+			return err
+		}
+
+		return &SetupDatabaseError{
+			err: err,
+		}
+	}
+
+	return nil
+}
+
+// UpsertDocuments implements the db.Databaser.UpsertDocuments method
+// using SQLite.
+func (c *Client) UpsertDocuments(ctx context.Context, documents []pars.Document) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	// the last copy of a repeated document wins as it does in a bulk
+	// request
+	positions := map[string]int{}
+	ids := []string{}
+	for i, document := range documents {
+		if _, ok := positions[document.ID]; !ok {
+			ids = append(ids, document.ID)
+		}
+		positions[document.ID] = i
+	}
+
+	err := c.transaction(ctx, func(tx *sql.Tx) error {
+		if err := deleteDocuments(ctx, tx, `id IN `+placeholders(len(ids)), stringArgs(ids)...); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if err := insertDocument(ctx, tx, documents[positions[id]]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return &UpsertDocumentsError{
+			err: err,
+		}
+	}
+
+	return nil
+}
+
+func insertDocument(ctx context.Context, tx *sql.Tx, document pars.Document) error {
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO documents (id, entity, file_bucket, file_key, file_version, file_extension, content_type, last_modified, indexed_at, skip_reason) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		document.ID,
+		document.Entity,
+		document.FileBucket,
+		document.FileKey,
+		document.FileVersion,
+		document.FileExtension,
+		document.ContentType,
+		unixMilli(document.LastModified),
+		unixMilli(document.IndexedAt),
+		document.SkipReason,
+	); err != nil {
+		return err
+	}
+
+	for pagePosition, page := range document.Pages {
+		tables, err := json.Marshal(page.Tables)
+		if err != nil {
+			return err
+		}
+
+		fields, err := json.Marshal(page.Fields)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO pages (id, document_id, entity, page_number, tables, fields, position) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			page.ID,
+			document.ID,
+			page.Entity,
+			page.PageNumber,
+			string(tables),
+			string(fields),
+			pagePosition,
+		); err != nil {
+			return err
+		}
+
+		for _, field := range page.Fields {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO field_terms (document_id, key_terms, value_terms, confidence) VALUES (?, ?, ?, ?)`,
+				document.ID,
+				db.NormalizeField(field.Key),
+				db.NormalizeField(field.Value),
+				field.Confidence,
+			); err != nil {
+				return err
+			}
+		}
+
+		for linePosition, line := range page.Lines {
+			coordinates, err := json.Marshal(line.Coordinates)
+			if err != nil {
+				return err
+			}
+
+			words, err := json.Marshal(line.Words)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO lines (id, document_id, page_id, entity, text, confidence, coordinates, words, position) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				line.ID,
+				document.ID,
+				page.ID,
+				line.Entity,
+				line.Text,
+				line.Confidence,
+				string(coordinates),
+				string(words),
+				linePosition,
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// DeleteDocumentsByKeys implements the db.Databaser.DeleteDocumentsByKeys
+// method using SQLite, matching the documents by the IDs derived from
+// their keys.
+func (c *Client) DeleteDocumentsByKeys(ctx context.Context, fileKeys []db.FileKey) error {
+	if len(fileKeys) == 0 {
+		return nil
+	}
+
+	ids := []string{}
+	for _, fileKey := range fileKeys {
+		ids = append(ids, pars.DocumentID(fileKey.Bucket, fileKey.Key))
+	}
+
+	return c.deleteDocuments(ctx, `id IN `+placeholders(len(ids)), stringArgs(ids)...)
+}
+
+// DeleteDocumentsByBuckets implements the
+// db.Databaser.DeleteDocumentsByBuckets method using SQLite.
+func (c *Client) DeleteDocumentsByBuckets(ctx context.Context, buckets []string) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	return c.deleteDocuments(ctx, `file_bucket IN `+placeholders(len(buckets)), stringArgs(buckets)...)
+}
+
+func (c *Client) deleteDocuments(ctx context.Context, condition string, args ...interface{}) error {
+	err := c.transaction(ctx, func(tx *sql.Tx) error {
+		return deleteDocuments(ctx, tx, condition, args...)
+	})
+	if err != nil {
+		return &DeleteDocumentsError{
+			err: err,
+		}
+	}
+
+	return nil
+}
+
+// deleteDocuments removes the documents matching the condition on the
+// documents table along with their pages, lines, and form fields.
+func deleteDocuments(ctx context.Context, tx *sql.Tx, condition string, args ...interface{}) error {
+	selectIDs := `SELECT id FROM documents WHERE ` + condition
+	for _, statement := range []string{
+		`DELETE FROM lines WHERE document_id IN (` + selectIDs + `)`,
+		`DELETE FROM field_terms WHERE document_id IN (` + selectIDs + `)`,
+		`DELETE FROM pages WHERE document_id IN (` + selectIDs + `)`,
+		`DELETE FROM documents WHERE ` + condition,
+	} {
+		if _, err := tx.ExecContext(ctx, statement, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) transaction(ctx context.Context, run func(tx *sql.Tx) error) error {
+	tx, err := c.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := run(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func placeholders(count int) string {
+	return `(` + strings.TrimSuffix(strings.Repeat(`?, `, count), `, `) + `)`
+}
+
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+
+	return args
+}
+
+func unixMilli(value *time.Time) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	return value.UnixMilli()
+}
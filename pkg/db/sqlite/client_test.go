@@ -0,0 +1,170 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/db/dbtest"
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+func newTestClient(t *testing.T) *Client {
+	client, err := New(filepath.Join(t.TempDir(), "findfile.db"))
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	return client
+}
+
+func TestClient(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) db.Databaser {
+		return newTestClient(t)
+	})
+}
+
+func TestSetupDatabase(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	if err := client.SetupDatabase(ctx); err != nil {
+		t.Fatalf("incorrect error, received: %v, expected: nil", err)
+	}
+
+	if err := client.SetupDatabase(ctx); err != nil {
+		t.Fatalf("incorrect error on existing schema, received: %v, expected: nil", err)
+	}
+
+	if _, err := client.database.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, len(migrations)+1)); err != nil {
+		t.Fatalf("error setting schema version: %v", err)
+	}
+
+	err := client.SetupDatabase(ctx)
+	var mismatchErr *SchemaMismatchError
+	if !errors.As(err, &mismatchErr) {
+		t.Errorf("incorrect error, received: %v, expected: %T", err, mismatchErr)
+	}
+}
+
+func TestSetupDatabaseMigrations(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	statements := append(append([]string{}, migrations[0]...),
+		`INSERT INTO documents (id, entity, file_bucket, file_key, file_version, file_extension, content_type) VALUES ('old_id', 'document', 'bucket', 'old.pdf', '', 'pdf', 'application/pdf')`,
+		`INSERT INTO pages (id, document_id, entity, page_number, position) VALUES ('old_page_id', 'old_id', 'page', 1, 0)`,
+		`INSERT INTO lines (id, document_id, page_id, entity, text, coordinates, position) VALUES ('old_line_id', 'old_id', 'old_page_id', 'line', 'migrated text', '{}', 0)`,
+		`PRAGMA user_version = 1`,
+	)
+	for _, statement := range statements {
+		if _, err := client.database.Exec(statement); err != nil {
+			t.Fatalf("error creating version 1 database: %v", err)
+		}
+	}
+
+	if err := client.SetupDatabase(ctx); err != nil {
+		t.Fatalf("incorrect error, received: %v, expected: nil", err)
+	}
+
+	var version int
+	if err := client.database.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		t.Fatalf("error reading schema version: %v", err)
+	}
+
+	if version != len(migrations) {
+		t.Errorf("incorrect schema version, received: %d, expected: %d", version, len(migrations))
+	}
+
+	if err := client.UpsertDocuments(ctx, []pars.Document{
+		{
+			ID:         "new_id",
+			FileBucket: "bucket",
+			FileKey:    "new.pdf",
+			Pages: []pars.Page{
+				{
+					ID:         "new_page_id",
+					PageNumber: 1,
+					Lines: []pars.Line{
+						{
+							ID:         "new_line_id",
+							Text:       "new text",
+							Confidence: 90,
+						},
+					},
+					Fields: []pars.Field{
+						{
+							Key:        "name",
+							Value:      "value",
+							Confidence: 90,
+						},
+					},
+				},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("error upserting documents: %v", err)
+	}
+
+	result, err := client.QueryDocuments(ctx, db.Query{
+		Text: "text",
+	})
+	if err != nil {
+		t.Fatalf("error querying documents: %v", err)
+	}
+
+	if result.Total != 2 {
+		t.Errorf("incorrect total, received: %d, expected: 2", result.Total)
+	}
+}
+
+func TestUpsertDocuments(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	if err := client.SetupDatabase(ctx); err != nil {
+		t.Fatalf("error setting up database: %v", err)
+	}
+
+	document := func(text string) pars.Document {
+		return pars.Document{
+			ID:         "doc_id",
+			FileBucket: "bucket",
+			FileKey:    "key.pdf",
+			Pages: []pars.Page{
+				{
+					ID:         "page_id",
+					PageNumber: 1,
+					Lines: []pars.Line{
+						{
+							ID:   "line_id",
+							Text: text,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	if err := client.UpsertDocuments(ctx, []pars.Document{document("first"), document("second")}); err != nil {
+		t.Fatalf("incorrect error, received: %v, expected: nil", err)
+	}
+
+	result, err := client.QueryDocuments(ctx, db.Query{
+		Text: "second",
+	})
+	if err != nil {
+		t.Fatalf("error querying documents: %v", err)
+	}
+
+	if result.Total != 1 || result.Hits[0].Matches[0].Highlight != "<em>second</em>" {
+		t.Errorf("incorrect result, received: %+v, expected: the second document", result)
+	}
+}
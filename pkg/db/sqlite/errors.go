@@ -0,0 +1,66 @@
+package sqlite
+
+import "fmt"
+
+const errorMessage = "package sqlite: %v"
+
+// NewClientError wraps errors returned by sqlite.New.
+type NewClientError struct {
+	err error
+}
+
+func (e *NewClientError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// SetupDatabaseError wraps errors returned by the migration statements
+// in db.Databaser.SetupDatabase.
+type SetupDatabaseError struct {
+	err error
+}
+
+func (e *SetupDatabaseError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// SchemaMismatchError is returned by db.Databaser.SetupDatabase when
+// the existing database has schema migrations newer than this version
+// knows.
+type SchemaMismatchError struct {
+	err error
+}
+
+func (e *SchemaMismatchError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// UpsertDocumentsError wraps errors returned by the statements in
+// db.Databaser.UpsertDocuments.
+type UpsertDocumentsError struct {
+	err error
+}
+
+func (e *UpsertDocumentsError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// DeleteDocumentsError wraps errors returned by the statements in
+// db.Databaser.DeleteDocumentsByKeys and
+// db.Databaser.DeleteDocumentsByBuckets.
+type DeleteDocumentsError struct {
+	err error
+}
+
+func (e *DeleteDocumentsError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
+
+// QueryDocumentsError wraps errors returned by the statements in
+// db.Databaser.QueryDocuments.
+type QueryDocumentsError struct {
+	err error
+}
+
+func (e *QueryDocumentsError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err)
+}
@@ -0,0 +1,690 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/db/internal/match"
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+const (
+	// private use characters mark highlighted terms so the line text
+	// can be HTML-escaped before the marks are replaced with tags
+	highlightStart = "\ue000"
+	highlightEnd   = "\ue001"
+)
+
+// leaf is a term clause compiled into an FTS5 match expression and
+// stored as a common table expression of the matching documents and
+// their scores.
+type leaf struct {
+	name       string
+	expression string
+	negated    bool
+}
+
+// statement holds a query compiled into SQL conditions on the
+// documents table, aliased as "d", along with the term clauses they
+// reference and the minimum confidence of the lines and form fields
+// they match.
+type statement struct {
+	leaves        []leaf
+	condition     string
+	args          []interface{}
+	minConfidence float64
+}
+
+// with returns the common table expressions for the term clauses and
+// their arguments.
+func (s statement) with() (string, []interface{}) {
+	if len(s.leaves) == 0 {
+		return "", nil
+	}
+
+	tables := []string{}
+	args := []interface{}{}
+	for _, leaf := range s.leaves {
+		tables = append(tables, leaf.name+` (document_id, score) AS (
+			SELECT l.document_id, -SUM(m.score)
+			FROM (SELECT rowid, rank AS score FROM lines_fts WHERE lines_fts MATCH ?) m
+			JOIN lines l ON l.row = m.rowid
+			WHERE l.confidence >= ?
+			GROUP BY l.document_id
+		)`)
+		args = append(args, leaf.expression, s.minConfidence)
+	}
+
+	return `WITH ` + strings.Join(tables, `, `) + ` `, args
+}
+
+// score returns the relevance expression for a document summing the
+// scores of the term clauses that are not negated.
+func (s statement) score() string {
+	scores := []string{}
+	for _, leaf := range s.leaves {
+		if !leaf.negated {
+			scores = append(scores, `COALESCE((SELECT score FROM `+leaf.name+` WHERE document_id = d.id), 0.0)`)
+		}
+	}
+
+	if len(scores) == 0 {
+		return `1.0`
+	}
+
+	return `(` + strings.Join(scores, ` + `) + `)`
+}
+
+// compile converts the query into SQL conditions. Validate must be
+// called before compile.
+func (c *Client) compile(ctx context.Context, query db.Query) (*statement, error) {
+	s := &statement{
+		minConfidence: query.MinConfidence,
+	}
+	conditions := []string{}
+
+	if root := query.Root(); root != nil {
+		condition, err := c.compileClause(ctx, s, *root, false)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if query.Filters != nil {
+		condition, args := filtersSQL(*query.Filters)
+		conditions = append(conditions, condition)
+		s.args = append(s.args, args...)
+	}
+
+	if len(conditions) == 0 {
+		conditions = append(conditions, `1`)
+	}
+	s.condition = strings.Join(conditions, ` AND `)
+
+	return s, nil
+}
+
+func (c *Client) compileClause(ctx context.Context, s *statement, clause db.Clause, negated bool) (string, error) {
+	switch clause.Type {
+	case db.ClauseAnd, db.ClauseOr, db.ClauseNot:
+		children := []string{}
+		for _, child := range clause.Clauses {
+			childNegated := negated
+			if clause.Type == db.ClauseNot {
+				childNegated = !negated
+			}
+
+			condition, err := c.compileClause(ctx, s, child, childNegated)
+			if err != nil {
+				return "", err
+			}
+			children = append(children, condition)
+		}
+
+		switch clause.Type {
+		case db.ClauseAnd:
+			return `(` + strings.Join(children, ` AND `) + `)`, nil
+		case db.ClauseOr:
+			return `(` + strings.Join(children, ` OR `) + `)`, nil
+		default:
+			return `NOT (` + strings.Join(children, ` OR `) + `)`, nil
+		}
+
+	case db.ClauseField:
+		s.args = append(s.args, db.NormalizeField(clause.Field), db.NormalizeField(clause.Text), s.minConfidence)
+
+		return `d.id IN (SELECT document_id FROM field_terms WHERE key_terms = ? AND value_terms = ? AND confidence >= ?)`, nil
+
+	default:
+		expression, err := c.expression(ctx, clause)
+		if err != nil {
+			return "", err
+		}
+
+		if expression == "" {
+			return `0`, nil
+		}
+
+		name := fmt.Sprintf("leaf_%d", len(s.leaves))
+		s.leaves = append(s.leaves, leaf{
+			name:       name,
+			expression: expression,
+			negated:    negated,
+		})
+
+		return `d.id IN (SELECT document_id FROM ` + name + `)`, nil
+	}
+}
+
+// expression converts a term clause into an FTS5 match expression.
+// Fuzzy, prefix, and wildcard terms are expanded against the indexed
+// vocabulary; an empty expression matches no lines.
+func (c *Client) expression(ctx context.Context, clause db.Clause) (string, error) {
+	switch clause.Type {
+	case db.ClausePhrase:
+		phrase := strings.Join(terms(clause.Text), " ")
+		if phrase == "" {
+			return "", nil
+		}
+
+		return quote(phrase), nil
+
+	case db.ClausePrefix:
+		return c.expand(ctx, `term GLOB ?`, []interface{}{escapeGlob(strings.ToLower(clause.Text)) + "*"}, match.MaxPatternExpansions)
+
+	case db.ClauseWildcard:
+		return c.expand(ctx, `term GLOB ?`, []interface{}{strings.ReplaceAll(strings.ToLower(clause.Text), "[", "[[]")}, match.MaxPatternExpansions)
+
+	default:
+		fuzziness := strings.ToUpper(clause.Fuzziness)
+		expanded := []string{}
+		for _, term := range terms(clause.Text) {
+			edits := match.MaxEdits(term, fuzziness)
+			if edits == 0 {
+				expanded = append(expanded, quote(term))
+				continue
+			}
+
+			length := utf8.RuneCountInString(term)
+			candidates, err := c.vocabulary(ctx, `length(term) BETWEEN ? AND ?`, []interface{}{length - edits, length + edits}, -1)
+			if err != nil {
+				return "", err
+			}
+
+			for _, candidate := range match.Expand(term, edits, candidates) {
+				expanded = append(expanded, quote(candidate))
+			}
+		}
+
+		return strings.Join(expanded, " OR "), nil
+	}
+}
+
+func (c *Client) expand(ctx context.Context, condition string, args []interface{}, limit int) (string, error) {
+	candidates, err := c.vocabulary(ctx, condition, args, limit)
+	if err != nil {
+		return "", err
+	}
+
+	expanded := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		expanded[i] = quote(candidate)
+	}
+
+	return strings.Join(expanded, " OR "), nil
+}
+
+// vocabulary returns the indexed terms matching the condition in
+// term order.
+func (c *Client) vocabulary(ctx context.Context, condition string, args []interface{}, limit int) ([]string, error) {
+	rows, err := c.database.QueryContext(ctx, `SELECT term FROM lines_vocab WHERE `+condition+` ORDER BY term LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	output := []string{}
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, err
+		}
+		output = append(output, term)
+	}
+
+	return output, rows.Err()
+}
+
+func filtersSQL(filters db.Filters) (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if len(filters.Buckets) > 0 {
+		conditions = append(conditions, `d.file_bucket IN `+placeholders(len(filters.Buckets)))
+		args = append(args, stringArgs(filters.Buckets)...)
+	}
+
+	if filters.KeyPrefix != "" {
+		conditions = append(conditions, `substr(d.file_key, 1, ?) = ?`)
+		args = append(args, utf8.RuneCountInString(filters.KeyPrefix), filters.KeyPrefix)
+	}
+
+	if len(filters.FileTypes) > 0 {
+		extensions := []string{}
+		contentTypes := []string{}
+		for _, fileType := range filters.FileTypes {
+			if strings.Contains(fileType, "/") {
+				contentTypes = append(contentTypes, strings.ToLower(strings.TrimSpace(fileType)))
+			} else {
+				extensions = append(extensions, strings.ToLower(strings.TrimPrefix(strings.TrimSpace(fileType), ".")))
+			}
+		}
+
+		fileTypes := []string{}
+		if len(extensions) > 0 {
+			fileTypes = append(fileTypes, `d.file_extension IN `+placeholders(len(extensions)))
+			args = append(args, stringArgs(extensions)...)
+		}
+
+		if len(contentTypes) > 0 {
+			fileTypes = append(fileTypes, `d.content_type IN `+placeholders(len(contentTypes)))
+			args = append(args, stringArgs(contentTypes)...)
+		}
+
+		conditions = append(conditions, `(`+strings.Join(fileTypes, ` OR `)+`)`)
+	}
+
+	for _, dateFilter := range []struct {
+		column    string
+		dateRange *db.DateRange
+	}{
+		{
+			column:    `d.last_modified`,
+			dateRange: filters.LastModified,
+		},
+		{
+			column:    `d.indexed_at`,
+			dateRange: filters.IndexedAt,
+		},
+	} {
+		if dateFilter.dateRange == nil {
+			continue
+		}
+
+		if dateFilter.dateRange.From != nil {
+			conditions = append(conditions, dateFilter.column+` >= ?`)
+			args = append(args, dateFilter.dateRange.From.UnixMilli())
+		}
+
+		if dateFilter.dateRange.To != nil {
+			conditions = append(conditions, dateFilter.column+` <= ?`)
+			args = append(args, dateFilter.dateRange.To.UnixMilli())
+		}
+	}
+
+	if filters.Skipped != nil {
+		if *filters.Skipped {
+			conditions = append(conditions, `d.skip_reason != ''`)
+		} else {
+			conditions = append(conditions, `d.skip_reason = ''`)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return `1`, nil
+	}
+
+	return strings.Join(conditions, ` AND `), args
+}
+
+// sortSQL returns the expression documents are sorted by and its
+// arguments. Timestamps are sorted as epoch milliseconds with missing
+// values placed last as OpenSearch does.
+func sortSQL(query db.Query, s *statement) (string, []interface{}) {
+	switch query.SortField() {
+	case db.SortKey:
+		return `d.file_key`, nil
+
+	case db.SortIndexedAt:
+		missing := float64(math.MinInt64)
+		if query.SortOrder() == db.OrderAsc {
+			missing = float64(math.MaxInt64)
+		}
+
+		return `COALESCE(CAST(d.indexed_at AS REAL), ?)`, []interface{}{missing}
+
+	default:
+		return s.score(), nil
+	}
+}
+
+// QueryDocuments implements the db.Databaser.QueryDocuments method
+// using SQLite with the same matching, filtering, sorting, and
+// pagination behavior as db.Client.
+func (c *Client) QueryDocuments(ctx context.Context, query db.Query) (*db.Result, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := &db.Result{
+		Hits: []db.Hit{},
+	}
+
+	if query.IsEmpty() {
+		return result, nil
+	}
+
+	s, err := c.compile(ctx, query)
+	if err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	with, withArgs := s.with()
+
+	if err := c.database.QueryRowContext(ctx,
+		with+`SELECT COUNT(*) FROM documents d WHERE `+s.condition,
+		append(withArgs, s.args...)...,
+	).Scan(&result.Total); err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	value, valueArgs := sortSQL(query, s)
+	order := `ASC`
+	comparison := `>`
+	if query.SortOrder() == db.OrderDesc {
+		order = `DESC`
+		comparison = `<`
+	}
+
+	args := append(append(append([]interface{}{}, withArgs...), valueArgs...), s.args...)
+	after := `1`
+	if query.Cursor != "" {
+		values, err := query.SearchAfter()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(values) == 2 {
+			after = `(value ` + comparison + ` ? OR (value = ? AND id > ?))`
+			args = append(args, values[0], values[0], values[1])
+		}
+	}
+	args = append(args, query.PageSize()+1)
+
+	rows, err := c.database.QueryContext(ctx,
+		with+`SELECT id, value FROM (SELECT d.id AS id, `+value+` AS value FROM documents d WHERE `+s.condition+`) WHERE `+after+` ORDER BY value `+order+`, id ASC LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	ids := []string{}
+	values := []interface{}{}
+	for rows.Next() {
+		var id string
+		var value interface{}
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return nil, &QueryDocumentsError{
+				err: err,
+			}
+		}
+
+		if integer, ok := value.(int64); ok {
+			value = float64(integer)
+		}
+
+		ids = append(ids, id)
+		values = append(values, value)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	if len(ids) > query.PageSize() {
+		ids = ids[:query.PageSize()]
+
+		next, err := query.EncodeCursor([]interface{}{values[len(ids)-1], ids[len(ids)-1]})
+		if err != nil {
+			return nil, &QueryDocumentsError{
+				err: err,
+			}
+		}
+		result.Next = next
+	}
+
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	documents, err := c.loadDocuments(ctx, ids)
+	if err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	highlights, err := c.highlights(ctx, s, ids)
+	if err != nil {
+		return nil, &QueryDocumentsError{
+			err: err,
+		}
+	}
+
+	for _, id := range ids {
+		loaded := documents[id]
+		result.Hits = append(result.Hits, db.Hit{
+			Document: loaded.document,
+			Matches:  loaded.matches(highlights),
+		})
+	}
+
+	return result, nil
+}
+
+// loaded holds a document read back from the database along with the
+// row of each of its lines.
+type loaded struct {
+	document pars.Document
+	rows     [][]int64
+}
+
+// matches returns the highlighted lines of the document in page and
+// line order.
+func (l loaded) matches(highlights map[int64]string) []db.Match {
+	output := []db.Match{}
+	for pageIndex, page := range l.document.Pages {
+		for lineIndex, line := range page.Lines {
+			highlight, ok := highlights[l.rows[pageIndex][lineIndex]]
+			if !ok {
+				continue
+			}
+
+			output = append(output, db.NewMatch(page.PageNumber, line, highlight))
+		}
+	}
+
+	return output
+}
+
+func (c *Client) loadDocuments(ctx context.Context, ids []string) (map[string]*loaded, error) {
+	output := map[string]*loaded{}
+	args := stringArgs(ids)
+
+	documentRows, err := c.database.QueryContext(ctx,
+		`SELECT id, entity, file_bucket, file_key, file_version, file_extension, content_type, last_modified, indexed_at, skip_reason FROM documents WHERE id IN `+placeholders(len(ids)),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer documentRows.Close()
+
+	for documentRows.Next() {
+		document := pars.Document{}
+		var lastModified, indexedAt sql.NullInt64
+		if err := documentRows.Scan(
+			&document.ID,
+			&document.Entity,
+			&document.FileBucket,
+			&document.FileKey,
+			&document.FileVersion,
+			&document.FileExtension,
+			&document.ContentType,
+			&lastModified,
+			&indexedAt,
+			&document.SkipReason,
+		); err != nil {
+			return nil, err
+		}
+
+		document.LastModified = fromUnixMilli(lastModified)
+		document.IndexedAt = fromUnixMilli(indexedAt)
+
+		output[document.ID] = &loaded{
+			document: document,
+		}
+	}
+
+	if err := documentRows.Err(); err != nil {
+		return nil, err
+	}
+	documentRows.Close()
+
+	pageRows, err := c.database.QueryContext(ctx,
+		`SELECT id, document_id, entity, page_number, tables, fields FROM pages WHERE document_id IN `+placeholders(len(ids))+` ORDER BY document_id, position`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer pageRows.Close()
+
+	pagePositions := map[string]int{}
+	for pageRows.Next() {
+		page := pars.Page{}
+		var documentID, tables, fields string
+		if err := pageRows.Scan(&page.ID, &documentID, &page.Entity, &page.PageNumber, &tables, &fields); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(tables), &page.Tables); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(fields), &page.Fields); err != nil {
+			return nil, err
+		}
+
+		document := output[documentID]
+		pagePositions[page.ID] = len(document.document.Pages)
+		document.document.Pages = append(document.document.Pages, page)
+		document.rows = append(document.rows, []int64{})
+	}
+
+	if err := pageRows.Err(); err != nil {
+		return nil, err
+	}
+	pageRows.Close()
+
+	lineRows, err := c.database.QueryContext(ctx,
+		`SELECT row, id, document_id, page_id, entity, text, confidence, coordinates, words FROM lines WHERE document_id IN `+placeholders(len(ids))+` ORDER BY document_id, page_id, position`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer lineRows.Close()
+
+	for lineRows.Next() {
+		line := pars.Line{}
+		var row int64
+		var documentID, pageID, coordinates, words string
+		if err := lineRows.Scan(&row, &line.ID, &documentID, &pageID, &line.Entity, &line.Text, &line.Confidence, &coordinates, &words); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(coordinates), &line.Coordinates); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(words), &line.Words); err != nil {
+			return nil, err
+		}
+
+		document := output[documentID]
+		position := pagePositions[pageID]
+		document.document.Pages[position].Lines = append(document.document.Pages[position].Lines, line)
+		document.rows[position] = append(document.rows[position], row)
+	}
+
+	return output, lineRows.Err()
+}
+
+// highlights returns the highlighted text of each matching line in
+// the documents keyed by line row. When several term clauses match a
+// line the highlight with the most highlighted terms is kept.
+func (c *Client) highlights(ctx context.Context, s *statement, ids []string) (map[int64]string, error) {
+	output := map[int64]string{}
+	for _, leaf := range s.leaves {
+		if leaf.negated {
+			continue
+		}
+
+		rows, err := c.database.QueryContext(ctx,
+			`SELECT l.row, highlight(lines_fts, 0, ?, ?) FROM lines_fts JOIN lines l ON l.row = lines_fts.rowid WHERE lines_fts MATCH ? AND l.confidence >= ? AND l.document_id IN `+placeholders(len(ids)),
+			append([]interface{}{highlightStart, highlightEnd, leaf.expression, s.minConfidence}, stringArgs(ids)...)...,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var row int64
+			var text string
+			if err := rows.Scan(&row, &text); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			highlight := strings.NewReplacer(highlightStart, "<em>", highlightEnd, "</em>").Replace(html.EscapeString(text))
+			if existing, ok := output[row]; !ok || strings.Count(highlight, "<em>") > strings.Count(existing, "<em>") {
+				output[row] = highlight
+			}
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
+
+func fromUnixMilli(value sql.NullInt64) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+
+	output := time.UnixMilli(value.Int64).UTC()
+	return &output
+}
+
+// terms splits text into lowercased terms the way the FTS5 tokenizer
+// configured on the lines table does.
+func terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_')
+	})
+}
+
+func quote(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
+
+// escapeGlob escapes the GLOB wildcard characters in text so that it
+// is matched literally.
+func escapeGlob(text string) string {
+	return strings.NewReplacer("[", "[[]", "*", "[*]", "?", "[?]").Replace(text)
+}
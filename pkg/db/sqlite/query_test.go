@@ -0,0 +1,59 @@
+package sqlite
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/forstmeier/findfile/pkg/db"
+)
+
+func Test_terms(t *testing.T) {
+	received := terms(`Total due: $250, ACME_Corp "café"`)
+	expected := []string{"total", "due", "250", "acme_corp", "café"}
+
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("incorrect terms, received: %v, expected: %v", received, expected)
+	}
+}
+
+func Test_escapeGlob(t *testing.T) {
+	received := escapeGlob("a[b]*c?")
+	expected := "a[[]b][*]c[?]"
+
+	if received != expected {
+		t.Errorf("incorrect pattern, received: %s, expected: %s", received, expected)
+	}
+}
+
+func Test_quote(t *testing.T) {
+	received := quote(`say "hi"`)
+	expected := `"say ""hi"""`
+
+	if received != expected {
+		t.Errorf("incorrect expression, received: %s, expected: %s", received, expected)
+	}
+}
+
+func Test_filtersSQL(t *testing.T) {
+	from := time.Date(2021, time.November, 1, 0, 0, 0, 0, time.UTC)
+
+	condition, args := filtersSQL(db.Filters{
+		Buckets:   []string{"invoices", "receipts"},
+		KeyPrefix: "2021/",
+		FileTypes: []string{".PDF", "image/png"},
+		IndexedAt: &db.DateRange{
+			From: &from,
+		},
+	})
+
+	expectedCondition := `d.file_bucket IN (?, ?) AND substr(d.file_key, 1, ?) = ? AND (d.file_extension IN (?) OR d.content_type IN (?)) AND d.indexed_at >= ?`
+	if condition != expectedCondition {
+		t.Errorf("incorrect condition, received: %s, expected: %s", condition, expectedCondition)
+	}
+
+	expectedArgs := []interface{}{"invoices", "receipts", 5, "2021/", "pdf", "image/png", from.UnixMilli()}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("incorrect args, received: %v, expected: %v", args, expectedArgs)
+	}
+}
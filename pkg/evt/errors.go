@@ -21,3 +21,23 @@ type PutEventValuesError struct {
 func (e *PutEventValuesError) Error() string {
 	return fmt.Sprintf(errorMessage, e.err.Error())
 }
+
+// ReadObjectsError wraps errors returned reading the objects in file
+// events in evt.ReadObjects and evt.ReadMessage.
+type ReadObjectsError struct {
+	err error
+}
+
+func (e *ReadObjectsError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// WriteMessageError wraps errors returned writing the objects to a queue
+// message body in evt.WriteMessage.
+type WriteMessageError struct {
+	err error
+}
+
+func (e *WriteMessageError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
@@ -30,3 +30,29 @@ func TestPutEventValuesError(t *testing.T) {
 		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
 	}
 }
+
+func TestReadObjectsError(t *testing.T) {
+	err := &ReadObjectsError{
+		err: errors.New("mock read objects error"),
+	}
+
+	recieved := err.Error()
+	expected := "package evt: mock read objects error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestWriteMessageError(t *testing.T) {
+	err := &WriteMessageError{
+		err: errors.New("mock write message error"),
+	}
+
+	recieved := err.Error()
+	expected := "package evt: mock write message error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
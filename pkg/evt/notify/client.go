@@ -0,0 +1,238 @@
+// Package notify implements evt.Eventer with S3 bucket event
+// notifications sent to an SQS queue or to EventBridge in place of
+// CloudTrail data events.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/forstmeier/findfile/pkg/evt"
+)
+
+// DefaultID is the notification configuration ID used for queue
+// destinations when none is provided.
+const DefaultID = "findfile"
+
+// Events are the S3 event types sent to queue destinations.
+var Events = []string{
+	s3.EventS3ObjectCreated,
+	s3.EventS3ObjectRemoved,
+}
+
+// Config holds the destination for the bucket notifications. When
+// QueueARN is set the events are sent to the queue under a notification
+// configuration with the ID, otherwise they are sent to EventBridge and
+// matched by the rule with RuleName.
+type Config struct {
+	ID       string
+	QueueARN string
+	RuleName string
+}
+
+var _ evt.Eventer = &Client{}
+
+// Client implements the evt.Eventer methods using S3 bucket event
+// notifications.
+//
+// Queue destinations are added as a queue configuration on each bucket
+// and removed by its ID. EventBridge delivery is a single switch on a
+// bucket that other consumers may rely on, so it is enabled when
+// buckets are added but never disabled; instead the buckets are added
+// to and removed from the bucket names matched by the rule, which is
+// disabled once no buckets remain.
+//
+// Other notification configurations on the buckets are preserved.
+type Client struct {
+	id       string
+	queueARN string
+	ruleName string
+	helper   helper
+}
+
+// New generates a notify.Client pointer instance with S3 bucket event
+// notifications.
+func New(newSession *session.Session, config Config) *Client {
+	id := config.ID
+	if id == "" {
+		id = DefaultID
+	}
+
+	return &Client{
+		id:       id,
+		queueARN: config.QueueARN,
+		ruleName: config.RuleName,
+		helper: &help{
+			s3Client:          s3.New(newSession),
+			eventbridgeClient: eventbridge.New(newSession),
+		},
+	}
+}
+
+// AddBucketListeners implements the evt.Eventer.AddBucketListeners method
+// using S3 bucket event notifications.
+func (c *Client) AddBucketListeners(ctx context.Context, buckets []string) error {
+	for _, bucket := range buckets {
+		current, err := c.helper.getNotifications(bucket)
+		if err != nil {
+			return &GetNotificationsError{
+				err: err,
+			}
+		}
+
+		if c.queueARN != "" {
+			queueConfigurations := c.otherQueueConfigurations(current.configuration.QueueConfigurations)
+			current.configuration.QueueConfigurations = append(queueConfigurations, &s3.QueueConfiguration{
+				Id:       aws.String(c.id),
+				QueueArn: aws.String(c.queueARN),
+				Events:   aws.StringSlice(Events),
+			})
+		} else if current.eventBridge {
+			continue
+		} else {
+			current.eventBridge = true
+		}
+
+		if err := c.helper.putNotifications(bucket, current); err != nil {
+			return &PutNotificationsError{
+				err: err,
+			}
+		}
+	}
+
+	if c.queueARN != "" {
+		return nil
+	}
+
+	return c.updateRule(buckets, nil)
+}
+
+// RemoveBucketListeners implements the evt.Eventer.RemoveBucketListeners
+// method using S3 bucket event notifications.
+func (c *Client) RemoveBucketListeners(ctx context.Context, buckets []string) error {
+	if c.queueARN == "" {
+		return c.updateRule(nil, buckets)
+	}
+
+	for _, bucket := range buckets {
+		current, err := c.helper.getNotifications(bucket)
+		if err != nil {
+			return &GetNotificationsError{
+				err: err,
+			}
+		}
+
+		queueConfigurations := c.otherQueueConfigurations(current.configuration.QueueConfigurations)
+		if len(queueConfigurations) == len(current.configuration.QueueConfigurations) {
+			continue
+		}
+		current.configuration.QueueConfigurations = queueConfigurations
+
+		if err := c.helper.putNotifications(bucket, current); err != nil {
+			return &PutNotificationsError{
+				err: err,
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) otherQueueConfigurations(queueConfigurations []*s3.QueueConfiguration) []*s3.QueueConfiguration {
+	others := []*s3.QueueConfiguration{}
+	for _, queueConfiguration := range queueConfigurations {
+		if aws.StringValue(queueConfiguration.Id) != c.id {
+			others = append(others, queueConfiguration)
+		}
+	}
+
+	return others
+}
+
+func (c *Client) updateRule(addBuckets, removeBuckets []string) error {
+	rule, err := c.helper.getRule(c.ruleName)
+	if err != nil {
+		return &GetRuleError{
+			err: err,
+		}
+	}
+
+	pattern := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(aws.StringValue(rule.EventPattern)), &pattern); err != nil {
+		return &RulePatternError{
+			err: err,
+		}
+	}
+
+	detail, _ := pattern["detail"].(map[string]interface{})
+	if detail == nil {
+		detail = map[string]interface{}{}
+		pattern["detail"] = detail
+	}
+
+	bucket, _ := detail["bucket"].(map[string]interface{})
+	if bucket == nil {
+		bucket = map[string]interface{}{}
+		detail["bucket"] = bucket
+	}
+
+	namesMap := map[string]struct{}{}
+	names, _ := bucket["name"].([]interface{})
+	for _, name := range names {
+		if value, ok := name.(string); ok {
+			namesMap[value] = struct{}{}
+		}
+	}
+
+	for _, addBucket := range addBuckets {
+		namesMap[addBucket] = struct{}{}
+	}
+
+	for _, removeBucket := range removeBuckets {
+		delete(namesMap, removeBucket)
+	}
+
+	newNames := []string{}
+	for name := range namesMap {
+		newNames = append(newNames, name)
+	}
+	sort.Strings(newNames)
+
+	state := eventbridge.RuleStateEnabled
+	if len(newNames) == 0 {
+		// event patterns may not hold empty lists so the last names are
+		// left in place on the disabled rule
+		state = eventbridge.RuleStateDisabled
+	} else {
+		bucket["name"] = newNames
+	}
+
+	eventPattern, err := json.Marshal(pattern)
+	if err != nil {
+		return &RulePatternError{
+			err: err,
+		}
+	}
+
+	if err := c.helper.putRule(&eventbridge.PutRuleInput{
+		Name:               rule.Name,
+		Description:        rule.Description,
+		EventBusName:       rule.EventBusName,
+		EventPattern:       aws.String(string(eventPattern)),
+		RoleArn:            rule.RoleArn,
+		ScheduleExpression: rule.ScheduleExpression,
+		State:              aws.String(state),
+	}); err != nil {
+		return &PutRuleError{
+			err: err,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,391 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type mockHelper struct {
+	mockGetNotificationsOutput   *notifications
+	mockGetNotificationsError    error
+	mockPutNotificationsReceived *notifications
+	mockPutNotificationsError    error
+	mockGetRuleOutput            *eventbridge.DescribeRuleOutput
+	mockGetRuleError             error
+	mockPutRuleReceived          *eventbridge.PutRuleInput
+	mockPutRuleError             error
+}
+
+func (mh *mockHelper) getNotifications(bucket string) (*notifications, error) {
+	return mh.mockGetNotificationsOutput, mh.mockGetNotificationsError
+}
+
+func (mh *mockHelper) putNotifications(bucket string, current *notifications) error {
+	mh.mockPutNotificationsReceived = current
+	return mh.mockPutNotificationsError
+}
+
+func (mh *mockHelper) getRule(ruleName string) (*eventbridge.DescribeRuleOutput, error) {
+	return mh.mockGetRuleOutput, mh.mockGetRuleError
+}
+
+func (mh *mockHelper) putRule(input *eventbridge.PutRuleInput) error {
+	mh.mockPutRuleReceived = input
+	return mh.mockPutRuleError
+}
+
+func queueConfiguration(id, queueARN string) *s3.QueueConfiguration {
+	return &s3.QueueConfiguration{
+		Id:       aws.String(id),
+		QueueArn: aws.String(queueARN),
+		Events:   aws.StringSlice(Events),
+	}
+}
+
+func rule(eventPattern string) *eventbridge.DescribeRuleOutput {
+	return &eventbridge.DescribeRuleOutput{
+		Name:         aws.String("rule"),
+		Description:  aws.String("description"),
+		EventPattern: aws.String(eventPattern),
+		State:        aws.String(eventbridge.RuleStateEnabled),
+	}
+}
+
+func putRule(eventPattern, state string) *eventbridge.PutRuleInput {
+	return &eventbridge.PutRuleInput{
+		Name:         aws.String("rule"),
+		Description:  aws.String("description"),
+		EventPattern: aws.String(eventPattern),
+		State:        aws.String(state),
+	}
+}
+
+func TestAddBucketListeners(t *testing.T) {
+	otherQueue := queueConfiguration("other", "arn:aws:sqs:us-east-1:123456789012:other")
+
+	tests := []struct {
+		description                  string
+		queueARN                     string
+		mockGetNotificationsOutput   *notifications
+		mockGetNotificationsError    error
+		mockPutNotificationsReceived *notifications
+		mockPutNotificationsError    error
+		mockGetRuleOutput            *eventbridge.DescribeRuleOutput
+		mockGetRuleError             error
+		mockPutRuleReceived          *eventbridge.PutRuleInput
+		mockPutRuleError             error
+		error                        error
+	}{
+		{
+			description:                "get notifications error",
+			queueARN:                   "queue",
+			mockGetNotificationsOutput: nil,
+			mockGetNotificationsError:  errors.New("mock get notifications error"),
+			error:                      &GetNotificationsError{},
+		},
+		{
+			description: "put notifications error",
+			queueARN:    "queue",
+			mockGetNotificationsOutput: &notifications{
+				configuration: &s3.NotificationConfiguration{},
+			},
+			mockPutNotificationsError: errors.New("mock put notifications error"),
+			error:                     &PutNotificationsError{},
+		},
+		{
+			description: "successful queue invocation preserving other configurations",
+			queueARN:    "queue",
+			mockGetNotificationsOutput: &notifications{
+				configuration: &s3.NotificationConfiguration{
+					QueueConfigurations: []*s3.QueueConfiguration{
+						otherQueue,
+						queueConfiguration(DefaultID, "old_queue"),
+					},
+				},
+				eventBridge: true,
+			},
+			mockPutNotificationsReceived: &notifications{
+				configuration: &s3.NotificationConfiguration{
+					QueueConfigurations: []*s3.QueueConfiguration{
+						otherQueue,
+						queueConfiguration(DefaultID, "queue"),
+					},
+				},
+				eventBridge: true,
+			},
+			error: nil,
+		},
+		{
+			description: "get rule error",
+			queueARN:    "",
+			mockGetNotificationsOutput: &notifications{
+				configuration: &s3.NotificationConfiguration{},
+				eventBridge:   true,
+			},
+			mockGetRuleError: errors.New("mock get rule error"),
+			error:            &GetRuleError{},
+		},
+		{
+			description: "rule pattern error",
+			queueARN:    "",
+			mockGetNotificationsOutput: &notifications{
+				configuration: &s3.NotificationConfiguration{},
+				eventBridge:   true,
+			},
+			mockGetRuleOutput: rule("not json"),
+			error:             &RulePatternError{},
+		},
+		{
+			description: "put rule error",
+			queueARN:    "",
+			mockGetNotificationsOutput: &notifications{
+				configuration: &s3.NotificationConfiguration{},
+				eventBridge:   true,
+			},
+			mockGetRuleOutput: rule(`{"source":["aws.s3"]}`),
+			mockPutRuleError:  errors.New("mock put rule error"),
+			error:             &PutRuleError{},
+		},
+		{
+			description: "successful eventbridge invocation enabling delivery",
+			queueARN:    "",
+			mockGetNotificationsOutput: &notifications{
+				configuration: &s3.NotificationConfiguration{
+					QueueConfigurations: []*s3.QueueConfiguration{otherQueue},
+				},
+				eventBridge: false,
+			},
+			mockPutNotificationsReceived: &notifications{
+				configuration: &s3.NotificationConfiguration{
+					QueueConfigurations: []*s3.QueueConfiguration{otherQueue},
+				},
+				eventBridge: true,
+			},
+			mockGetRuleOutput:   rule(`{"source":["aws.s3"]}`),
+			mockPutRuleReceived: putRule(`{"detail":{"bucket":{"name":["new_bucket"]}},"source":["aws.s3"]}`, eventbridge.RuleStateEnabled),
+			error:               nil,
+		},
+		{
+			description: "successful eventbridge invocation already enabled",
+			queueARN:    "",
+			mockGetNotificationsOutput: &notifications{
+				configuration: &s3.NotificationConfiguration{},
+				eventBridge:   true,
+			},
+			mockPutNotificationsReceived: nil,
+			mockGetRuleOutput:            rule(`{"detail":{"bucket":{"name":["old_bucket"]}},"source":["aws.s3"]}`),
+			mockPutRuleReceived:          putRule(`{"detail":{"bucket":{"name":["new_bucket","old_bucket"]}},"source":["aws.s3"]}`, eventbridge.RuleStateEnabled),
+			error:                        nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			h := &mockHelper{
+				mockGetNotificationsOutput: test.mockGetNotificationsOutput,
+				mockGetNotificationsError:  test.mockGetNotificationsError,
+				mockPutNotificationsError:  test.mockPutNotificationsError,
+				mockGetRuleOutput:          test.mockGetRuleOutput,
+				mockGetRuleError:           test.mockGetRuleError,
+				mockPutRuleError:           test.mockPutRuleError,
+			}
+
+			c := &Client{
+				id:       DefaultID,
+				queueARN: test.queueARN,
+				ruleName: "rule",
+				helper:   h,
+			}
+
+			err := c.AddBucketListeners(context.Background(), []string{"new_bucket"})
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *GetNotificationsError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *PutNotificationsError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *GetRuleError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *RulePatternError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *PutRuleError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else {
+				if test.error != nil {
+					t.Fatalf("incorrect error, received: nil, expected: %v", test.error)
+				}
+
+				if !reflect.DeepEqual(h.mockPutNotificationsReceived, test.mockPutNotificationsReceived) {
+					t.Errorf("incorrect notifications, received: %+v, expected: %+v", h.mockPutNotificationsReceived, test.mockPutNotificationsReceived)
+				}
+
+				if !reflect.DeepEqual(h.mockPutRuleReceived, test.mockPutRuleReceived) {
+					t.Errorf("incorrect rule, received: %v, expected: %v", h.mockPutRuleReceived, test.mockPutRuleReceived)
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveBucketListeners(t *testing.T) {
+	otherQueue := queueConfiguration("other", "arn:aws:sqs:us-east-1:123456789012:other")
+
+	tests := []struct {
+		description                  string
+		queueARN                     string
+		mockGetNotificationsOutput   *notifications
+		mockGetNotificationsError    error
+		mockPutNotificationsReceived *notifications
+		mockPutNotificationsError    error
+		mockGetRuleOutput            *eventbridge.DescribeRuleOutput
+		mockGetRuleError             error
+		mockPutRuleReceived          *eventbridge.PutRuleInput
+		mockPutRuleError             error
+		error                        error
+	}{
+		{
+			description:                "get notifications error",
+			queueARN:                   "queue",
+			mockGetNotificationsOutput: nil,
+			mockGetNotificationsError:  errors.New("mock get notifications error"),
+			error:                      &GetNotificationsError{},
+		},
+		{
+			description: "put notifications error",
+			queueARN:    "queue",
+			mockGetNotificationsOutput: &notifications{
+				configuration: &s3.NotificationConfiguration{
+					QueueConfigurations: []*s3.QueueConfiguration{
+						queueConfiguration(DefaultID, "queue"),
+					},
+				},
+			},
+			mockPutNotificationsError: errors.New("mock put notifications error"),
+			error:                     &PutNotificationsError{},
+		},
+		{
+			description: "successful queue invocation without configuration",
+			queueARN:    "queue",
+			mockGetNotificationsOutput: &notifications{
+				configuration: &s3.NotificationConfiguration{
+					QueueConfigurations: []*s3.QueueConfiguration{otherQueue},
+				},
+			},
+			mockPutNotificationsReceived: nil,
+			error:                        nil,
+		},
+		{
+			description: "successful queue invocation preserving other configurations",
+			queueARN:    "queue",
+			mockGetNotificationsOutput: &notifications{
+				configuration: &s3.NotificationConfiguration{
+					QueueConfigurations: []*s3.QueueConfiguration{
+						otherQueue,
+						queueConfiguration(DefaultID, "queue"),
+					},
+				},
+				eventBridge: true,
+			},
+			mockPutNotificationsReceived: &notifications{
+				configuration: &s3.NotificationConfiguration{
+					QueueConfigurations: []*s3.QueueConfiguration{otherQueue},
+				},
+				eventBridge: true,
+			},
+			error: nil,
+		},
+		{
+			description:      "get rule error",
+			queueARN:         "",
+			mockGetRuleError: errors.New("mock get rule error"),
+			error:            &GetRuleError{},
+		},
+		{
+			description:         "successful eventbridge invocation",
+			queueARN:            "",
+			mockGetRuleOutput:   rule(`{"detail":{"bucket":{"name":["old_bucket","remove_bucket"]}},"source":["aws.s3"]}`),
+			mockPutRuleReceived: putRule(`{"detail":{"bucket":{"name":["old_bucket"]}},"source":["aws.s3"]}`, eventbridge.RuleStateEnabled),
+			error:               nil,
+		},
+		{
+			description:         "successful eventbridge invocation disabling rule",
+			queueARN:            "",
+			mockGetRuleOutput:   rule(`{"detail":{"bucket":{"name":["remove_bucket"]}},"source":["aws.s3"]}`),
+			mockPutRuleReceived: putRule(`{"detail":{"bucket":{"name":["remove_bucket"]}},"source":["aws.s3"]}`, eventbridge.RuleStateDisabled),
+			error:               nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			h := &mockHelper{
+				mockGetNotificationsOutput: test.mockGetNotificationsOutput,
+				mockGetNotificationsError:  test.mockGetNotificationsError,
+				mockPutNotificationsError:  test.mockPutNotificationsError,
+				mockGetRuleOutput:          test.mockGetRuleOutput,
+				mockGetRuleError:           test.mockGetRuleError,
+				mockPutRuleError:           test.mockPutRuleError,
+			}
+
+			c := &Client{
+				id:       DefaultID,
+				queueARN: test.queueARN,
+				ruleName: "rule",
+				helper:   h,
+			}
+
+			err := c.RemoveBucketListeners(context.Background(), []string{"remove_bucket"})
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *GetNotificationsError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *PutNotificationsError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *GetRuleError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else {
+				if test.error != nil {
+					t.Fatalf("incorrect error, received: nil, expected: %v", test.error)
+				}
+
+				if !reflect.DeepEqual(h.mockPutNotificationsReceived, test.mockPutNotificationsReceived) {
+					t.Errorf("incorrect notifications, received: %+v, expected: %+v", h.mockPutNotificationsReceived, test.mockPutNotificationsReceived)
+				}
+
+				if !reflect.DeepEqual(h.mockPutRuleReceived, test.mockPutRuleReceived) {
+					t.Errorf("incorrect rule, received: %v, expected: %v", h.mockPutRuleReceived, test.mockPutRuleReceived)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package notify
+
+import "fmt"
+
+const errorMessage = "package notify: %s"
+
+// GetNotificationsError wraps errors returned by
+// notify.helper.getNotifications.
+type GetNotificationsError struct {
+	err error
+}
+
+func (e *GetNotificationsError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// PutNotificationsError wraps errors returned by
+// notify.helper.putNotifications.
+type PutNotificationsError struct {
+	err error
+}
+
+func (e *PutNotificationsError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// GetRuleError wraps errors returned by notify.helper.getRule.
+type GetRuleError struct {
+	err error
+}
+
+func (e *GetRuleError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// PutRuleError wraps errors returned by notify.helper.putRule.
+type PutRuleError struct {
+	err error
+}
+
+func (e *PutRuleError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// RulePatternError wraps errors returned reading and writing the event
+// pattern of the EventBridge rule.
+type RulePatternError struct {
+	err error
+}
+
+func (e *RulePatternError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
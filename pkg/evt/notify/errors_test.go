@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetNotificationsError(t *testing.T) {
+	err := &GetNotificationsError{
+		err: errors.New("mock get notifications error"),
+	}
+
+	recieved := err.Error()
+	expected := "package notify: mock get notifications error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestPutNotificationsError(t *testing.T) {
+	err := &PutNotificationsError{
+		err: errors.New("mock put notifications error"),
+	}
+
+	recieved := err.Error()
+	expected := "package notify: mock put notifications error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestGetRuleError(t *testing.T) {
+	err := &GetRuleError{
+		err: errors.New("mock get rule error"),
+	}
+
+	recieved := err.Error()
+	expected := "package notify: mock get rule error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestPutRuleError(t *testing.T) {
+	err := &PutRuleError{
+		err: errors.New("mock put rule error"),
+	}
+
+	recieved := err.Error()
+	expected := "package notify: mock put rule error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestRulePatternError(t *testing.T) {
+	err := &RulePatternError{
+		err: errors.New("mock rule pattern error"),
+	}
+
+	recieved := err.Error()
+	expected := "package notify: mock rule pattern error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/private/protocol/restxml"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// eventBridgeElement enables EventBridge delivery in a bucket
+// notification configuration. The element is not modeled by the AWS SDK
+// version in use so it is read from and written to the raw XML bodies.
+const eventBridgeElement = "<EventBridgeConfiguration></EventBridgeConfiguration>"
+
+var notificationEnd = []byte("</NotificationConfiguration>")
+
+// notifications holds the notification configuration of a bucket.
+type notifications struct {
+	configuration *s3.NotificationConfiguration
+	eventBridge   bool
+}
+
+type helper interface {
+	getNotifications(bucket string) (*notifications, error)
+	putNotifications(bucket string, current *notifications) error
+	getRule(ruleName string) (*eventbridge.DescribeRuleOutput, error)
+	putRule(input *eventbridge.PutRuleInput) error
+}
+
+type help struct {
+	s3Client          s3Client
+	eventbridgeClient eventbridgeClient
+}
+
+type s3Client interface {
+	GetBucketNotificationConfigurationRequest(input *s3.GetBucketNotificationConfigurationRequest) (*request.Request, *s3.NotificationConfiguration)
+	PutBucketNotificationConfigurationRequest(input *s3.PutBucketNotificationConfigurationInput) (*request.Request, *s3.PutBucketNotificationConfigurationOutput)
+}
+
+type eventbridgeClient interface {
+	DescribeRule(input *eventbridge.DescribeRuleInput) (*eventbridge.DescribeRuleOutput, error)
+	PutRule(input *eventbridge.PutRuleInput) (*eventbridge.PutRuleOutput, error)
+}
+
+func (h *help) getNotifications(bucket string) (*notifications, error) {
+	eventBridge := false
+
+	req, output := h.s3Client.GetBucketNotificationConfigurationRequest(&s3.GetBucketNotificationConfigurationRequest{
+		Bucket: &bucket,
+	})
+	req.Handlers.Unmarshal.Swap(restxml.UnmarshalHandler.Name, request.NamedHandler{
+		Name: restxml.UnmarshalHandler.Name,
+		Fn: func(r *request.Request) {
+			defer r.HTTPResponse.Body.Close()
+			body, err := ioutil.ReadAll(r.HTTPResponse.Body)
+			if err != nil {
+				r.Error = awserr.New(request.ErrCodeSerialization, "failed reading notification configuration", err)
+				return
+			}
+
+			eventBridge = hasEventBridge(body)
+
+			r.HTTPResponse.Body = ioutil.NopCloser(bytes.NewReader(body))
+			restxml.Unmarshal(r)
+		},
+	})
+
+	if err := req.Send(); err != nil {
+		return nil, err
+	}
+
+	return &notifications{
+		configuration: output,
+		eventBridge:   eventBridge,
+	}, nil
+}
+
+func (h *help) putNotifications(bucket string, current *notifications) error {
+	req, _ := h.s3Client.PutBucketNotificationConfigurationRequest(&s3.PutBucketNotificationConfigurationInput{
+		Bucket:                    &bucket,
+		NotificationConfiguration: current.configuration,
+	})
+	req.Handlers.Build.Swap(restxml.BuildHandler.Name, request.NamedHandler{
+		Name: restxml.BuildHandler.Name,
+		Fn: func(r *request.Request) {
+			restxml.Build(r)
+			if r.Error != nil || !current.eventBridge {
+				return
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				r.Error = awserr.New(request.ErrCodeSerialization, "failed reading notification configuration", err)
+				return
+			}
+
+			r.SetBufferBody(addEventBridge(body))
+		},
+	})
+
+	return req.Send()
+}
+
+func (h *help) getRule(ruleName string) (*eventbridge.DescribeRuleOutput, error) {
+	return h.eventbridgeClient.DescribeRule(&eventbridge.DescribeRuleInput{
+		Name: &ruleName,
+	})
+}
+
+func (h *help) putRule(input *eventbridge.PutRuleInput) error {
+	_, err := h.eventbridgeClient.PutRule(input)
+	return err
+}
+
+func hasEventBridge(body []byte) bool {
+	configuration := struct {
+		EventBridgeConfiguration *struct{} `xml:"EventBridgeConfiguration"`
+	}{}
+	if err := xml.Unmarshal(body, &configuration); err != nil {
+		return false
+	}
+
+	return configuration.EventBridgeConfiguration != nil
+}
+
+func addEventBridge(body []byte) []byte {
+	index := bytes.LastIndex(body, notificationEnd)
+	if index < 0 {
+		return body
+	}
+
+	output := make([]byte, 0, len(body)+len(eventBridgeElement))
+	output = append(output, body[:index]...)
+	output = append(output, eventBridgeElement...)
+	output = append(output, body[index:]...)
+
+	return output
+}
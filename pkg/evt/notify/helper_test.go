@@ -0,0 +1,300 @@
+package notify
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	testQueueConfiguration = `<QueueConfiguration><Id>other</Id><Queue>arn:aws:sqs:us-east-1:123456789012:other</Queue><Event>s3:ObjectCreated:*</Event></QueueConfiguration>`
+	testNotificationStart  = `<NotificationConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">`
+)
+
+func newTestS3Client(t *testing.T, handler http.HandlerFunc) *s3.S3 {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	newSession := session.Must(session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		DisableSSL:       aws.Bool(true),
+		MaxRetries:       aws.Int(0),
+	}))
+
+	return s3.New(newSession)
+}
+
+type mockEventBridgeClient struct {
+	mockDescribeRuleOutput *eventbridge.DescribeRuleOutput
+	mockDescribeRuleError  error
+	mockPutRuleError       error
+}
+
+func (mc *mockEventBridgeClient) DescribeRule(input *eventbridge.DescribeRuleInput) (*eventbridge.DescribeRuleOutput, error) {
+	return mc.mockDescribeRuleOutput, mc.mockDescribeRuleError
+}
+
+func (mc *mockEventBridgeClient) PutRule(input *eventbridge.PutRuleInput) (*eventbridge.PutRuleOutput, error) {
+	return nil, mc.mockPutRuleError
+}
+
+func Test_getNotifications(t *testing.T) {
+	tests := []struct {
+		description   string
+		status        int
+		body          string
+		queueIDs      []string
+		eventBridge   bool
+		errorExpected bool
+	}{
+		{
+			description:   "error getting notification configuration",
+			status:        http.StatusForbidden,
+			body:          `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`,
+			errorExpected: true,
+		},
+		{
+			description: "empty notification configuration received",
+			status:      http.StatusOK,
+			body:        testNotificationStart + `</NotificationConfiguration>`,
+			queueIDs:    []string{},
+			eventBridge: false,
+		},
+		{
+			description: "successful invocation with eventbridge enabled",
+			status:      http.StatusOK,
+			body:        testNotificationStart + testQueueConfiguration + `<EventBridgeConfiguration/></NotificationConfiguration>`,
+			queueIDs:    []string{"other"},
+			eventBridge: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			s3Client := newTestS3Client(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			})
+
+			h := &help{
+				s3Client: s3Client,
+			}
+
+			current, err := h.getNotifications("bucket")
+
+			if test.errorExpected {
+				if err == nil {
+					t.Fatal("incorrect error, received: nil, expected: error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("incorrect error, received: %v, expected: nil", err)
+			}
+
+			queueIDs := []string{}
+			for _, queueConfiguration := range current.configuration.QueueConfigurations {
+				queueIDs = append(queueIDs, aws.StringValue(queueConfiguration.Id))
+			}
+
+			if !reflect.DeepEqual(queueIDs, test.queueIDs) {
+				t.Errorf("incorrect queue ids, received: %v, expected: %v", queueIDs, test.queueIDs)
+			}
+
+			if current.eventBridge != test.eventBridge {
+				t.Errorf("incorrect eventbridge, received: %t, expected: %t", current.eventBridge, test.eventBridge)
+			}
+		})
+	}
+}
+
+func Test_putNotifications(t *testing.T) {
+	tests := []struct {
+		description   string
+		status        int
+		eventBridge   bool
+		errorExpected bool
+	}{
+		{
+			description:   "error putting notification configuration",
+			status:        http.StatusForbidden,
+			errorExpected: true,
+		},
+		{
+			description: "successful invocation without eventbridge",
+			status:      http.StatusOK,
+			eventBridge: false,
+		},
+		{
+			description: "successful invocation with eventbridge",
+			status:      http.StatusOK,
+			eventBridge: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			body := ""
+			s3Client := newTestS3Client(t, func(w http.ResponseWriter, r *http.Request) {
+				data, _ := ioutil.ReadAll(r.Body)
+				body = string(data)
+				w.WriteHeader(test.status)
+			})
+
+			h := &help{
+				s3Client: s3Client,
+			}
+
+			err := h.putNotifications("bucket", &notifications{
+				configuration: &s3.NotificationConfiguration{
+					QueueConfigurations: []*s3.QueueConfiguration{
+						queueConfiguration(DefaultID, "queue"),
+					},
+				},
+				eventBridge: test.eventBridge,
+			})
+
+			if test.errorExpected {
+				if err == nil {
+					t.Fatal("incorrect error, received: nil, expected: error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("incorrect error, received: %v, expected: nil", err)
+			}
+
+			if !strings.Contains(body, "<Id>"+DefaultID+"</Id>") {
+				t.Errorf("incorrect body, received: %s, expected queue configuration", body)
+			}
+
+			if hasEventBridge([]byte(body)) != test.eventBridge {
+				t.Errorf("incorrect eventbridge, received: %s, expected: %t", body, test.eventBridge)
+			}
+		})
+	}
+}
+
+func Test_notificationsRoundTrip(t *testing.T) {
+	stored := testNotificationStart +
+		`<TopicConfiguration><Id>topic</Id><Topic>arn:aws:sns:us-east-1:123456789012:topic</Topic><Event>s3:ObjectRemoved:*</Event></TopicConfiguration>` +
+		testQueueConfiguration +
+		`<CloudFunctionConfiguration><Id>lambda</Id><CloudFunction>arn:aws:lambda:us-east-1:123456789012:function:lambda</CloudFunction><Event>s3:ObjectCreated:Put</Event></CloudFunctionConfiguration>` +
+		`<EventBridgeConfiguration/></NotificationConfiguration>`
+
+	s3Client := newTestS3Client(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			data, _ := ioutil.ReadAll(r.Body)
+			stored = string(data)
+			return
+		}
+		w.Write([]byte(stored))
+	})
+
+	h := &help{
+		s3Client: s3Client,
+	}
+
+	original, err := h.getNotifications("bucket")
+	if err != nil {
+		t.Fatalf("incorrect error, received: %v, expected: nil", err)
+	}
+
+	if err := h.putNotifications("bucket", original); err != nil {
+		t.Fatalf("incorrect error, received: %v, expected: nil", err)
+	}
+
+	if count := strings.Count(stored, "<EventBridgeConfiguration"); count != 1 {
+		t.Errorf("incorrect eventbridge count, received: %d, expected: 1", count)
+	}
+
+	for _, element := range []string{"<Id>topic</Id>", "<Id>other</Id>", "<Id>lambda</Id>"} {
+		if !strings.Contains(stored, element) {
+			t.Errorf("incorrect body, received: %s, expected: %s", stored, element)
+		}
+	}
+
+	current, err := h.getNotifications("bucket")
+	if err != nil {
+		t.Fatalf("incorrect error, received: %v, expected: nil", err)
+	}
+
+	if !reflect.DeepEqual(current, original) {
+		t.Errorf("incorrect notifications, received: %+v, expected: %+v", current, original)
+	}
+}
+
+func Test_getRule(t *testing.T) {
+	mockError := errors.New("mock describe rule error")
+
+	c := &mockEventBridgeClient{
+		mockDescribeRuleError: mockError,
+	}
+
+	h := &help{
+		eventbridgeClient: c,
+	}
+
+	if _, err := h.getRule("rule"); err != mockError {
+		t.Errorf("incorrect error, received: %v, expected: %v", err, mockError)
+	}
+}
+
+func Test_putRule(t *testing.T) {
+	mockError := errors.New("mock put rule error")
+
+	c := &mockEventBridgeClient{
+		mockPutRuleError: mockError,
+	}
+
+	h := &help{
+		eventbridgeClient: c,
+	}
+
+	if err := h.putRule(&eventbridge.PutRuleInput{}); err != mockError {
+		t.Errorf("incorrect error, received: %v, expected: %v", err, mockError)
+	}
+}
+
+func Test_addEventBridge(t *testing.T) {
+	tests := []struct {
+		description string
+		body        string
+		output      string
+	}{
+		{
+			description: "no closing element",
+			body:        "<Other></Other>",
+			output:      "<Other></Other>",
+		},
+		{
+			description: "successful invocation",
+			body:        testNotificationStart + testQueueConfiguration + "</NotificationConfiguration>",
+			output:      testNotificationStart + testQueueConfiguration + eventBridgeElement + "</NotificationConfiguration>",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			output := string(addEventBridge([]byte(test.body)))
+			if output != test.output {
+				t.Errorf("incorrect output, received: %s, expected: %s", output, test.output)
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package evt
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Object actions read from file events.
+const (
+	PutObject    = "put"
+	DeleteObject = "delete"
+)
+
+// EventBridge detail types of S3 event notifications.
+const (
+	objectCreated = "Object Created"
+	objectDeleted = "Object Deleted"
+)
+
+// CloudTrailEvents maps the names of the CloudTrail events for the S3
+// API calls that write and delete objects to their object actions.
+var CloudTrailEvents = map[string]string{
+	"PutObject":               PutObject,
+	"PostObject":              PutObject,
+	"CopyObject":              PutObject,
+	"CompleteMultipartUpload": PutObject,
+	"DeleteObject":            DeleteObject,
+	"DeleteObjects":           DeleteObject,
+}
+
+// Object holds a file written to or deleted from a bucket. Sequencer
+// orders the S3 events for the same key and is empty for events that
+// do not carry one, such as CloudTrail events.
+type Object struct {
+	Action    string
+	Bucket    string
+	Key       string
+	Sequencer string
+}
+
+// Notification holds the file events the functions are invoked with.
+// CloudTrail and S3 events are received from EventBridge in the embedded
+// CloudWatchEvent and S3 event notification records are received
+// directly or within SQS messages in Records.
+type Notification struct {
+	events.CloudWatchEvent
+	Records []NotificationRecord `json:"Records"`
+}
+
+// NotificationRecord holds an S3 event notification record or an SQS
+// message whose Body holds a Notification.
+type NotificationRecord struct {
+	events.S3EventRecord
+	Body string `json:"body"`
+}
+
+type detailsPayload struct {
+	EventName         string            `json:"eventName"`
+	RequestParameters requestParameters `json:"requestParameters"`
+}
+
+type requestParameters struct {
+	BucketName string           `json:"bucketName"`
+	Key        string           `json:"key"`
+	Delete     deleteParameters `json:"delete"`
+}
+
+// deleteParameters holds the keys in the request body of a DeleteObjects
+// call. CloudTrail converts the XML body to JSON, so a single key is an
+// object rather than a list and the body may be recorded under "Delete"
+// alongside an empty "delete" query parameter; values that are not
+// objects are ignored.
+type deleteParameters struct {
+	Object deleteObjects `json:"Object"`
+}
+
+func (d *deleteParameters) UnmarshalJSON(data []byte) error {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return nil
+	}
+
+	parameters := struct {
+		Object deleteObjects `json:"Object"`
+	}{}
+	if err := json.Unmarshal(data, &parameters); err != nil {
+		return err
+	}
+
+	if len(parameters.Object) > 0 {
+		d.Object = parameters.Object
+	}
+
+	return nil
+}
+
+type deleteObject struct {
+	Key string `json:"Key"`
+}
+
+type deleteObjects []deleteObject
+
+func (d *deleteObjects) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		object := deleteObject{}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return err
+		}
+
+		*d = deleteObjects{object}
+		return nil
+	}
+
+	objects := []deleteObject{}
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return err
+	}
+
+	*d = objects
+	return nil
+}
+
+type objectDetailsPayload struct {
+	Bucket struct {
+		Name string `json:"name"`
+	} `json:"bucket"`
+	Object struct {
+		Key       string `json:"key"`
+		Sequencer string `json:"sequencer,omitempty"`
+	} `json:"object"`
+}
+
+// messagePayload holds the S3 event notification records written by
+// WriteMessage.
+type messagePayload struct {
+	Records []messageRecord `json:"Records"`
+}
+
+type messageRecord struct {
+	EventSource string               `json:"eventSource"`
+	EventName   string               `json:"eventName"`
+	S3          objectDetailsPayload `json:"s3"`
+}
+
+// WriteMessage returns a queue message body holding the objects as S3
+// event notification records, which ReadMessage reads back in the same
+// order.
+func WriteMessage(objects []Object) (string, error) {
+	payload := messagePayload{
+		Records: []messageRecord{},
+	}
+
+	for _, object := range objects {
+		eventName := "ObjectCreated:Put"
+		if object.Action == DeleteObject {
+			eventName = "ObjectRemoved:Delete"
+		}
+
+		record := messageRecord{
+			EventSource: "aws:s3",
+			EventName:   eventName,
+		}
+		record.S3.Bucket.Name = object.Bucket
+		record.S3.Object.Key = url.QueryEscape(object.Key)
+		record.S3.Object.Sequencer = object.Sequencer
+
+		payload.Records = append(payload.Records, record)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", &WriteMessageError{
+			err: err,
+		}
+	}
+
+	return string(body), nil
+}
+
+// ReadMessage returns the objects in a queue message body holding a
+// Notification, such as S3 event notifications or EventBridge events
+// sent to SQS.
+func ReadMessage(body string) ([]Object, error) {
+	notification := Notification{}
+	if err := json.Unmarshal([]byte(body), &notification); err != nil {
+		return nil, &ReadObjectsError{
+			err: err,
+		}
+	}
+
+	return ReadObjects(notification)
+}
+
+// ReadObjects returns the objects written and deleted in the
+// notification in the order they are received. Events for other
+// activity, including S3 test events, return no objects.
+func ReadObjects(notification Notification) ([]Object, error) {
+	if notification.Records != nil {
+		objects := []Object{}
+		for _, record := range notification.Records {
+			recordObjects := []Object{}
+			var err error
+			if record.EventSource == "aws:sqs" {
+				recordObjects, err = ReadMessage(record.Body)
+			} else {
+				recordObjects, err = readRecordObjects(record.S3EventRecord)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			objects = append(objects, recordObjects...)
+		}
+
+		return objects, nil
+	}
+
+	if notification.DetailType == objectCreated || notification.DetailType == objectDeleted {
+		detailsJSON := objectDetailsPayload{}
+		if err := json.Unmarshal(notification.Detail, &detailsJSON); err != nil {
+			return nil, &ReadObjectsError{
+				err: err,
+			}
+		}
+
+		key, err := url.QueryUnescape(detailsJSON.Object.Key)
+		if err != nil {
+			return nil, &ReadObjectsError{
+				err: err,
+			}
+		}
+
+		action := PutObject
+		if notification.DetailType == objectDeleted {
+			action = DeleteObject
+		}
+
+		return []Object{
+			{
+				Action:    action,
+				Bucket:    detailsJSON.Bucket.Name,
+				Key:       key,
+				Sequencer: detailsJSON.Object.Sequencer,
+			},
+		}, nil
+	}
+
+	if notification.Detail == nil {
+		return []Object{}, nil
+	}
+
+	detailsJSON := detailsPayload{}
+	if err := json.Unmarshal(notification.Detail, &detailsJSON); err != nil {
+		return nil, &ReadObjectsError{
+			err: err,
+		}
+	}
+
+	action, ok := CloudTrailEvents[detailsJSON.EventName]
+	if !ok {
+		return []Object{}, nil
+	}
+
+	parameters := detailsJSON.RequestParameters
+
+	if detailsJSON.EventName == "DeleteObjects" {
+		objects := []Object{}
+		for _, object := range parameters.Delete.Object {
+			objects = append(objects, Object{
+				Action: action,
+				Bucket: parameters.BucketName,
+				Key:    object.Key,
+			})
+		}
+
+		return objects, nil
+	}
+
+	return []Object{
+		{
+			Action: action,
+			Bucket: parameters.BucketName,
+			Key:    parameters.Key,
+		},
+	}, nil
+}
+
+// readRecordObjects returns the object in the S3 event notification
+// record, whose event names are ObjectCreated:* and ObjectRemoved:* types
+// without the "s3:" prefix and whose keys are URL encoded.
+func readRecordObjects(record events.S3EventRecord) ([]Object, error) {
+	action := ""
+	if strings.HasPrefix(record.EventName, "ObjectCreated:") {
+		action = PutObject
+	} else if strings.HasPrefix(record.EventName, "ObjectRemoved:") {
+		action = DeleteObject
+	} else {
+		return []Object{}, nil
+	}
+
+	key, err := url.QueryUnescape(record.S3.Object.Key)
+	if err != nil {
+		return nil, &ReadObjectsError{
+			err: err,
+		}
+	}
+
+	return []Object{
+		{
+			Action:    action,
+			Bucket:    record.S3.Bucket.Name,
+			Key:       key,
+			Sequencer: record.S3.Object.Sequencer,
+		},
+	}, nil
+}
+
+// CompareSequencers compares the sequencers of two events for the same
+// key, returning a negative number if a is earlier than b, a positive
+// number if it is later, and zero if they cannot be ordered. Sequencers
+// are hexadecimal values of varying length, so the shorter one is
+// padded with trailing zeros before they are compared as strings. An
+// empty sequencer is earlier than any other and equal to another empty
+// one.
+func CompareSequencers(a, b string) int {
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	case b == "":
+		return 1
+	}
+
+	a, b = strings.ToUpper(a), strings.ToUpper(b)
+	if len(a) < len(b) {
+		a += strings.Repeat("0", len(b)-len(a))
+	} else if len(b) < len(a) {
+		b += strings.Repeat("0", len(a)-len(b))
+	}
+
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,548 @@
+package evt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestReadObjects(t *testing.T) {
+	tests := []struct {
+		description  string
+		notification Notification
+		objects      []Object
+		error        bool
+	}{
+		{
+			description: "invalid cloudtrail detail",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					Detail: []byte(`not json`),
+				},
+			},
+			objects: nil,
+			error:   true,
+		},
+		{
+			description: "unhandled cloudtrail event",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: "AWS API Call via CloudTrail",
+					Detail:     []byte(`{ "eventName": "GetObject", "requestParameters": { "bucketName": "bucket", "key": "key.jpeg" } }`),
+				},
+			},
+			objects: []Object{},
+			error:   false,
+		},
+		{
+			description: "cloudtrail put event",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: "AWS API Call via CloudTrail",
+					Detail:     []byte(`{ "eventName": "PutObject", "requestParameters": { "bucketName": "bucket", "key": "folder/key.jpeg" } }`),
+				},
+			},
+			objects: []Object{
+				{
+					Action: PutObject,
+					Bucket: "bucket",
+					Key:    "folder/key.jpeg",
+				},
+			},
+			error: false,
+		},
+		{
+			description: "cloudtrail PostObject event",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: "AWS API Call via CloudTrail",
+					Detail:     []byte(`{ "eventName": "PostObject", "requestParameters": { "bucketName": "bucket", "key": "folder/large file.pdf" } }`),
+				},
+			},
+			objects: []Object{
+				{
+					Action: PutObject,
+					Bucket: "bucket",
+					Key:    "folder/large file.pdf",
+				},
+			},
+			error: false,
+		},
+		{
+			description: "cloudtrail CopyObject event",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: "AWS API Call via CloudTrail",
+					Detail:     []byte(`{ "eventName": "CopyObject", "requestParameters": { "bucketName": "bucket", "key": "folder/large file.pdf" } }`),
+				},
+			},
+			objects: []Object{
+				{
+					Action: PutObject,
+					Bucket: "bucket",
+					Key:    "folder/large file.pdf",
+				},
+			},
+			error: false,
+		},
+		{
+			description: "cloudtrail CompleteMultipartUpload event",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: "AWS API Call via CloudTrail",
+					Detail:     []byte(`{ "eventName": "CompleteMultipartUpload", "requestParameters": { "bucketName": "bucket", "key": "folder/large file.pdf" } }`),
+				},
+			},
+			objects: []Object{
+				{
+					Action: PutObject,
+					Bucket: "bucket",
+					Key:    "folder/large file.pdf",
+				},
+			},
+			error: false,
+		},
+		{
+			description: "cloudtrail DeleteObject event",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: "AWS API Call via CloudTrail",
+					Detail:     []byte(`{ "eventName": "DeleteObject", "requestParameters": { "bucketName": "bucket", "key": "folder/key.jpeg" } }`),
+				},
+			},
+			objects: []Object{
+				{
+					Action: DeleteObject,
+					Bucket: "bucket",
+					Key:    "folder/key.jpeg",
+				},
+			},
+			error: false,
+		},
+		{
+			description: "cloudtrail DeleteObjects event with several keys",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: "AWS API Call via CloudTrail",
+					Detail:     []byte(`{ "eventName": "DeleteObjects", "requestParameters": { "bucketName": "bucket", "delete": { "Object": [ { "Key": "a.pdf" }, { "Key": "folder/b.pdf" } ], "Quiet": "true" } } }`),
+				},
+			},
+			objects: []Object{
+				{
+					Action: DeleteObject,
+					Bucket: "bucket",
+					Key:    "a.pdf",
+				},
+				{
+					Action: DeleteObject,
+					Bucket: "bucket",
+					Key:    "folder/b.pdf",
+				},
+			},
+			error: false,
+		},
+		{
+			description: "cloudtrail DeleteObjects event with one key",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: "AWS API Call via CloudTrail",
+					Detail:     []byte(`{ "eventName": "DeleteObjects", "requestParameters": { "bucketName": "bucket", "delete": { "Object": { "Key": "café.pdf" } } } }`),
+				},
+			},
+			objects: []Object{
+				{
+					Action: DeleteObject,
+					Bucket: "bucket",
+					Key:    "café.pdf",
+				},
+			},
+			error: false,
+		},
+		{
+			description: "cloudtrail DeleteObjects event with body after query parameter",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: "AWS API Call via CloudTrail",
+					Detail:     []byte(`{ "eventName": "DeleteObjects", "requestParameters": { "bucketName": "bucket", "delete": "", "Delete": { "Object": [ { "Key": "a.pdf" } ] } } }`),
+				},
+			},
+			objects: []Object{
+				{
+					Action: DeleteObject,
+					Bucket: "bucket",
+					Key:    "a.pdf",
+				},
+			},
+			error: false,
+		},
+		{
+			description: "cloudtrail DeleteObjects event with body before query parameter",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: "AWS API Call via CloudTrail",
+					Detail:     []byte(`{ "eventName": "DeleteObjects", "requestParameters": { "bucketName": "bucket", "Delete": { "Object": [ { "Key": "a.pdf" } ] }, "delete": "" } }`),
+				},
+			},
+			objects: []Object{
+				{
+					Action: DeleteObject,
+					Bucket: "bucket",
+					Key:    "a.pdf",
+				},
+			},
+			error: false,
+		},
+		{
+			description: "cloudtrail DeleteObjects event without keys",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: "AWS API Call via CloudTrail",
+					Detail:     []byte(`{ "eventName": "DeleteObjects", "requestParameters": { "bucketName": "bucket", "delete": "" } }`),
+				},
+			},
+			objects: []Object{},
+			error:   false,
+		},
+		{
+			description: "invalid cloudtrail DeleteObjects keys",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: "AWS API Call via CloudTrail",
+					Detail:     []byte(`{ "eventName": "DeleteObjects", "requestParameters": { "bucketName": "bucket", "delete": { "Object": "a.pdf" } } }`),
+				},
+			},
+			objects: nil,
+			error:   true,
+		},
+		{
+			description: "eventbridge object created event",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: objectCreated,
+					Detail:     []byte(`{ "bucket": { "name": "bucket" }, "object": { "key": "folder/key+name.jpeg" } }`),
+				},
+			},
+			objects: []Object{
+				{
+					Action: PutObject,
+					Bucket: "bucket",
+					Key:    "folder/key name.jpeg",
+				},
+			},
+			error: false,
+		},
+		{
+			description: "eventbridge object deleted event",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: objectDeleted,
+					Detail:     []byte(`{ "bucket": { "name": "bucket" }, "object": { "key": "key.jpeg" } }`),
+				},
+			},
+			objects: []Object{
+				{
+					Action: DeleteObject,
+					Bucket: "bucket",
+					Key:    "key.jpeg",
+				},
+			},
+			error: false,
+		},
+		{
+			description: "invalid eventbridge object key",
+			notification: Notification{
+				CloudWatchEvent: events.CloudWatchEvent{
+					DetailType: objectDeleted,
+					Detail:     []byte(`{ "bucket": { "name": "bucket" }, "object": { "key": "key%zz" } }`),
+				},
+			},
+			objects: nil,
+			error:   true,
+		},
+		{
+			description: "s3 notification records",
+			notification: Notification{
+				Records: []NotificationRecord{
+					{
+						S3EventRecord: s3Record("ObjectCreated:CompleteMultipartUpload", "bucket", "caf%C3%A9.pdf"),
+					},
+					{
+						S3EventRecord: s3Record("ObjectRemoved:DeleteMarkerCreated", "bucket", "key.jpeg"),
+					},
+					{
+						S3EventRecord: s3Record("ObjectRestore:Completed", "bucket", "key.jpeg"),
+					},
+				},
+			},
+			objects: []Object{
+				{
+					Action: PutObject,
+					Bucket: "bucket",
+					Key:    "café.pdf",
+				},
+				{
+					Action: DeleteObject,
+					Bucket: "bucket",
+					Key:    "key.jpeg",
+				},
+			},
+			error: false,
+		},
+		{
+			description: "invalid sqs message body",
+			notification: Notification{
+				Records: []NotificationRecord{
+					{
+						S3EventRecord: events.S3EventRecord{
+							EventSource: "aws:sqs",
+						},
+						Body: "not json",
+					},
+				},
+			},
+			objects: nil,
+			error:   true,
+		},
+		{
+			description: "s3 notification records in sqs messages",
+			notification: Notification{
+				Records: []NotificationRecord{
+					{
+						S3EventRecord: events.S3EventRecord{
+							EventSource: "aws:sqs",
+						},
+						Body: `{ "Records": [ { "eventSource": "aws:s3", "eventName": "ObjectCreated:Put", "s3": { "bucket": { "name": "bucket" }, "object": { "key": "folder/key.jpeg" } } } ] }`,
+					},
+					{
+						S3EventRecord: events.S3EventRecord{
+							EventSource: "aws:sqs",
+						},
+						Body: `{ "Service": "Amazon S3", "Event": "s3:TestEvent", "Bucket": "bucket" }`,
+					},
+				},
+			},
+			objects: []Object{
+				{
+					Action: PutObject,
+					Bucket: "bucket",
+					Key:    "folder/key.jpeg",
+				},
+			},
+			error: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			objects, err := ReadObjects(test.notification)
+
+			if (err != nil) != test.error {
+				t.Fatalf("incorrect error, received: %v, expected error: %t", err, test.error)
+			}
+
+			if !reflect.DeepEqual(objects, test.objects) {
+				t.Errorf("incorrect objects, received: %+v, expected: %+v", objects, test.objects)
+			}
+		})
+	}
+}
+
+func s3Record(eventName, bucket, key string) events.S3EventRecord {
+	return events.S3EventRecord{
+		EventSource: "aws:s3",
+		EventName:   eventName,
+		S3: events.S3Entity{
+			Bucket: events.S3Bucket{
+				Name: bucket,
+			},
+			Object: events.S3Object{
+				Key: key,
+			},
+		},
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	tests := []struct {
+		description string
+		body        string
+		objects     []Object
+		error       error
+	}{
+		{
+			description: "invalid message body",
+			body:        "not json",
+			objects:     nil,
+			error:       &ReadObjectsError{},
+		},
+		{
+			description: "s3 test event",
+			body:        `{ "Service": "Amazon S3", "Event": "s3:TestEvent", "Bucket": "bucket" }`,
+			objects:     []Object{},
+			error:       nil,
+		},
+		{
+			description: "eventbridge event",
+			body:        `{ "detail-type": "Object Deleted", "source": "aws.s3", "detail": { "bucket": { "name": "bucket" }, "object": { "key": "key.jpeg", "sequencer": "617f08299329d189" } } }`,
+			objects: []Object{
+				{
+					Action:    DeleteObject,
+					Bucket:    "bucket",
+					Key:       "key.jpeg",
+					Sequencer: "617f08299329d189",
+				},
+			},
+			error: nil,
+		},
+		{
+			description: "s3 notification records",
+			body:        `{ "Records": [ { "eventSource": "aws:s3", "eventName": "ObjectCreated:Put", "s3": { "bucket": { "name": "bucket" }, "object": { "key": "key.jpeg", "sequencer": "0055AED6DCD90281E5" } } } ] }`,
+			objects: []Object{
+				{
+					Action:    PutObject,
+					Bucket:    "bucket",
+					Key:       "key.jpeg",
+					Sequencer: "0055AED6DCD90281E5",
+				},
+			},
+			error: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			objects, err := ReadMessage(test.body)
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *ReadObjectsError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else if test.error != nil {
+				t.Fatalf("incorrect error, received: nil, expected: %v", test.error)
+			}
+
+			if !reflect.DeepEqual(objects, test.objects) {
+				t.Errorf("incorrect objects, received: %+v, expected: %+v", objects, test.objects)
+			}
+		})
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	tests := []struct {
+		description string
+		objects     []Object
+		body        string
+	}{
+		{
+			description: "no objects",
+			objects:     []Object{},
+			body:        `{"Records":[]}`,
+		},
+		{
+			description: "put and delete objects",
+			objects: []Object{
+				{
+					Action: PutObject,
+					Bucket: "bucket",
+					Key:    "folder/key with spaces.jpeg",
+				},
+				{
+					Action: DeleteObject,
+					Bucket: "bucket",
+					Key:    "key+plus.jpeg",
+				},
+			},
+			body: `{"Records":[{"eventSource":"aws:s3","eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"bucket"},"object":{"key":"folder%2Fkey+with+spaces.jpeg"}}},{"eventSource":"aws:s3","eventName":"ObjectRemoved:Delete","s3":{"bucket":{"name":"bucket"},"object":{"key":"key%2Bplus.jpeg"}}}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			body, err := WriteMessage(test.objects)
+			if err != nil {
+				t.Fatalf("error writing message: %v", err)
+			}
+
+			if body != test.body {
+				t.Errorf("incorrect body, received: %s, expected: %s", body, test.body)
+			}
+
+			objects, err := ReadMessage(body)
+			if err != nil {
+				t.Fatalf("error reading message: %v", err)
+			}
+
+			if !reflect.DeepEqual(objects, test.objects) {
+				t.Errorf("incorrect objects, received: %+v, expected: %+v", objects, test.objects)
+			}
+		})
+	}
+}
+
+func TestCompareSequencers(t *testing.T) {
+	tests := []struct {
+		description string
+		a           string
+		b           string
+		result      int
+	}{
+		{
+			description: "both empty",
+			a:           "",
+			b:           "",
+			result:      0,
+		},
+		{
+			description: "empty earlier",
+			a:           "",
+			b:           "0055AED6DCD90281E5",
+			result:      -1,
+		},
+		{
+			description: "empty later",
+			a:           "0055AED6DCD90281E5",
+			b:           "",
+			result:      1,
+		},
+		{
+			description: "equal values",
+			a:           "0055AED6DCD90281E5",
+			b:           "0055aed6dcd90281e5",
+			result:      0,
+		},
+		{
+			description: "greater value later",
+			a:           "0055AED6DCD90281E6",
+			b:           "0055AED6DCD90281E5",
+			result:      1,
+		},
+		{
+			description: "shorter value padded",
+			a:           "FF",
+			b:           "0100",
+			result:      1,
+		},
+		{
+			description: "shorter value padded on the right",
+			a:           "0F",
+			b:           "1",
+			result:      -1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			if result := CompareSequencers(test.a, test.b); result != test.result {
+				t.Errorf("incorrect result, received: %d, expected: %d", result, test.result)
+			}
+		})
+	}
+}
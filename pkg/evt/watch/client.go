@@ -0,0 +1,392 @@
+// Package watch implements evt.Eventer on local directories by
+// watching them for file changes with inotify and the equivalent
+// notification APIs of other operating systems.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/forstmeier/findfile/pkg/evt"
+	"github.com/forstmeier/findfile/pkg/fs/disk"
+)
+
+// Event types sent by the client.
+const (
+	Put    = "put"
+	Delete = "delete"
+)
+
+// Event holds a change to a file in a watched directory. Bucket is the
+// watched directory and Key the slash-separated path of the file within
+// it, matching the buckets and keys of disk.Client.
+type Event struct {
+	Type   string
+	Bucket string
+	Key    string
+}
+
+var _ evt.Eventer = &Client{}
+
+// Client implements the evt.Eventer methods by watching local
+// directories and sends an Event for each file created, modified,
+// renamed, or deleted within them. Puts are sent once a file has not
+// changed for the settle duration so that files still being written
+// are not read.
+type Client struct {
+	watcher *fsnotify.Watcher
+	settle  time.Duration
+	events  chan Event
+	errors  chan error
+	done    chan struct{}
+
+	mutex   sync.Mutex
+	buckets map[string]bool
+	dirs    map[string]string
+	files   map[string]string
+	pending map[string]*time.Timer
+}
+
+// New generates a watch.Client pointer instance that sends puts after
+// files have settled for the provided duration. The client watches
+// until Close is called.
+func New(settle time.Duration) (*Client, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, &NewWatcherError{err: err}
+	}
+
+	c := &Client{
+		watcher: watcher,
+		settle:  settle,
+		events:  make(chan Event, 100),
+		errors:  make(chan error, 10),
+		done:    make(chan struct{}),
+		buckets: map[string]bool{},
+		dirs:    map[string]string{},
+		files:   map[string]string{},
+		pending: map[string]*time.Timer{},
+	}
+
+	go c.run()
+
+	return c, nil
+}
+
+// Events returns the channel the file change events are sent on.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Errors returns the channel errors from watching the directories are
+// sent on.
+func (c *Client) Errors() <-chan error {
+	return c.errors
+}
+
+// Close stops watching the directories.
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	for path, timer := range c.pending {
+		timer.Stop()
+		delete(c.pending, path)
+	}
+	c.mutex.Unlock()
+
+	close(c.done)
+	return c.watcher.Close()
+}
+
+// AddBucketListeners implements the evt.Eventer.AddBucketListeners
+// method by watching each bucket directory and its subdirectories.
+// Hidden files and directories are not watched, as they are not listed
+// by disk.Client.
+func (c *Client) AddBucketListeners(ctx context.Context, buckets []string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, bucket := range buckets {
+		info, err := os.Stat(bucket)
+		if err != nil {
+			return &AddWatchError{err: err}
+		}
+
+		if !info.IsDir() {
+			return &AddWatchError{err: fmt.Errorf("%s is not a directory", bucket)}
+		}
+
+		c.buckets[bucket] = true
+		if _, err := c.watch(bucket, bucket); err != nil {
+			return &AddWatchError{err: err}
+		}
+	}
+
+	return nil
+}
+
+// RemoveBucketListeners implements the evt.Eventer.RemoveBucketListeners
+// method by no longer watching the bucket directories. No events are
+// sent for their files.
+func (c *Client) RemoveBucketListeners(ctx context.Context, buckets []string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, bucket := range buckets {
+		if !c.buckets[bucket] {
+			continue
+		}
+		delete(c.buckets, bucket)
+
+		for dir, dirBucket := range c.dirs {
+			if dirBucket != bucket {
+				continue
+			}
+
+			delete(c.dirs, dir)
+			if err := c.watcher.Remove(dir); err != nil && exists(dir) {
+				return &RemoveWatchError{err: err}
+			}
+		}
+
+		for path, fileBucket := range c.files {
+			if fileBucket == bucket {
+				c.forget(path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// watch adds watches to the directory and its subdirectories within the
+// bucket and returns the files found in them.
+func (c *Client) watch(bucket, dir string) ([]string, error) {
+	files := []string{}
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path != bucket && disk.Hidden(entry.Name()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() {
+			if _, ok := c.dirs[path]; ok {
+				return nil
+			}
+
+			if err := c.watcher.Add(path); err != nil {
+				return err
+			}
+			c.dirs[path] = bucket
+			return nil
+		}
+
+		if entry.Type().IsRegular() {
+			c.files[path] = bucket
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+// run translates the watcher events into file change events until the
+// client is closed.
+func (c *Client) run() {
+	for {
+		select {
+		case <-c.done:
+			return
+
+		case event, ok := <-c.watcher.Events:
+			if !ok {
+				return
+			}
+
+			for _, fileEvent := range c.handle(event) {
+				c.send(fileEvent)
+			}
+
+		case err, ok := <-c.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			c.sendError(&WatchError{err: err})
+		}
+	}
+}
+
+// handle updates the watched directories and known files for the
+// watcher event and returns the deletes it causes. Puts are scheduled
+// to be sent once the file settles.
+func (c *Client) handle(event fsnotify.Event) []Event {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	path := event.Name
+	bucket, ok := c.bucket(path)
+	if !ok {
+		return nil
+	}
+
+	events := []Event{}
+	switch {
+	case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		if _, ok := c.dirs[path]; ok {
+			for dir := range c.dirs {
+				if within(path, dir) {
+					delete(c.dirs, dir)
+					c.watcher.Remove(dir)
+				}
+			}
+
+			for file := range c.files {
+				if within(path, file) {
+					events = append(events, c.delete(file))
+				}
+			}
+		} else if _, ok := c.files[path]; ok {
+			events = append(events, c.delete(path))
+		}
+
+	case event.Op&(fsnotify.Create|fsnotify.Write) != 0:
+		info, err := os.Lstat(path)
+		if err != nil {
+			return events
+		}
+
+		if disk.Hidden(info.Name()) {
+			return events
+		}
+
+		if info.IsDir() {
+			// files created before the watch was added send no events
+			files, err := c.watch(bucket, path)
+			if err != nil {
+				c.sendError(&AddWatchError{err: err})
+			}
+
+			for _, file := range files {
+				c.schedule(file)
+			}
+		} else if info.Mode().IsRegular() {
+			c.files[path] = bucket
+			c.schedule(path)
+		}
+	}
+
+	return events
+}
+
+// bucket returns the watched bucket directory containing the path.
+func (c *Client) bucket(path string) (string, bool) {
+	if bucket, ok := c.dirs[filepath.Dir(path)]; ok {
+		return bucket, true
+	}
+
+	if bucket, ok := c.dirs[path]; ok {
+		return bucket, true
+	}
+
+	return "", false
+}
+
+// schedule sends a put for the file once it has not changed for the
+// settle duration.
+func (c *Client) schedule(path string) {
+	if timer, ok := c.pending[path]; ok {
+		timer.Reset(c.settle)
+		return
+	}
+
+	c.pending[path] = time.AfterFunc(c.settle, func() {
+		c.mutex.Lock()
+		delete(c.pending, path)
+		bucket, ok := c.files[path]
+		c.mutex.Unlock()
+
+		if !ok {
+			return
+		}
+
+		if event, err := newEvent(Put, bucket, path); err == nil {
+			c.send(event)
+		}
+	})
+}
+
+// delete forgets the file and returns its delete event.
+func (c *Client) delete(path string) Event {
+	bucket := c.files[path]
+	c.forget(path)
+
+	event, _ := newEvent(Delete, bucket, path)
+	return event
+}
+
+// forget stops tracking the file and cancels any pending put.
+func (c *Client) forget(path string) {
+	delete(c.files, path)
+	if timer, ok := c.pending[path]; ok {
+		timer.Stop()
+		delete(c.pending, path)
+	}
+}
+
+func (c *Client) send(event Event) {
+	select {
+	case c.events <- event:
+	case <-c.done:
+	}
+}
+
+// sendError sends the error without blocking, dropping it if the
+// errors are not being read.
+func (c *Client) sendError(err error) {
+	select {
+	case c.errors <- err:
+	default:
+	}
+}
+
+func newEvent(eventType, bucket, path string) (Event, error) {
+	key, err := disk.Key(bucket, path)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		Type:   eventType,
+		Bucket: bucket,
+		Key:    key,
+	}, nil
+}
+
+// exists reports whether the path exists; the watches of deleted
+// directories are removed by the watcher.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// within reports whether the path is the directory or within it.
+func within(dir, path string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
@@ -0,0 +1,263 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testSettle = 50 * time.Millisecond
+
+func newTestClient(t *testing.T) (*Client, string) {
+	t.Helper()
+
+	client, err := New(testSettle)
+	if err != nil {
+		t.Fatalf("error creating watch client: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	bucket := t.TempDir()
+	if err := client.AddBucketListeners(context.Background(), []string{bucket}); err != nil {
+		t.Fatalf("error adding bucket listeners: %v", err)
+	}
+
+	return client, bucket
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("error creating directory: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+}
+
+// receive returns the events sent until none are sent for several
+// settle durations.
+func receive(t *testing.T, client *Client) []Event {
+	t.Helper()
+
+	events := []Event{}
+	for {
+		select {
+		case event := <-client.Events():
+			events = append(events, event)
+		case err := <-client.Errors():
+			t.Fatalf("unexpected watch error: %v", err)
+		case <-time.After(10 * testSettle):
+			return events
+		}
+	}
+}
+
+func TestAddBucketListeners(t *testing.T) {
+	bucket := t.TempDir()
+	file := filepath.Join(bucket, "file.pdf")
+	writeFile(t, file, "%PDF-1.7")
+
+	tests := []struct {
+		description string
+		buckets     []string
+		error       error
+	}{
+		{
+			description: "missing directory",
+			buckets:     []string{filepath.Join(bucket, "missing")},
+			error:       &AddWatchError{},
+		},
+		{
+			description: "file instead of directory",
+			buckets:     []string{file},
+			error:       &AddWatchError{},
+		},
+		{
+			description: "successful invocation",
+			buckets:     []string{bucket},
+			error:       nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			client, err := New(testSettle)
+			if err != nil {
+				t.Fatalf("error creating watch client: %v", err)
+			}
+			defer client.Close()
+
+			err = client.AddBucketListeners(context.Background(), test.buckets)
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *AddWatchError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else if test.error != nil {
+				t.Errorf("incorrect error, received: nil, expected: %v", test.error)
+			}
+		})
+	}
+}
+
+func TestEvents(t *testing.T) {
+	tests := []struct {
+		description string
+		setup       func(t *testing.T, bucket string)
+		change      func(t *testing.T, bucket string)
+		events      []Event
+	}{
+		{
+			description: "created file",
+			change: func(t *testing.T, bucket string) {
+				writeFile(t, filepath.Join(bucket, "scan.JPG"), "image")
+			},
+			events: []Event{
+				{Type: Put, Key: "scan.JPG"},
+			},
+		},
+		{
+			description: "file written several times",
+			change: func(t *testing.T, bucket string) {
+				path := filepath.Join(bucket, "invoice.pdf")
+				writeFile(t, path, "%PDF-1.7")
+				for i := 0; i < 3; i++ {
+					writeFile(t, path, "%PDF-1.7 updated")
+					time.Sleep(testSettle / 5)
+				}
+			},
+			events: []Event{
+				{Type: Put, Key: "invoice.pdf"},
+			},
+		},
+		{
+			description: "renamed file",
+			setup: func(t *testing.T, bucket string) {
+				writeFile(t, filepath.Join(bucket, "draft.pdf"), "%PDF-1.7")
+			},
+			change: func(t *testing.T, bucket string) {
+				if err := os.Rename(filepath.Join(bucket, "draft.pdf"), filepath.Join(bucket, "final.pdf")); err != nil {
+					t.Fatalf("error renaming file: %v", err)
+				}
+			},
+			events: []Event{
+				{Type: Delete, Key: "draft.pdf"},
+				{Type: Put, Key: "final.pdf"},
+			},
+		},
+		{
+			description: "deleted file",
+			setup: func(t *testing.T, bucket string) {
+				writeFile(t, filepath.Join(bucket, "old.pdf"), "%PDF-1.7")
+			},
+			change: func(t *testing.T, bucket string) {
+				if err := os.Remove(filepath.Join(bucket, "old.pdf")); err != nil {
+					t.Fatalf("error removing file: %v", err)
+				}
+			},
+			events: []Event{
+				{Type: Delete, Key: "old.pdf"},
+			},
+		},
+		{
+			description: "directory moved in",
+			change: func(t *testing.T, bucket string) {
+				outside := t.TempDir()
+				writeFile(t, filepath.Join(outside, "2021", "acme.pdf"), "%PDF-1.7")
+				if err := os.Rename(outside, filepath.Join(bucket, "invoices")); err != nil {
+					t.Fatalf("error moving directory: %v", err)
+				}
+			},
+			events: []Event{
+				{Type: Put, Key: "invoices/2021/acme.pdf"},
+			},
+		},
+		{
+			description: "directory moved out",
+			setup: func(t *testing.T, bucket string) {
+				writeFile(t, filepath.Join(bucket, "invoices", "2021", "acme.pdf"), "%PDF-1.7")
+			},
+			change: func(t *testing.T, bucket string) {
+				if err := os.Rename(filepath.Join(bucket, "invoices"), filepath.Join(t.TempDir(), "invoices")); err != nil {
+					t.Fatalf("error moving directory: %v", err)
+				}
+			},
+			events: []Event{
+				{Type: Delete, Key: "invoices/2021/acme.pdf"},
+			},
+		},
+		{
+			description: "hidden file",
+			change: func(t *testing.T, bucket string) {
+				writeFile(t, filepath.Join(bucket, ".scan.pdf.swp"), "swap")
+			},
+			events: []Event{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			bucket := t.TempDir()
+			if test.setup != nil {
+				test.setup(t, bucket)
+			}
+
+			client, err := New(testSettle)
+			if err != nil {
+				t.Fatalf("error creating watch client: %v", err)
+			}
+			defer client.Close()
+
+			if err := client.AddBucketListeners(context.Background(), []string{bucket}); err != nil {
+				t.Fatalf("error adding bucket listeners: %v", err)
+			}
+
+			test.change(t, bucket)
+
+			events := receive(t, client)
+			for i := range test.events {
+				test.events[i].Bucket = bucket
+			}
+
+			if !reflect.DeepEqual(events, test.events) {
+				t.Errorf("incorrect events, received: %+v, expected: %+v", events, test.events)
+			}
+		})
+	}
+}
+
+func TestRemoveBucketListeners(t *testing.T) {
+	client, bucket := newTestClient(t)
+	writeFile(t, filepath.Join(bucket, "nested", "file.pdf"), "%PDF-1.7")
+
+	if events := receive(t, client); len(events) != 1 {
+		t.Fatalf("incorrect events before removal, received: %+v", events)
+	}
+
+	if err := client.RemoveBucketListeners(context.Background(), []string{bucket}); err != nil {
+		t.Fatalf("error removing bucket listeners: %v", err)
+	}
+
+	writeFile(t, filepath.Join(bucket, "nested", "other.pdf"), "%PDF-1.7")
+	if err := os.Remove(filepath.Join(bucket, "nested", "file.pdf")); err != nil {
+		t.Fatalf("error removing file: %v", err)
+	}
+
+	if events := receive(t, client); len(events) != 0 {
+		t.Errorf("incorrect events after removal, received: %+v", events)
+	}
+}
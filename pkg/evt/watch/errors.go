@@ -0,0 +1,45 @@
+package watch
+
+import "fmt"
+
+const errorMessage = "package watch: %s"
+
+// NewWatcherError wraps errors returned creating the file watcher in
+// watch.New.
+type NewWatcherError struct {
+	err error
+}
+
+func (e *NewWatcherError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// AddWatchError wraps errors returned watching directories in the
+// evt.Eventer.AddBucketListeners method and for directories created
+// within watched directories.
+type AddWatchError struct {
+	err error
+}
+
+func (e *AddWatchError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// RemoveWatchError wraps errors returned no longer watching directories
+// in the evt.Eventer.RemoveBucketListeners method.
+type RemoveWatchError struct {
+	err error
+}
+
+func (e *RemoveWatchError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// WatchError wraps errors sent by the file watcher.
+type WatchError struct {
+	err error
+}
+
+func (e *WatchError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
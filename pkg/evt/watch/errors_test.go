@@ -0,0 +1,58 @@
+package watch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewWatcherError(t *testing.T) {
+	err := &NewWatcherError{
+		err: errors.New("mock new watcher error"),
+	}
+
+	recieved := err.Error()
+	expected := "package watch: mock new watcher error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestAddWatchError(t *testing.T) {
+	err := &AddWatchError{
+		err: errors.New("mock add watch error"),
+	}
+
+	recieved := err.Error()
+	expected := "package watch: mock add watch error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestRemoveWatchError(t *testing.T) {
+	err := &RemoveWatchError{
+		err: errors.New("mock remove watch error"),
+	}
+
+	recieved := err.Error()
+	expected := "package watch: mock remove watch error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestWatchError(t *testing.T) {
+	err := &WatchError{
+		err: errors.New("mock watch error"),
+	}
+
+	recieved := err.Error()
+	expected := "package watch: mock watch error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
@@ -0,0 +1,155 @@
+// Package disk implements fs.Filesystemer on local directories, such
+// as network shares and developer machines, in place of S3 buckets.
+package disk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	filesystem "github.com/forstmeier/findfile/pkg/fs"
+	"github.com/forstmeier/findfile/pkg/pars"
+	"github.com/forstmeier/findfile/pkg/sniff"
+)
+
+// sniffLength is the number of leading bytes of a file read to detect
+// its type.
+const sniffLength = 1024
+
+var _ filesystem.Filesystemer = &Client{}
+
+// Client implements the fs.Filesystemer methods on the local
+// filesystem. Buckets are directory paths and keys are the
+// slash-separated paths of the files within them.
+type Client struct{}
+
+// New generates a disk.Client pointer instance.
+func New() *Client {
+	return &Client{}
+}
+
+// ListFiles implements the fs.Filesystemer.ListFiles method by walking
+// the directory tree. Hidden files and directories, whose names start
+// with a period, and files other than regular files are not listed.
+func (c *Client) ListFiles(ctx context.Context, bucket string) ([]string, error) {
+	keys := []string{}
+
+	err := filepath.WalkDir(bucket, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if path != bucket && Hidden(entry.Name()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		key, err := Key(bucket, path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+
+		return nil
+	})
+	if err != nil {
+		return nil, &WalkDirectoryError{err: err}
+	}
+
+	return keys, nil
+}
+
+// Open opens the file at the key within the bucket directory for
+// reading and returns it with its metadata. The file version is its
+// modification time and size and its content type is detected from
+// its leading bytes with sniff.DetectContentType.
+func (c *Client) Open(bucket, key string) (io.ReadCloser, *pars.File, error) {
+	path, err := Path(bucket, key)
+	if err != nil {
+		return nil, nil, &OpenFileError{err: err}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, &OpenFileError{err: err}
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, &OpenFileError{err: err}
+	}
+
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, nil, &OpenFileError{err: fmt.Errorf("%s is not a regular file", path)}
+	}
+
+	data := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, data)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		file.Close()
+		return nil, nil, &OpenFileError{err: err}
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, nil, &OpenFileError{err: err}
+	}
+
+	lastModified := info.ModTime().UTC()
+
+	return file, &pars.File{
+		Bucket:       bucket,
+		Key:          key,
+		Version:      strconv.FormatInt(lastModified.UnixNano(), 10) + "-" + strconv.FormatInt(info.Size(), 10),
+		ContentType:  sniff.DetectContentType(data[:n], "", key),
+		LastModified: &lastModified,
+	}, nil
+}
+
+// Key returns the slash-separated key of the path within the bucket
+// directory.
+func Key(bucket, path string) (string, error) {
+	relative, err := filepath.Rel(bucket, path)
+	if err != nil {
+		return "", err
+	}
+
+	if relative == "." || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not within %s", path, bucket)
+	}
+
+	return filepath.ToSlash(relative), nil
+}
+
+// Path returns the path of the key within the bucket directory. Keys
+// that resolve outside of the directory are rejected.
+func Path(bucket, key string) (string, error) {
+	path := filepath.Join(bucket, filepath.FromSlash(key))
+	if _, err := Key(bucket, path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// Hidden reports whether the file or directory name is hidden.
+func Hidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
@@ -0,0 +1,178 @@
+package disk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	bucket := t.TempDir()
+	for key, content := range files {
+		path := filepath.Join(bucket, filepath.FromSlash(key))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	return bucket
+}
+
+func TestListFiles(t *testing.T) {
+	bucket := writeFiles(t, map[string]string{
+		"scan.JPG":               "\xff\xd8\xff",
+		"invoices/2021/acme.pdf": "%PDF-1.7",
+		".cache/thumbnail.png":   "\x89PNG\r\n\x1a\n",
+		"invoices/.draft.pdf":    "%PDF-1.7",
+	})
+
+	if err := os.Symlink(filepath.Join(bucket, "scan.JPG"), filepath.Join(bucket, "link.jpg")); err != nil {
+		t.Fatalf("error creating symlink: %v", err)
+	}
+
+	tests := []struct {
+		description string
+		bucket      string
+		files       []string
+		error       error
+	}{
+		{
+			description: "missing directory",
+			bucket:      filepath.Join(bucket, "missing"),
+			files:       nil,
+			error:       &WalkDirectoryError{},
+		},
+		{
+			description: "successful invocation",
+			bucket:      bucket,
+			files:       []string{"invoices/2021/acme.pdf", "scan.JPG"},
+			error:       nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			client := New()
+
+			files, err := client.ListFiles(context.Background(), test.bucket)
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *WalkDirectoryError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else {
+				if !reflect.DeepEqual(files, test.files) {
+					t.Errorf("incorrect output, received: %v, expected: %v", files, test.files)
+				}
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	bucket := writeFiles(t, map[string]string{
+		"scans/scan.JPG": "\xff\xd8\xff\xe0 image",
+		"notapdf":        "plain text",
+	})
+
+	lastModified := time.Date(2021, time.November, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(bucket, "scans", "scan.JPG"), lastModified, lastModified); err != nil {
+		t.Fatalf("error setting file times: %v", err)
+	}
+
+	tests := []struct {
+		description string
+		key         string
+		contentType string
+		version     string
+		error       error
+	}{
+		{
+			description: "missing file",
+			key:         "missing.pdf",
+			error:       &OpenFileError{},
+		},
+		{
+			description: "key outside of bucket",
+			key:         "../outside.pdf",
+			error:       &OpenFileError{},
+		},
+		{
+			description: "directory key",
+			key:         "scans",
+			error:       &OpenFileError{},
+		},
+		{
+			description: "detected content type",
+			key:         "scans/scan.JPG",
+			contentType: "image/jpeg",
+			version:     "1635768000000000000-10",
+			error:       nil,
+		},
+		{
+			description: "unknown content type",
+			key:         "notapdf",
+			contentType: "",
+			error:       nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			client := New()
+
+			reader, file, err := client.Open(bucket, test.key)
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *OpenFileError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+				return
+			}
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("error reading file: %v", err)
+			}
+
+			content, _ := os.ReadFile(filepath.Join(bucket, filepath.FromSlash(test.key)))
+			if string(data) != string(content) {
+				t.Errorf("incorrect content, received: %q, expected: %q", data, content)
+			}
+
+			if file.Bucket != bucket || file.Key != test.key || file.ContentType != test.contentType {
+				t.Errorf("incorrect file, received: %+v", file)
+			}
+
+			if test.version != "" && file.Version != test.version {
+				t.Errorf("incorrect version, received: %s, expected: %s", file.Version, test.version)
+			}
+
+			if file.LastModified == nil {
+				t.Errorf("no last modified time, received: %+v", file)
+			}
+		})
+	}
+}
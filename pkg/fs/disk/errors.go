@@ -0,0 +1,25 @@
+package disk
+
+import "fmt"
+
+const errorMessage = "package disk: %s"
+
+// WalkDirectoryError wraps errors returned walking the directory tree
+// in the fs.Filesystemer.ListFiles method.
+type WalkDirectoryError struct {
+	err error
+}
+
+func (e *WalkDirectoryError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// OpenFileError wraps errors returned opening and reading the file in
+// the disk.Client.Open method.
+type OpenFileError struct {
+	err error
+}
+
+func (e *OpenFileError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
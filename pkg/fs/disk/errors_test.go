@@ -0,0 +1,32 @@
+package disk
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWalkDirectoryError(t *testing.T) {
+	err := &WalkDirectoryError{
+		err: errors.New("mock walk directory error"),
+	}
+
+	recieved := err.Error()
+	expected := "package disk: mock walk directory error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestOpenFileError(t *testing.T) {
+	err := &OpenFileError{
+		err: errors.New("mock open file error"),
+	}
+
+	recieved := err.Error()
+	expected := "package disk: mock open file error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
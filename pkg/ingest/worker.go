@@ -0,0 +1,378 @@
+// Package ingest parses and stores the files in batches of queued file
+// events, retrying failed messages with backoff and moving messages that
+// keep failing to a dead-letter queue.
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/evt"
+	"github.com/forstmeier/findfile/pkg/pars"
+	"github.com/forstmeier/findfile/pkg/queue"
+	"github.com/forstmeier/findfile/pkg/sniff"
+	"github.com/forstmeier/findfile/util"
+)
+
+// Defaults applied to zero Config values.
+const (
+	DefaultConcurrency = 4
+	DefaultMaxReceives = 5
+	DefaultBackoff     = 30 * time.Second
+	DefaultMaxBackoff  = 15 * time.Minute
+	DefaultReserve     = 30 * time.Second
+)
+
+// maxMessages is the largest batch SQS returns from a single receive.
+const maxMessages = 10
+
+// Attributes set on the messages sent to the dead-letter queue.
+const (
+	ErrorAttribute        = "error"
+	MessageIDAttribute    = "message_id"
+	ReceiveCountAttribute = "receive_count"
+)
+
+// Config holds the queues and retry settings of a Worker.
+//
+// QueueURL is the queue the messages are received from and
+// DeadLetterQueueURL the queue failed messages are moved to. Up to
+// Concurrency files are parsed at once. A failed message is hidden for
+// Backoff, doubling on each receive up to MaxBackoff, and is moved to the
+// dead-letter queue when it fails on its MaxReceives receive. Files must
+// be parsed Reserve before the deadline of the context passed to
+// Process, leaving time to store them and update the messages; files
+// still being parsed then fail.
+type Config struct {
+	QueueURL           string
+	DeadLetterQueueURL string
+	Concurrency        int
+	MaxReceives        int
+	Backoff            time.Duration
+	MaxBackoff         time.Duration
+	Reserve            time.Duration
+}
+
+// Worker processes batches of queue messages holding file events, such
+// as S3 event notifications sent to SQS.
+type Worker struct {
+	sniffClient sniff.Sniffer
+	parsClient  pars.Parser
+	dbClient    db.Databaser
+	queueClient queue.Queuer
+	config      Config
+}
+
+// task holds the latest event for a file in a batch along with the
+// messages holding events for the file.
+type task struct {
+	object     evt.Object
+	messageIDs []string
+	document   *pars.Document
+	err        error
+}
+
+// New generates an ingest.Worker pointer instance.
+func New(sniffClient sniff.Sniffer, parsClient pars.Parser, dbClient db.Databaser, queueClient queue.Queuer, config Config) *Worker {
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultConcurrency
+	}
+	if config.MaxReceives <= 0 {
+		config.MaxReceives = DefaultMaxReceives
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = DefaultBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+	if config.Reserve <= 0 {
+		config.Reserve = DefaultReserve
+	}
+
+	return &Worker{
+		sniffClient: sniffClient,
+		parsClient:  parsClient,
+		dbClient:    dbClient,
+		queueClient: queueClient,
+		config:      config,
+	}
+}
+
+// Poll receives a batch of messages from the queue, processes them, and
+// deletes the messages that do not need to be retried. It returns the
+// number of messages received.
+func (w *Worker) Poll(ctx context.Context) (int, error) {
+	messages, err := w.queueClient.ReceiveMessages(ctx, w.config.QueueURL, maxMessages)
+	if err != nil {
+		return 0, err
+	}
+
+	retries := map[string]struct{}{}
+	for _, messageID := range w.Process(ctx, messages) {
+		retries[messageID] = struct{}{}
+	}
+
+	for _, message := range messages {
+		if _, ok := retries[message.ID]; ok {
+			continue
+		}
+
+		if err := w.queueClient.DeleteMessage(ctx, w.config.QueueURL, message.ReceiptHandle); err != nil {
+			return len(messages), err
+		}
+	}
+
+	return len(messages), nil
+}
+
+// Process parses and stores the files in a batch of messages and returns
+// the IDs of the messages that should be received again.
+//
+// Files are parsed concurrently and the latest event for each file in
+// the batch is applied, with the parsed documents upserted together and
+// the deleted files removed together. Since messages are not received
+// in order, the latest event is the one with the greatest S3 sequencer,
+// falling back to the batch order for events without one. Each message
+// fails if the event of any of its files fails. A failed message is
+// hidden with backoff before it is retried, unless it has been received
+// the maximum number of times or cannot be read, in which case it is
+// moved to the dead-letter queue with the errors recorded in its
+// attributes and is not retried.
+func (w *Worker) Process(ctx context.Context, messages []queue.Message) []string {
+	tasks := []*task{}
+	tasksByObject := map[string]*task{}
+	failures := map[string][]string{}
+	unreadable := map[string]struct{}{}
+
+	for _, message := range messages {
+		objects, err := evt.ReadMessage(message.Body)
+		if err != nil {
+			util.Log("READ_MESSAGE_ERROR", err.Error())
+			failures[message.ID] = []string{err.Error()}
+			unreadable[message.ID] = struct{}{}
+			continue
+		}
+
+		for _, object := range objects {
+			objectKey := object.Bucket + "/" + object.Key
+			if current, ok := tasksByObject[objectKey]; ok {
+				if evt.CompareSequencers(object.Sequencer, current.object.Sequencer) >= 0 {
+					current.object = object
+				}
+				current.messageIDs = append(current.messageIDs, message.ID)
+				continue
+			}
+
+			newTask := &task{
+				object:     object,
+				messageIDs: []string{message.ID},
+			}
+			tasks = append(tasks, newTask)
+			tasksByObject[objectKey] = newTask
+		}
+	}
+
+	w.parse(ctx, tasks)
+	w.upsert(ctx, tasks)
+	w.delete(ctx, tasks)
+
+	for _, task := range tasks {
+		if task.err == nil {
+			continue
+		}
+
+		for _, messageID := range task.messageIDs {
+			failures[messageID] = append(failures[messageID], fmt.Sprintf("%s/%s: %s", task.object.Bucket, task.object.Key, task.err.Error()))
+		}
+	}
+
+	retries := []string{}
+	for _, message := range messages {
+		errs, ok := failures[message.ID]
+		if !ok {
+			continue
+		}
+
+		_, permanent := unreadable[message.ID]
+		if w.retry(ctx, message, errs, permanent) {
+			retries = append(retries, message.ID)
+		}
+	}
+
+	return retries
+}
+
+// parse reads the files of the put tasks with up to the configured number
+// of files at once. If the context has a deadline the files must be read
+// the configured reserve before it; files that are not fail with the
+// context error.
+func (w *Worker) parse(ctx context.Context, tasks []*task) {
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-w.config.Reserve))
+		defer cancel()
+	}
+
+	semaphore := make(chan struct{}, w.config.Concurrency)
+	wait := sync.WaitGroup{}
+
+	for _, current := range tasks {
+		if current.object.Action != evt.PutObject {
+			continue
+		}
+
+		wait.Add(1)
+		semaphore <- struct{}{}
+		go func(current *task) {
+			defer func() {
+				<-semaphore
+				wait.Done()
+			}()
+
+			if err := ctx.Err(); err != nil {
+				util.Log("PARSE_ERROR", err.Error())
+				current.err = err
+				return
+			}
+
+			bucket, key := current.object.Bucket, current.object.Key
+
+			file, err := w.sniffClient.Sniff(ctx, bucket, key)
+			if err != nil {
+				util.Log("SNIFF_ERROR", err.Error())
+				current.err = err
+				return
+			}
+
+			if !file.Supported {
+				util.Log("SKIPPED_FILE", fmt.Sprintf("%s/%s: %s", bucket, key, file.Reason))
+				skipped := pars.NewSkippedDocument(file.Metadata, file.Reason)
+				current.document = &skipped
+				return
+			}
+
+			document, err := w.parsClient.Parse(ctx, file.Metadata)
+			if err != nil {
+				util.Log("PARSE_ERROR", err.Error())
+				current.err = err
+				return
+			}
+
+			current.document = document
+		}(current)
+	}
+
+	wait.Wait()
+}
+
+// upsert stores the parsed documents together, falling back to storing
+// them one at a time when the bulk upsert fails so that only the
+// documents that cannot be stored fail.
+func (w *Worker) upsert(ctx context.Context, tasks []*task) {
+	upsertTasks := []*task{}
+	documents := []pars.Document{}
+	for _, current := range tasks {
+		if current.document != nil {
+			upsertTasks = append(upsertTasks, current)
+			documents = append(documents, *current.document)
+		}
+	}
+
+	if len(documents) == 0 {
+		return
+	}
+
+	err := w.dbClient.UpsertDocuments(ctx, documents)
+	if err == nil {
+		return
+	}
+	util.Log("UPSERT_DOCUMENTS_ERROR", err.Error())
+
+	if len(documents) == 1 {
+		upsertTasks[0].err = err
+		return
+	}
+
+	for _, current := range upsertTasks {
+		if err := w.dbClient.UpsertDocuments(ctx, []pars.Document{*current.document}); err != nil {
+			util.Log("UPSERT_DOCUMENTS_ERROR", err.Error())
+			current.err = err
+		}
+	}
+}
+
+// delete removes the documents of the delete tasks together.
+func (w *Worker) delete(ctx context.Context, tasks []*task) {
+	deleteTasks := []*task{}
+	fileKeys := []db.FileKey{}
+	for _, current := range tasks {
+		if current.object.Action == evt.DeleteObject {
+			deleteTasks = append(deleteTasks, current)
+			fileKeys = append(fileKeys, db.FileKey{Bucket: current.object.Bucket, Key: current.object.Key})
+		}
+	}
+
+	if len(fileKeys) == 0 {
+		return
+	}
+
+	if err := w.dbClient.DeleteDocumentsByKeys(ctx, fileKeys); err != nil {
+		util.Log("DELETE_DOCUMENTS_ERROR", err.Error())
+		for _, current := range deleteTasks {
+			current.err = err
+		}
+	}
+}
+
+// retry hides the failed message with backoff and returns true if it
+// should be received again, or moves it to the dead-letter queue and
+// returns false if the failure is permanent or the message has reached
+// the maximum number of receives. Messages are retried if they cannot be
+// moved.
+func (w *Worker) retry(ctx context.Context, message queue.Message, errs []string, permanent bool) bool {
+	sort.Strings(errs)
+	errorText := strings.Join(errs, "; ")
+
+	if !permanent && message.ReceiveCount < w.config.MaxReceives {
+		if err := w.queueClient.ChangeMessageVisibility(ctx, w.config.QueueURL, message.ReceiptHandle, w.backoff(message.ReceiveCount)); err != nil {
+			util.Log("CHANGE_MESSAGE_VISIBILITY_ERROR", err.Error())
+		}
+
+		return true
+	}
+
+	if err := w.queueClient.SendMessage(ctx, w.config.DeadLetterQueueURL, message.Body, map[string]string{
+		ErrorAttribute:        errorText,
+		MessageIDAttribute:    message.ID,
+		ReceiveCountAttribute: strconv.Itoa(message.ReceiveCount),
+	}); err != nil {
+		util.Log("SEND_DEAD_LETTER_ERROR", err.Error())
+		return true
+	}
+
+	util.Log("DEAD_LETTER_MESSAGE", fmt.Sprintf("%s: %s", message.ID, errorText))
+
+	return false
+}
+
+// backoff returns the visibility timeout of a message failing on the
+// provided receive.
+func (w *Worker) backoff(receiveCount int) time.Duration {
+	backoff := w.config.Backoff
+	for i := 1; i < receiveCount && backoff < w.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > w.config.MaxBackoff {
+		return w.config.MaxBackoff
+	}
+
+	return backoff
+}
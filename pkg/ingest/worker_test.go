@@ -0,0 +1,567 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/forstmeier/findfile/pkg/db"
+	"github.com/forstmeier/findfile/pkg/pars"
+	"github.com/forstmeier/findfile/pkg/queue"
+	"github.com/forstmeier/findfile/pkg/queue/mem"
+	"github.com/forstmeier/findfile/pkg/sniff"
+)
+
+const (
+	testQueueURL           = "queue"
+	testDeadLetterQueueURL = "dead-letter-queue"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+type mockSniffClient struct{}
+
+func (m *mockSniffClient) Sniff(ctx context.Context, bucket, key string) (*sniff.File, error) {
+	if key == "notes.txt" {
+		return &sniff.File{
+			ContentType: "text/plain",
+			Reason:      "unsupported content type text/plain",
+			Metadata:    pars.File{Bucket: bucket, Key: key, ContentType: "text/plain"},
+		}, nil
+	}
+
+	return &sniff.File{
+		ContentType: "application/pdf",
+		Supported:   true,
+		Metadata:    pars.File{Bucket: bucket, Key: key, ContentType: "application/pdf"},
+	}, nil
+}
+
+type mockParsClient struct {
+	mutex       sync.Mutex
+	parseErrors map[string]error
+	parseDelays map[string]time.Duration
+	inFlight    int
+	maxInFlight int
+}
+
+func (m *mockParsClient) Parse(ctx context.Context, file pars.File) (*pars.Document, error) {
+	m.mutex.Lock()
+	m.inFlight++
+	if m.inFlight > m.maxInFlight {
+		m.maxInFlight = m.inFlight
+	}
+	m.mutex.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	m.mutex.Lock()
+	m.inFlight--
+	err := m.parseErrors[file.Key]
+	delay := m.parseDelays[file.Key]
+	m.mutex.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(delay):
+	}
+
+	return &pars.Document{
+		ID:         pars.DocumentID(file.Bucket, file.Key),
+		FileBucket: file.Bucket,
+		FileKey:    file.Key,
+	}, nil
+}
+
+func (m *mockParsClient) ParseReader(ctx context.Context, reader io.Reader, file pars.File) (*pars.Document, error) {
+	return nil, nil
+}
+
+type mockDBClient struct {
+	upsertCalls    int
+	upsertedKeys   []string
+	upsertErrorKey string
+	deletedKeys    []db.FileKey
+	deleteError    error
+}
+
+func (m *mockDBClient) SetupDatabase(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockDBClient) UpsertDocuments(ctx context.Context, documents []pars.Document) error {
+	m.upsertCalls++
+	for _, document := range documents {
+		if document.FileKey == m.upsertErrorKey {
+			return errors.New("mock upsert error")
+		}
+	}
+
+	for _, document := range documents {
+		m.upsertedKeys = append(m.upsertedKeys, document.FileKey)
+	}
+
+	return nil
+}
+
+func (m *mockDBClient) DeleteDocumentsByKeys(ctx context.Context, fileKeys []db.FileKey) error {
+	if m.deleteError != nil {
+		return m.deleteError
+	}
+
+	m.deletedKeys = append(m.deletedKeys, fileKeys...)
+	return nil
+}
+
+func (m *mockDBClient) DeleteDocumentsByBuckets(ctx context.Context, buckets []string) error {
+	return nil
+}
+
+func (m *mockDBClient) QueryDocuments(ctx context.Context, query db.Query) (*db.Result, error) {
+	return nil, nil
+}
+
+func s3Body(eventName string, keys ...string) string {
+	records := ""
+	for i, key := range keys {
+		if i > 0 {
+			records += ","
+		}
+		records += fmt.Sprintf(`{ "eventSource": "aws:s3", "eventName": %q, "s3": { "bucket": { "name": "bucket" }, "object": { "key": %q } } }`, eventName, key)
+	}
+
+	return `{ "Records": [ ` + records + ` ] }`
+}
+
+func sequencedBody(eventName, key, sequencer string) string {
+	return fmt.Sprintf(`{ "Records": [ { "eventSource": "aws:s3", "eventName": %q, "s3": { "bucket": { "name": "bucket" }, "object": { "key": %q, "sequencer": %q } } } ] }`, eventName, key, sequencer)
+}
+
+func TestProcess(t *testing.T) {
+	parseError := errors.New("mock parse error")
+
+	tests := []struct {
+		description    string
+		messages       []queue.Message
+		parseErrors    map[string]error
+		upsertErrorKey string
+		deleteError    error
+		retries        []string
+		upsertCalls    int
+		upsertedKeys   []string
+		deletedKeys    []db.FileKey
+		deadLetters    []string
+		hidden         []string
+	}{
+		{
+			description: "unreadable message moved to dead-letter queue",
+			messages: []queue.Message{
+				{ID: "1", Body: "not json", ReceiveCount: 1},
+			},
+			retries:      []string{},
+			upsertCalls:  0,
+			upsertedKeys: nil,
+			deletedKeys:  nil,
+			deadLetters:  []string{"1"},
+			hidden:       []string{},
+		},
+		{
+			description: "files parsed and upserted in bulk with skipped files",
+			messages: []queue.Message{
+				{ID: "1", Body: s3Body("ObjectCreated:Put", "a.pdf", "notes.txt"), ReceiveCount: 1},
+				{ID: "2", Body: s3Body("ObjectCreated:CompleteMultipartUpload", "b.pdf"), ReceiveCount: 1},
+			},
+			retries:      []string{},
+			upsertCalls:  1,
+			upsertedKeys: []string{"a.pdf", "b.pdf", "notes.txt"},
+			deletedKeys:  nil,
+			deadLetters:  []string{},
+			hidden:       []string{},
+		},
+		{
+			description: "parse error retried with backoff",
+			messages: []queue.Message{
+				{ID: "1", Body: s3Body("ObjectCreated:Put", "a.pdf"), ReceiveCount: 1},
+				{ID: "2", Body: s3Body("ObjectCreated:Put", "b.pdf"), ReceiveCount: 1},
+			},
+			parseErrors: map[string]error{
+				"b.pdf": parseError,
+			},
+			retries:      []string{"2"},
+			upsertCalls:  1,
+			upsertedKeys: []string{"a.pdf"},
+			deletedKeys:  nil,
+			deadLetters:  []string{},
+			hidden:       []string{"2"},
+		},
+		{
+			description: "parse error on last receive moved to dead-letter queue",
+			messages: []queue.Message{
+				{ID: "1", Body: s3Body("ObjectCreated:Put", "b.pdf"), ReceiveCount: DefaultMaxReceives},
+			},
+			parseErrors: map[string]error{
+				"b.pdf": parseError,
+			},
+			retries:      []string{},
+			upsertCalls:  0,
+			upsertedKeys: nil,
+			deletedKeys:  nil,
+			deadLetters:  []string{"1"},
+			hidden:       []string{},
+		},
+		{
+			description: "bulk upsert error isolated to failing document",
+			messages: []queue.Message{
+				{ID: "1", Body: s3Body("ObjectCreated:Put", "a.pdf"), ReceiveCount: 1},
+				{ID: "2", Body: s3Body("ObjectCreated:Put", "poison.pdf"), ReceiveCount: 1},
+			},
+			upsertErrorKey: "poison.pdf",
+			retries:        []string{"2"},
+			upsertCalls:    3,
+			upsertedKeys:   []string{"a.pdf"},
+			deletedKeys:    nil,
+			deadLetters:    []string{},
+			hidden:         []string{"2"},
+		},
+		{
+			description: "delete error retried",
+			messages: []queue.Message{
+				{ID: "1", Body: s3Body("ObjectRemoved:Delete", "a.pdf"), ReceiveCount: 1},
+			},
+			deleteError:  errors.New("mock delete error"),
+			retries:      []string{"1"},
+			upsertCalls:  0,
+			upsertedKeys: nil,
+			deletedKeys:  nil,
+			deadLetters:  []string{},
+			hidden:       []string{"1"},
+		},
+		{
+			description: "latest event applied for each file",
+			messages: []queue.Message{
+				{ID: "1", Body: s3Body("ObjectCreated:Put", "a.pdf", "b.pdf"), ReceiveCount: 1},
+				{ID: "2", Body: s3Body("ObjectRemoved:Delete", "a.pdf"), ReceiveCount: 1},
+			},
+			retries:      []string{},
+			upsertCalls:  1,
+			upsertedKeys: []string{"b.pdf"},
+			deletedKeys:  []db.FileKey{{Bucket: "bucket", Key: "a.pdf"}},
+			deadLetters:  []string{},
+			hidden:       []string{},
+		},
+		{
+			description: "event with greatest sequencer applied for each file",
+			messages: []queue.Message{
+				{ID: "1", Body: sequencedBody("ObjectRemoved:Delete", "a.pdf", "0055AED6DCD90281E6"), ReceiveCount: 1},
+				{ID: "2", Body: sequencedBody("ObjectCreated:Put", "a.pdf", "0055AED6DCD90281"), ReceiveCount: 1},
+				{ID: "3", Body: sequencedBody("ObjectCreated:Put", "b.pdf", "0055AED6DCD90281E7"), ReceiveCount: 1},
+				{ID: "4", Body: s3Body("ObjectRemoved:Delete", "b.pdf"), ReceiveCount: 1},
+			},
+			retries:      []string{},
+			upsertCalls:  1,
+			upsertedKeys: []string{"b.pdf"},
+			deletedKeys:  []db.FileKey{{Bucket: "bucket", Key: "a.pdf"}},
+			deadLetters:  []string{},
+			hidden:       []string{},
+		},
+		{
+			description: "encoded nested keys deleted",
+			messages: []queue.Message{
+				{ID: "1", Body: s3Body("ObjectRemoved:Delete", "a.pdf", "folder/b+c/%E6%96%87%E5%AD%97.pdf"), ReceiveCount: 1},
+			},
+			retries:     []string{},
+			upsertCalls: 0,
+			deletedKeys: []db.FileKey{
+				{Bucket: "bucket", Key: "a.pdf"},
+				{Bucket: "bucket", Key: "folder/b c/文字.pdf"},
+			},
+			deadLetters: []string{},
+			hidden:      []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			queueClient := mem.New(time.Minute)
+			for _, message := range test.messages {
+				if err := queueClient.SendMessage(context.Background(), testQueueURL, message.Body, nil); err != nil {
+					t.Fatalf("error sending message: %v", err)
+				}
+			}
+
+			received, err := queueClient.ReceiveMessages(context.Background(), testQueueURL, 10)
+			if err != nil {
+				t.Fatalf("error receiving messages: %v", err)
+			}
+
+			messages := []queue.Message{}
+			for i, message := range test.messages {
+				message.ReceiptHandle = received[i].ReceiptHandle
+				messages = append(messages, message)
+			}
+
+			dbClient := &mockDBClient{
+				upsertErrorKey: test.upsertErrorKey,
+				deleteError:    test.deleteError,
+			}
+
+			worker := New(&mockSniffClient{}, &mockParsClient{parseErrors: test.parseErrors}, dbClient, queueClient, Config{
+				QueueURL:           testQueueURL,
+				DeadLetterQueueURL: testDeadLetterQueueURL,
+				Backoff:            5 * time.Minute,
+			})
+
+			retries := worker.Process(context.Background(), messages)
+
+			if !reflect.DeepEqual(retries, test.retries) {
+				t.Errorf("incorrect retries, received: %v, expected: %v", retries, test.retries)
+			}
+
+			if dbClient.upsertCalls != test.upsertCalls {
+				t.Errorf("incorrect upsert calls, received: %d, expected: %d", dbClient.upsertCalls, test.upsertCalls)
+			}
+
+			sort.Strings(dbClient.upsertedKeys)
+			if !reflect.DeepEqual(dbClient.upsertedKeys, test.upsertedKeys) {
+				t.Errorf("incorrect upserted keys, received: %v, expected: %v", dbClient.upsertedKeys, test.upsertedKeys)
+			}
+
+			if !reflect.DeepEqual(dbClient.deletedKeys, test.deletedKeys) {
+				t.Errorf("incorrect deleted keys, received: %v, expected: %v", dbClient.deletedKeys, test.deletedKeys)
+			}
+
+			deadLetters := []string{}
+			for _, message := range queueClient.Messages(testDeadLetterQueueURL) {
+				deadLetters = append(deadLetters, message.Attributes[MessageIDAttribute])
+				if message.Attributes[ErrorAttribute] == "" {
+					t.Errorf("dead letter message %s missing error attribute", message.Attributes[MessageIDAttribute])
+				}
+			}
+
+			if !reflect.DeepEqual(deadLetters, test.deadLetters) {
+				t.Errorf("incorrect dead letters, received: %v, expected: %v", deadLetters, test.deadLetters)
+			}
+
+			// messages hidden with backoff are still hidden once the
+			// visibility timeout of the receive has passed while the
+			// other messages are left for the caller to delete
+			queueClient.Advance(time.Minute)
+			visible, err := queueClient.ReceiveMessages(context.Background(), testQueueURL, 10)
+			if err != nil {
+				t.Fatalf("error receiving messages: %v", err)
+			}
+
+			hidden := []string{}
+			for i := range test.messages {
+				found := false
+				for _, message := range visible {
+					if message.ID == received[i].ID {
+						found = true
+					}
+				}
+
+				if !found {
+					hidden = append(hidden, test.messages[i].ID)
+				}
+			}
+
+			if !reflect.DeepEqual(hidden, test.hidden) {
+				t.Errorf("incorrect hidden messages, received: %v, expected: %v", hidden, test.hidden)
+			}
+		})
+	}
+}
+
+func TestProcessDeadline(t *testing.T) {
+	queueClient := mem.New(time.Minute)
+	for _, key := range []string{"a.pdf", "slow.pdf"} {
+		if err := queueClient.SendMessage(context.Background(), testQueueURL, s3Body("ObjectCreated:Put", key), nil); err != nil {
+			t.Fatalf("error sending message: %v", err)
+		}
+	}
+
+	messages, err := queueClient.ReceiveMessages(context.Background(), testQueueURL, 10)
+	if err != nil {
+		t.Fatalf("error receiving messages: %v", err)
+	}
+
+	parsClient := &mockParsClient{
+		parseDelays: map[string]time.Duration{
+			"slow.pdf": time.Minute,
+		},
+	}
+	dbClient := &mockDBClient{}
+
+	worker := New(&mockSniffClient{}, parsClient, dbClient, queueClient, Config{
+		QueueURL:           testQueueURL,
+		DeadLetterQueueURL: testDeadLetterQueueURL,
+		Reserve:            time.Second,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second+100*time.Millisecond)
+	defer cancel()
+
+	retries := worker.Process(ctx, messages)
+
+	if !reflect.DeepEqual(retries, []string{messages[1].ID}) {
+		t.Errorf("incorrect retries, received: %v, expected: %v", retries, []string{messages[1].ID})
+	}
+
+	if !reflect.DeepEqual(dbClient.upsertedKeys, []string{"a.pdf"}) {
+		t.Errorf("incorrect upserted keys, received: %v, expected: %v", dbClient.upsertedKeys, []string{"a.pdf"})
+	}
+
+	if ctx.Err() != nil {
+		t.Errorf("incorrect context error, received: %v, expected: nil", ctx.Err())
+	}
+}
+
+func TestPoll(t *testing.T) {
+	ctx := context.Background()
+	queueClient := mem.New(time.Minute)
+
+	bodies := []string{
+		s3Body("ObjectCreated:Put", "a.pdf"),
+		s3Body("ObjectCreated:Put", "poison.pdf"),
+		s3Body("ObjectRemoved:Delete", "c.pdf"),
+	}
+	for _, body := range bodies {
+		if err := queueClient.SendMessage(ctx, testQueueURL, body, nil); err != nil {
+			t.Fatalf("error sending message: %v", err)
+		}
+	}
+
+	parsClient := &mockParsClient{
+		parseErrors: map[string]error{
+			"poison.pdf": errors.New("mock parse error"),
+		},
+	}
+	dbClient := &mockDBClient{}
+
+	worker := New(&mockSniffClient{}, parsClient, dbClient, queueClient, Config{
+		QueueURL:           testQueueURL,
+		DeadLetterQueueURL: testDeadLetterQueueURL,
+		MaxReceives:        3,
+		Backoff:            time.Minute,
+		MaxBackoff:         3 * time.Minute,
+	})
+
+	received := 0
+	for i := 0; i < 10; i++ {
+		count, err := worker.Poll(ctx)
+		if err != nil {
+			t.Fatalf("error polling: %v", err)
+		}
+		received += count
+
+		queueClient.Advance(3 * time.Minute)
+	}
+
+	// the failing message is received three times before it is moved
+	if received != 5 {
+		t.Errorf("incorrect received messages, received: %d, expected: %d", received, 5)
+	}
+
+	if remaining := queueClient.Messages(testQueueURL); len(remaining) != 0 {
+		t.Errorf("incorrect remaining messages, received: %+v", remaining)
+	}
+
+	deadLetters := queueClient.Messages(testDeadLetterQueueURL)
+	if len(deadLetters) != 1 {
+		t.Fatalf("incorrect dead letters, received: %+v", deadLetters)
+	}
+
+	expectedAttributes := map[string]string{
+		ErrorAttribute:        "bucket/poison.pdf: mock parse error",
+		MessageIDAttribute:    "message-2",
+		ReceiveCountAttribute: "3",
+	}
+	if deadLetters[0].Body != bodies[1] || !reflect.DeepEqual(deadLetters[0].Attributes, expectedAttributes) {
+		t.Errorf("incorrect dead letter, received: %+v, expected attributes: %v", deadLetters[0], expectedAttributes)
+	}
+
+	if !reflect.DeepEqual(dbClient.upsertedKeys, []string{"a.pdf"}) {
+		t.Errorf("incorrect upserted keys, received: %v, expected: %v", dbClient.upsertedKeys, []string{"a.pdf"})
+	}
+
+	if !reflect.DeepEqual(dbClient.deletedKeys, []db.FileKey{{Bucket: "bucket", Key: "c.pdf"}}) {
+		t.Errorf("incorrect deleted keys, received: %v, expected: %v", dbClient.deletedKeys, []db.FileKey{{Bucket: "bucket", Key: "c.pdf"}})
+	}
+}
+
+func TestProcessConcurrency(t *testing.T) {
+	keys := []string{}
+	messages := []queue.Message{}
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("%d.pdf", i)
+		keys = append(keys, key)
+		messages = append(messages, queue.Message{
+			ID:           fmt.Sprintf("%d", i),
+			Body:         s3Body("ObjectCreated:Put", key),
+			ReceiveCount: 1,
+		})
+	}
+
+	parsClient := &mockParsClient{}
+	dbClient := &mockDBClient{}
+
+	worker := New(&mockSniffClient{}, parsClient, dbClient, mem.New(time.Minute), Config{
+		Concurrency: 3,
+	})
+
+	if retries := worker.Process(context.Background(), messages); len(retries) != 0 {
+		t.Fatalf("incorrect retries, received: %v", retries)
+	}
+
+	if parsClient.maxInFlight > 3 || parsClient.maxInFlight < 2 {
+		t.Errorf("incorrect files parsed at once, received: %d, expected: up to %d", parsClient.maxInFlight, 3)
+	}
+
+	sort.Strings(dbClient.upsertedKeys)
+	if !reflect.DeepEqual(dbClient.upsertedKeys, keys) {
+		t.Errorf("incorrect upserted keys, received: %v, expected: %v", dbClient.upsertedKeys, keys)
+	}
+}
+
+func Test_backoff(t *testing.T) {
+	worker := New(nil, nil, nil, nil, Config{
+		Backoff:    time.Minute,
+		MaxBackoff: 5 * time.Minute,
+	})
+
+	tests := []struct {
+		receiveCount int
+		backoff      time.Duration
+	}{
+		{receiveCount: 1, backoff: time.Minute},
+		{receiveCount: 2, backoff: 2 * time.Minute},
+		{receiveCount: 3, backoff: 4 * time.Minute},
+		{receiveCount: 4, backoff: 5 * time.Minute},
+		{receiveCount: 40, backoff: 5 * time.Minute},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("receive %d", test.receiveCount), func(t *testing.T) {
+			if backoff := worker.backoff(test.receiveCount); backoff != test.backoff {
+				t.Errorf("incorrect backoff, received: %s, expected: %s", backoff, test.backoff)
+			}
+		})
+	}
+}
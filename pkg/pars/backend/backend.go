@@ -0,0 +1,63 @@
+// Package backend creates the pars.Parser implementation selected in
+// the function configuration.
+package backend
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/forstmeier/findfile/pkg/pars"
+	"github.com/forstmeier/findfile/pkg/pars/pdf"
+	"github.com/forstmeier/findfile/pkg/pars/tesseract"
+)
+
+// Backends supported by backend.New.
+const (
+	Textract  = "textract"
+	Tesseract = "tesseract"
+)
+
+// Config holds the values used to create a pars.Parser. Backend
+// defaults to "textract" if empty. Analysis is used by the Textract
+// backend to also extract tables and form fields. Command, PDFCommand,
+// and Language are used by the Tesseract backend, which rasterizes
+// PDFs with the pdftoppm PDFCommand. PDFTextLayer reads the text layer
+// of PDFs and sends files that are not PDFs or have any page without
+// text to the backend.
+type Config struct {
+	Backend      string
+	Analysis     bool
+	Command      string
+	PDFCommand   string
+	Language     string
+	PDFTextLayer bool
+}
+
+// New generates the pars.Parser implementation selected by the
+// config.
+func New(newSession *session.Session, config Config) (pars.Parser, error) {
+	var parser pars.Parser
+	switch config.Backend {
+	case "", Textract:
+		if config.Analysis {
+			parser = pars.NewAnalysis(newSession)
+		} else {
+			parser = pars.New(newSession)
+		}
+
+	case Tesseract:
+		parser = tesseract.New(newSession, config.Command, config.PDFCommand, config.Language)
+
+	default:
+		return nil, &UnsupportedBackendError{
+			err: fmt.Errorf("unsupported backend %q", config.Backend),
+		}
+	}
+
+	if config.PDFTextLayer {
+		parser = pdf.New(newSession, parser)
+	}
+
+	return parser, nil
+}
@@ -0,0 +1,152 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/forstmeier/findfile/pkg/pars"
+	"github.com/forstmeier/findfile/pkg/pars/pdf"
+	"github.com/forstmeier/findfile/pkg/pars/tesseract"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		description string
+		config      Config
+		parser      pars.Parser
+		error       error
+	}{
+		{
+			description: "default textract backend",
+			config:      Config{},
+			parser:      &pars.Client{},
+			error:       nil,
+		},
+		{
+			description: "textract analysis",
+			config: Config{
+				Analysis: true,
+			},
+			parser: &pars.Client{},
+			error:  nil,
+		},
+		{
+			description: "tesseract backend",
+			config: Config{
+				Backend:  Tesseract,
+				Language: "eng",
+			},
+			parser: &tesseract.Client{},
+			error:  nil,
+		},
+		{
+			description: "pdf text layer",
+			config: Config{
+				PDFTextLayer: true,
+			},
+			parser: &pdf.Client{},
+			error:  nil,
+		},
+		{
+			description: "unsupported backend",
+			config: Config{
+				Backend: "rekognition",
+			},
+			error: &UnsupportedBackendError{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			parser, err := New(session.New(), test.config)
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *UnsupportedBackendError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else if reflect.TypeOf(parser) != reflect.TypeOf(test.parser) {
+				t.Errorf("incorrect parser, received: %T, expected: %T", parser, test.parser)
+			}
+		})
+	}
+}
+
+// testTSV holds Tesseract TSV output with one line on each of two
+// pages.
+const testTSV = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+	"1\t1\t0\t0\t0\t0\t0\t0\t1000\t2000\t-1\t\n" +
+	"4\t1\t1\t1\t1\t0\t100\t200\t400\t50\t-1\t\n" +
+	"5\t1\t1\t1\t1\t1\t100\t200\t400\t50\t96.5\tInvoice\n" +
+	"1\t2\t0\t0\t0\t0\t0\t0\t1000\t2000\t-1\t\n" +
+	"4\t2\t1\t1\t1\t0\t100\t200\t400\t50\t-1\t\n" +
+	"5\t2\t1\t1\t1\t1\t100\t200\t400\t50\t88\tTotal\n"
+
+func writeScript(t *testing.T, directory, name, body string) string {
+	t.Helper()
+
+	path := filepath.Join(directory, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatalf("error writing script: %v", err)
+	}
+
+	return path
+}
+
+func TestNewTesseractPDF(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts not supported")
+	}
+
+	directory := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(directory, "output.tsv"), []byte(testTSV), 0644); err != nil {
+		t.Fatalf("error writing output: %v", err)
+	}
+
+	// the stand-in commands rasterize two pages and fail unless
+	// Tesseract is given the list of both page images
+	pdfCommand := writeScript(t, directory, "pdftoppm", `for page in 1 2; do printf image > "$5-$page.tif"; done`)
+	command := writeScript(t, directory, "tesseract", `test "$(wc -l < "$1")" -eq 2 || exit 1; cat "`+filepath.Join(directory, "output.tsv")+`"`)
+
+	parser, err := New(session.New(), Config{
+		Backend:    Tesseract,
+		Command:    command,
+		PDFCommand: pdfCommand,
+	})
+	if err != nil {
+		t.Fatalf("error creating parser: %v", err)
+	}
+
+	document, err := parser.ParseReader(context.Background(), strings.NewReader("%PDF-1.4"), pars.File{
+		Bucket:      "uploads",
+		Key:         "invoices/acme.pdf",
+		ContentType: "application/pdf",
+	})
+	if err != nil {
+		t.Fatalf("error parsing pdf: %v", err)
+	}
+
+	lines := []string{}
+	for _, page := range document.Pages {
+		for _, line := range page.Lines {
+			lines = append(lines, line.Text)
+		}
+	}
+
+	if expected := []string{"Invoice", "Total"}; !reflect.DeepEqual(lines, expected) {
+		t.Errorf("incorrect lines, received: %v, expected: %v", lines, expected)
+	}
+}
@@ -0,0 +1,19 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUnsupportedBackendError(t *testing.T) {
+	err := &UnsupportedBackendError{
+		err: errors.New("mock unsupported backend error"),
+	}
+
+	recieved := err.Error()
+	expected := "package backend: mock unsupported backend error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
@@ -2,11 +2,17 @@ package pars
 
 import (
 	"context"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/textract"
-	"github.com/google/uuid"
 )
 
 var _ Parser = &Client{}
@@ -14,11 +20,18 @@ var _ Parser = &Client{}
 // Client implements the pars.Parser methods using AWS Textract.
 type Client struct {
 	textractClient    textractClient
-	convertToDocument func(input *textract.DetectDocumentTextOutput, fileKey, fileBucket string) Document
+	analysis          bool
+	convertToDocument func(input *textract.DetectDocumentTextOutput, fileKey, fileBucket, fileVersion string) Document
+	wait              func(ctx context.Context, duration time.Duration) error
 }
 
 type textractClient interface {
 	DetectDocumentText(input *textract.DetectDocumentTextInput) (*textract.DetectDocumentTextOutput, error)
+	StartDocumentTextDetection(input *textract.StartDocumentTextDetectionInput) (*textract.StartDocumentTextDetectionOutput, error)
+	GetDocumentTextDetection(input *textract.GetDocumentTextDetectionInput) (*textract.GetDocumentTextDetectionOutput, error)
+	AnalyzeDocument(input *textract.AnalyzeDocumentInput) (*textract.AnalyzeDocumentOutput, error)
+	StartDocumentAnalysis(input *textract.StartDocumentAnalysisInput) (*textract.StartDocumentAnalysisOutput, error)
+	GetDocumentAnalysis(input *textract.GetDocumentAnalysisInput) (*textract.GetDocumentAnalysisOutput, error)
 }
 
 // New generates a Client pointer instance with an AWS Textract client.
@@ -28,109 +41,419 @@ func New(newSession *session.Session) *Client {
 	return &Client{
 		textractClient:    service,
 		convertToDocument: convertToDocument,
+		wait:              wait,
 	}
 }
 
+// NewAnalysis generates a Client pointer instance with an AWS Textract
+// client that also extracts the tables and form fields of each page
+// with Textract document analysis.
+func NewAnalysis(newSession *session.Session) *Client {
+	client := New(newSession)
+	client.analysis = true
+
+	return client
+}
+
 // Parse implements the pars.Parser.Parse interface method
-// using AWS Textract.
-func (c *Client) Parse(ctx context.Context, fileBucket, fileKey string) (*Document, error) {
-	input := &textract.DetectDocumentTextInput{
-		Document: &textract.Document{
-			S3Object: &textract.S3Object{
-				Bucket: aws.String(fileBucket),
-				Name:   aws.String(fileKey),
-			},
-		},
+// using AWS Textract. PDFs and TIFFs, which may have several pages,
+// are parsed with an asynchronous job and other images with a
+// synchronous call. Clients created with NewAnalysis analyze the
+// document for tables and forms instead of only detecting its text.
+func (c *Client) Parse(ctx context.Context, file File) (*Document, error) {
+	fileBucket, fileKey := file.Bucket, file.Key
+	multiPageFile := multiPage(fileKey, file.ContentType)
+	textractDocument := s3Document(fileBucket, fileKey)
+
+	var output *textract.DetectDocumentTextOutput
+	var err error
+	switch {
+	case multiPageFile && c.analysis:
+		output, err = c.analyzeDocumentJob(ctx, fileBucket, fileKey)
+	case multiPageFile:
+		output, err = c.detectDocumentTextJob(ctx, fileBucket, fileKey)
+	case c.analysis:
+		output, err = c.analyzeDocument(textractDocument)
+	default:
+		output, err = c.detectDocumentText(textractDocument)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	output, err := c.textractClient.DetectDocumentText(input)
+	return c.document(output, file), nil
+}
+
+// ParseReader implements the pars.Parser.ParseReader interface method
+// using AWS Textract. The file content is sent in the request to the
+// synchronous API so it must be within the Textract request size limit;
+// PDFs and TIFFs with several pages can only be read by jobs on S3
+// objects and must be parsed with Parse.
+func (c *Client) ParseReader(ctx context.Context, reader io.Reader, file File) (*Document, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, &ReadFileError{err: err}
+	}
+
+	textractDocument := &textract.Document{
+		Bytes: data,
+	}
+
+	var output *textract.DetectDocumentTextOutput
+	if c.analysis {
+		output, err = c.analyzeDocument(textractDocument)
+	} else {
+		output, err = c.detectDocumentText(textractDocument)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c.document(output, file), nil
+}
+
+// document converts the Textract output into a document with the file
+// metadata.
+func (c *Client) document(output *textract.DetectDocumentTextOutput, file File) *Document {
+	document := c.convertToDocument(output, file.Key, file.Bucket, file.Version)
+
+	SetFileMetadata(&document, file)
+
+	return &document
+}
+
+func (c *Client) detectDocumentText(textractDocument *textract.Document) (*textract.DetectDocumentTextOutput, error) {
+	output, err := c.textractClient.DetectDocumentText(&textract.DetectDocumentTextInput{
+		Document: textractDocument,
+	})
 	if err != nil {
 		return nil, &ParseDocumentError{err: err}
 	}
 
-	document := c.convertToDocument(output, fileKey, fileBucket)
+	return output, nil
+}
+
+// analyzeDocument runs a synchronous Textract document analysis and
+// returns its blocks in a text detection output.
+func (c *Client) analyzeDocument(textractDocument *textract.Document) (*textract.DetectDocumentTextOutput, error) {
+	output, err := c.textractClient.AnalyzeDocument(&textract.AnalyzeDocumentInput{
+		Document:     textractDocument,
+		FeatureTypes: featureTypes(),
+	})
+	if err != nil {
+		return nil, &ParseDocumentError{err: err}
+	}
 
-	return &document, nil
+	return &textract.DetectDocumentTextOutput{
+		DocumentMetadata: output.DocumentMetadata,
+		Blocks:           output.Blocks,
+	}, nil
 }
 
-func convertToDocument(input *textract.DetectDocumentTextOutput, fileKey, fileBucket string) Document {
+func s3Document(fileBucket, fileKey string) *textract.Document {
+	return &textract.Document{
+		S3Object: &textract.S3Object{
+			Bucket: aws.String(fileBucket),
+			Name:   aws.String(fileKey),
+		},
+	}
+}
+
+// featureTypes returns the Textract document analysis features used by
+// clients created with NewAnalysis.
+func featureTypes() []*string {
+	return aws.StringSlice([]string{
+		textract.FeatureTypeTables,
+		textract.FeatureTypeForms,
+	})
+}
+
+// FileExtension returns the lowercase extension of the provided file
+// key without the leading period.
+func FileExtension(fileKey string) string {
+	return strings.ToLower(strings.TrimPrefix(path.Ext(fileKey), "."))
+}
+
+// FileVersion returns the S3 version ID of the object if versioning
+// is enabled on the bucket and the object ETag otherwise.
+func FileVersion(output *s3.HeadObjectOutput) string {
+	if output.VersionId != nil && *output.VersionId != "null" {
+		return *output.VersionId
+	}
+
+	if output.ETag != nil {
+		return strings.Trim(*output.ETag, `"`)
+	}
+
+	return ""
+}
+
+// NewFile returns the file metadata of the S3 object at the provided
+// bucket and key.
+func NewFile(fileBucket, fileKey string, output *s3.HeadObjectOutput) File {
+	return File{
+		Bucket:       fileBucket,
+		Key:          fileKey,
+		Version:      FileVersion(output),
+		ContentType:  aws.StringValue(output.ContentType),
+		LastModified: output.LastModified,
+	}
+}
+
+// NewSkippedDocument returns a document without pages recording that
+// the file was not parsed for the provided reason.
+func NewSkippedDocument(file File, reason string) Document {
+	document := Document{
+		ID:            DocumentID(file.Bucket, file.Key),
+		Entity:        "document",
+		FileKey:       file.Key,
+		FileBucket:    file.Bucket,
+		FileVersion:   file.Version,
+		FileExtension: FileExtension(file.Key),
+		SkipReason:    reason,
+	}
+	SetFileMetadata(&document, file)
+
+	return document
+}
+
+// SetFileMetadata sets the content type and last modified time of the
+// file on the document along with the time it was indexed.
+func SetFileMetadata(document *Document, file File) {
+	document.ContentType = file.ContentType
+	if file.LastModified != nil {
+		lastModified := file.LastModified.UTC()
+		document.LastModified = &lastModified
+	}
+
+	indexedAt := time.Now().UTC()
+	document.IndexedAt = &indexedAt
+}
+
+// convertToDocument converts the Textract blocks into a document with
+// the words of each line taken from its child word blocks. Document
+// analysis blocks are carried in the same output type; their tables
+// and key-value sets are added to the page they were found on.
+func convertToDocument(input *textract.DetectDocumentTextOutput, fileKey, fileBucket, fileVersion string) Document {
 	document := Document{
-		ID:         uuid.NewString(),
-		Entity:     "document",
-		FileKey:    fileKey,
-		FileBucket: fileBucket,
+		ID:            DocumentID(fileBucket, fileKey),
+		Entity:        "document",
+		FileKey:       fileKey,
+		FileBucket:    fileBucket,
+		FileVersion:   fileVersion,
+		FileExtension: FileExtension(fileKey),
 	}
 
 	pages := []*textract.Block{}
-	lines := map[string]*textract.Block{}
+	tables := []*textract.Block{}
+	keys := []*textract.Block{}
+	blocks := map[string]*textract.Block{}
 
 	for _, block := range input.Blocks {
-		if *block.BlockType == textract.BlockTypePage {
-			pages = append(pages, block)
-		}
+		blocks[*block.Id] = block
 
-		if *block.BlockType == textract.BlockTypeLine {
-			lines[*block.Id] = block
+		switch *block.BlockType {
+		case textract.BlockTypePage:
+			pages = append(pages, block)
+		case textract.BlockTypeTable:
+			tables = append(tables, block)
+		case textract.BlockTypeKeyValueSet:
+			for _, entityType := range block.EntityTypes {
+				if aws.StringValue(entityType) == textract.EntityTypeKey {
+					keys = append(keys, block)
+				}
+			}
 		}
 	}
 
+	pagePositions := map[int64]int{}
 	for _, pageBlock := range pages {
 		page := Page{
-			ID:     uuid.NewString(),
-			Entity: "page",
-			Lines:  []Line{},
+			Entity:     "page",
+			PageNumber: blockPage(pageBlock),
+			Lines:      []Line{},
 		}
 
-		if pageBlock.Page == nil {
-			page.PageNumber = 1
-		} else {
-			page.PageNumber = *pageBlock.Page
-		}
+		page.ID = NewID(document.ID, fileVersion, page.Entity, strconv.FormatInt(page.PageNumber, 10))
 
 		if len(pageBlock.Relationships) == 0 {
 			continue
 		}
 
 		for _, id := range pageBlock.Relationships[0].Ids {
-			// not all child IDs are "lines" which requires an ok check
-			if lineBlock, ok := lines[*id]; ok {
-				left := *lineBlock.Geometry.BoundingBox.Left
-				top := *lineBlock.Geometry.BoundingBox.Top
-				height := *lineBlock.Geometry.BoundingBox.Height
-				width := *lineBlock.Geometry.BoundingBox.Width
-
-				data := Line{
-					ID:     uuid.NewString(),
-					Entity: "line",
-					Text:   *lineBlock.Text,
-					Coordinates: Coordinates{
-						ID:     uuid.NewString(),
-						Entity: "coordinates",
-						TopLeft: Point{
-							X: left,
-							Y: top,
-						},
-						TopRight: Point{
-							X: left + width,
-							Y: top,
-						},
-						BottomLeft: Point{
-							X: left,
-							Y: top + height,
-						},
-						BottomRight: Point{
-							X: left + width,
-							Y: top + height,
-						},
-					},
+			// not all child IDs are "lines" which requires a type check
+			lineBlock, ok := blocks[*id]
+			if !ok || *lineBlock.BlockType != textract.BlockTypeLine {
+				continue
+			}
+
+			lineID := NewID(page.ID, "line", strconv.Itoa(len(page.Lines)))
+
+			data := Line{
+				ID:          lineID,
+				Entity:      "line",
+				Text:        *lineBlock.Text,
+				Confidence:  aws.Float64Value(lineBlock.Confidence),
+				Coordinates: blockCoordinates(NewID(lineID, "coordinates"), lineBlock),
+			}
+
+			for _, wordBlock := range relatedBlocks(blocks, lineBlock, textract.RelationshipTypeChild) {
+				if *wordBlock.BlockType != textract.BlockTypeWord {
+					continue
 				}
 
-				page.Lines = append(page.Lines, data)
+				id := NewID(lineID, "word", strconv.Itoa(len(data.Words)))
+				data.Words = append(data.Words, Word{
+					ID:          id,
+					Entity:      "word",
+					Text:        aws.StringValue(wordBlock.Text),
+					Confidence:  aws.Float64Value(wordBlock.Confidence),
+					Coordinates: blockCoordinates(NewID(id, "coordinates"), wordBlock),
+				})
 			}
+
+			page.Lines = append(page.Lines, data)
 		}
 
+		pagePositions[page.PageNumber] = len(document.Pages)
 		document.Pages = append(document.Pages, page)
 	}
 
+	for _, tableBlock := range tables {
+		position, ok := pagePositions[blockPage(tableBlock)]
+		if !ok {
+			continue
+		}
+		page := &document.Pages[position]
+
+		tableID := NewID(page.ID, "table", strconv.Itoa(len(page.Tables)))
+		table := Table{
+			ID:          tableID,
+			Entity:      "table",
+			Confidence:  aws.Float64Value(tableBlock.Confidence),
+			Coordinates: blockCoordinates(NewID(tableID, "coordinates"), tableBlock),
+		}
+
+		for _, cellBlock := range relatedBlocks(blocks, tableBlock, textract.RelationshipTypeChild) {
+			if *cellBlock.BlockType != textract.BlockTypeCell {
+				continue
+			}
+
+			cellID := NewID(tableID, "cell", strconv.Itoa(len(table.Cells)))
+			table.Cells = append(table.Cells, Cell{
+				ID:          cellID,
+				Entity:      "cell",
+				RowIndex:    aws.Int64Value(cellBlock.RowIndex),
+				ColumnIndex: aws.Int64Value(cellBlock.ColumnIndex),
+				RowSpan:     aws.Int64Value(cellBlock.RowSpan),
+				ColumnSpan:  aws.Int64Value(cellBlock.ColumnSpan),
+				Text:        blockText(blocks, cellBlock),
+				Confidence:  aws.Float64Value(cellBlock.Confidence),
+				Coordinates: blockCoordinates(NewID(cellID, "coordinates"), cellBlock),
+			})
+		}
+
+		page.Tables = append(page.Tables, table)
+	}
+
+	for _, keyBlock := range keys {
+		position, ok := pagePositions[blockPage(keyBlock)]
+		if !ok {
+			continue
+		}
+		page := &document.Pages[position]
+
+		key := blockText(blocks, keyBlock)
+		if key == "" {
+			continue
+		}
+
+		confidence := aws.Float64Value(keyBlock.Confidence)
+		fieldBlocks := []*textract.Block{keyBlock}
+		values := []string{}
+		for _, valueBlock := range relatedBlocks(blocks, keyBlock, textract.RelationshipTypeValue) {
+			if value := blockText(blocks, valueBlock); value != "" {
+				values = append(values, value)
+			}
+			confidence = math.Min(confidence, aws.Float64Value(valueBlock.Confidence))
+			fieldBlocks = append(fieldBlocks, valueBlock)
+		}
+
+		fieldID := NewID(page.ID, "field", strconv.Itoa(len(page.Fields)))
+		page.Fields = append(page.Fields, Field{
+			ID:          fieldID,
+			Entity:      "field",
+			Key:         key,
+			Value:       strings.Join(values, " "),
+			Confidence:  confidence,
+			Coordinates: blockCoordinates(NewID(fieldID, "coordinates"), fieldBlocks...),
+		})
+	}
+
 	return document
 }
+
+// blockPage returns the page number of the block; synchronous outputs
+// only hold a single page and do not set it.
+func blockPage(block *textract.Block) int64 {
+	if block.Page == nil {
+		return 1
+	}
+
+	return *block.Page
+}
+
+// relatedBlocks returns the blocks the block has a relationship of
+// the provided type with.
+func relatedBlocks(blocks map[string]*textract.Block, block *textract.Block, relationshipType string) []*textract.Block {
+	output := []*textract.Block{}
+	for _, relationship := range block.Relationships {
+		if aws.StringValue(relationship.Type) != relationshipType {
+			continue
+		}
+
+		for _, id := range relationship.Ids {
+			if related, ok := blocks[*id]; ok {
+				output = append(output, related)
+			}
+		}
+	}
+
+	return output
+}
+
+// blockText returns the text of the child words of a cell or key-value
+// set block; selection elements such as checkboxes are given as their
+// selection status.
+func blockText(blocks map[string]*textract.Block, block *textract.Block) string {
+	texts := []string{}
+	for _, child := range relatedBlocks(blocks, block, textract.RelationshipTypeChild) {
+		switch *child.BlockType {
+		case textract.BlockTypeWord:
+			texts = append(texts, aws.StringValue(child.Text))
+		case textract.BlockTypeSelectionElement:
+			texts = append(texts, aws.StringValue(child.SelectionStatus))
+		}
+	}
+
+	return strings.Join(texts, " ")
+}
+
+// blockCoordinates returns the coordinates of the box enclosing the
+// bounding boxes of the blocks.
+func blockCoordinates(id string, blocks ...*textract.Block) Coordinates {
+	box := blocks[0].Geometry.BoundingBox
+	left, top, width, height := *box.Left, *box.Top, *box.Width, *box.Height
+
+	for _, block := range blocks[1:] {
+		box := block.Geometry.BoundingBox
+		right := math.Max(left+width, *box.Left+*box.Width)
+		bottom := math.Max(top+height, *box.Top+*box.Height)
+		left = math.Min(left, *box.Left)
+		top = math.Min(top, *box.Top)
+		width = right - left
+		height = bottom - top
+	}
+
+	return NewCoordinates(id, left, top, width, height)
+}
@@ -1,10 +1,15 @@
 package pars
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
 	"math"
+	"reflect"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -19,22 +24,83 @@ func TestNew(t *testing.T) {
 }
 
 type mockTextractClient struct {
-	textractClientOutput *textract.DetectDocumentTextOutput
-	textractClientError  error
+	mockDetectInput        *textract.DetectDocumentTextInput
+	textractClientOutput   *textract.DetectDocumentTextOutput
+	textractClientError    error
+	mockStartInput         *textract.StartDocumentTextDetectionInput
+	mockStartError         error
+	mockGetInputs          []*textract.GetDocumentTextDetectionInput
+	mockGetOutputs         []*textract.GetDocumentTextDetectionOutput
+	mockGetError           error
+	mockAnalyzeInput       *textract.AnalyzeDocumentInput
+	mockAnalyzeOutput      *textract.AnalyzeDocumentOutput
+	mockAnalyzeError       error
+	mockStartAnalysisInput *textract.StartDocumentAnalysisInput
+	mockGetAnalysisInputs  []*textract.GetDocumentAnalysisInput
+	mockGetAnalysisOutputs []*textract.GetDocumentAnalysisOutput
 }
 
 func (m *mockTextractClient) DetectDocumentText(input *textract.DetectDocumentTextInput) (*textract.DetectDocumentTextOutput, error) {
+	m.mockDetectInput = input
 	return m.textractClientOutput, m.textractClientError
 }
 
+func (m *mockTextractClient) StartDocumentTextDetection(input *textract.StartDocumentTextDetectionInput) (*textract.StartDocumentTextDetectionOutput, error) {
+	m.mockStartInput = input
+	if m.mockStartError != nil {
+		return nil, m.mockStartError
+	}
+
+	return &textract.StartDocumentTextDetectionOutput{
+		JobId: aws.String("job_id"),
+	}, nil
+}
+
+// GetDocumentTextDetection returns the mock outputs in order, acting
+// as a job that is polled and then paged through.
+func (m *mockTextractClient) GetDocumentTextDetection(input *textract.GetDocumentTextDetectionInput) (*textract.GetDocumentTextDetectionOutput, error) {
+	m.mockGetInputs = append(m.mockGetInputs, input)
+	if m.mockGetError != nil {
+		return nil, m.mockGetError
+	}
+
+	output := m.mockGetOutputs[0]
+	m.mockGetOutputs = m.mockGetOutputs[1:]
+	return output, nil
+}
+
+func (m *mockTextractClient) AnalyzeDocument(input *textract.AnalyzeDocumentInput) (*textract.AnalyzeDocumentOutput, error) {
+	m.mockAnalyzeInput = input
+	return m.mockAnalyzeOutput, m.mockAnalyzeError
+}
+
+func (m *mockTextractClient) StartDocumentAnalysis(input *textract.StartDocumentAnalysisInput) (*textract.StartDocumentAnalysisOutput, error) {
+	m.mockStartAnalysisInput = input
+	return &textract.StartDocumentAnalysisOutput{
+		JobId: aws.String("analysis_job_id"),
+	}, nil
+}
+
+// GetDocumentAnalysis returns the mock analysis outputs in order.
+func (m *mockTextractClient) GetDocumentAnalysis(input *textract.GetDocumentAnalysisInput) (*textract.GetDocumentAnalysisOutput, error) {
+	m.mockGetAnalysisInputs = append(m.mockGetAnalysisInputs, input)
+
+	output := m.mockGetAnalysisOutputs[0]
+	m.mockGetAnalysisOutputs = m.mockGetAnalysisOutputs[1:]
+	return output, nil
+}
+
 func TestParse(t *testing.T) {
 	fileKey := "test.jpg"
 	fileBucket := "s3://bucket"
+	lastModified := time.Date(2021, time.November, 1, 12, 0, 0, 0, time.UTC)
 
 	tests := []struct {
 		description          string
+		analysis             bool
 		textractClientOutput *textract.DetectDocumentTextOutput
 		textractClientError  error
+		mockAnalyzeError     error
 		document             Document
 		error                error
 	}{
@@ -45,6 +111,21 @@ func TestParse(t *testing.T) {
 			document:             Document{},
 			error:                &ParseDocumentError{},
 		},
+		{
+			description:      "textract client analyze error",
+			analysis:         true,
+			mockAnalyzeError: errors.New("mock analyze error"),
+			document:         Document{},
+			error:            &ParseDocumentError{},
+		},
+		{
+			description: "analyzed document returned",
+			analysis:    true,
+			document: Document{
+				ID: "document_0",
+			},
+			error: nil,
+		},
 		{
 			description:          "no pages/lines returned",
 			textractClientOutput: &textract.DetectDocumentTextOutput{},
@@ -96,12 +177,17 @@ func TestParse(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
+			textractClient := &mockTextractClient{
+				textractClientOutput: test.textractClientOutput,
+				textractClientError:  test.textractClientError,
+				mockAnalyzeOutput:    &textract.AnalyzeDocumentOutput{},
+				mockAnalyzeError:     test.mockAnalyzeError,
+			}
+
 			client := &Client{
-				textractClient: &mockTextractClient{
-					textractClientOutput: test.textractClientOutput,
-					textractClientError:  test.textractClientError,
-				},
-				convertToDocument: func(input *textract.DetectDocumentTextOutput, fileKey, fileBucket string) Document {
+				textractClient: textractClient,
+				analysis:       test.analysis,
+				convertToDocument: func(input *textract.DetectDocumentTextOutput, fileKey, fileBucket, fileVersion string) Document {
 					test.document.FileKey = fileKey
 					test.document.FileBucket = fileBucket
 					return test.document
@@ -110,7 +196,26 @@ func TestParse(t *testing.T) {
 
 			ctx := context.Background()
 
-			document, err := client.Parse(ctx, fileBucket, fileKey)
+			document, err := client.Parse(ctx, File{
+				Bucket:       fileBucket,
+				Key:          fileKey,
+				Version:      "etag",
+				ContentType:  "image/jpeg",
+				LastModified: &lastModified,
+			})
+
+			if test.analysis {
+				if textractClient.mockAnalyzeInput == nil {
+					t.Fatal("document not analyzed")
+				}
+
+				featureTypes := aws.StringValueSlice(textractClient.mockAnalyzeInput.FeatureTypes)
+				if !reflect.DeepEqual(featureTypes, []string{textract.FeatureTypeTables, textract.FeatureTypeForms}) {
+					t.Errorf("incorrect feature types, received: %v", featureTypes)
+				}
+			} else if textractClient.mockAnalyzeInput != nil {
+				t.Error("document analyzed without analysis")
+			}
 
 			if err != nil {
 				switch e := test.error.(type) {
@@ -126,6 +231,18 @@ func TestParse(t *testing.T) {
 					t.Errorf("no document id, received: %+v", document)
 				}
 
+				if document.ContentType != "image/jpeg" {
+					t.Errorf("incorrect content type, received: %s, expected: image/jpeg", document.ContentType)
+				}
+
+				if document.LastModified == nil || !document.LastModified.Equal(lastModified) {
+					t.Errorf("incorrect last modified, received: %v, expected: %v", document.LastModified, lastModified)
+				}
+
+				if document.IndexedAt == nil {
+					t.Errorf("no indexed at time, received: %+v", document)
+				}
+
 				if len(document.Pages) != len(test.document.Pages) {
 					t.Errorf("unequal pages lengths, received: %+v, expected: %+v", document.Pages, test.document.Pages)
 				} else {
@@ -152,6 +269,127 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseReader(t *testing.T) {
+	lastModified := time.Date(2021, time.November, 1, 12, 0, 0, 0, time.Local)
+	file := File{
+		Bucket:       "uploads",
+		Key:          "scan.png",
+		Version:      "version",
+		ContentType:  "image/png",
+		LastModified: &lastModified,
+	}
+
+	tests := []struct {
+		description          string
+		analysis             bool
+		readError            error
+		textractClientOutput *textract.DetectDocumentTextOutput
+		textractClientError  error
+		mockAnalyzeError     error
+		error                error
+	}{
+		{
+			description: "read file error",
+			readError:   errors.New("mock read error"),
+			error:       &ReadFileError{},
+		},
+		{
+			description:         "textract client parse error",
+			textractClientError: errors.New("mock parse error"),
+			error:               &ParseDocumentError{},
+		},
+		{
+			description:      "textract client analyze error",
+			analysis:         true,
+			mockAnalyzeError: errors.New("mock analyze error"),
+			error:            &ParseDocumentError{},
+		},
+		{
+			description:          "detected document returned",
+			textractClientOutput: &textract.DetectDocumentTextOutput{},
+			error:                nil,
+		},
+		{
+			description: "analyzed document returned",
+			analysis:    true,
+			error:       nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			textractClient := &mockTextractClient{
+				textractClientOutput: test.textractClientOutput,
+				textractClientError:  test.textractClientError,
+				mockAnalyzeOutput:    &textract.AnalyzeDocumentOutput{},
+				mockAnalyzeError:     test.mockAnalyzeError,
+			}
+
+			var received []string
+			client := &Client{
+				textractClient: textractClient,
+				analysis:       test.analysis,
+				convertToDocument: func(input *textract.DetectDocumentTextOutput, fileKey, fileBucket, fileVersion string) Document {
+					received = []string{fileKey, fileBucket, fileVersion}
+					return Document{
+						ID: DocumentID(fileBucket, fileKey),
+					}
+				},
+			}
+
+			var reader io.Reader = bytes.NewReader([]byte("content"))
+			if test.readError != nil {
+				reader = iotest.ErrReader(test.readError)
+			}
+
+			document, err := client.ParseReader(context.Background(), reader, file)
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *ReadFileError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *ParseDocumentError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+				return
+			}
+
+			var textractDocument *textract.Document
+			if test.analysis {
+				textractDocument = textractClient.mockAnalyzeInput.Document
+			} else {
+				textractDocument = textractClient.mockDetectInput.Document
+			}
+
+			if string(textractDocument.Bytes) != "content" || textractDocument.S3Object != nil {
+				t.Errorf("incorrect textract document, received: %+v", textractDocument)
+			}
+
+			if !reflect.DeepEqual(received, []string{file.Key, file.Bucket, file.Version}) {
+				t.Errorf("incorrect file values, received: %v", received)
+			}
+
+			if document.ContentType != file.ContentType {
+				t.Errorf("incorrect content type, received: %s, expected: %s", document.ContentType, file.ContentType)
+			}
+
+			if document.LastModified == nil || !document.LastModified.Equal(lastModified) || document.LastModified.Location() != time.UTC {
+				t.Errorf("incorrect last modified, received: %v, expected: %v", document.LastModified, lastModified)
+			}
+
+			if document.IndexedAt == nil {
+				t.Errorf("no indexed at time, received: %+v", document)
+			}
+		})
+	}
+}
+
 func Test_convertToContent(t *testing.T) {
 	fileKey := "test.jpg"
 	fileBucket := "s3://bucket"
@@ -436,16 +674,353 @@ func Test_convertToContent(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "one line with words and confidence",
+			input: &textract.DetectDocumentTextOutput{
+				Blocks: []*textract.Block{
+					{
+						Id:        aws.String("page_0"),
+						BlockType: aws.String(textract.BlockTypePage),
+						Relationships: []*textract.Relationship{
+							{
+								Type: aws.String(textract.RelationshipTypeChild),
+								Ids: []*string{
+									aws.String("line_0"),
+								},
+							},
+						},
+					},
+					{
+						Id:         aws.String("line_0"),
+						BlockType:  aws.String(textract.BlockTypeLine),
+						Text:       aws.String("test words"),
+						Confidence: aws.Float64(95.5),
+						Geometry: &textract.Geometry{
+							BoundingBox: &textract.BoundingBox{
+								Height: aws.Float64(0.2),
+								Width:  aws.Float64(0.5),
+								Top:    aws.Float64(0.1),
+								Left:   aws.Float64(0.1),
+							},
+						},
+						Relationships: []*textract.Relationship{
+							{
+								Type: aws.String(textract.RelationshipTypeChild),
+								Ids: []*string{
+									aws.String("word_0"),
+									aws.String("word_1"),
+								},
+							},
+						},
+					},
+					{
+						Id:         aws.String("word_0"),
+						BlockType:  aws.String(textract.BlockTypeWord),
+						Text:       aws.String("test"),
+						Confidence: aws.Float64(99),
+						Geometry: &textract.Geometry{
+							BoundingBox: &textract.BoundingBox{
+								Height: aws.Float64(0.2),
+								Width:  aws.Float64(0.2),
+								Top:    aws.Float64(0.1),
+								Left:   aws.Float64(0.1),
+							},
+						},
+					},
+					{
+						Id:         aws.String("word_1"),
+						BlockType:  aws.String(textract.BlockTypeWord),
+						Text:       aws.String("words"),
+						Confidence: aws.Float64(92),
+						Geometry: &textract.Geometry{
+							BoundingBox: &textract.BoundingBox{
+								Height: aws.Float64(0.2),
+								Width:  aws.Float64(0.25),
+								Top:    aws.Float64(0.1),
+								Left:   aws.Float64(0.35),
+							},
+						},
+					},
+				},
+			},
+			document: Document{
+				FileKey:    fileKey,
+				FileBucket: fileBucket,
+				Pages: []Page{
+					{
+						PageNumber: 1,
+						Lines: []Line{
+							{
+								Text:        "test words",
+								Confidence:  95.5,
+								Coordinates: NewCoordinates("", 0.1, 0.1, 0.5, 0.2),
+								Words: []Word{
+									{
+										Text:        "test",
+										Confidence:  99,
+										Coordinates: NewCoordinates("", 0.1, 0.1, 0.2, 0.2),
+									},
+									{
+										Text:        "words",
+										Confidence:  92,
+										Coordinates: NewCoordinates("", 0.35, 0.1, 0.25, 0.2),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "tables and form fields",
+			input: &textract.DetectDocumentTextOutput{
+				Blocks: []*textract.Block{
+					{
+						Id:        aws.String("page_0"),
+						BlockType: aws.String(textract.BlockTypePage),
+						Relationships: []*textract.Relationship{
+							{
+								Type: aws.String(textract.RelationshipTypeChild),
+								Ids: aws.StringSlice([]string{
+									"line_0",
+									"table_0",
+									"key_0",
+									"value_0",
+									"key_1",
+									"value_1",
+								}),
+							},
+						},
+					},
+					{
+						Id:         aws.String("line_0"),
+						BlockType:  aws.String(textract.BlockTypeLine),
+						Text:       aws.String("Invoice Number: 1234"),
+						Confidence: aws.Float64(99),
+						Geometry:   geometry(0.1, 0.1, 0.35, 0.05),
+					},
+					{
+						Id:         aws.String("table_0"),
+						BlockType:  aws.String(textract.BlockTypeTable),
+						Confidence: aws.Float64(98),
+						Geometry:   geometry(0.1, 0.5, 0.8, 0.2),
+						Relationships: []*textract.Relationship{
+							{
+								Type: aws.String(textract.RelationshipTypeChild),
+								Ids:  aws.StringSlice([]string{"cell_0", "cell_1"}),
+							},
+						},
+					},
+					{
+						Id:          aws.String("cell_0"),
+						BlockType:   aws.String(textract.BlockTypeCell),
+						RowIndex:    aws.Int64(1),
+						ColumnIndex: aws.Int64(1),
+						RowSpan:     aws.Int64(1),
+						ColumnSpan:  aws.Int64(1),
+						Confidence:  aws.Float64(97),
+						Geometry:    geometry(0.1, 0.5, 0.4, 0.2),
+						Relationships: []*textract.Relationship{
+							{
+								Type: aws.String(textract.RelationshipTypeChild),
+								Ids:  aws.StringSlice([]string{"word_item"}),
+							},
+						},
+					},
+					{
+						Id:          aws.String("cell_1"),
+						BlockType:   aws.String(textract.BlockTypeCell),
+						RowIndex:    aws.Int64(1),
+						ColumnIndex: aws.Int64(2),
+						RowSpan:     aws.Int64(1),
+						ColumnSpan:  aws.Int64(1),
+						Confidence:  aws.Float64(96),
+						Geometry:    geometry(0.5, 0.5, 0.4, 0.2),
+						Relationships: []*textract.Relationship{
+							{
+								Type: aws.String(textract.RelationshipTypeChild),
+								Ids:  aws.StringSlice([]string{"word_quantity"}),
+							},
+						},
+					},
+					{
+						Id:          aws.String("key_0"),
+						BlockType:   aws.String(textract.BlockTypeKeyValueSet),
+						EntityTypes: aws.StringSlice([]string{textract.EntityTypeKey}),
+						Confidence:  aws.Float64(90),
+						Geometry:    geometry(0.1, 0.1, 0.2, 0.05),
+						Relationships: []*textract.Relationship{
+							{
+								Type: aws.String(textract.RelationshipTypeValue),
+								Ids:  aws.StringSlice([]string{"value_0"}),
+							},
+							{
+								Type: aws.String(textract.RelationshipTypeChild),
+								Ids:  aws.StringSlice([]string{"word_invoice", "word_number"}),
+							},
+						},
+					},
+					{
+						Id:          aws.String("value_0"),
+						BlockType:   aws.String(textract.BlockTypeKeyValueSet),
+						EntityTypes: aws.StringSlice([]string{textract.EntityTypeValue}),
+						Confidence:  aws.Float64(85),
+						Geometry:    geometry(0.35, 0.1, 0.1, 0.05),
+						Relationships: []*textract.Relationship{
+							{
+								Type: aws.String(textract.RelationshipTypeChild),
+								Ids:  aws.StringSlice([]string{"word_value"}),
+							},
+						},
+					},
+					{
+						Id:          aws.String("key_1"),
+						BlockType:   aws.String(textract.BlockTypeKeyValueSet),
+						EntityTypes: aws.StringSlice([]string{textract.EntityTypeKey}),
+						Confidence:  aws.Float64(95),
+						Geometry:    geometry(0.1, 0.8, 0.1, 0.05),
+						Relationships: []*textract.Relationship{
+							{
+								Type: aws.String(textract.RelationshipTypeValue),
+								Ids:  aws.StringSlice([]string{"value_1"}),
+							},
+							{
+								Type: aws.String(textract.RelationshipTypeChild),
+								Ids:  aws.StringSlice([]string{"word_paid"}),
+							},
+						},
+					},
+					{
+						Id:          aws.String("value_1"),
+						BlockType:   aws.String(textract.BlockTypeKeyValueSet),
+						EntityTypes: aws.StringSlice([]string{textract.EntityTypeValue}),
+						Confidence:  aws.Float64(99),
+						Geometry:    geometry(0.25, 0.8, 0.05, 0.05),
+						Relationships: []*textract.Relationship{
+							{
+								Type: aws.String(textract.RelationshipTypeChild),
+								Ids:  aws.StringSlice([]string{"selection_0"}),
+							},
+						},
+					},
+					{
+						Id:        aws.String("word_item"),
+						BlockType: aws.String(textract.BlockTypeWord),
+						Text:      aws.String("Widget"),
+					},
+					{
+						Id:        aws.String("word_quantity"),
+						BlockType: aws.String(textract.BlockTypeWord),
+						Text:      aws.String("4"),
+					},
+					{
+						Id:        aws.String("word_invoice"),
+						BlockType: aws.String(textract.BlockTypeWord),
+						Text:      aws.String("Invoice"),
+					},
+					{
+						Id:        aws.String("word_number"),
+						BlockType: aws.String(textract.BlockTypeWord),
+						Text:      aws.String("Number:"),
+					},
+					{
+						Id:        aws.String("word_value"),
+						BlockType: aws.String(textract.BlockTypeWord),
+						Text:      aws.String("1234"),
+					},
+					{
+						Id:        aws.String("word_paid"),
+						BlockType: aws.String(textract.BlockTypeWord),
+						Text:      aws.String("Paid"),
+					},
+					{
+						Id:              aws.String("selection_0"),
+						BlockType:       aws.String(textract.BlockTypeSelectionElement),
+						SelectionStatus: aws.String(textract.SelectionStatusSelected),
+					},
+				},
+			},
+			document: Document{
+				FileKey:    fileKey,
+				FileBucket: fileBucket,
+				Pages: []Page{
+					{
+						PageNumber: 1,
+						Lines: []Line{
+							{
+								Text:        "Invoice Number: 1234",
+								Confidence:  99,
+								Coordinates: NewCoordinates("", 0.1, 0.1, 0.35, 0.05),
+							},
+						},
+						Tables: []Table{
+							{
+								Confidence:  98,
+								Coordinates: NewCoordinates("", 0.1, 0.5, 0.8, 0.2),
+								Cells: []Cell{
+									{
+										RowIndex:    1,
+										ColumnIndex: 1,
+										RowSpan:     1,
+										ColumnSpan:  1,
+										Text:        "Widget",
+										Confidence:  97,
+										Coordinates: NewCoordinates("", 0.1, 0.5, 0.4, 0.2),
+									},
+									{
+										RowIndex:    1,
+										ColumnIndex: 2,
+										RowSpan:     1,
+										ColumnSpan:  1,
+										Text:        "4",
+										Confidence:  96,
+										Coordinates: NewCoordinates("", 0.5, 0.5, 0.4, 0.2),
+									},
+								},
+							},
+						},
+						Fields: []Field{
+							{
+								Key:         "Invoice Number:",
+								Value:       "1234",
+								Confidence:  85,
+								Coordinates: NewCoordinates("", 0.1, 0.1, 0.35, 0.05),
+							},
+							{
+								Key:         "Paid",
+								Value:       textract.SelectionStatusSelected,
+								Confidence:  95,
+								Coordinates: NewCoordinates("", 0.1, 0.8, 0.2, 0.05),
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
-			document := convertToDocument(test.input, fileKey, fileBucket)
+			document := convertToDocument(test.input, fileKey, fileBucket, "version")
+
+			if document.ID != DocumentID(fileBucket, fileKey) {
+				t.Errorf("incorrect document id, received: %s, expected: %s", document.ID, DocumentID(fileBucket, fileKey))
+			}
+
+			repeated := convertToDocument(test.input, fileKey, fileBucket, "version")
+			if !reflect.DeepEqual(document, repeated) {
+				t.Errorf("non-deterministic document, received: %+v, expected: %+v", repeated, document)
+			}
 
 			if document.FileKey != fileKey {
 				t.Errorf("incorrect document file key, received: %s, expected: %s", document.FileKey, fileKey)
 			}
 
+			if document.FileExtension != "jpg" {
+				t.Errorf("incorrect document file extension, received: %s, expected: jpg", document.FileExtension)
+			}
+
 			if document.FileBucket != fileBucket {
 				t.Errorf("incorrect document file bucket, received: %s, expected: %s", document.FileBucket, fileBucket)
 			}
@@ -469,12 +1044,136 @@ func Test_convertToContent(t *testing.T) {
 					if !checkCoordinates(t, receivedLine.Coordinates, expectedLine.Coordinates) {
 						t.Errorf("incorrect line coordinates, received: %+v, expected: %+v", receivedLine.Coordinates, expectedLine.Coordinates)
 					}
+
+					if receivedLine.Confidence != expectedLine.Confidence {
+						t.Errorf("incorrect line confidence, received: %f, expected: %f", receivedLine.Confidence, expectedLine.Confidence)
+					}
+
+					if len(receivedLine.Words) != len(expectedLine.Words) {
+						t.Fatalf("incorrect words count, received: %d, expected: %d", len(receivedLine.Words), len(expectedLine.Words))
+					}
+
+					for k, receivedWord := range receivedLine.Words {
+						expectedWord := expectedLine.Words[k]
+						if receivedWord.Text != expectedWord.Text || receivedWord.Confidence != expectedWord.Confidence {
+							t.Errorf("incorrect word, received: %+v, expected: %+v", receivedWord, expectedWord)
+						}
+
+						if !checkCoordinates(t, receivedWord.Coordinates, expectedWord.Coordinates) {
+							t.Errorf("incorrect word coordinates, received: %+v, expected: %+v", receivedWord.Coordinates, expectedWord.Coordinates)
+						}
+					}
+				}
+
+				if len(receivedPage.Tables) != len(expectedPage.Tables) {
+					t.Fatalf("incorrect tables count, received: %d, expected: %d", len(receivedPage.Tables), len(expectedPage.Tables))
+				}
+
+				for j, receivedTable := range receivedPage.Tables {
+					expectedTable := expectedPage.Tables[j]
+					if receivedTable.Confidence != expectedTable.Confidence || !checkCoordinates(t, receivedTable.Coordinates, expectedTable.Coordinates) {
+						t.Errorf("incorrect table, received: %+v, expected: %+v", receivedTable, expectedTable)
+					}
+
+					if len(receivedTable.Cells) != len(expectedTable.Cells) {
+						t.Fatalf("incorrect cells count, received: %d, expected: %d", len(receivedTable.Cells), len(expectedTable.Cells))
+					}
+
+					for k, receivedCell := range receivedTable.Cells {
+						expectedCell := expectedTable.Cells[k]
+						if receivedCell.RowIndex != expectedCell.RowIndex ||
+							receivedCell.ColumnIndex != expectedCell.ColumnIndex ||
+							receivedCell.RowSpan != expectedCell.RowSpan ||
+							receivedCell.ColumnSpan != expectedCell.ColumnSpan ||
+							receivedCell.Text != expectedCell.Text ||
+							receivedCell.Confidence != expectedCell.Confidence ||
+							!checkCoordinates(t, receivedCell.Coordinates, expectedCell.Coordinates) {
+							t.Errorf("incorrect cell, received: %+v, expected: %+v", receivedCell, expectedCell)
+						}
+					}
+				}
+
+				if len(receivedPage.Fields) != len(expectedPage.Fields) {
+					t.Fatalf("incorrect fields count, received: %d, expected: %d", len(receivedPage.Fields), len(expectedPage.Fields))
+				}
+
+				for j, receivedField := range receivedPage.Fields {
+					expectedField := expectedPage.Fields[j]
+					if receivedField.Key != expectedField.Key ||
+						receivedField.Value != expectedField.Value ||
+						receivedField.Confidence != expectedField.Confidence ||
+						!checkCoordinates(t, receivedField.Coordinates, expectedField.Coordinates) {
+						t.Errorf("incorrect field, received: %+v, expected: %+v", receivedField, expectedField)
+					}
 				}
 			}
 		})
 	}
 }
 
+func TestFileExtension(t *testing.T) {
+	tests := []struct {
+		fileKey   string
+		extension string
+	}{
+		{"scan.JPG", "jpg"},
+		{"folder.v2/report.pdf", "pdf"},
+		{"folder.v2/notapdf", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fileKey, func(t *testing.T) {
+			if extension := FileExtension(test.fileKey); extension != test.extension {
+				t.Errorf("incorrect extension, received: %s, expected: %s", extension, test.extension)
+			}
+		})
+	}
+}
+
+func TestNewSkippedDocument(t *testing.T) {
+	lastModified := time.Date(2021, time.November, 1, 12, 0, 0, 0, time.UTC)
+
+	document := NewSkippedDocument(File{
+		Bucket:       "bucket",
+		Key:          "folder/notes.TXT",
+		Version:      "version",
+		ContentType:  "text/plain",
+		LastModified: &lastModified,
+	}, "unsupported content type text/plain")
+
+	if document.IndexedAt == nil {
+		t.Errorf("incorrect indexed at, received: nil")
+	}
+	document.IndexedAt = nil
+
+	expected := Document{
+		ID:            DocumentID("bucket", "folder/notes.TXT"),
+		Entity:        "document",
+		FileBucket:    "bucket",
+		FileKey:       "folder/notes.TXT",
+		FileVersion:   "version",
+		FileExtension: "txt",
+		ContentType:   "text/plain",
+		LastModified:  &lastModified,
+		SkipReason:    "unsupported content type text/plain",
+	}
+
+	if !reflect.DeepEqual(document, expected) {
+		t.Errorf("incorrect document, received: %+v, expected: %+v", document, expected)
+	}
+}
+
+func geometry(left, top, width, height float64) *textract.Geometry {
+	return &textract.Geometry{
+		BoundingBox: &textract.BoundingBox{
+			Left:   aws.Float64(left),
+			Top:    aws.Float64(top),
+			Width:  aws.Float64(width),
+			Height: aws.Float64(height),
+		},
+	}
+}
+
 func checkCoordinates(t *testing.T, a, b Coordinates) bool {
 	t.Helper()
 
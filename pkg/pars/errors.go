@@ -5,8 +5,9 @@ import "fmt"
 const errorMessage = "package pars: %s"
 
 // ParseDocumentError wraps errors returned by
-// textract.Textract.AnalyzeDocument in the pars.Parser.Parse
-// method.
+// textract.Textract.DetectDocumentText and
+// textract.Textract.AnalyzeDocument in the pars.Parser.Parse and
+// pars.Parser.ParseReader methods.
 type ParseDocumentError struct {
 	err error
 }
@@ -14,3 +15,37 @@ type ParseDocumentError struct {
 func (e *ParseDocumentError) Error() string {
 	return fmt.Sprintf(errorMessage, e.err.Error())
 }
+
+// ReadFileError wraps errors returned reading the file in the
+// pars.Parser.ParseReader method.
+type ReadFileError struct {
+	err error
+}
+
+func (e *ReadFileError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// StartJobError wraps errors returned by
+// textract.Textract.StartDocumentTextDetection and
+// textract.Textract.StartDocumentAnalysis in the pars.Parser.Parse
+// method.
+type StartJobError struct {
+	err error
+}
+
+func (e *StartJobError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// GetJobError wraps errors returned by
+// textract.Textract.GetDocumentTextDetection,
+// textract.Textract.GetDocumentAnalysis, and failed jobs in the
+// pars.Parser.Parse method.
+type GetJobError struct {
+	err error
+}
+
+func (e *GetJobError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
@@ -15,3 +15,36 @@ func TestParseDocumentError(t *testing.T) {
 		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
 	}
 }
+
+func TestReadFileError(t *testing.T) {
+	err := &ReadFileError{err: errors.New("mock read file error")}
+
+	recieved := err.Error()
+	expected := "package pars: mock read file error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestStartJobError(t *testing.T) {
+	err := &StartJobError{err: errors.New("mock start job error")}
+
+	recieved := err.Error()
+	expected := "package pars: mock start job error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestGetJobError(t *testing.T) {
+	err := &GetJobError{err: errors.New("mock get job error")}
+
+	recieved := err.Error()
+	expected := "package pars: mock get job error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
@@ -0,0 +1,19 @@
+package pars
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// DocumentID returns the identifier for the document parsed from the
+// file at the provided bucket and key. The value is stable across
+// parses so that re-indexing a file overwrites its existing entry.
+func DocumentID(fileBucket, fileKey string) string {
+	return NewID(fileBucket, fileKey)
+}
+
+// NewID returns a deterministic UUID derived from the provided values.
+func NewID(values ...string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(strings.Join(values, "\x00"))).String()
+}
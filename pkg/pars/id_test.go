@@ -0,0 +1,26 @@
+package pars
+
+import "testing"
+
+func TestDocumentID(t *testing.T) {
+	first := DocumentID("bucket", "folder/key.jpg")
+	second := DocumentID("bucket", "folder/key.jpg")
+
+	if first != second {
+		t.Errorf("unstable document id, received: %s, expected: %s", second, first)
+	}
+
+	if other := DocumentID("bucket/folder", "key.jpg"); other == first {
+		t.Errorf("colliding document id, received: %s, expected different from: %s", other, first)
+	}
+}
+
+func TestNewID(t *testing.T) {
+	if NewID("a", "b") == NewID("a", "c") {
+		t.Error("identical ids generated for different values")
+	}
+
+	if NewID("a", "b") != NewID("a", "b") {
+		t.Error("different ids generated for identical values")
+	}
+}
@@ -0,0 +1,203 @@
+package pars
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/textract"
+)
+
+const (
+	// maxJobResults is the largest number of blocks Textract returns
+	// per job results request.
+	maxJobResults = 1000
+
+	// jobPollInterval and maxJobPollInterval bound the wait between
+	// checks of a running job.
+	jobPollInterval    = time.Second
+	maxJobPollInterval = 10 * time.Second
+)
+
+// multiPage reports whether the file is a format Textract can only
+// read several pages of through an asynchronous job.
+func multiPage(fileKey, contentType string) bool {
+	switch FileExtension(fileKey) {
+	case "pdf", "tif", "tiff":
+		return true
+	}
+
+	switch strings.ToLower(contentType) {
+	case "application/pdf", "image/tiff":
+		return true
+	}
+
+	return false
+}
+
+// jobResults holds a page of the results of a text detection or
+// document analysis job.
+type jobResults struct {
+	status           *string
+	statusMessage    *string
+	documentMetadata *textract.DocumentMetadata
+	blocks           []*textract.Block
+	nextToken        *string
+}
+
+// detectDocumentTextJob runs a Textract text detection job on the
+// file, polling until it completes, and returns the blocks of every
+// page in a single output.
+func (c *Client) detectDocumentTextJob(ctx context.Context, fileBucket, fileKey string) (*textract.DetectDocumentTextOutput, error) {
+	start := func() (*string, error) {
+		output, err := c.textractClient.StartDocumentTextDetection(&textract.StartDocumentTextDetectionInput{
+			DocumentLocation: documentLocation(fileBucket, fileKey),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return output.JobId, nil
+	}
+
+	get := func(jobID, nextToken *string) (*jobResults, error) {
+		output, err := c.textractClient.GetDocumentTextDetection(&textract.GetDocumentTextDetectionInput{
+			JobId:      jobID,
+			MaxResults: aws.Int64(maxJobResults),
+			NextToken:  nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &jobResults{
+			status:           output.JobStatus,
+			statusMessage:    output.StatusMessage,
+			documentMetadata: output.DocumentMetadata,
+			blocks:           output.Blocks,
+			nextToken:        output.NextToken,
+		}, nil
+	}
+
+	return c.runJob(ctx, start, get)
+}
+
+// analyzeDocumentJob runs a Textract document analysis job extracting
+// tables and forms from the file, polling until it completes, and
+// returns the blocks of every page in a single output.
+func (c *Client) analyzeDocumentJob(ctx context.Context, fileBucket, fileKey string) (*textract.DetectDocumentTextOutput, error) {
+	start := func() (*string, error) {
+		output, err := c.textractClient.StartDocumentAnalysis(&textract.StartDocumentAnalysisInput{
+			DocumentLocation: documentLocation(fileBucket, fileKey),
+			FeatureTypes:     featureTypes(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return output.JobId, nil
+	}
+
+	get := func(jobID, nextToken *string) (*jobResults, error) {
+		output, err := c.textractClient.GetDocumentAnalysis(&textract.GetDocumentAnalysisInput{
+			JobId:      jobID,
+			MaxResults: aws.Int64(maxJobResults),
+			NextToken:  nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &jobResults{
+			status:           output.JobStatus,
+			statusMessage:    output.StatusMessage,
+			documentMetadata: output.DocumentMetadata,
+			blocks:           output.Blocks,
+			nextToken:        output.NextToken,
+		}, nil
+	}
+
+	return c.runJob(ctx, start, get)
+}
+
+// runJob starts a job, polls its results until it completes, and pages
+// through them, collecting the blocks of every page in a single output.
+func (c *Client) runJob(ctx context.Context, start func() (*string, error), get func(jobID, nextToken *string) (*jobResults, error)) (*textract.DetectDocumentTextOutput, error) {
+	jobID, err := start()
+	if err != nil {
+		return nil, &StartJobError{err: err}
+	}
+
+	output := &textract.DetectDocumentTextOutput{}
+
+	var nextToken *string
+	for attempt := 0; ; {
+		results, err := get(jobID, nextToken)
+		if err != nil {
+			return nil, &GetJobError{err: err}
+		}
+
+		switch aws.StringValue(results.status) {
+		case textract.JobStatusInProgress:
+			if err := c.wait(ctx, pollInterval(attempt)); err != nil {
+				return nil, &GetJobError{err: err}
+			}
+			attempt++
+			continue
+
+		case textract.JobStatusSucceeded, textract.JobStatusPartialSuccess:
+
+		default:
+			return nil, &GetJobError{
+				err: fmt.Errorf("job %s status %s: %s", aws.StringValue(jobID), aws.StringValue(results.status), aws.StringValue(results.statusMessage)),
+			}
+		}
+
+		if output.DocumentMetadata == nil {
+			output.DocumentMetadata = results.documentMetadata
+		}
+		output.Blocks = append(output.Blocks, results.blocks...)
+
+		if aws.StringValue(results.nextToken) == "" {
+			return output, nil
+		}
+		nextToken = results.nextToken
+	}
+}
+
+func documentLocation(fileBucket, fileKey string) *textract.DocumentLocation {
+	return &textract.DocumentLocation{
+		S3Object: &textract.S3Object{
+			Bucket: aws.String(fileBucket),
+			Name:   aws.String(fileKey),
+		},
+	}
+}
+
+// pollInterval returns the wait before the provided job status check,
+// doubling from jobPollInterval up to maxJobPollInterval.
+func pollInterval(attempt int) time.Duration {
+	if attempt > 4 {
+		return maxJobPollInterval
+	}
+
+	if interval := jobPollInterval << attempt; interval < maxJobPollInterval {
+		return interval
+	}
+
+	return maxJobPollInterval
+}
+
+func wait(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,340 @@
+package pars
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/textract"
+)
+
+func pageBlocks(pageNumber int64, lines ...string) []*textract.Block {
+	pageID := "page_" + string(rune('0'+pageNumber))
+	page := &textract.Block{
+		Id:        aws.String(pageID),
+		BlockType: aws.String(textract.BlockTypePage),
+		Page:      aws.Int64(pageNumber),
+		Relationships: []*textract.Relationship{
+			{
+				Type: aws.String(textract.RelationshipTypeChild),
+			},
+		},
+	}
+
+	blocks := []*textract.Block{page}
+	for i, text := range lines {
+		lineID := pageID + "_line_" + string(rune('0'+i))
+		page.Relationships[0].Ids = append(page.Relationships[0].Ids, aws.String(lineID))
+		blocks = append(blocks, &textract.Block{
+			Id:        aws.String(lineID),
+			BlockType: aws.String(textract.BlockTypeLine),
+			Page:      aws.Int64(pageNumber),
+			Text:      aws.String(text),
+			Geometry: &textract.Geometry{
+				BoundingBox: &textract.BoundingBox{
+					Height: aws.Float64(0.1),
+					Width:  aws.Float64(0.5),
+					Top:    aws.Float64(0.1),
+					Left:   aws.Float64(0.1),
+				},
+			},
+		})
+	}
+
+	return blocks
+}
+
+func TestParseJob(t *testing.T) {
+	inProgress := &textract.GetDocumentTextDetectionOutput{
+		JobStatus: aws.String(textract.JobStatusInProgress),
+	}
+
+	firstPageBlocks := pageBlocks(1, "first page")
+	secondPageBlocks := pageBlocks(2, "second page", "last line")
+
+	tests := []struct {
+		description    string
+		fileKey        string
+		contentType    string
+		mockStartError error
+		mockGetOutputs []*textract.GetDocumentTextDetectionOutput
+		mockGetError   error
+		waitError      error
+		waits          int
+		nextTokens     []*string
+		pages          map[int64][]string
+		error          error
+	}{
+		{
+			description:    "start job error",
+			fileKey:        "file.pdf",
+			mockStartError: errors.New("mock start error"),
+			error:          &StartJobError{},
+		},
+		{
+			description:  "get job error",
+			fileKey:      "file.pdf",
+			mockGetError: errors.New("mock get error"),
+			nextTokens:   []*string{nil},
+			error:        &GetJobError{},
+		},
+		{
+			description: "failed job",
+			fileKey:     "file.pdf",
+			mockGetOutputs: []*textract.GetDocumentTextDetectionOutput{
+				inProgress,
+				{
+					JobStatus:     aws.String(textract.JobStatusFailed),
+					StatusMessage: aws.String("unsupported document"),
+				},
+			},
+			waits:      1,
+			nextTokens: []*string{nil, nil},
+			error:      &GetJobError{},
+		},
+		{
+			description: "wait error",
+			fileKey:     "file.pdf",
+			mockGetOutputs: []*textract.GetDocumentTextDetectionOutput{
+				inProgress,
+			},
+			waitError:  context.DeadlineExceeded,
+			waits:      1,
+			nextTokens: []*string{nil},
+			error:      &GetJobError{},
+		},
+		{
+			description: "multi-page tiff by content type paged across results",
+			fileKey:     "scan",
+			contentType: "image/tiff",
+			mockGetOutputs: []*textract.GetDocumentTextDetectionOutput{
+				inProgress,
+				inProgress,
+				{
+					JobStatus: aws.String(textract.JobStatusSucceeded),
+					DocumentMetadata: &textract.DocumentMetadata{
+						Pages: aws.Int64(2),
+					},
+					Blocks:    append(firstPageBlocks, secondPageBlocks[:2]...),
+					NextToken: aws.String("token"),
+				},
+				{
+					JobStatus: aws.String(textract.JobStatusSucceeded),
+					DocumentMetadata: &textract.DocumentMetadata{
+						Pages: aws.Int64(2),
+					},
+					Blocks: secondPageBlocks[2:],
+				},
+			},
+			waits:      2,
+			nextTokens: []*string{nil, nil, nil, aws.String("token")},
+			pages: map[int64][]string{
+				1: {"first page"},
+				2: {"second page", "last line"},
+			},
+			error: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			textractClient := &mockTextractClient{
+				textractClientError: errors.New("synchronous detection used"),
+				mockStartError:      test.mockStartError,
+				mockGetOutputs:      test.mockGetOutputs,
+				mockGetError:        test.mockGetError,
+			}
+
+			waits := []time.Duration{}
+			client := &Client{
+				textractClient:    textractClient,
+				convertToDocument: convertToDocument,
+				wait: func(ctx context.Context, duration time.Duration) error {
+					waits = append(waits, duration)
+					return test.waitError
+				},
+			}
+
+			document, err := client.Parse(context.Background(), File{
+				Bucket:      "bucket",
+				Key:         test.fileKey,
+				ContentType: test.contentType,
+			})
+
+			if len(waits) != test.waits {
+				t.Errorf("incorrect waits, received: %v, expected: %d", waits, test.waits)
+			}
+
+			nextTokens := []*string{}
+			for _, input := range textractClient.mockGetInputs {
+				if aws.StringValue(input.JobId) != "job_id" {
+					t.Errorf("incorrect job id, received: %s, expected: job_id", aws.StringValue(input.JobId))
+				}
+				nextTokens = append(nextTokens, input.NextToken)
+			}
+
+			if len(nextTokens) != len(test.nextTokens) {
+				t.Errorf("incorrect get calls, received: %d, expected: %d", len(nextTokens), len(test.nextTokens))
+			} else {
+				for i := range nextTokens {
+					if aws.StringValue(nextTokens[i]) != aws.StringValue(test.nextTokens[i]) {
+						t.Errorf("incorrect next token %d, received: %v, expected: %v", i, aws.StringValue(nextTokens[i]), aws.StringValue(test.nextTokens[i]))
+					}
+				}
+			}
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *StartJobError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *GetJobError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+				return
+			}
+
+			pages := map[int64][]string{}
+			for _, page := range document.Pages {
+				for _, line := range page.Lines {
+					pages[page.PageNumber] = append(pages[page.PageNumber], line.Text)
+				}
+			}
+
+			if !reflect.DeepEqual(pages, test.pages) {
+				t.Errorf("incorrect pages, received: %v, expected: %v", pages, test.pages)
+			}
+		})
+	}
+}
+
+func TestParseAnalysisJob(t *testing.T) {
+	textractClient := &mockTextractClient{
+		textractClientError: errors.New("synchronous detection used"),
+		mockStartError:      errors.New("text detection job used"),
+		mockAnalyzeError:    errors.New("synchronous analysis used"),
+		mockGetAnalysisOutputs: []*textract.GetDocumentAnalysisOutput{
+			{
+				JobStatus: aws.String(textract.JobStatusInProgress),
+			},
+			{
+				JobStatus: aws.String(textract.JobStatusSucceeded),
+				Blocks:    pageBlocks(1, "first page"),
+				NextToken: aws.String("token"),
+			},
+			{
+				JobStatus: aws.String(textract.JobStatusSucceeded),
+				Blocks:    pageBlocks(2, "second page"),
+			},
+		},
+	}
+
+	waits := 0
+	client := &Client{
+		textractClient:    textractClient,
+		analysis:          true,
+		convertToDocument: convertToDocument,
+		wait: func(ctx context.Context, duration time.Duration) error {
+			waits++
+			return nil
+		},
+	}
+
+	document, err := client.Parse(context.Background(), File{
+		Bucket: "bucket",
+		Key:    "file.pdf",
+	})
+	if err != nil {
+		t.Fatalf("error parsing document: %v", err)
+	}
+
+	featureTypes := aws.StringValueSlice(textractClient.mockStartAnalysisInput.FeatureTypes)
+	if !reflect.DeepEqual(featureTypes, []string{textract.FeatureTypeTables, textract.FeatureTypeForms}) {
+		t.Errorf("incorrect feature types, received: %v", featureTypes)
+	}
+
+	if waits != 1 {
+		t.Errorf("incorrect waits, received: %d, expected: 1", waits)
+	}
+
+	nextTokens := []string{}
+	for _, input := range textractClient.mockGetAnalysisInputs {
+		if aws.StringValue(input.JobId) != "analysis_job_id" {
+			t.Errorf("incorrect job id, received: %s, expected: analysis_job_id", aws.StringValue(input.JobId))
+		}
+		nextTokens = append(nextTokens, aws.StringValue(input.NextToken))
+	}
+
+	if !reflect.DeepEqual(nextTokens, []string{"", "", "token"}) {
+		t.Errorf("incorrect next tokens, received: %v", nextTokens)
+	}
+
+	if len(document.Pages) != 2 || document.Pages[1].Lines[0].Text != "second page" {
+		t.Errorf("incorrect pages, received: %+v", document.Pages)
+	}
+}
+
+func Test_multiPage(t *testing.T) {
+	tests := []struct {
+		fileKey     string
+		contentType string
+		multiPage   bool
+	}{
+		{
+			fileKey:     "file.pdf",
+			contentType: "",
+			multiPage:   true,
+		},
+		{
+			fileKey:     "file.TIFF",
+			contentType: "",
+			multiPage:   true,
+		},
+		{
+			fileKey:     "file.tif",
+			contentType: "",
+			multiPage:   true,
+		},
+		{
+			fileKey:     "file",
+			contentType: "application/pdf",
+			multiPage:   true,
+		},
+		{
+			fileKey:     "file.jpg",
+			contentType: "image/jpeg",
+			multiPage:   false,
+		},
+		{
+			fileKey:     "file.png",
+			contentType: "",
+			multiPage:   false,
+		},
+	}
+
+	for _, test := range tests {
+		if received := multiPage(test.fileKey, test.contentType); received != test.multiPage {
+			t.Errorf("incorrect multi-page for %s %s, received: %t, expected: %t", test.fileKey, test.contentType, received, test.multiPage)
+		}
+	}
+}
+
+func Test_pollInterval(t *testing.T) {
+	received := []time.Duration{}
+	for attempt := 0; attempt < 6; attempt++ {
+		received = append(received, pollInterval(attempt))
+	}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("incorrect intervals, received: %v, expected: %v", received, expected)
+	}
+}
@@ -1,29 +1,94 @@
 package pars
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 // Document holds the output of parsing the provided image file.
+// SkipReason is set instead of Pages for files that were not parsed
+// because their type is not supported.
 type Document struct {
-	ID         string `json:"id"`
-	Entity     string `json:"entity"`
-	FileBucket string `json:"file_bucket"`
-	FileKey    string `json:"file_key"`
-	Pages      []Page `json:"pages,omitempty"`
+	ID            string     `json:"id"`
+	Entity        string     `json:"entity"`
+	FileBucket    string     `json:"file_bucket"`
+	FileKey       string     `json:"file_key"`
+	FileVersion   string     `json:"file_version,omitempty"`
+	FileExtension string     `json:"file_extension,omitempty"`
+	ContentType   string     `json:"content_type,omitempty"`
+	LastModified  *time.Time `json:"last_modified,omitempty"`
+	IndexedAt     *time.Time `json:"indexed_at,omitempty"`
+	Pages         []Page     `json:"pages,omitempty"`
+	SkipReason    string     `json:"skip_reason,omitempty"`
 }
 
 // Page holds the output of parsing a page of the provided image file.
+// Tables and Fields are only extracted by parsers that analyze the
+// layout of the page.
 type Page struct {
-	ID         string `json:"id"`
-	Entity     string `json:"entity"`
-	PageNumber int64  `json:"page_number"`
-	Lines      []Line `json:"lines,omitempty"`
+	ID         string  `json:"id"`
+	Entity     string  `json:"entity"`
+	PageNumber int64   `json:"page_number"`
+	Lines      []Line  `json:"lines,omitempty"`
+	Tables     []Table `json:"tables,omitempty"`
+	Fields     []Field `json:"fields,omitempty"`
 }
 
 // Line holds text and location coordinates retrieved from the image file.
+// Confidence is the 0-100 certainty of the recognized text, the mean of
+// the word confidences where the parser does not report one per line.
 type Line struct {
 	ID          string      `json:"id"`
 	Entity      string      `json:"entity"`
 	Text        string      `json:"text"`
+	Confidence  float64     `json:"confidence"`
+	Coordinates Coordinates `json:"coordinates,omitempty"`
+	Words       []Word      `json:"words,omitempty"`
+}
+
+// Word holds a single word of a line with its location coordinates and
+// 0-100 recognition confidence.
+type Word struct {
+	ID          string      `json:"id"`
+	Entity      string      `json:"entity"`
+	Text        string      `json:"text"`
+	Confidence  float64     `json:"confidence"`
+	Coordinates Coordinates `json:"coordinates,omitempty"`
+}
+
+// Table holds the cells of a table detected on a page.
+type Table struct {
+	ID          string      `json:"id"`
+	Entity      string      `json:"entity"`
+	Confidence  float64     `json:"confidence"`
+	Coordinates Coordinates `json:"coordinates,omitempty"`
+	Cells       []Cell      `json:"cells,omitempty"`
+}
+
+// Cell holds the text of a table cell along with its 1-based row and
+// column indexes and the number of rows and columns it spans.
+type Cell struct {
+	ID          string      `json:"id"`
+	Entity      string      `json:"entity"`
+	RowIndex    int64       `json:"row_index"`
+	ColumnIndex int64       `json:"column_index"`
+	RowSpan     int64       `json:"row_span,omitempty"`
+	ColumnSpan  int64       `json:"column_span,omitempty"`
+	Text        string      `json:"text"`
+	Confidence  float64     `json:"confidence"`
+	Coordinates Coordinates `json:"coordinates,omitempty"`
+}
+
+// Field holds a form key and its value detected on a page. Confidence
+// is the lower of the key and value confidences and Coordinates enclose
+// both of them.
+type Field struct {
+	ID          string      `json:"id"`
+	Entity      string      `json:"entity"`
+	Key         string      `json:"key"`
+	Value       string      `json:"value"`
+	Confidence  float64     `json:"confidence"`
 	Coordinates Coordinates `json:"coordinates,omitempty"`
 }
 
@@ -37,14 +102,55 @@ type Coordinates struct {
 	BottomRight Point  `json:"bottom_right"`
 }
 
+// NewCoordinates returns the coordinates of a box with the provided
+// top left corner and size in the 0-1 range of the page.
+func NewCoordinates(id string, left, top, width, height float64) Coordinates {
+	return Coordinates{
+		ID:     id,
+		Entity: "coordinates",
+		TopLeft: Point{
+			X: left,
+			Y: top,
+		},
+		TopRight: Point{
+			X: left + width,
+			Y: top,
+		},
+		BottomLeft: Point{
+			X: left,
+			Y: top + height,
+		},
+		BottomRight: Point{
+			X: left + width,
+			Y: top + height,
+		},
+	}
+}
+
 // Point holds the X and Y values for a point in text coordinates.
 type Point struct {
 	X float64 `json:"x"`
 	Y float64 `json:"y"`
 }
 
-// Parser defines the method needed for converting the provided
-// image file into database content.
+// File holds the location and metadata of a file to parse. Bucket and
+// Key identify the document and name the S3 object read by Parse, but
+// need not name an S3 object when the file is parsed from a reader;
+// Version, ContentType, and LastModified are optional and stored on the
+// document as provided.
+type File struct {
+	Bucket       string
+	Key          string
+	Version      string
+	ContentType  string
+	LastModified *time.Time
+}
+
+// Parser defines the methods needed for converting the provided
+// image file into database content. Parse reads the file from S3 and
+// ParseReader reads the file content from the reader; neither reads
+// the file metadata, which is provided by the caller.
 type Parser interface {
-	Parse(ctx context.Context, fileBucket, fileKey string) (*Document, error)
+	Parse(ctx context.Context, file File) (*Document, error)
+	ParseReader(ctx context.Context, reader io.Reader, file File) (*Document, error)
 }
@@ -0,0 +1,24 @@
+package pars
+
+import "testing"
+
+func TestNewCoordinates(t *testing.T) {
+	coordinates := NewCoordinates("coordinates_id", 0.1, 0.2, 0.5, 0.25)
+
+	expected := Coordinates{
+		ID:          "coordinates_id",
+		Entity:      "coordinates",
+		TopLeft:     Point{X: 0.1, Y: 0.2},
+		TopRight:    Point{X: 0.6, Y: 0.2},
+		BottomLeft:  Point{X: 0.1, Y: 0.45},
+		BottomRight: Point{X: 0.6, Y: 0.45},
+	}
+
+	if coordinates.ID != expected.ID || coordinates.Entity != expected.Entity {
+		t.Errorf("incorrect coordinates identity, received: %+v, expected: %+v", coordinates, expected)
+	}
+
+	if !checkCoordinates(t, coordinates, expected) {
+		t.Errorf("incorrect coordinates, received: %+v, expected: %+v", coordinates, expected)
+	}
+}
@@ -0,0 +1,228 @@
+// Package pdf implements pars.Parser by reading the text layer of PDF
+// files and OCRing the files without one. A PDF with any page without
+// text is OCRed in full, since the OCR parsers read whole files, and
+// only the pages without text are taken from the OCR output.
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+const contentType = "application/pdf"
+
+// textConfidence is the confidence of text read from the text layer.
+const textConfidence = 100
+
+var _ pars.Parser = &Client{}
+
+// Client implements the pars.Parser methods by reading PDF text
+// layers and falling back to an OCR pars.Parser.
+type Client struct {
+	s3Client  s3Client
+	ocrClient pars.Parser
+	readPages func(data []byte) ([]page, error)
+}
+
+type s3Client interface {
+	GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error)
+}
+
+// New generates a Client pointer instance with an AWS S3 client that
+// parses files without a complete text layer with the provided OCR
+// parser.
+func New(newSession *session.Session, ocrClient pars.Parser) *Client {
+	return &Client{
+		s3Client:  s3.New(newSession),
+		ocrClient: ocrClient,
+		readPages: readPages,
+	}
+}
+
+// Parse implements the pars.Parser.Parse interface method. Lines are
+// read from the text layer of PDF files; files that are not PDFs or
+// cannot be read are parsed by the OCR parser, as are PDFs with pages
+// without text. The whole file is OCRed in that case, and is charged
+// for every page by Textract, but only the OCRed pages replace the
+// empty ones.
+func (c *Client) Parse(ctx context.Context, file pars.File) (*pars.Document, error) {
+	ocr := func() (*pars.Document, error) {
+		return c.ocrClient.Parse(ctx, file)
+	}
+
+	if !isPDF(file.Key, file.ContentType) {
+		return c.ocr(ocr)
+	}
+
+	getOutput, err := c.s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(file.Bucket),
+		Key:    aws.String(file.Key),
+	})
+	if err != nil {
+		return nil, &GetObjectError{err: err}
+	}
+	defer getOutput.Body.Close()
+
+	data, err := io.ReadAll(getOutput.Body)
+	if err != nil {
+		return nil, &GetObjectError{err: err}
+	}
+
+	return c.parse(data, file, ocr)
+}
+
+// ParseReader implements the pars.Parser.ParseReader interface method
+// in the same way as Parse, passing the file content to the OCR
+// parser's ParseReader method.
+func (c *Client) ParseReader(ctx context.Context, reader io.Reader, file pars.File) (*pars.Document, error) {
+	if !isPDF(file.Key, file.ContentType) {
+		return c.ocr(func() (*pars.Document, error) {
+			return c.ocrClient.ParseReader(ctx, reader, file)
+		})
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, &ReadFileError{err: err}
+	}
+
+	return c.parse(data, file, func() (*pars.Document, error) {
+		return c.ocrClient.ParseReader(ctx, bytes.NewReader(data), file)
+	})
+}
+
+// parse reads the text layer of the PDF data, running the provided OCR
+// parse on the whole file if it cannot be read or any page has no
+// text.
+func (c *Client) parse(data []byte, file pars.File, ocr func() (*pars.Document, error)) (*pars.Document, error) {
+	pages, err := c.readPages(data)
+	if err != nil {
+		return c.ocr(ocr)
+	}
+
+	document := convertToDocument(pages, file.Key, file.Bucket, file.Version)
+
+	missing := map[int64]int{}
+	for i, documentPage := range document.Pages {
+		if len(documentPage.Lines) == 0 {
+			missing[documentPage.PageNumber] = i
+		}
+	}
+
+	if len(missing) > 0 {
+		ocrDocument, err := c.ocr(ocr)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ocrPage := range ocrDocument.Pages {
+			if i, ok := missing[ocrPage.PageNumber]; ok {
+				document.Pages[i] = ocrPage
+			}
+		}
+	}
+
+	pars.SetFileMetadata(&document, file)
+
+	return &document, nil
+}
+
+func (c *Client) ocr(parse func() (*pars.Document, error)) (*pars.Document, error) {
+	document, err := parse()
+	if err != nil {
+		return nil, &ParseOCRError{err: err}
+	}
+
+	return document, nil
+}
+
+func isPDF(fileKey, fileContentType string) bool {
+	return pars.FileExtension(fileKey) == "pdf" || strings.HasPrefix(strings.ToLower(fileContentType), contentType)
+}
+
+// convertToDocument converts the pages read from the text layer into
+// a document with the line and word coordinates normalized to the 0-1
+// range of the page with the origin at the top left as Textract reports
+// them. Text layer lines are exact so they have full confidence. Pages
+// without text are included without lines.
+func convertToDocument(pages []page, fileKey, fileBucket, fileVersion string) pars.Document {
+	document := pars.Document{
+		ID:            pars.DocumentID(fileBucket, fileKey),
+		Entity:        "document",
+		FileKey:       fileKey,
+		FileBucket:    fileBucket,
+		FileVersion:   fileVersion,
+		FileExtension: pars.FileExtension(fileKey),
+	}
+
+	for _, textPage := range pages {
+		documentPage := pars.Page{
+			Entity:     "page",
+			PageNumber: textPage.number,
+			Lines:      []pars.Line{},
+		}
+
+		documentPage.ID = pars.NewID(document.ID, fileVersion, documentPage.Entity, strconv.FormatInt(documentPage.PageNumber, 10))
+
+		for _, textLine := range textPage.lines {
+			lineID := pars.NewID(documentPage.ID, "line", strconv.Itoa(len(documentPage.Lines)))
+
+			documentLine := pars.Line{
+				ID:          lineID,
+				Entity:      "line",
+				Text:        textLine.text,
+				Confidence:  textConfidence,
+				Coordinates: textPage.box.coordinates(pars.NewID(lineID, "coordinates"), textLine.box),
+			}
+
+			for _, textWord := range textLine.words {
+				wordID := pars.NewID(lineID, "word", strconv.Itoa(len(documentLine.Words)))
+
+				documentLine.Words = append(documentLine.Words, pars.Word{
+					ID:          wordID,
+					Entity:      "word",
+					Text:        textWord.text,
+					Confidence:  textConfidence,
+					Coordinates: textPage.box.coordinates(pars.NewID(wordID, "coordinates"), textWord.box),
+				})
+			}
+
+			documentPage.Lines = append(documentPage.Lines, documentLine)
+		}
+
+		document.Pages = append(document.Pages, documentPage)
+	}
+
+	return document
+}
+
+// coordinates returns the coordinates of the text box within the page
+// box normalized to the 0-1 range of the page with the origin at the
+// top left.
+func (b box) coordinates(id string, text box) pars.Coordinates {
+	width := b.right - b.left
+	height := b.top - b.bottom
+
+	left := normalize((text.left - b.left) / width)
+	right := normalize((text.right - b.left) / width)
+	top := normalize((b.top - text.top) / height)
+	bottom := normalize((b.top - text.bottom) / height)
+
+	return pars.NewCoordinates(id, left, top, right-left, bottom-top)
+}
+
+// normalize clamps a page relative value to the page for text drawn
+// past its edges.
+func normalize(value float64) float64 {
+	return math.Max(0, math.Min(1, value))
+}
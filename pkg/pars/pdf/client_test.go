@@ -0,0 +1,419 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+func TestNew(t *testing.T) {
+	client := New(session.New(), &mockOCRClient{})
+	if client == nil {
+		t.Error("error creating parser client")
+	}
+}
+
+type mockS3Client struct {
+	mockGetObjectOutput *s3.GetObjectOutput
+	mockGetObjectError  error
+}
+
+func (m *mockS3Client) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	return m.mockGetObjectOutput, m.mockGetObjectError
+}
+
+type mockOCRClient struct {
+	mockParseCalls       int
+	mockParseReaderCalls int
+	mockParseReaderData  []byte
+	mockParseOutput      *pars.Document
+	mockParseError       error
+}
+
+func (m *mockOCRClient) Parse(ctx context.Context, file pars.File) (*pars.Document, error) {
+	m.mockParseCalls++
+	return m.mockParseOutput, m.mockParseError
+}
+
+func (m *mockOCRClient) ParseReader(ctx context.Context, reader io.Reader, file pars.File) (*pars.Document, error) {
+	m.mockParseReaderCalls++
+	m.mockParseReaderData, _ = io.ReadAll(reader)
+	return m.mockParseOutput, m.mockParseError
+}
+
+func TestParse(t *testing.T) {
+	fileBucket := "bucket"
+	lastModified := time.Date(2021, time.November, 1, 12, 0, 0, 0, time.UTC)
+
+	ocrDocument := &pars.Document{
+		ID: "ocr",
+		Pages: []pars.Page{
+			{
+				PageNumber: 1,
+				Lines: []pars.Line{
+					{
+						Text: "ocr page one",
+					},
+				},
+			},
+			{
+				PageNumber: 2,
+				Lines: []pars.Line{
+					{
+						Text: "ocr page two",
+					},
+				},
+			},
+		},
+	}
+
+	textPDF := newTestPDF("BT /F1 10 Tf 72 700 Td (text page one) Tj ET")
+	mixedPDF := newTestPDF("BT /F1 10 Tf 72 700 Td (text page one) Tj ET", "")
+
+	tests := []struct {
+		description        string
+		fileKey            string
+		contentType        string
+		mockGetObjectError error
+		data               []byte
+		mockParseError     error
+		ocrCalls           int
+		lines              []string
+		error              error
+	}{
+		{
+			description:        "s3 client get object error",
+			fileKey:            "file.pdf",
+			mockGetObjectError: errors.New("mock get object error"),
+			error:              &GetObjectError{},
+		},
+		{
+			description:    "ocr parse error",
+			fileKey:        "file.png",
+			contentType:    "image/png",
+			mockParseError: errors.New("mock parse error"),
+			ocrCalls:       1,
+			error:          &ParseOCRError{},
+		},
+		{
+			description: "non-pdf file parsed by ocr",
+			fileKey:     "file.png",
+			contentType: "image/png",
+			ocrCalls:    1,
+			lines:       []string{"ocr page one", "ocr page two"},
+			error:       nil,
+		},
+		{
+			description: "unreadable pdf parsed by ocr",
+			fileKey:     "file.pdf",
+			data:        []byte("not a pdf"),
+			ocrCalls:    1,
+			lines:       []string{"ocr page one", "ocr page two"},
+			error:       nil,
+		},
+		{
+			description: "pdf content type with text layer",
+			fileKey:     "file",
+			contentType: "application/pdf",
+			data:        textPDF,
+			ocrCalls:    0,
+			lines:       []string{"text page one"},
+			error:       nil,
+		},
+		{
+			description: "image-only page parsed by ocr",
+			fileKey:     "file.pdf",
+			data:        mixedPDF,
+			ocrCalls:    1,
+			lines:       []string{"text page one", "ocr page two"},
+			error:       nil,
+		},
+		{
+			description:    "image-only page ocr parse error",
+			fileKey:        "file.pdf",
+			data:           mixedPDF,
+			mockParseError: errors.New("mock parse error"),
+			ocrCalls:       1,
+			error:          &ParseOCRError{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			ocrClient := &mockOCRClient{
+				mockParseOutput: ocrDocument,
+				mockParseError:  test.mockParseError,
+			}
+
+			client := &Client{
+				s3Client: &mockS3Client{
+					mockGetObjectOutput: &s3.GetObjectOutput{
+						Body: io.NopCloser(bytes.NewReader(test.data)),
+					},
+					mockGetObjectError: test.mockGetObjectError,
+				},
+				ocrClient: ocrClient,
+				readPages: readPages,
+			}
+
+			document, err := client.Parse(context.Background(), pars.File{
+				Bucket:       fileBucket,
+				Key:          test.fileKey,
+				Version:      "etag",
+				ContentType:  test.contentType,
+				LastModified: &lastModified,
+			})
+
+			if ocrClient.mockParseCalls != test.ocrCalls {
+				t.Errorf("incorrect ocr calls, received: %d, expected: %d", ocrClient.mockParseCalls, test.ocrCalls)
+			}
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *GetObjectError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *ParseOCRError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+				return
+			}
+
+			lines := []string{}
+			for _, page := range document.Pages {
+				for _, line := range page.Lines {
+					lines = append(lines, line.Text)
+				}
+			}
+
+			if !reflect.DeepEqual(lines, test.lines) {
+				t.Errorf("incorrect lines, received: %v, expected: %v", lines, test.lines)
+			}
+
+			if test.ocrCalls == 0 {
+				if document.ID != pars.DocumentID(fileBucket, test.fileKey) || document.FileVersion != "etag" {
+					t.Errorf("incorrect document identity, received: %+v", document)
+				}
+
+				if document.LastModified == nil || !document.LastModified.Equal(lastModified) || document.IndexedAt == nil {
+					t.Errorf("incorrect document times, received: %+v", document)
+				}
+			}
+		})
+	}
+}
+
+func TestParseReader(t *testing.T) {
+	ocrDocument := &pars.Document{
+		Pages: []pars.Page{
+			{
+				PageNumber: 2,
+				Lines: []pars.Line{
+					{
+						Text: "ocr page two",
+					},
+				},
+			},
+		},
+	}
+
+	textPDF := newTestPDF("BT /F1 10 Tf 72 700 Td (text page one) Tj ET")
+	mixedPDF := newTestPDF("BT /F1 10 Tf 72 700 Td (text page one) Tj ET", "")
+
+	tests := []struct {
+		description string
+		file        pars.File
+		reader      io.Reader
+		ocrData     []byte
+		lines       []string
+		error       error
+	}{
+		{
+			description: "read file error",
+			file: pars.File{
+				Key: "file.pdf",
+			},
+			reader: iotest.ErrReader(errors.New("mock read error")),
+			error:  &ReadFileError{},
+		},
+		{
+			description: "non-pdf file parsed by ocr",
+			file: pars.File{
+				Key:         "file.png",
+				ContentType: "image/png",
+			},
+			reader:  bytes.NewReader([]byte("image")),
+			ocrData: []byte("image"),
+			lines:   []string{"ocr page two"},
+			error:   nil,
+		},
+		{
+			description: "pdf with text layer",
+			file: pars.File{
+				Key:         "upload",
+				ContentType: "application/pdf",
+			},
+			reader:  bytes.NewReader(textPDF),
+			ocrData: nil,
+			lines:   []string{"text page one"},
+			error:   nil,
+		},
+		{
+			description: "image-only page parsed by ocr",
+			file: pars.File{
+				Key: "file.pdf",
+			},
+			reader:  bytes.NewReader(mixedPDF),
+			ocrData: mixedPDF,
+			lines:   []string{"text page one", "ocr page two"},
+			error:   nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			ocrClient := &mockOCRClient{
+				mockParseOutput: ocrDocument,
+			}
+
+			client := &Client{
+				ocrClient: ocrClient,
+				readPages: readPages,
+			}
+
+			document, err := client.ParseReader(context.Background(), test.reader, test.file)
+
+			if ocrClient.mockParseCalls != 0 {
+				t.Errorf("incorrect ocr parse calls, received: %d, expected: 0", ocrClient.mockParseCalls)
+			}
+
+			if !bytes.Equal(ocrClient.mockParseReaderData, test.ocrData) {
+				t.Errorf("incorrect ocr data, received: %q, expected: %q", ocrClient.mockParseReaderData, test.ocrData)
+			}
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *ReadFileError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+				return
+			}
+
+			lines := []string{}
+			for _, page := range document.Pages {
+				for _, line := range page.Lines {
+					lines = append(lines, line.Text)
+				}
+			}
+
+			if !reflect.DeepEqual(lines, test.lines) {
+				t.Errorf("incorrect lines, received: %v, expected: %v", lines, test.lines)
+			}
+		})
+	}
+}
+
+func Test_convertToDocument(t *testing.T) {
+	document := convertToDocument([]page{
+		{
+			number: 1,
+			box: box{
+				left:   0,
+				bottom: 0,
+				right:  200,
+				top:    100,
+			},
+			lines: []line{
+				{
+					text: "line",
+					box: box{
+						left:   20,
+						bottom: 70,
+						right:  120,
+						top:    90,
+					},
+					words: []word{
+						{
+							text: "line",
+							box: box{
+								left:   20,
+								bottom: 70,
+								right:  120,
+								top:    90,
+							},
+						},
+					},
+				},
+				{
+					text: "overflow",
+					box: box{
+						left:   180,
+						bottom: 10,
+						right:  250,
+						top:    20,
+					},
+				},
+			},
+		},
+	}, "file.pdf", "bucket", "version")
+
+	if len(document.Pages) != 1 || len(document.Pages[0].Lines) != 2 {
+		t.Fatalf("incorrect pages, received: %+v", document.Pages)
+	}
+
+	for i, expected := range []pars.Coordinates{
+		{
+			TopLeft:     pars.Point{X: 0.1, Y: 0.1},
+			TopRight:    pars.Point{X: 0.6, Y: 0.1},
+			BottomLeft:  pars.Point{X: 0.1, Y: 0.3},
+			BottomRight: pars.Point{X: 0.6, Y: 0.3},
+		},
+		{
+			TopLeft:     pars.Point{X: 0.9, Y: 0.8},
+			TopRight:    pars.Point{X: 1, Y: 0.8},
+			BottomLeft:  pars.Point{X: 0.9, Y: 0.9},
+			BottomRight: pars.Point{X: 1, Y: 0.9},
+		},
+	} {
+		received := document.Pages[0].Lines[i].Coordinates
+		for _, points := range [][2]pars.Point{
+			{received.TopLeft, expected.TopLeft},
+			{received.TopRight, expected.TopRight},
+			{received.BottomLeft, expected.BottomLeft},
+			{received.BottomRight, expected.BottomRight},
+		} {
+			if math.Abs(points[0].X-points[1].X) > 1e-9 || math.Abs(points[0].Y-points[1].Y) > 1e-9 {
+				t.Errorf("incorrect coordinates, received: %+v, expected: %+v", received, expected)
+			}
+		}
+	}
+
+	line := document.Pages[0].Lines[0]
+	if line.Confidence != 100 || len(line.Words) != 1 || line.Words[0].Text != "line" || line.Words[0].Confidence != 100 {
+		t.Errorf("incorrect line words, received: %+v", line)
+	}
+
+	if line.Words[0].Coordinates.TopLeft != line.Coordinates.TopLeft || line.Words[0].Coordinates.BottomRight != line.Coordinates.BottomRight {
+		t.Errorf("incorrect word coordinates, received: %+v, expected: %+v", line.Words[0].Coordinates, line.Coordinates)
+	}
+}
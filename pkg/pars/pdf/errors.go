@@ -0,0 +1,36 @@
+package pdf
+
+import "fmt"
+
+const errorMessage = "package pdf: %s"
+
+// GetObjectError wraps errors returned by s3.S3.GetObject and reading
+// its body in the pars.Parser.Parse method.
+type GetObjectError struct {
+	err error
+}
+
+func (e *GetObjectError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// ReadFileError wraps errors returned reading the file in the
+// pars.Parser.ParseReader method.
+type ReadFileError struct {
+	err error
+}
+
+func (e *ReadFileError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// ParseOCRError wraps errors returned by the OCR pars.Parser.Parse
+// and pars.Parser.ParseReader methods for files and pages without a
+// text layer.
+type ParseOCRError struct {
+	err error
+}
+
+func (e *ParseOCRError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
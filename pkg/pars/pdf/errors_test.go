@@ -0,0 +1,39 @@
+package pdf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetObjectError(t *testing.T) {
+	err := &GetObjectError{err: errors.New("mock get object error")}
+
+	recieved := err.Error()
+	expected := "package pdf: mock get object error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestReadFileError(t *testing.T) {
+	err := &ReadFileError{err: errors.New("mock read file error")}
+
+	recieved := err.Error()
+	expected := "package pdf: mock read file error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestParseOCRError(t *testing.T) {
+	err := &ParseOCRError{err: errors.New("mock parse ocr error")}
+
+	recieved := err.Error()
+	expected := "package pdf: mock parse ocr error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
@@ -0,0 +1,234 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	rscpdf "rsc.io/pdf"
+)
+
+const (
+	// rowTolerance is the share of the font size two glyph baselines
+	// may differ by and still be placed on the same line.
+	rowTolerance = 0.3
+
+	// spaceGap is the share of the font size between two glyphs above
+	// which a space is inserted between them; the reader drops the
+	// space glyphs themselves.
+	spaceGap = 0.1
+
+	// columnGap is the share of the font size between two glyphs above
+	// which they are split into separate lines, as in table cells or
+	// page columns.
+	columnGap = 2.0
+
+	// ascent and descent are the shares of the font size a line's box
+	// extends above and below its baseline.
+	ascent  = 0.8
+	descent = 0.2
+)
+
+// box holds a rectangle in PDF user space with the origin at the
+// bottom left of the page.
+type box struct {
+	left   float64
+	bottom float64
+	right  float64
+	top    float64
+}
+
+// word holds the text of a word on a page and its bounding box.
+type word struct {
+	text string
+	box  box
+}
+
+// line holds the text of a line on a page, its bounding box, and the
+// words it is made of.
+type line struct {
+	text  string
+	box   box
+	words []word
+}
+
+// page holds the lines of text read from a page and its media box. A
+// page without lines has no text layer and must be OCRed.
+type page struct {
+	number int64
+	box    box
+	lines  []line
+}
+
+// readPages reads the text layer of each page of the PDF. Pages whose
+// content cannot be read are returned without lines so that they are
+// OCRed instead.
+func readPages(data []byte) (pages []page, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pages, err = nil, fmt.Errorf("reading pdf: %v", r)
+		}
+	}()
+
+	reader, err := rscpdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	count := reader.NumPage()
+	if count == 0 {
+		return nil, fmt.Errorf("reading pdf: no pages")
+	}
+
+	for number := 1; number <= count; number++ {
+		pdfPage := reader.Page(number)
+		if pdfPage.V.IsNull() {
+			return nil, fmt.Errorf("reading pdf: page %d not found", number)
+		}
+
+		pages = append(pages, page{
+			number: int64(number),
+			box:    mediaBox(pdfPage),
+			lines:  readLines(pdfPage),
+		})
+	}
+
+	return pages, nil
+}
+
+// mediaBox returns the media box of the page, which may be inherited
+// from its parents, defaulting to US Letter.
+func mediaBox(pdfPage rscpdf.Page) box {
+	for value := pdfPage.V; !value.IsNull(); value = value.Key("Parent") {
+		mediaBox := value.Key("MediaBox")
+		if mediaBox.Kind() != rscpdf.Array || mediaBox.Len() != 4 {
+			continue
+		}
+
+		output := box{
+			left:   math.Min(mediaBox.Index(0).Float64(), mediaBox.Index(2).Float64()),
+			bottom: math.Min(mediaBox.Index(1).Float64(), mediaBox.Index(3).Float64()),
+			right:  math.Max(mediaBox.Index(0).Float64(), mediaBox.Index(2).Float64()),
+			top:    math.Max(mediaBox.Index(1).Float64(), mediaBox.Index(3).Float64()),
+		}
+
+		if output.right > output.left && output.top > output.bottom {
+			return output
+		}
+	}
+
+	return box{
+		right: 612,
+		top:   792,
+	}
+}
+
+// readLines returns the lines of text drawn on the page from top to
+// bottom and left to right, or none if the content cannot be read.
+func readLines(pdfPage rscpdf.Page) (lines []line) {
+	defer func() {
+		if r := recover(); r != nil {
+			lines = nil
+		}
+	}()
+
+	glyphs := []rscpdf.Text{}
+	for _, text := range pdfPage.Content().Text {
+		if strings.TrimSpace(text.S) != "" && text.FontSize > 0 {
+			glyphs = append(glyphs, text)
+		}
+	}
+
+	return groupLines(glyphs)
+}
+
+// groupLines groups glyphs sharing a baseline into rows and splits
+// each row into lines at wide gaps and each line into words at spaces.
+func groupLines(glyphs []rscpdf.Text) []line {
+	sort.SliceStable(glyphs, func(i, j int) bool {
+		if glyphs[i].Y != glyphs[j].Y {
+			return glyphs[i].Y > glyphs[j].Y
+		}
+		return glyphs[i].X < glyphs[j].X
+	})
+
+	rows := [][]rscpdf.Text{}
+	for _, glyph := range glyphs {
+		if len(rows) > 0 {
+			previous := rows[len(rows)-1]
+			first := previous[0]
+			if math.Abs(first.Y-glyph.Y) <= rowTolerance*math.Max(first.FontSize, glyph.FontSize) {
+				rows[len(rows)-1] = append(previous, glyph)
+				continue
+			}
+		}
+
+		rows = append(rows, []rscpdf.Text{glyph})
+	}
+
+	output := []line{}
+	for _, row := range rows {
+		sort.SliceStable(row, func(i, j int) bool {
+			return row[i].X < row[j].X
+		})
+
+		var current *line
+		for _, glyph := range row {
+			glyphBox := box{
+				left:   glyph.X,
+				bottom: glyph.Y - descent*glyph.FontSize,
+				right:  glyph.X + glyph.W,
+				top:    glyph.Y + ascent*glyph.FontSize,
+			}
+
+			if current != nil && glyph.X-current.box.right > columnGap*glyph.FontSize {
+				output = append(output, *current)
+				current = nil
+			}
+
+			if current == nil {
+				current = &line{
+					text: glyph.S,
+					box:  glyphBox,
+					words: []word{
+						{
+							text: glyph.S,
+							box:  glyphBox,
+						},
+					},
+				}
+				continue
+			}
+
+			if glyph.X-current.box.right > spaceGap*glyph.FontSize {
+				current.text += " "
+				current.words = append(current.words, word{
+					box: glyphBox,
+				})
+			}
+			current.text += glyph.S
+			current.box = current.box.union(glyphBox)
+
+			last := &current.words[len(current.words)-1]
+			last.text += glyph.S
+			last.box = last.box.union(glyphBox)
+		}
+
+		if current != nil {
+			output = append(output, *current)
+		}
+	}
+
+	return output
+}
+
+func (b box) union(other box) box {
+	return box{
+		left:   math.Min(b.left, other.left),
+		bottom: math.Min(b.bottom, other.bottom),
+		right:  math.Max(b.right, other.right),
+		top:    math.Max(b.top, other.top),
+	}
+}
@@ -0,0 +1,243 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	rscpdf "rsc.io/pdf"
+)
+
+// newTestPDF returns a PDF with a US Letter page for each content
+// stream drawn with a monospaced font whose glyphs are 0.6 of the
+// font size wide; an empty content stream is an image-only page.
+func newTestPDF(contents ...string) []byte {
+	widths := strings.TrimSpace(strings.Repeat("600 ", 95))
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"",
+		fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /FirstChar 32 /LastChar 126 /Widths [%s] >>", widths),
+	}
+
+	kids := []string{}
+	for _, content := range contents {
+		pageNumber := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageNumber))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pageNumber+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 612 792] >>", strings.Join(kids, " "), len(contents))
+
+	buffer := &bytes.Buffer{}
+	buffer.WriteString("%PDF-1.4\n")
+
+	offsets := []int{}
+	for i, object := range objects {
+		offsets = append(offsets, buffer.Len())
+		fmt.Fprintf(buffer, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buffer.Len()
+	fmt.Fprintf(buffer, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buffer, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(buffer, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buffer.Bytes()
+}
+
+func Test_readPages(t *testing.T) {
+	tests := []struct {
+		description string
+		data        []byte
+		pages       []page
+		error       bool
+	}{
+		{
+			description: "invalid pdf",
+			data:        []byte("not a pdf"),
+			pages:       nil,
+			error:       true,
+		},
+		{
+			description: "text and image-only pages",
+			data: newTestPDF(
+				"BT /F1 10 Tf 72 700 Td (Invoice #1001) Tj ET BT /F1 10 Tf 400 700 Td (Paid) Tj ET BT /F1 20 Tf 72 650 Td (Total) Tj ET",
+				"",
+			),
+			pages: []page{
+				{
+					number: 1,
+					box: box{
+						right: 612,
+						top:   792,
+					},
+					lines: []line{
+						{
+							text: "Invoice #1001",
+							box: box{
+								left:   72,
+								bottom: 698,
+								right:  150,
+								top:    708,
+							},
+							words: []word{
+								{
+									text: "Invoice",
+									box: box{
+										left:   72,
+										bottom: 698,
+										right:  114,
+										top:    708,
+									},
+								},
+								{
+									text: "#1001",
+									box: box{
+										left:   120,
+										bottom: 698,
+										right:  150,
+										top:    708,
+									},
+								},
+							},
+						},
+						{
+							text: "Paid",
+							box: box{
+								left:   400,
+								bottom: 698,
+								right:  424,
+								top:    708,
+							},
+							words: []word{
+								{
+									text: "Paid",
+									box: box{
+										left:   400,
+										bottom: 698,
+										right:  424,
+										top:    708,
+									},
+								},
+							},
+						},
+						{
+							text: "Total",
+							box: box{
+								left:   72,
+								bottom: 646,
+								right:  132,
+								top:    666,
+							},
+							words: []word{
+								{
+									text: "Total",
+									box: box{
+										left:   72,
+										bottom: 646,
+										right:  132,
+										top:    666,
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					number: 2,
+					box: box{
+						right: 612,
+						top:   792,
+					},
+					lines: []line{},
+				},
+			},
+			error: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			pages, err := readPages(test.data)
+
+			if (err != nil) != test.error {
+				t.Fatalf("incorrect error, received: %v, expected error: %t", err, test.error)
+			}
+
+			for _, received := range pages {
+				for i := range received.lines {
+					received.lines[i].box = roundBox(received.lines[i].box)
+					for j := range received.lines[i].words {
+						received.lines[i].words[j].box = roundBox(received.lines[i].words[j].box)
+					}
+				}
+			}
+
+			if !reflect.DeepEqual(pages, test.pages) {
+				t.Errorf("incorrect pages, received: %+v, expected: %+v", pages, test.pages)
+			}
+		})
+	}
+}
+
+func Test_groupLines(t *testing.T) {
+	glyphs := []rscpdf.Text{}
+	for i, character := range "ab d" {
+		// readLines drops space glyphs before grouping
+		if character == ' ' {
+			continue
+		}
+
+		glyphs = append(glyphs, rscpdf.Text{
+			FontSize: 10,
+			X:        float64(100 + i*6),
+			Y:        500.5,
+			W:        6,
+			S:        string(character),
+		})
+	}
+
+	glyphs = append(glyphs, rscpdf.Text{
+		FontSize: 10,
+		X:        100,
+		Y:        520,
+		W:        6,
+		S:        "z",
+	})
+
+	received := []string{}
+	words := []string{}
+	for _, line := range groupLines(glyphs) {
+		received = append(received, line.text)
+		for _, word := range line.words {
+			words = append(words, word.text)
+		}
+	}
+
+	expected := []string{"z", "ab d"}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("incorrect lines, received: %v, expected: %v", received, expected)
+	}
+
+	expectedWords := []string{"z", "ab", "d"}
+	if !reflect.DeepEqual(words, expectedWords) {
+		t.Errorf("incorrect words, received: %v, expected: %v", words, expectedWords)
+	}
+}
+
+func roundBox(value box) box {
+	return box{
+		left:   math.Round(value.left*1000) / 1000,
+		bottom: math.Round(value.bottom*1000) / 1000,
+		right:  math.Round(value.right*1000) / 1000,
+		top:    math.Round(value.top*1000) / 1000,
+	}
+}
@@ -0,0 +1,287 @@
+// Package tesseract implements pars.Parser by running the Tesseract
+// OCR command locally on files downloaded from S3 or read from a
+// reader. PDFs are rasterized into page images with pdftoppm first
+// since Tesseract does not read PDFs.
+package tesseract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+// defaultCommand is the Tesseract command run if none is provided.
+const defaultCommand = "tesseract"
+
+// defaultPDFCommand is the pdftoppm command run if none is provided.
+const defaultPDFCommand = "pdftoppm"
+
+// pdfResolution is the resolution in DPI PDF pages are rasterized at.
+const pdfResolution = "300"
+
+var _ pars.Parser = &Client{}
+
+// Client implements the pars.Parser methods using Tesseract.
+type Client struct {
+	s3Client   s3Client
+	command    string
+	pdfCommand string
+	language   string
+	runCommand func(ctx context.Context, input io.Reader, name string, args ...string) ([]byte, error)
+}
+
+type s3Client interface {
+	GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error)
+}
+
+// New generates a Client pointer instance with an AWS S3 client that
+// runs the provided Tesseract command, "tesseract" on the path if
+// empty, with the provided language, the Tesseract default if empty.
+// PDFs are rasterized with the provided pdftoppm command, "pdftoppm"
+// on the path if empty.
+func New(newSession *session.Session, command, pdfCommand, language string) *Client {
+	if command == "" {
+		command = defaultCommand
+	}
+
+	if pdfCommand == "" {
+		pdfCommand = defaultPDFCommand
+	}
+
+	return &Client{
+		s3Client:   s3.New(newSession),
+		command:    command,
+		pdfCommand: pdfCommand,
+		language:   language,
+		runCommand: runCommand,
+	}
+}
+
+// Parse implements the pars.Parser.Parse interface method using
+// Tesseract by downloading the file and parsing it with ParseReader.
+func (c *Client) Parse(ctx context.Context, file pars.File) (*pars.Document, error) {
+	getOutput, err := c.s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(file.Bucket),
+		Key:    aws.String(file.Key),
+	})
+	if err != nil {
+		return nil, &GetObjectError{err: err}
+	}
+	defer getOutput.Body.Close()
+
+	return c.ParseReader(ctx, getOutput.Body, file)
+}
+
+// ParseReader implements the pars.Parser.ParseReader interface method
+// using Tesseract. Images are passed to Tesseract on stdin so they
+// must be in a format Tesseract reads, including multi-page TIFFs.
+// PDFs are rasterized into one image per page which are then passed
+// to Tesseract together.
+func (c *Client) ParseReader(ctx context.Context, reader io.Reader, file pars.File) (*pars.Document, error) {
+	var output []byte
+	var err error
+	if isPDF(file) {
+		output, err = c.runPDF(ctx, reader)
+	} else {
+		output, err = c.runCommand(ctx, reader, c.command, c.args("stdin")...)
+		if err != nil {
+			err = &RunTesseractError{err: err}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := parseTSV(output)
+	if err != nil {
+		return nil, &ParseOutputError{err: err}
+	}
+
+	document := convertToDocument(pages, file.Key, file.Bucket, file.Version)
+
+	pars.SetFileMetadata(&document, file)
+
+	return &document, nil
+}
+
+// args returns the Tesseract arguments reading the provided input and
+// writing TSV output to stdout.
+func (c *Client) args(input string) []string {
+	args := []string{input, "stdout"}
+	if c.language != "" {
+		args = append(args, "-l", c.language)
+	}
+
+	return append(args, "tsv")
+}
+
+// runPDF writes the PDF to a temporary directory, rasterizes its pages
+// into TIFF images, and runs Tesseract on a list of the images so that
+// each image is read as a page in order.
+func (c *Client) runPDF(ctx context.Context, reader io.Reader) ([]byte, error) {
+	directory, err := os.MkdirTemp("", "findfile-tesseract-")
+	if err != nil {
+		return nil, &RasterizePDFError{err: err}
+	}
+	defer os.RemoveAll(directory)
+
+	pdfPath := filepath.Join(directory, "file.pdf")
+	if err := writeFile(pdfPath, reader); err != nil {
+		return nil, &RasterizePDFError{err: err}
+	}
+
+	pagePrefix := filepath.Join(directory, "page")
+	if _, err := c.runCommand(ctx, nil, c.pdfCommand, "-r", pdfResolution, "-tiff", pdfPath, pagePrefix); err != nil {
+		return nil, &RasterizePDFError{err: err}
+	}
+
+	// pdftoppm pads the page numbers to the same width so the names
+	// sort in page order
+	pagePaths, err := filepath.Glob(pagePrefix + "-*.tif")
+	if err != nil {
+		return nil, &RasterizePDFError{err: err}
+	}
+
+	if len(pagePaths) == 0 {
+		return nil, &RasterizePDFError{err: errors.New("no pages rasterized")}
+	}
+	sort.Strings(pagePaths)
+
+	listPath := filepath.Join(directory, "pages.txt")
+	if err := writeFile(listPath, strings.NewReader(strings.Join(pagePaths, "\n")+"\n")); err != nil {
+		return nil, &RasterizePDFError{err: err}
+	}
+
+	output, err := c.runCommand(ctx, nil, c.command, c.args(listPath)...)
+	if err != nil {
+		return nil, &RunTesseractError{err: err}
+	}
+
+	return output, nil
+}
+
+// isPDF returns true if the file is a PDF by its extension or content
+// type.
+func isPDF(file pars.File) bool {
+	return pars.FileExtension(file.Key) == "pdf" || strings.ToLower(file.ContentType) == "application/pdf"
+}
+
+func writeFile(path string, reader io.Reader) error {
+	output, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(output, reader); err != nil {
+		output.Close()
+		return err
+	}
+
+	return output.Close()
+}
+
+// runCommand runs the named command with the input on stdin and
+// returns its stdout, including stderr in the error if it fails.
+func runCommand(ctx context.Context, input io.Reader, name string, args ...string) ([]byte, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	command := exec.CommandContext(ctx, name, args...)
+	command.Stdin = input
+	command.Stdout = stdout
+	command.Stderr = stderr
+
+	if err := command.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// convertToDocument converts the recognized pages into a document with
+// the line and word coordinates normalized to the 0-1 range of the page
+// size as Textract reports them. Line confidence is the mean of its
+// word confidences.
+func convertToDocument(pages []page, fileKey, fileBucket, fileVersion string) pars.Document {
+	document := pars.Document{
+		ID:            pars.DocumentID(fileBucket, fileKey),
+		Entity:        "document",
+		FileKey:       fileKey,
+		FileBucket:    fileBucket,
+		FileVersion:   fileVersion,
+		FileExtension: pars.FileExtension(fileKey),
+	}
+
+	for _, recognizedPage := range pages {
+		documentPage := pars.Page{
+			Entity:     "page",
+			PageNumber: recognizedPage.number,
+			Lines:      []pars.Line{},
+		}
+
+		documentPage.ID = pars.NewID(document.ID, fileVersion, documentPage.Entity, strconv.FormatInt(documentPage.PageNumber, 10))
+
+		if recognizedPage.box.width <= 0 || recognizedPage.box.height <= 0 {
+			continue
+		}
+
+		for _, recognizedLine := range recognizedPage.lines {
+			lineID := pars.NewID(documentPage.ID, "line", strconv.Itoa(len(documentPage.Lines)))
+
+			documentLine := pars.Line{
+				ID:          lineID,
+				Entity:      "line",
+				Coordinates: recognizedPage.box.coordinates(pars.NewID(lineID, "coordinates"), recognizedLine.box),
+			}
+
+			texts := []string{}
+			for _, recognizedWord := range recognizedLine.words {
+				wordID := pars.NewID(lineID, "word", strconv.Itoa(len(documentLine.Words)))
+
+				documentLine.Words = append(documentLine.Words, pars.Word{
+					ID:          wordID,
+					Entity:      "word",
+					Text:        recognizedWord.text,
+					Confidence:  recognizedWord.conf,
+					Coordinates: recognizedPage.box.coordinates(pars.NewID(wordID, "coordinates"), recognizedWord.box),
+				})
+
+				texts = append(texts, recognizedWord.text)
+				documentLine.Confidence += recognizedWord.conf / float64(len(recognizedLine.words))
+			}
+			documentLine.Text = strings.Join(texts, " ")
+
+			documentPage.Lines = append(documentPage.Lines, documentLine)
+		}
+
+		document.Pages = append(document.Pages, documentPage)
+	}
+
+	return document
+}
+
+// coordinates returns the coordinates of the pixel box within the page
+// box normalized to the 0-1 range of the page size.
+func (b box) coordinates(id string, pixels box) pars.Coordinates {
+	return pars.NewCoordinates(
+		id,
+		pixels.left/b.width,
+		pixels.top/b.height,
+		pixels.width/b.width,
+		pixels.height/b.height,
+	)
+}
@@ -0,0 +1,392 @@
+package tesseract
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+func TestNew(t *testing.T) {
+	client := New(session.New(), "", "", "eng")
+	if client == nil {
+		t.Fatal("error creating parser client")
+	}
+
+	if client.command != defaultCommand {
+		t.Errorf("incorrect command, received: %s, expected: %s", client.command, defaultCommand)
+	}
+
+	if client.pdfCommand != defaultPDFCommand {
+		t.Errorf("incorrect pdf command, received: %s, expected: %s", client.pdfCommand, defaultPDFCommand)
+	}
+}
+
+type mockS3Client struct {
+	mockGetObjectOutput *s3.GetObjectOutput
+	mockGetObjectError  error
+}
+
+func (m *mockS3Client) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	return m.mockGetObjectOutput, m.mockGetObjectError
+}
+
+func TestParse(t *testing.T) {
+	fileKey := "scans/test.tiff"
+	fileBucket := "bucket"
+	lastModified := time.Date(2021, time.November, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		description        string
+		mockGetObjectError error
+		runCommandOutput   string
+		runCommandError    error
+		lines              []string
+		error              error
+	}{
+		{
+			description:        "s3 client get object error",
+			mockGetObjectError: errors.New("mock get object error"),
+			error:              &GetObjectError{},
+		},
+		{
+			description:     "run tesseract error",
+			runCommandError: errors.New("mock run command error"),
+			error:           &RunTesseractError{},
+		},
+		{
+			description:      "parse output error",
+			runCommandOutput: "not\ttsv\n1\n",
+			error:            &ParseOutputError{},
+		},
+		{
+			description:      "successful invocation",
+			runCommandOutput: testTSV,
+			lines:            []string{"Invoice #1001", "Total"},
+			error:            nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var receivedInput, receivedName string
+			var receivedArgs []string
+
+			client := &Client{
+				s3Client: &mockS3Client{
+					mockGetObjectOutput: &s3.GetObjectOutput{
+						Body: io.NopCloser(strings.NewReader("image")),
+					},
+					mockGetObjectError: test.mockGetObjectError,
+				},
+				command:  "tesseract",
+				language: "eng",
+				runCommand: func(ctx context.Context, input io.Reader, name string, args ...string) ([]byte, error) {
+					data, _ := io.ReadAll(input)
+					receivedInput, receivedName, receivedArgs = string(data), name, args
+					return []byte(test.runCommandOutput), test.runCommandError
+				},
+			}
+
+			document, err := client.Parse(context.Background(), pars.File{
+				Bucket:       fileBucket,
+				Key:          fileKey,
+				Version:      "etag",
+				ContentType:  "image/tiff",
+				LastModified: &lastModified,
+			})
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *GetObjectError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *RunTesseractError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *ParseOutputError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else {
+				if receivedInput != "image" || receivedName != "tesseract" {
+					t.Errorf("incorrect command input, received: %s %s, expected: image tesseract", receivedInput, receivedName)
+				}
+
+				expectedArgs := []string{"stdin", "stdout", "-l", "eng", "tsv"}
+				if !reflect.DeepEqual(receivedArgs, expectedArgs) {
+					t.Errorf("incorrect args, received: %v, expected: %v", receivedArgs, expectedArgs)
+				}
+
+				if document.ID != pars.DocumentID(fileBucket, fileKey) {
+					t.Errorf("incorrect document id, received: %s", document.ID)
+				}
+
+				if document.FileVersion != "etag" || document.FileExtension != "tiff" || document.ContentType != "image/tiff" {
+					t.Errorf("incorrect file metadata, received: %+v", document)
+				}
+
+				if document.LastModified == nil || !document.LastModified.Equal(lastModified) {
+					t.Errorf("incorrect last modified, received: %v, expected: %v", document.LastModified, lastModified)
+				}
+
+				if document.IndexedAt == nil {
+					t.Errorf("no indexed at time, received: %+v", document)
+				}
+
+				lines := []string{}
+				for _, page := range document.Pages {
+					for _, line := range page.Lines {
+						lines = append(lines, line.Text)
+					}
+				}
+
+				if !reflect.DeepEqual(lines, test.lines) {
+					t.Errorf("incorrect lines, received: %v, expected: %v", lines, test.lines)
+				}
+			}
+		})
+	}
+}
+
+func TestParseReader(t *testing.T) {
+	file := pars.File{
+		Bucket:      "uploads",
+		Key:         "scan.PNG",
+		ContentType: "image/png",
+	}
+
+	var receivedInput string
+	client := &Client{
+		command: "tesseract",
+		runCommand: func(ctx context.Context, input io.Reader, name string, args ...string) ([]byte, error) {
+			data, _ := io.ReadAll(input)
+			receivedInput = string(data)
+			return []byte(testTSV), nil
+		},
+	}
+
+	document, err := client.ParseReader(context.Background(), strings.NewReader("image"), file)
+	if err != nil {
+		t.Fatalf("error parsing reader: %v", err)
+	}
+
+	if receivedInput != "image" {
+		t.Errorf("incorrect command input, received: %s, expected: image", receivedInput)
+	}
+
+	if document.ID != pars.DocumentID(file.Bucket, file.Key) || document.FileVersion != "" {
+		t.Errorf("incorrect document identity, received: %+v", document)
+	}
+
+	if document.FileExtension != "png" || document.ContentType != "image/png" || document.LastModified != nil {
+		t.Errorf("incorrect file metadata, received: %+v", document)
+	}
+
+	if len(document.Pages) != 2 || len(document.Pages[0].Lines) != 1 {
+		t.Errorf("incorrect pages, received: %+v", document.Pages)
+	}
+}
+
+func TestParseReaderPDF(t *testing.T) {
+	tests := []struct {
+		description     string
+		file            pars.File
+		pages           []string
+		rasterizeError  error
+		runCommandError error
+		pageList        []string
+		error           error
+	}{
+		{
+			description:    "rasterize pdf error",
+			file:           pars.File{Bucket: "uploads", Key: "scan.pdf"},
+			rasterizeError: errors.New("mock rasterize error"),
+			error:          &RasterizePDFError{},
+		},
+		{
+			description: "no pages rasterized",
+			file:        pars.File{Bucket: "uploads", Key: "scan.pdf"},
+			pages:       []string{},
+			error:       &RasterizePDFError{},
+		},
+		{
+			description:     "run tesseract error",
+			file:            pars.File{Bucket: "uploads", Key: "scan.pdf"},
+			pages:           []string{"1"},
+			runCommandError: errors.New("mock run command error"),
+			error:           &RunTesseractError{},
+		},
+		{
+			description: "successful invocation by content type",
+			file:        pars.File{Bucket: "uploads", Key: "scan", ContentType: "application/pdf"},
+			pages:       []string{"10", "02", "01"},
+			pageList:    []string{"page-01.tif", "page-02.tif", "page-10.tif"},
+			error:       nil,
+		},
+		{
+			description: "successful invocation by extension",
+			file:        pars.File{Bucket: "uploads", Key: "scans/2021/scan.PDF"},
+			pages:       []string{"1", "2"},
+			pageList:    []string{"page-1.tif", "page-2.tif"},
+			error:       nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var receivedPDF string
+			var receivedList []string
+			var receivedArgs []string
+
+			client := &Client{
+				command:    "tesseract",
+				pdfCommand: "pdftoppm",
+				language:   "eng",
+				runCommand: func(ctx context.Context, input io.Reader, name string, args ...string) ([]byte, error) {
+					if name == "pdftoppm" {
+						if test.rasterizeError != nil {
+							return nil, test.rasterizeError
+						}
+
+						data, _ := os.ReadFile(args[len(args)-2])
+						receivedPDF = string(data)
+
+						for _, page := range test.pages {
+							os.WriteFile(args[len(args)-1]+"-"+page+".tif", []byte("image"), 0644)
+						}
+
+						return nil, nil
+					}
+
+					data, _ := os.ReadFile(args[0])
+					for _, path := range strings.Fields(string(data)) {
+						receivedList = append(receivedList, filepath.Base(path))
+					}
+					receivedArgs = args[1:]
+
+					return []byte(testTSV), test.runCommandError
+				},
+			}
+
+			document, err := client.ParseReader(context.Background(), strings.NewReader("pdf"), test.file)
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *RasterizePDFError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *RunTesseractError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else {
+				if receivedPDF != "pdf" {
+					t.Errorf("incorrect rasterized pdf, received: %s, expected: pdf", receivedPDF)
+				}
+
+				if !reflect.DeepEqual(receivedList, test.pageList) {
+					t.Errorf("incorrect page list, received: %v, expected: %v", receivedList, test.pageList)
+				}
+
+				expectedArgs := []string{"stdout", "-l", "eng", "tsv"}
+				if !reflect.DeepEqual(receivedArgs, expectedArgs) {
+					t.Errorf("incorrect args, received: %v, expected: %v", receivedArgs, expectedArgs)
+				}
+
+				if len(document.Pages) != 2 {
+					t.Errorf("incorrect pages, received: %+v", document.Pages)
+				}
+			}
+		})
+	}
+}
+
+func Test_runCommand(t *testing.T) {
+	output, err := runCommand(context.Background(), strings.NewReader("input"), "cat")
+	if err != nil {
+		t.Fatalf("error running command: %v", err)
+	}
+
+	if string(output) != "input" {
+		t.Errorf("incorrect output, received: %s, expected: input", output)
+	}
+
+	if _, err := runCommand(context.Background(), strings.NewReader(""), "sh", "-c", "echo failed >&2; exit 1"); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("incorrect error, received: %v, expected stderr in error", err)
+	}
+}
+
+func Test_convertToDocument(t *testing.T) {
+	pages, err := parseTSV([]byte(testTSV))
+	if err != nil {
+		t.Fatalf("error parsing tsv: %v", err)
+	}
+
+	document := convertToDocument(pages, "test.tiff", "bucket", "version")
+
+	if len(document.Pages) != 2 {
+		t.Fatalf("incorrect pages, received: %+v", document.Pages)
+	}
+
+	if document.Pages[0].PageNumber != 1 || document.Pages[1].PageNumber != 2 {
+		t.Errorf("incorrect page numbers, received: %d, %d", document.Pages[0].PageNumber, document.Pages[1].PageNumber)
+	}
+
+	received := document.Pages[0].Lines[0].Coordinates
+	expected := pars.Coordinates{
+		TopLeft:     pars.Point{X: 0.1, Y: 0.1},
+		TopRight:    pars.Point{X: 0.5, Y: 0.1},
+		BottomLeft:  pars.Point{X: 0.1, Y: 0.125},
+		BottomRight: pars.Point{X: 0.5, Y: 0.125},
+	}
+
+	for _, points := range [][2]pars.Point{
+		{received.TopLeft, expected.TopLeft},
+		{received.TopRight, expected.TopRight},
+		{received.BottomLeft, expected.BottomLeft},
+		{received.BottomRight, expected.BottomRight},
+	} {
+		if math.Abs(points[0].X-points[1].X) > 1e-9 || math.Abs(points[0].Y-points[1].Y) > 1e-9 {
+			t.Errorf("incorrect coordinates, received: %+v, expected: %+v", received, expected)
+		}
+	}
+
+	if received.ID == "" || received.Entity != "coordinates" {
+		t.Errorf("incorrect coordinates identity, received: %+v", received)
+	}
+
+	line := document.Pages[0].Lines[0]
+	if math.Abs(line.Confidence-93.85) > 1e-9 {
+		t.Errorf("incorrect line confidence, received: %f, expected: 93.85", line.Confidence)
+	}
+
+	if len(line.Words) != 2 || line.Words[1].Text != "#1001" || line.Words[1].Confidence != 91.2 {
+		t.Fatalf("incorrect words, received: %+v", line.Words)
+	}
+
+	word := line.Words[1].Coordinates
+	if math.Abs(word.TopLeft.X-0.3) > 1e-9 || math.Abs(word.BottomRight.X-0.5) > 1e-9 || math.Abs(word.BottomRight.Y-0.125) > 1e-9 {
+		t.Errorf("incorrect word coordinates, received: %+v", word)
+	}
+}
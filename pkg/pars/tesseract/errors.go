@@ -0,0 +1,45 @@
+package tesseract
+
+import "fmt"
+
+const errorMessage = "package tesseract: %s"
+
+// GetObjectError wraps errors returned by s3.S3.GetObject in the
+// pars.Parser.Parse method.
+type GetObjectError struct {
+	err error
+}
+
+func (e *GetObjectError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// RunTesseractError wraps errors returned by running the Tesseract
+// command in the pars.Parser.Parse method.
+type RunTesseractError struct {
+	err error
+}
+
+func (e *RunTesseractError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// RasterizePDFError wraps errors returned when writing a PDF to disk
+// and rasterizing its pages in the pars.Parser.ParseReader method.
+type RasterizePDFError struct {
+	err error
+}
+
+func (e *RasterizePDFError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// ParseOutputError wraps errors returned when reading the Tesseract
+// TSV output in the pars.Parser.Parse method.
+type ParseOutputError struct {
+	err error
+}
+
+func (e *ParseOutputError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
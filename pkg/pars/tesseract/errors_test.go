@@ -0,0 +1,52 @@
+package tesseract
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetObjectError(t *testing.T) {
+	err := &GetObjectError{err: errors.New("mock get object error")}
+
+	recieved := err.Error()
+	expected := "package tesseract: mock get object error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestRunTesseractError(t *testing.T) {
+	err := &RunTesseractError{err: errors.New("mock run tesseract error")}
+
+	recieved := err.Error()
+	expected := "package tesseract: mock run tesseract error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestParseOutputError(t *testing.T) {
+	err := &ParseOutputError{err: errors.New("mock parse output error")}
+
+	recieved := err.Error()
+	expected := "package tesseract: mock parse output error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestRasterizePDFError(t *testing.T) {
+	err := &RasterizePDFError{
+		err: errors.New("mock rasterize pdf error"),
+	}
+
+	recieved := err.Error()
+	expected := "package tesseract: mock rasterize pdf error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
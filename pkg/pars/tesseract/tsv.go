@@ -0,0 +1,175 @@
+package tesseract
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Tesseract TSV levels used to build the parsed pages and lines.
+const (
+	levelPage = 1
+	levelLine = 4
+	levelWord = 5
+)
+
+// tsvColumns is the number of columns in a Tesseract TSV row; the
+// trailing text column may be dropped when it is empty.
+const tsvColumns = 12
+
+// box holds the pixel bounding box of a Tesseract TSV row.
+type box struct {
+	left   float64
+	top    float64
+	width  float64
+	height float64
+}
+
+// word holds the text, bounding box, and confidence of a recognized
+// word.
+type word struct {
+	text string
+	box  box
+	conf float64
+}
+
+// line holds the words and bounding box of a recognized line.
+type line struct {
+	box   box
+	words []word
+}
+
+// page holds the size and recognized lines of a page.
+type page struct {
+	number int64
+	box    box
+	lines  []*line
+}
+
+// row holds the values of a Tesseract TSV row.
+type row struct {
+	level   int
+	page    int64
+	box     box
+	conf    float64
+	text    string
+	lineKey string
+}
+
+// parseTSV reads the pages and lines recognized by Tesseract from its
+// TSV output. Line text is the recognized words joined by spaces and
+// lines without any words are dropped.
+func parseTSV(data []byte) ([]page, error) {
+	pages := []page{}
+	lines := map[string]*line{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	header := true
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if header {
+			header = false
+			if strings.HasPrefix(text, "level\t") {
+				continue
+			}
+		}
+
+		if text == "" {
+			continue
+		}
+
+		tsvRow, err := parseRow(text)
+		if err != nil {
+			return nil, err
+		}
+
+		switch tsvRow.level {
+		case levelPage:
+			pages = append(pages, page{
+				number: tsvRow.page,
+				box:    tsvRow.box,
+			})
+
+		case levelLine:
+			if len(pages) == 0 {
+				return nil, fmt.Errorf("line %q before page", text)
+			}
+
+			current := &line{
+				box: tsvRow.box,
+			}
+			lines[tsvRow.lineKey] = current
+			pages[len(pages)-1].lines = append(pages[len(pages)-1].lines, current)
+
+		case levelWord:
+			current, ok := lines[tsvRow.lineKey]
+			if !ok {
+				return nil, fmt.Errorf("word %q before line", text)
+			}
+
+			if text := strings.TrimSpace(tsvRow.text); text != "" && tsvRow.conf >= 0 {
+				current.words = append(current.words, word{
+					text: text,
+					box:  tsvRow.box,
+					conf: tsvRow.conf,
+				})
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range pages {
+		recognized := []*line{}
+		for _, current := range pages[i].lines {
+			if len(current.words) > 0 {
+				recognized = append(recognized, current)
+			}
+		}
+		pages[i].lines = recognized
+	}
+
+	return pages, nil
+}
+
+func parseRow(text string) (row, error) {
+	columns := strings.Split(text, "\t")
+	if len(columns) < tsvColumns-1 || len(columns) > tsvColumns {
+		return row{}, fmt.Errorf("row %q has %d columns, expected %d", text, len(columns), tsvColumns)
+	}
+
+	values := make([]float64, tsvColumns-1)
+	for i := range values {
+		value, err := strconv.ParseFloat(columns[i], 64)
+		if err != nil {
+			return row{}, fmt.Errorf("row %q column %d: %w", text, i+1, err)
+		}
+		values[i] = value
+	}
+
+	output := row{
+		level: int(values[0]),
+		page:  int64(values[1]),
+		box: box{
+			left:   values[6],
+			top:    values[7],
+			width:  values[8],
+			height: values[9],
+		},
+		conf: values[10],
+	}
+	// the page, block, paragraph, and line numbers identify a line
+	output.lineKey = strings.Join(columns[1:5], "/")
+
+	if len(columns) == tsvColumns {
+		output.text = columns[11]
+	}
+
+	return output, nil
+}
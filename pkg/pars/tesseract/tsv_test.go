@@ -0,0 +1,142 @@
+package tesseract
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testTSV = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+	"1\t1\t0\t0\t0\t0\t0\t0\t1000\t2000\t-1\t\n" +
+	"2\t1\t1\t0\t0\t0\t100\t200\t500\t100\t-1\t\n" +
+	"3\t1\t1\t1\t0\t0\t100\t200\t500\t100\t-1\t\n" +
+	"4\t1\t1\t1\t1\t0\t100\t200\t400\t50\t-1\t\n" +
+	"5\t1\t1\t1\t1\t1\t100\t200\t150\t50\t96.5\tInvoice\n" +
+	"5\t1\t1\t1\t1\t2\t300\t200\t200\t50\t91.2\t#1001\n" +
+	"4\t1\t1\t1\t2\t0\t100\t250\t400\t50\t-1\t\n" +
+	"5\t1\t1\t1\t2\t1\t100\t250\t400\t50\t95.0\t \n" +
+	"1\t2\t0\t0\t0\t0\t0\t0\t1000\t2000\t-1\t\n" +
+	"4\t2\t1\t1\t1\t0\t0\t1000\t1000\t200\t-1\t\n" +
+	"5\t2\t1\t1\t1\t1\t0\t1000\t1000\t200\t88\tTotal\n"
+
+func Test_parseTSV(t *testing.T) {
+	tests := []struct {
+		description string
+		data        string
+		pages       []page
+		error       bool
+	}{
+		{
+			description: "invalid row columns",
+			data:        "1\t1\t0\n",
+			pages:       nil,
+			error:       true,
+		},
+		{
+			description: "invalid row value",
+			data:        "1\tone\t0\t0\t0\t0\t0\t0\t1000\t2000\t-1\t\n",
+			pages:       nil,
+			error:       true,
+		},
+		{
+			description: "line before page",
+			data:        "4\t1\t1\t1\t1\t0\t100\t200\t400\t50\t-1\t\n",
+			pages:       nil,
+			error:       true,
+		},
+		{
+			description: "empty output",
+			data:        "",
+			pages:       []page{},
+			error:       false,
+		},
+		{
+			description: "pages and lines returned",
+			data:        testTSV,
+			pages: []page{
+				{
+					number: 1,
+					box: box{
+						width:  1000,
+						height: 2000,
+					},
+					lines: []*line{
+						{
+							box: box{
+								left:   100,
+								top:    200,
+								width:  400,
+								height: 50,
+							},
+							words: []word{
+								{
+									text: "Invoice",
+									box: box{
+										left:   100,
+										top:    200,
+										width:  150,
+										height: 50,
+									},
+									conf: 96.5,
+								},
+								{
+									text: "#1001",
+									box: box{
+										left:   300,
+										top:    200,
+										width:  200,
+										height: 50,
+									},
+									conf: 91.2,
+								},
+							},
+						},
+					},
+				},
+				{
+					number: 2,
+					box: box{
+						width:  1000,
+						height: 2000,
+					},
+					lines: []*line{
+						{
+							box: box{
+								left:   0,
+								top:    1000,
+								width:  1000,
+								height: 200,
+							},
+							words: []word{
+								{
+									text: "Total",
+									box: box{
+										left:   0,
+										top:    1000,
+										width:  1000,
+										height: 200,
+									},
+									conf: 88,
+								},
+							},
+						},
+					},
+				},
+			},
+			error: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			pages, err := parseTSV([]byte(test.data))
+
+			if (err != nil) != test.error {
+				t.Fatalf("incorrect error, received: %v, expected error: %t", err, test.error)
+			}
+
+			if !reflect.DeepEqual(pages, test.pages) {
+				t.Errorf("incorrect pages, received: %+v, expected: %+v", pages, test.pages)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// receiveWait is the long polling duration of ReceiveMessages.
+const receiveWait = 20
+
+// maxBatchMessages and maxBatchSize are the most messages and the largest
+// total body size in bytes SQS accepts in a single batch send.
+const (
+	maxBatchMessages = 10
+	maxBatchSize     = 256 * 1024
+)
+
+var _ Queuer = &Client{}
+
+// Client implements the queue.Queuer methods using AWS SQS.
+type Client struct {
+	sqsClient sqsClient
+}
+
+type sqsClient interface {
+	SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error)
+	SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, opts ...request.Option) (*sqs.SendMessageBatchOutput, error)
+	ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error)
+	ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, opts ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error)
+	DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error)
+}
+
+// New generates a queue.Client pointer instance with AWS SQS. A non-empty
+// endpoint replaces the SQS endpoint, e.g. to use a local SQS stand-in
+// such as ElasticMQ.
+func New(newSession *session.Session, endpoint string) *Client {
+	config := aws.NewConfig()
+	if endpoint != "" {
+		config = config.WithEndpoint(endpoint)
+	}
+
+	return &Client{
+		sqsClient: sqs.New(newSession, config),
+	}
+}
+
+// SendMessage implements the queue.Queuer.SendMessage method using SQS.
+func (c *Client) SendMessage(ctx context.Context, queueURL, body string, attributes map[string]string) error {
+	messageAttributes := map[string]*sqs.MessageAttributeValue{}
+	for name, value := range attributes {
+		messageAttributes[name] = &sqs.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+
+	if len(messageAttributes) == 0 {
+		messageAttributes = nil
+	}
+
+	_, err := c.sqsClient.SendMessageWithContext(ctx, &sqs.SendMessageInput{
+		QueueUrl:          &queueURL,
+		MessageBody:       &body,
+		MessageAttributes: messageAttributes,
+	})
+	if err != nil {
+		return &SendMessageError{
+			err: err,
+		}
+	}
+
+	return nil
+}
+
+// SendMessages implements the queue.Queuer.SendMessages method using SQS.
+// The bodies are sent in batches of up to 10 messages and 256 KiB, and
+// the messages SQS fails to send in a batch are returned as an error.
+func (c *Client) SendMessages(ctx context.Context, queueURL string, bodies []string) error {
+	entries := []*sqs.SendMessageBatchRequestEntry{}
+	size := 0
+	for i := range bodies {
+		if len(entries) == maxBatchMessages || (len(entries) > 0 && size+len(bodies[i]) > maxBatchSize) {
+			if err := c.sendBatch(ctx, queueURL, entries); err != nil {
+				return err
+			}
+
+			entries = []*sqs.SendMessageBatchRequestEntry{}
+			size = 0
+		}
+
+		entries = append(entries, &sqs.SendMessageBatchRequestEntry{
+			Id:          aws.String(strconv.Itoa(i)),
+			MessageBody: &bodies[i],
+		})
+		size += len(bodies[i])
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return c.sendBatch(ctx, queueURL, entries)
+}
+
+func (c *Client) sendBatch(ctx context.Context, queueURL string, entries []*sqs.SendMessageBatchRequestEntry) error {
+	output, err := c.sqsClient.SendMessageBatchWithContext(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: &queueURL,
+		Entries:  entries,
+	})
+	if err != nil {
+		return &SendMessagesError{
+			err: err,
+		}
+	}
+
+	if len(output.Failed) > 0 {
+		return &SendMessagesError{
+			err: fmt.Errorf("%d of %d messages not sent: %s", len(output.Failed), len(entries), aws.StringValue(output.Failed[0].Message)),
+		}
+	}
+
+	return nil
+}
+
+// ReceiveMessages implements the queue.Queuer.ReceiveMessages method
+// using SQS. It waits up to 20 seconds for messages to arrive.
+func (c *Client) ReceiveMessages(ctx context.Context, queueURL string, maxMessages int) ([]Message, error) {
+	output, err := c.sqsClient.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:              &queueURL,
+		MaxNumberOfMessages:   aws.Int64(int64(maxMessages)),
+		WaitTimeSeconds:       aws.Int64(receiveWait),
+		AttributeNames:        aws.StringSlice([]string{sqs.MessageSystemAttributeNameApproximateReceiveCount}),
+		MessageAttributeNames: aws.StringSlice([]string{"All"}),
+	})
+	if err != nil {
+		return nil, &ReceiveMessagesError{
+			err: err,
+		}
+	}
+
+	messages := []Message{}
+	for _, message := range output.Messages {
+		receiveCount, _ := strconv.Atoi(aws.StringValue(message.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]))
+
+		attributes := map[string]string{}
+		for name, value := range message.MessageAttributes {
+			if value.StringValue != nil {
+				attributes[name] = *value.StringValue
+			}
+		}
+
+		messages = append(messages, Message{
+			ID:            aws.StringValue(message.MessageId),
+			ReceiptHandle: aws.StringValue(message.ReceiptHandle),
+			Body:          aws.StringValue(message.Body),
+			ReceiveCount:  receiveCount,
+			Attributes:    attributes,
+		})
+	}
+
+	return messages, nil
+}
+
+// ChangeMessageVisibility implements the
+// queue.Queuer.ChangeMessageVisibility method using SQS. The timeout is
+// rounded down to whole seconds.
+func (c *Client) ChangeMessageVisibility(ctx context.Context, queueURL, receiptHandle string, timeout time.Duration) error {
+	_, err := c.sqsClient.ChangeMessageVisibilityWithContext(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          &queueURL,
+		ReceiptHandle:     &receiptHandle,
+		VisibilityTimeout: aws.Int64(int64(timeout / time.Second)),
+	})
+	if err != nil {
+		return &ChangeMessageVisibilityError{
+			err: err,
+		}
+	}
+
+	return nil
+}
+
+// DeleteMessage implements the queue.Queuer.DeleteMessage method using
+// SQS.
+func (c *Client) DeleteMessage(ctx context.Context, queueURL, receiptHandle string) error {
+	_, err := c.sqsClient.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      &queueURL,
+		ReceiptHandle: &receiptHandle,
+	})
+	if err != nil {
+		return &DeleteMessageError{
+			err: err,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,384 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+type mockSQSClient struct {
+	mockSendMessageInput             *sqs.SendMessageInput
+	mockSendMessageError             error
+	mockSendMessageBatchInputs       []*sqs.SendMessageBatchInput
+	mockSendMessageBatchOutput       *sqs.SendMessageBatchOutput
+	mockSendMessageBatchError        error
+	mockReceiveMessageOutput         *sqs.ReceiveMessageOutput
+	mockReceiveMessageError          error
+	mockChangeMessageVisibilityInput *sqs.ChangeMessageVisibilityInput
+	mockChangeMessageVisibilityError error
+	mockDeleteMessageError           error
+}
+
+func (m *mockSQSClient) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error) {
+	m.mockSendMessageInput = input
+	return nil, m.mockSendMessageError
+}
+
+func (m *mockSQSClient) SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, opts ...request.Option) (*sqs.SendMessageBatchOutput, error) {
+	m.mockSendMessageBatchInputs = append(m.mockSendMessageBatchInputs, input)
+	return m.mockSendMessageBatchOutput, m.mockSendMessageBatchError
+}
+
+func (m *mockSQSClient) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	return m.mockReceiveMessageOutput, m.mockReceiveMessageError
+}
+
+func (m *mockSQSClient) ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, opts ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	m.mockChangeMessageVisibilityInput = input
+	return nil, m.mockChangeMessageVisibilityError
+}
+
+func (m *mockSQSClient) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	return nil, m.mockDeleteMessageError
+}
+
+func TestSendMessage(t *testing.T) {
+	tests := []struct {
+		description          string
+		attributes           map[string]string
+		mockSendMessageError error
+		messageAttributes    map[string]*sqs.MessageAttributeValue
+		error                error
+	}{
+		{
+			description:          "error sending message",
+			attributes:           nil,
+			mockSendMessageError: errors.New("mock send message error"),
+			messageAttributes:    nil,
+			error:                &SendMessageError{},
+		},
+		{
+			description:          "successful invocation without attributes",
+			attributes:           nil,
+			mockSendMessageError: nil,
+			messageAttributes:    nil,
+			error:                nil,
+		},
+		{
+			description: "successful invocation with attributes",
+			attributes: map[string]string{
+				"error": "mock error",
+			},
+			mockSendMessageError: nil,
+			messageAttributes: map[string]*sqs.MessageAttributeValue{
+				"error": {
+					DataType:    aws.String("String"),
+					StringValue: aws.String("mock error"),
+				},
+			},
+			error: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			m := &mockSQSClient{
+				mockSendMessageError: test.mockSendMessageError,
+			}
+
+			c := &Client{
+				sqsClient: m,
+			}
+
+			err := c.SendMessage(context.Background(), "queue", "body", test.attributes)
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *SendMessageError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else if !reflect.DeepEqual(m.mockSendMessageInput.MessageAttributes, test.messageAttributes) {
+				t.Errorf("incorrect attributes, received: %v, expected: %v", m.mockSendMessageInput.MessageAttributes, test.messageAttributes)
+			}
+		})
+	}
+}
+
+func TestSendMessages(t *testing.T) {
+	largeBody := strings.Repeat("a", 100*1024)
+
+	tests := []struct {
+		description                string
+		bodies                     []string
+		mockSendMessageBatchOutput *sqs.SendMessageBatchOutput
+		mockSendMessageBatchError  error
+		batchSizes                 []int
+		error                      error
+	}{
+		{
+			description:                "no bodies",
+			bodies:                     []string{},
+			mockSendMessageBatchOutput: nil,
+			mockSendMessageBatchError:  nil,
+			batchSizes:                 []int{},
+			error:                      nil,
+		},
+		{
+			description:                "send message batch error",
+			bodies:                     []string{"body"},
+			mockSendMessageBatchOutput: nil,
+			mockSendMessageBatchError:  errors.New("mock send message batch error"),
+			batchSizes:                 []int{1},
+			error:                      &SendMessagesError{},
+		},
+		{
+			description: "failed messages in batch",
+			bodies:      []string{"first", "second"},
+			mockSendMessageBatchOutput: &sqs.SendMessageBatchOutput{
+				Failed: []*sqs.BatchResultErrorEntry{
+					{
+						Id:      aws.String("1"),
+						Message: aws.String("mock failed message"),
+					},
+				},
+			},
+			mockSendMessageBatchError: nil,
+			batchSizes:                []int{2},
+			error:                     &SendMessagesError{},
+		},
+		{
+			description:                "batches split by message count",
+			bodies:                     make([]string, 25),
+			mockSendMessageBatchOutput: &sqs.SendMessageBatchOutput{},
+			mockSendMessageBatchError:  nil,
+			batchSizes:                 []int{10, 10, 5},
+			error:                      nil,
+		},
+		{
+			description:                "batches split by body size",
+			bodies:                     []string{largeBody, largeBody, largeBody, "body"},
+			mockSendMessageBatchOutput: &sqs.SendMessageBatchOutput{},
+			mockSendMessageBatchError:  nil,
+			batchSizes:                 []int{2, 2},
+			error:                      nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			m := &mockSQSClient{
+				mockSendMessageBatchOutput: test.mockSendMessageBatchOutput,
+				mockSendMessageBatchError:  test.mockSendMessageBatchError,
+			}
+
+			c := &Client{
+				sqsClient: m,
+			}
+
+			err := c.SendMessages(context.Background(), "queue", test.bodies)
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *SendMessagesError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else if test.error != nil {
+				t.Fatalf("incorrect error, received: nil, expected: %v", test.error)
+			}
+
+			batchSizes := []int{}
+			for _, input := range m.mockSendMessageBatchInputs {
+				batchSizes = append(batchSizes, len(input.Entries))
+			}
+
+			if !reflect.DeepEqual(batchSizes, test.batchSizes) {
+				t.Errorf("incorrect batch sizes, received: %v, expected: %v", batchSizes, test.batchSizes)
+			}
+		})
+	}
+}
+
+func TestReceiveMessages(t *testing.T) {
+	tests := []struct {
+		description              string
+		mockReceiveMessageOutput *sqs.ReceiveMessageOutput
+		mockReceiveMessageError  error
+		messages                 []Message
+		error                    error
+	}{
+		{
+			description:              "error receiving messages",
+			mockReceiveMessageOutput: nil,
+			mockReceiveMessageError:  errors.New("mock receive message error"),
+			messages:                 nil,
+			error:                    &ReceiveMessagesError{},
+		},
+		{
+			description: "successful invocation",
+			mockReceiveMessageOutput: &sqs.ReceiveMessageOutput{
+				Messages: []*sqs.Message{
+					{
+						MessageId:     aws.String("id"),
+						ReceiptHandle: aws.String("handle"),
+						Body:          aws.String("body"),
+						Attributes: map[string]*string{
+							sqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String("2"),
+						},
+						MessageAttributes: map[string]*sqs.MessageAttributeValue{
+							"error": {
+								DataType:    aws.String("String"),
+								StringValue: aws.String("mock error"),
+							},
+						},
+					},
+				},
+			},
+			mockReceiveMessageError: nil,
+			messages: []Message{
+				{
+					ID:            "id",
+					ReceiptHandle: "handle",
+					Body:          "body",
+					ReceiveCount:  2,
+					Attributes: map[string]string{
+						"error": "mock error",
+					},
+				},
+			},
+			error: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			c := &Client{
+				sqsClient: &mockSQSClient{
+					mockReceiveMessageOutput: test.mockReceiveMessageOutput,
+					mockReceiveMessageError:  test.mockReceiveMessageError,
+				},
+			}
+
+			messages, err := c.ReceiveMessages(context.Background(), "queue", 10)
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *ReceiveMessagesError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			}
+
+			if !reflect.DeepEqual(messages, test.messages) {
+				t.Errorf("incorrect messages, received: %+v, expected: %+v", messages, test.messages)
+			}
+		})
+	}
+}
+
+func TestChangeMessageVisibility(t *testing.T) {
+	tests := []struct {
+		description                      string
+		mockChangeMessageVisibilityError error
+		error                            error
+	}{
+		{
+			description:                      "error changing message visibility",
+			mockChangeMessageVisibilityError: errors.New("mock change message visibility error"),
+			error:                            &ChangeMessageVisibilityError{},
+		},
+		{
+			description:                      "successful invocation",
+			mockChangeMessageVisibilityError: nil,
+			error:                            nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			m := &mockSQSClient{
+				mockChangeMessageVisibilityError: test.mockChangeMessageVisibilityError,
+			}
+
+			c := &Client{
+				sqsClient: m,
+			}
+
+			err := c.ChangeMessageVisibility(context.Background(), "queue", "handle", 90*time.Second)
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *ChangeMessageVisibilityError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else if *m.mockChangeMessageVisibilityInput.VisibilityTimeout != 90 {
+				t.Errorf("incorrect visibility timeout, received: %d, expected: %d", *m.mockChangeMessageVisibilityInput.VisibilityTimeout, 90)
+			}
+		})
+	}
+}
+
+func TestDeleteMessage(t *testing.T) {
+	tests := []struct {
+		description            string
+		mockDeleteMessageError error
+		error                  error
+	}{
+		{
+			description:            "error deleting message",
+			mockDeleteMessageError: errors.New("mock delete message error"),
+			error:                  &DeleteMessageError{},
+		},
+		{
+			description:            "successful invocation",
+			mockDeleteMessageError: nil,
+			error:                  nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			c := &Client{
+				sqsClient: &mockSQSClient{
+					mockDeleteMessageError: test.mockDeleteMessageError,
+				},
+			}
+
+			err := c.DeleteMessage(context.Background(), "queue", "handle")
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *DeleteMessageError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else if test.error != nil {
+				t.Errorf("incorrect error, received: nil, expected: %v", test.error)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package queue
+
+import "fmt"
+
+const errorMessage = "package queue: %s"
+
+// SendMessageError wraps errors returned by sqs.SQS.SendMessageWithContext
+// in the SendMessage method.
+type SendMessageError struct {
+	err error
+}
+
+func (e *SendMessageError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// SendMessagesError wraps errors returned by
+// sqs.SQS.SendMessageBatchWithContext and the messages it fails to send
+// in the SendMessages method.
+type SendMessagesError struct {
+	err error
+}
+
+func (e *SendMessagesError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// ReceiveMessagesError wraps errors returned by
+// sqs.SQS.ReceiveMessageWithContext in the ReceiveMessages method.
+type ReceiveMessagesError struct {
+	err error
+}
+
+func (e *ReceiveMessagesError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// ChangeMessageVisibilityError wraps errors returned by
+// sqs.SQS.ChangeMessageVisibilityWithContext in the
+// ChangeMessageVisibility method.
+type ChangeMessageVisibilityError struct {
+	err error
+}
+
+func (e *ChangeMessageVisibilityError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// DeleteMessageError wraps errors returned by
+// sqs.SQS.DeleteMessageWithContext in the DeleteMessage method.
+type DeleteMessageError struct {
+	err error
+}
+
+func (e *DeleteMessageError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
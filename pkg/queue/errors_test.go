@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSendMessageError(t *testing.T) {
+	err := &SendMessageError{
+		err: errors.New("mock send message error"),
+	}
+
+	recieved := err.Error()
+	expected := "package queue: mock send message error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestSendMessagesError(t *testing.T) {
+	err := &SendMessagesError{
+		err: errors.New("mock send messages error"),
+	}
+
+	recieved := err.Error()
+	expected := "package queue: mock send messages error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestReceiveMessagesError(t *testing.T) {
+	err := &ReceiveMessagesError{
+		err: errors.New("mock receive messages error"),
+	}
+
+	recieved := err.Error()
+	expected := "package queue: mock receive messages error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestChangeMessageVisibilityError(t *testing.T) {
+	err := &ChangeMessageVisibilityError{
+		err: errors.New("mock change message visibility error"),
+	}
+
+	recieved := err.Error()
+	expected := "package queue: mock change message visibility error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestDeleteMessageError(t *testing.T) {
+	err := &DeleteMessageError{
+		err: errors.New("mock delete message error"),
+	}
+
+	recieved := err.Error()
+	expected := "package queue: mock delete message error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
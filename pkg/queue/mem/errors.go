@@ -0,0 +1,15 @@
+package mem
+
+import "fmt"
+
+const errorMessage = "package mem: %s"
+
+// ReceiptHandleError wraps errors returned when a receipt handle does not
+// match the latest receive of a message in the queue.
+type ReceiptHandleError struct {
+	err error
+}
+
+func (e *ReceiptHandleError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
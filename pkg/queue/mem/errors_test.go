@@ -0,0 +1,19 @@
+package mem
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReceiptHandleError(t *testing.T) {
+	err := &ReceiptHandleError{
+		err: errors.New("mock receipt handle error"),
+	}
+
+	recieved := err.Error()
+	expected := "package mem: mock receipt handle error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
@@ -0,0 +1,195 @@
+// Package mem implements queue.Queuer by holding the messages of each
+// queue in memory with the receive, visibility, and receive count
+// behavior of SQS. It is intended as a local SQS stand-in for tests and
+// local development.
+package mem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/forstmeier/findfile/pkg/queue"
+)
+
+var _ queue.Queuer = &Client{}
+
+// Client implements the queue.Queuer methods in memory. Queues are
+// created when a message is first sent to their URL.
+type Client struct {
+	mutex             sync.Mutex
+	visibilityTimeout time.Duration
+	offset            time.Duration
+	count             int
+	queues            map[string][]*message
+}
+
+type message struct {
+	id            string
+	body          string
+	attributes    map[string]string
+	receiveCount  int
+	receiptHandle string
+	visibleAt     time.Time
+}
+
+// New generates an empty mem.Client pointer instance that hides received
+// messages for the provided visibility timeout.
+func New(visibilityTimeout time.Duration) *Client {
+	return &Client{
+		visibilityTimeout: visibilityTimeout,
+		queues:            map[string][]*message{},
+	}
+}
+
+// Advance moves the clock of the client forward so that messages hidden
+// for up to the duration become visible without waiting.
+func (c *Client) Advance(duration time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.offset += duration
+}
+
+// Messages returns every message in the queue, including hidden
+// messages, without receiving them.
+func (c *Client) Messages(queueURL string) []queue.Message {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	messages := []queue.Message{}
+	for _, queued := range c.queues[queueURL] {
+		messages = append(messages, queued.message())
+	}
+
+	return messages
+}
+
+// SendMessage implements the queue.Queuer.SendMessage method in memory.
+func (c *Client) SendMessage(ctx context.Context, queueURL, body string, attributes map[string]string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.count++
+
+	copied := map[string]string{}
+	for name, value := range attributes {
+		copied[name] = value
+	}
+
+	c.queues[queueURL] = append(c.queues[queueURL], &message{
+		id:         fmt.Sprintf("message-%d", c.count),
+		body:       body,
+		attributes: copied,
+	})
+
+	return nil
+}
+
+// SendMessages implements the queue.Queuer.SendMessages method in
+// memory.
+func (c *Client) SendMessages(ctx context.Context, queueURL string, bodies []string) error {
+	for _, body := range bodies {
+		if err := c.SendMessage(ctx, queueURL, body, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReceiveMessages implements the queue.Queuer.ReceiveMessages method in
+// memory. It returns the visible messages in the order they were sent
+// without waiting for more to arrive.
+func (c *Client) ReceiveMessages(ctx context.Context, queueURL string, maxMessages int) ([]queue.Message, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+
+	messages := []queue.Message{}
+	for _, queued := range c.queues[queueURL] {
+		if len(messages) == maxMessages {
+			break
+		}
+
+		if queued.visibleAt.After(now) {
+			continue
+		}
+
+		queued.receiveCount++
+		queued.receiptHandle = fmt.Sprintf("%s-%d", queued.id, queued.receiveCount)
+		queued.visibleAt = now.Add(c.visibilityTimeout)
+
+		messages = append(messages, queued.message())
+	}
+
+	return messages, nil
+}
+
+// ChangeMessageVisibility implements the
+// queue.Queuer.ChangeMessageVisibility method in memory.
+func (c *Client) ChangeMessageVisibility(ctx context.Context, queueURL, receiptHandle string, timeout time.Duration) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	index, err := c.find(queueURL, receiptHandle)
+	if err != nil {
+		return err
+	}
+
+	c.queues[queueURL][index].visibleAt = c.now().Add(timeout)
+
+	return nil
+}
+
+// DeleteMessage implements the queue.Queuer.DeleteMessage method in
+// memory.
+func (c *Client) DeleteMessage(ctx context.Context, queueURL, receiptHandle string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	index, err := c.find(queueURL, receiptHandle)
+	if err != nil {
+		return err
+	}
+
+	queued := c.queues[queueURL]
+	c.queues[queueURL] = append(queued[:index], queued[index+1:]...)
+
+	return nil
+}
+
+func (c *Client) now() time.Time {
+	return time.Now().Add(c.offset)
+}
+
+// find returns the index of the message last received with the receipt
+// handle.
+func (c *Client) find(queueURL, receiptHandle string) (int, error) {
+	for index, queued := range c.queues[queueURL] {
+		if queued.receiptHandle != "" && queued.receiptHandle == receiptHandle {
+			return index, nil
+		}
+	}
+
+	return 0, &ReceiptHandleError{
+		err: fmt.Errorf("receipt handle %q not found in queue %q", receiptHandle, queueURL),
+	}
+}
+
+func (m *message) message() queue.Message {
+	attributes := map[string]string{}
+	for name, value := range m.attributes {
+		attributes[name] = value
+	}
+
+	return queue.Message{
+		ID:            m.id,
+		ReceiptHandle: m.receiptHandle,
+		Body:          m.body,
+		ReceiveCount:  m.receiveCount,
+		Attributes:    attributes,
+	}
+}
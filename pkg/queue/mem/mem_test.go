@@ -0,0 +1,93 @@
+package mem
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	c := New(30 * time.Second)
+
+	if err := c.SendMessage(ctx, "queue", "first", map[string]string{"name": "value"}); err != nil {
+		t.Fatalf("error sending message: %v", err)
+	}
+
+	if err := c.SendMessage(ctx, "queue", "second", nil); err != nil {
+		t.Fatalf("error sending message: %v", err)
+	}
+
+	messages, err := c.ReceiveMessages(ctx, "queue", 1)
+	if err != nil {
+		t.Fatalf("error receiving messages: %v", err)
+	}
+
+	if len(messages) != 1 || messages[0].Body != "first" || messages[0].ReceiveCount != 1 || messages[0].Attributes["name"] != "value" {
+		t.Fatalf("incorrect messages, received: %+v", messages)
+	}
+	first := messages[0]
+
+	messages, err = c.ReceiveMessages(ctx, "queue", 10)
+	if err != nil {
+		t.Fatalf("error receiving messages: %v", err)
+	}
+
+	if len(messages) != 1 || messages[0].Body != "second" {
+		t.Fatalf("incorrect messages after first hidden, received: %+v", messages)
+	}
+	second := messages[0]
+
+	if err := c.DeleteMessage(ctx, "queue", second.ReceiptHandle); err != nil {
+		t.Fatalf("error deleting message: %v", err)
+	}
+
+	if err := c.ChangeMessageVisibility(ctx, "queue", first.ReceiptHandle, time.Minute); err != nil {
+		t.Fatalf("error changing message visibility: %v", err)
+	}
+
+	c.Advance(30 * time.Second)
+
+	messages, err = c.ReceiveMessages(ctx, "queue", 10)
+	if err != nil {
+		t.Fatalf("error receiving messages: %v", err)
+	}
+
+	if len(messages) != 0 {
+		t.Fatalf("incorrect messages while visibility extended, received: %+v", messages)
+	}
+
+	c.Advance(30 * time.Second)
+
+	messages, err = c.ReceiveMessages(ctx, "queue", 10)
+	if err != nil {
+		t.Fatalf("error receiving messages: %v", err)
+	}
+
+	if len(messages) != 1 || messages[0].Body != "first" || messages[0].ReceiveCount != 2 {
+		t.Fatalf("incorrect messages after visibility timeout, received: %+v", messages)
+	}
+
+	err = c.DeleteMessage(ctx, "queue", first.ReceiptHandle)
+	var receiptHandleError *ReceiptHandleError
+	if !errors.As(err, &receiptHandleError) {
+		t.Errorf("incorrect error for stale receipt handle, received: %v, expected: %v", err, receiptHandleError)
+	}
+
+	if err := c.DeleteMessage(ctx, "queue", messages[0].ReceiptHandle); err != nil {
+		t.Fatalf("error deleting message: %v", err)
+	}
+
+	if remaining := c.Messages("queue"); len(remaining) != 0 {
+		t.Errorf("incorrect remaining messages, received: %+v", remaining)
+	}
+
+	if err := c.SendMessages(ctx, "queue", []string{"third", "fourth"}); err != nil {
+		t.Fatalf("error sending messages: %v", err)
+	}
+
+	if sent := c.Messages("queue"); len(sent) != 2 || sent[0].Body != "third" || sent[1].Body != "fourth" {
+		t.Errorf("incorrect sent messages, received: %+v", sent)
+	}
+}
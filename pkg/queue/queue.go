@@ -0,0 +1,28 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// Queuer defines the methods for sending and consuming the messages in
+// a queue.
+type Queuer interface {
+	SendMessage(ctx context.Context, queueURL, body string, attributes map[string]string) error
+	SendMessages(ctx context.Context, queueURL string, bodies []string) error
+	ReceiveMessages(ctx context.Context, queueURL string, maxMessages int) ([]Message, error)
+	ChangeMessageVisibility(ctx context.Context, queueURL, receiptHandle string, timeout time.Duration) error
+	DeleteMessage(ctx context.Context, queueURL, receiptHandle string) error
+}
+
+// Message holds a message received from a queue. ReceiveCount is the
+// number of times the message has been received, including the current
+// receive, and Attributes holds the string message attributes it was
+// sent with.
+type Message struct {
+	ID            string
+	ReceiptHandle string
+	Body          string
+	ReceiveCount  int
+	Attributes    map[string]string
+}
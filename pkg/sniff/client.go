@@ -0,0 +1,81 @@
+package sniff
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+var _ Sniffer = &Client{}
+
+// Client implements the sniff.Sniffer methods using AWS S3.
+type Client struct {
+	s3Client s3Client
+	types    Types
+}
+
+type s3Client interface {
+	HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error)
+	GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error)
+}
+
+// New generates a Client pointer instance with an AWS S3 client that
+// supports the provided content types, or DefaultContentTypes if none
+// are provided.
+func New(newSession *session.Session, contentTypes []string) *Client {
+	return &Client{
+		s3Client: s3.New(newSession),
+		types:    NewTypes(contentTypes),
+	}
+}
+
+// Sniff implements the sniff.Sniffer.Sniff interface method using AWS
+// S3. The object metadata is read with HeadObject and only the leading
+// bytes of non-empty objects are downloaded to detect their type.
+func (c *Client) Sniff(ctx context.Context, bucket, key string) (*File, error) {
+	headOutput, err := c.s3Client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, &HeadObjectError{err: err}
+	}
+
+	metadata := pars.NewFile(bucket, key, headOutput)
+
+	if aws.Int64Value(headOutput.ContentLength) == 0 {
+		return &File{
+			Reason:   "empty file",
+			Metadata: metadata,
+		}, nil
+	}
+
+	getOutput, err := c.s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", sniffLength-1)),
+	})
+	if err != nil {
+		return nil, &GetObjectError{err: err}
+	}
+	defer getOutput.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(getOutput.Body, sniffLength))
+	if err != nil {
+		return nil, &GetObjectError{err: err}
+	}
+
+	file := c.types.File(DetectContentType(data, aws.StringValue(headOutput.ContentType), key))
+	if file.ContentType != "" {
+		metadata.ContentType = file.ContentType
+	}
+	file.Metadata = metadata
+
+	return file, nil
+}
@@ -0,0 +1,208 @@
+package sniff
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+func TestNew(t *testing.T) {
+	client := New(session.New(), nil)
+	if client == nil {
+		t.Error("error creating sniff client")
+	}
+
+	if len(client.types) != len(DefaultContentTypes) {
+		t.Errorf("incorrect content types, received: %v, expected: %v", client.types, DefaultContentTypes)
+	}
+}
+
+type mockS3Client struct {
+	mockHeadObjectOutput *s3.HeadObjectOutput
+	mockHeadObjectError  error
+	mockGetObjectInput   *s3.GetObjectInput
+	mockGetObjectOutput  *s3.GetObjectOutput
+	mockGetObjectError   error
+}
+
+func (m *mockS3Client) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	return m.mockHeadObjectOutput, m.mockHeadObjectError
+}
+
+func (m *mockS3Client) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	m.mockGetObjectInput = input
+	return m.mockGetObjectOutput, m.mockGetObjectError
+}
+
+func TestSniff(t *testing.T) {
+	lastModified := time.Date(2021, time.November, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		description         string
+		key                 string
+		contentTypes        []string
+		contentLength       int64
+		contentType         string
+		mockHeadObjectError error
+		mockGetObjectError  error
+		data                string
+		file                *File
+		error               error
+	}{
+		{
+			description:         "s3 client head object error",
+			key:                 "file.pdf",
+			mockHeadObjectError: errors.New("mock head object error"),
+			file:                nil,
+			error:               &HeadObjectError{},
+		},
+		{
+			description:        "s3 client get object error",
+			key:                "file.pdf",
+			contentLength:      10,
+			mockGetObjectError: errors.New("mock get object error"),
+			file:               nil,
+			error:              &GetObjectError{},
+		},
+		{
+			description:   "empty file",
+			key:           "folder/",
+			contentLength: 0,
+			file: &File{
+				Reason: "empty file",
+			},
+			error: nil,
+		},
+		{
+			description:   "supported file with uppercase extension",
+			key:           "scan.JPG",
+			contentLength: 10,
+			data:          "\xff\xd8\xff\xe0",
+			file: &File{
+				ContentType: "image/jpeg",
+				Supported:   true,
+			},
+			error: nil,
+		},
+		{
+			description:   "pdf suffix without pdf content",
+			key:           "notapdf",
+			contentLength: 10,
+			contentType:   "text/plain",
+			data:          "plain text",
+			file: &File{
+				ContentType: "text/plain",
+				Reason:      "unsupported content type text/plain",
+			},
+			error: nil,
+		},
+		{
+			description:   "unknown content type",
+			key:           "file.bin",
+			contentLength: 10,
+			data:          "\x00\x01\x02",
+			file: &File{
+				Reason: "unknown content type",
+			},
+			error: nil,
+		},
+		{
+			description:   "configured content type",
+			key:           "image.gif",
+			contentTypes:  []string{"image/gif"},
+			contentLength: 10,
+			data:          "GIF89a",
+			file: &File{
+				ContentType: "image/gif",
+				Supported:   true,
+			},
+			error: nil,
+		},
+		{
+			description:   "default content type not configured",
+			key:           "file.pdf",
+			contentTypes:  []string{"image/gif"},
+			contentLength: 10,
+			data:          "%PDF-1.7",
+			file: &File{
+				ContentType: "application/pdf",
+				Reason:      "unsupported content type application/pdf",
+			},
+			error: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			s3Client := &mockS3Client{
+				mockHeadObjectOutput: &s3.HeadObjectOutput{
+					ContentLength: aws.Int64(test.contentLength),
+					ContentType:   aws.String(test.contentType),
+					ETag:          aws.String(`"etag"`),
+					LastModified:  aws.Time(lastModified),
+				},
+				mockHeadObjectError: test.mockHeadObjectError,
+				mockGetObjectOutput: &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewReader([]byte(test.data))),
+				},
+				mockGetObjectError: test.mockGetObjectError,
+			}
+
+			client := &Client{
+				s3Client: s3Client,
+				types:    NewTypes(test.contentTypes),
+			}
+
+			file, err := client.Sniff(context.Background(), "bucket", test.key)
+
+			if err != nil {
+				switch e := test.error.(type) {
+				case *HeadObjectError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				case *GetObjectError:
+					if !errors.As(err, &e) {
+						t.Errorf("incorrect error, received: %v, expected: %v", err, e)
+					}
+				default:
+					t.Fatalf("unexpected error type: %v", err)
+				}
+			} else {
+				metadata := pars.File{
+					Bucket:       "bucket",
+					Key:          test.key,
+					Version:      "etag",
+					ContentType:  test.contentType,
+					LastModified: &lastModified,
+				}
+				if test.file.ContentType != "" {
+					metadata.ContentType = test.file.ContentType
+				}
+
+				if !reflect.DeepEqual(file.Metadata, metadata) {
+					t.Errorf("incorrect metadata, received: %+v, expected: %+v", file.Metadata, metadata)
+				}
+				file.Metadata = pars.File{}
+
+				if !reflect.DeepEqual(file, test.file) {
+					t.Errorf("incorrect file, received: %+v, expected: %+v", file, test.file)
+				}
+
+				if s3Client.mockGetObjectInput != nil && aws.StringValue(s3Client.mockGetObjectInput.Range) != "bytes=0-1023" {
+					t.Errorf("incorrect range, received: %s, expected: bytes=0-1023", aws.StringValue(s3Client.mockGetObjectInput.Range))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package sniff
+
+import "fmt"
+
+const errorMessage = "package sniff: %s"
+
+// HeadObjectError wraps errors returned by s3.S3.HeadObject in the
+// sniff.Sniffer.Sniff method.
+type HeadObjectError struct {
+	err error
+}
+
+func (e *HeadObjectError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
+
+// GetObjectError wraps errors returned by s3.S3.GetObject and reading
+// its body in the sniff.Sniffer.Sniff method.
+type GetObjectError struct {
+	err error
+}
+
+func (e *GetObjectError) Error() string {
+	return fmt.Sprintf(errorMessage, e.err.Error())
+}
@@ -0,0 +1,32 @@
+package sniff
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHeadObjectError(t *testing.T) {
+	err := &HeadObjectError{
+		err: errors.New("mock head object error"),
+	}
+
+	recieved := err.Error()
+	expected := "package sniff: mock head object error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
+
+func TestGetObjectError(t *testing.T) {
+	err := &GetObjectError{
+		err: errors.New("mock get object error"),
+	}
+
+	recieved := err.Error()
+	expected := "package sniff: mock get object error"
+
+	if recieved != expected {
+		t.Errorf("incorrect error message, received: %s, expected: %s", recieved, expected)
+	}
+}
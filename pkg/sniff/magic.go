@@ -0,0 +1,150 @@
+package sniff
+
+import (
+	"bytes"
+	"mime"
+	"strings"
+
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+// sniffLength is the number of leading bytes of a file read to detect
+// its type. PDF readers accept a header anywhere in the first 1024
+// bytes.
+const sniffLength = 1024
+
+// signature matches the leading bytes of files of a content type.
+type signature struct {
+	contentType string
+	match       func(data []byte) bool
+}
+
+var signatures = []signature{
+	{
+		contentType: "application/pdf",
+		match: func(data []byte) bool {
+			return bytes.Contains(data, []byte("%PDF-"))
+		},
+	},
+	{
+		contentType: "image/png",
+		match:       prefix("\x89PNG\r\n\x1a\n"),
+	},
+	{
+		contentType: "image/jpeg",
+		match:       prefix("\xff\xd8\xff"),
+	},
+	{
+		contentType: "image/tiff",
+		match:       prefix("II*\x00", "MM\x00*"),
+	},
+	{
+		contentType: "image/gif",
+		match:       prefix("GIF87a", "GIF89a"),
+	},
+	{
+		contentType: "image/bmp",
+		match:       prefix("BM"),
+	},
+	{
+		contentType: "image/webp",
+		match: func(data []byte) bool {
+			return len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP"))
+		},
+	},
+}
+
+// extensionContentTypes maps lowercase file extensions to the content
+// types they are commonly used for.
+var extensionContentTypes = map[string]string{
+	"pdf":  "application/pdf",
+	"png":  "image/png",
+	"jpg":  "image/jpeg",
+	"jpeg": "image/jpeg",
+	"tif":  "image/tiff",
+	"tiff": "image/tiff",
+	"gif":  "image/gif",
+	"bmp":  "image/bmp",
+	"webp": "image/webp",
+}
+
+// contentTypeAliases maps nonstandard content types to the registered
+// type.
+var contentTypeAliases = map[string]string{
+	"application/x-pdf": "application/pdf",
+	"image/jpg":         "image/jpeg",
+	"image/pjpeg":       "image/jpeg",
+	"image/x-png":       "image/png",
+	"image/tif":         "image/tiff",
+	"image/x-ms-bmp":    "image/bmp",
+}
+
+func prefix(magics ...string) func(data []byte) bool {
+	return func(data []byte) bool {
+		for _, magic := range magics {
+			if bytes.HasPrefix(data, []byte(magic)) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// DetectContentType returns the content type of a file from its leading
+// bytes, falling back to the declared content type and then the
+// extension of the key for formats without a known signature. Declared
+// types and extensions of formats with a signature the data does not
+// carry are ignored so mislabeled files are not trusted. It returns an
+// empty string if the type is unknown.
+func DetectContentType(data []byte, declaredContentType, key string) string {
+	for _, signature := range signatures {
+		if signature.match(data) {
+			return signature.contentType
+		}
+	}
+
+	candidates := []string{
+		normalizeContentType(declaredContentType),
+		extensionContentTypes[pars.FileExtension(key)],
+	}
+
+	for _, candidate := range candidates {
+		if candidate != "" && !signed(candidate) {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+func signed(contentType string) bool {
+	for _, signature := range signatures {
+		if signature.contentType == contentType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeContentType returns the lowercase media type of the content
+// type without parameters, or an empty string for generic binary types
+// that say nothing about the format.
+func normalizeContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(contentType))
+	if err != nil {
+		return ""
+	}
+
+	if alias, ok := contentTypeAliases[mediaType]; ok {
+		mediaType = alias
+	}
+
+	switch mediaType {
+	case "application/octet-stream", "binary/octet-stream":
+		return ""
+	}
+
+	return mediaType
+}
@@ -0,0 +1,100 @@
+package sniff
+
+import "testing"
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		description         string
+		data                string
+		declaredContentType string
+		key                 string
+		contentType         string
+	}{
+		{
+			description: "pdf signature",
+			data:        "%PDF-1.7\n",
+			key:         "notapdf",
+			contentType: "application/pdf",
+		},
+		{
+			description: "pdf signature after leading bytes",
+			data:        "\x00\x00%PDF-1.4\n",
+			key:         "file",
+			contentType: "application/pdf",
+		},
+		{
+			description: "png signature",
+			data:        "\x89PNG\r\n\x1a\n\x00\x00",
+			key:         "file.pdf",
+			contentType: "image/png",
+		},
+		{
+			description: "jpeg signature with uppercase extension",
+			data:        "\xff\xd8\xff\xe0",
+			key:         "scan.JPG",
+			contentType: "image/jpeg",
+		},
+		{
+			description: "little endian tiff signature",
+			data:        "II*\x00\x08\x00",
+			key:         "scan.tif",
+			contentType: "image/tiff",
+		},
+		{
+			description: "big endian tiff signature",
+			data:        "MM\x00*\x00\x08",
+			key:         "scan.tif",
+			contentType: "image/tiff",
+		},
+		{
+			description: "webp signature",
+			data:        "RIFF\x00\x00\x00\x00WEBPVP8 ",
+			key:         "image",
+			contentType: "image/webp",
+		},
+		{
+			description:         "declared content type without signature",
+			data:                "\x00\x00\x00\x1cftypheic",
+			declaredContentType: "image/heic",
+			key:                 "photo",
+			contentType:         "image/heic",
+		},
+		{
+			description:         "declared content type contradicted by data",
+			data:                "<html></html>",
+			declaredContentType: "application/pdf; charset=binary",
+			key:                 "file",
+			contentType:         "",
+		},
+		{
+			description: "extension contradicted by data",
+			data:        "plain text",
+			key:         "notes.PDF",
+			contentType: "",
+		},
+		{
+			description:         "generic declared content type",
+			data:                "plain text",
+			declaredContentType: "binary/octet-stream",
+			key:                 "notapdf",
+			contentType:         "",
+		},
+		{
+			description:         "text declared content type",
+			data:                "plain text",
+			declaredContentType: "text/plain",
+			key:                 "notes.txt",
+			contentType:         "text/plain",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			contentType := DetectContentType([]byte(test.data), test.declaredContentType, test.key)
+
+			if contentType != test.contentType {
+				t.Errorf("incorrect content type, received: %q, expected: %q", contentType, test.contentType)
+			}
+		})
+	}
+}
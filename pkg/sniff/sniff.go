@@ -0,0 +1,87 @@
+package sniff
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/forstmeier/findfile/pkg/pars"
+)
+
+// Sniffer defines the methods for detecting the type of files before
+// they are parsed.
+type Sniffer interface {
+	Sniff(ctx context.Context, bucket, key string) (*File, error)
+}
+
+// File holds the detected type of a file. ContentType is empty if the
+// type could not be detected and Reason describes why files that are
+// not Supported are skipped. Metadata holds the location and metadata
+// of the file read while sniffing it, with the detected content type,
+// to pass to the parser.
+type File struct {
+	ContentType string    `json:"content_type"`
+	Supported   bool      `json:"supported"`
+	Reason      string    `json:"reason,omitempty"`
+	Metadata    pars.File `json:"-"`
+}
+
+// DefaultContentTypes are the content types supported when no allowed
+// types are configured, which are the formats AWS Textract parses.
+var DefaultContentTypes = []string{
+	"application/pdf",
+	"image/jpeg",
+	"image/png",
+	"image/tiff",
+}
+
+// ParseContentTypes splits a comma-separated list of content types
+// into normalized content types. It returns nil if the list is empty.
+func ParseContentTypes(value string) []string {
+	var contentTypes []string
+	for _, contentType := range strings.Split(value, ",") {
+		if contentType = normalizeContentType(contentType); contentType != "" {
+			contentTypes = append(contentTypes, contentType)
+		}
+	}
+
+	return contentTypes
+}
+
+// Types holds the set of supported content types.
+type Types map[string]bool
+
+// NewTypes returns the set of the provided content types, or
+// DefaultContentTypes if none are provided.
+func NewTypes(contentTypes []string) Types {
+	if len(contentTypes) == 0 {
+		contentTypes = DefaultContentTypes
+	}
+
+	types := Types{}
+	for _, contentType := range contentTypes {
+		if contentType = normalizeContentType(contentType); contentType != "" {
+			types[contentType] = true
+		}
+	}
+
+	return types
+}
+
+// File returns the file of the detected content type with whether it
+// is one of the supported types.
+func (t Types) File(contentType string) *File {
+	file := &File{
+		ContentType: contentType,
+		Supported:   t[contentType],
+	}
+
+	switch {
+	case contentType == "":
+		file.Reason = "unknown content type"
+	case !file.Supported:
+		file.Reason = fmt.Sprintf("unsupported content type %s", contentType)
+	}
+
+	return file
+}
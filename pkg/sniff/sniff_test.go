@@ -0,0 +1,84 @@
+package sniff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseContentTypes(t *testing.T) {
+	tests := []struct {
+		description  string
+		value        string
+		contentTypes []string
+	}{
+		{
+			description:  "empty value",
+			value:        "",
+			contentTypes: nil,
+		},
+		{
+			description:  "normalized content types",
+			value:        " Application/PDF , image/jpg,,image/gif ",
+			contentTypes: []string{"application/pdf", "image/jpeg", "image/gif"},
+		},
+		{
+			description:  "generic content types dropped",
+			value:        "application/octet-stream,image/png",
+			contentTypes: []string{"image/png"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			contentTypes := ParseContentTypes(test.value)
+
+			if !reflect.DeepEqual(contentTypes, test.contentTypes) {
+				t.Errorf("incorrect content types, received: %v, expected: %v", contentTypes, test.contentTypes)
+			}
+		})
+	}
+}
+
+func TestTypesFile(t *testing.T) {
+	types := NewTypes([]string{"image/JPG", "image/png"})
+
+	tests := []struct {
+		description string
+		contentType string
+		file        *File
+	}{
+		{
+			description: "supported content type",
+			contentType: "image/jpeg",
+			file: &File{
+				ContentType: "image/jpeg",
+				Supported:   true,
+			},
+		},
+		{
+			description: "unsupported content type",
+			contentType: "application/pdf",
+			file: &File{
+				ContentType: "application/pdf",
+				Reason:      "unsupported content type application/pdf",
+			},
+		},
+		{
+			description: "unknown content type",
+			contentType: "",
+			file: &File{
+				Reason: "unknown content type",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			file := types.File(test.contentType)
+
+			if !reflect.DeepEqual(file, test.file) {
+				t.Errorf("incorrect file, received: %+v, expected: %+v", file, test.file)
+			}
+		})
+	}
+}
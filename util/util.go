@@ -41,12 +41,17 @@ func SendResponse(statusCode int, payload interface{}, message string) (events.A
 			Message        string `json:"message"`
 			BucketsAdded   int    `json:"buckets_added"`
 			BucketsRemoved int    `json:"buckets_removed"`
+			FilesQueued    int    `json:"files_queued"`
 		}{
 			Message:        "success",
 			BucketsAdded:   t["buckets_added"],
 			BucketsRemoved: t["buckets_removed"],
+			FilesQueued:    t["files_queued"],
 		}
 
+	default:
+		body = payload
+
 	}
 
 	bodyBytes, err := json.Marshal(body)